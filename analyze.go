@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/clobrano/prow-helper/internal/config"
+	"github.com/clobrano/prow-helper/internal/junit"
+	"github.com/clobrano/prow-helper/internal/loggrep"
+)
+
+// DefaultAnalyzeMaxMatches is how many matching log lines analyze prints by
+// default.
+const DefaultAnalyzeMaxMatches = 50
+
+var flagAnalyzeGrep string
+var flagAnalyzeMaxMatches int
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze <artifacts-dir | prow-url>",
+	Short: "Summarize the JUnit results and log errors of a build",
+	Long: `analyze runs the built-in analyzers on a build's artifacts: a summary of its
+JUnit reports (junit*.xml) with the failed tests and their messages, and the
+lines of its logs (build-log.txt and *.log files) that match a pattern.
+
+Given a local directory, such as one downloaded earlier, analyze works fully
+offline. Given a Prow URL, the build is first downloaded into the destination
+directory, or an earlier download there is reused.
+
+Example:
+  prow-helper analyze ./artifacts/20240224-1030-job-name-12345
+  prow-helper analyze --grep 'timed out|OOMKilled' ./artifacts/job-name/12345`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAnalyze,
+}
+
+func init() {
+	analyzeCmd.Flags().StringVar(&flagAnalyzeGrep, "grep", loggrep.DefaultPattern, "Regular expression selecting the log lines to report")
+	analyzeCmd.Flags().IntVar(&flagAnalyzeMaxMatches, "max-matches", DefaultAnalyzeMaxMatches, "Maximum number of log lines to report (0 for all)")
+	analyzeCmd.Flags().StringVar(&flagDest, "dest", "", "Download destination directory, when given a Prow URL")
+	rootCmd.AddCommand(analyzeCmd)
+}
+
+func runAnalyze(cmd *cobra.Command, args []string) error {
+	re, err := regexp.Compile(flagAnalyzeGrep)
+	if err != nil {
+		return fmt.Errorf("invalid --grep: %w", err)
+	}
+
+	dir, err := resolveBuildDir(args[0])
+	if err != nil {
+		return err
+	}
+	return analyzeDir(os.Stdout, dir, re, flagAnalyzeMaxMatches)
+}
+
+// resolveBuildDir returns arg itself when it is a local directory, without
+// touching the network. Otherwise arg is taken as a Prow URL and the build is
+// fetched into the configured destination (see fetchBuild).
+func resolveBuildDir(arg string) (string, error) {
+	if info, err := os.Stat(arg); err == nil && info.IsDir() {
+		return arg, nil
+	}
+
+	cfg, err := config.Load(&config.Config{Dest: flagDest})
+	if err != nil {
+		return "", fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if reportConfigIssues(config.Validate(cfg)) {
+		return "", fmt.Errorf("invalid configuration")
+	}
+	applyConfig(cfg)
+	return fetchBuild(arg, cfg.Dest)
+}
+
+// analyzeDir writes the JUnit summary and the log lines matching re (at most
+// max, 0 for all) of the artifacts in dir to w.
+func analyzeDir(w io.Writer, dir string, re *regexp.Regexp, max int) error {
+	results, err := junit.ParseDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read JUnit reports: %w", err)
+	}
+	printJUnitSummary(w, results)
+
+	matches, err := loggrep.Grep(dir, re, max)
+	if err != nil {
+		return fmt.Errorf("failed to search logs: %w", err)
+	}
+	fmt.Fprintln(w)
+	if len(matches) == 0 {
+		fmt.Fprintln(w, "Logs: no matching lines")
+		return nil
+	}
+	fmt.Fprintln(w, "Logs:")
+	for _, m := range matches {
+		fmt.Fprintf(w, "  %s:%d: %s\n", m.File, m.Line, m.Text)
+	}
+	if max > 0 && len(matches) == max {
+		fmt.Fprintf(w, "  (stopped after %d lines, see --max-matches)\n", max)
+	}
+	return nil
+}
+
+// printJUnitSummary writes the test counts by status and the failed tests,
+// sorted by ID, with the first line of their message.
+func printJUnitSummary(w io.Writer, results junit.Results) {
+	if len(results) == 0 {
+		fmt.Fprintln(w, "Tests: no JUnit reports found")
+		return
+	}
+
+	counts := make(map[junit.Status]int)
+	var failed []junit.TestCase
+	for _, c := range results {
+		counts[c.Status]++
+		if c.Status == junit.StatusFailed {
+			failed = append(failed, c)
+		}
+	}
+	fmt.Fprintf(w, "Tests: %d passed, %d failed, %d skipped\n",
+		counts[junit.StatusPassed], counts[junit.StatusFailed], counts[junit.StatusSkipped])
+
+	sort.Slice(failed, func(i, j int) bool { return failed[i].ID() < failed[j].ID() })
+	for _, c := range failed {
+		fmt.Fprintf(w, "  FAILED  %s\n", c.ID())
+		if msg, _, _ := strings.Cut(strings.TrimSpace(c.Message), "\n"); msg != "" {
+			fmt.Fprintf(w, "          %s\n", msg)
+		}
+	}
+}
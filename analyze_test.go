@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/clobrano/prow-helper/internal/httpclient"
+	"github.com/clobrano/prow-helper/internal/loggrep"
+)
+
+// offlineTransport fails the test on any HTTP request.
+type offlineTransport struct{ t *testing.T }
+
+func (o offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	o.t.Errorf("unexpected HTTP request to %s", req.URL)
+	return nil, errors.New("network disabled in test")
+}
+
+func TestAnalyzeDir_Offline(t *testing.T) {
+	orig := httpclient.Client.Transport
+	httpclient.Client.Transport = offlineTransport{t}
+	defer func() { httpclient.Client.Transport = orig }()
+
+	dir, err := resolveBuildDir("testdata/analyze")
+	if err != nil {
+		t.Fatalf("resolveBuildDir() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := analyzeDir(&buf, dir, regexp.MustCompile(loggrep.DefaultPattern), 0); err != nil {
+		t.Fatalf("analyzeDir() error = %v", err)
+	}
+
+	want := `Tests: 2 passed, 1 failed, 1 skipped
+  FAILED  e2e: reboots a node
+          timed out waiting for node to be Ready
+
+Logs:
+  artifacts/e2e/node.log:2: worker-0 error: failed to pull image quay.io/example/app:latest
+  build-log.txt:2: INFO[2024-02-24T10:58:12Z] Step e2e-test failed after 28m12s.
+  build-log.txt:3: INFO[2024-02-24T10:58:13Z] Reporting job state 'failed'
+`
+	if buf.String() != want {
+		t.Errorf("analyzeDir() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestAnalyzeDir_MaxMatches(t *testing.T) {
+	var buf bytes.Buffer
+	if err := analyzeDir(&buf, "testdata/analyze", regexp.MustCompile(loggrep.DefaultPattern), 1); err != nil {
+		t.Fatalf("analyzeDir() error = %v", err)
+	}
+
+	want := `Logs:
+  artifacts/e2e/node.log:2: worker-0 error: failed to pull image quay.io/example/app:latest
+  (stopped after 1 lines, see --max-matches)
+`
+	if got := buf.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("analyzeDir() =\n%s\nwant suffix\n%s", got, want)
+	}
+}
+
+func TestAnalyzeDir_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := analyzeDir(&buf, t.TempDir(), regexp.MustCompile(loggrep.DefaultPattern), 0); err != nil {
+		t.Fatalf("analyzeDir() error = %v", err)
+	}
+
+	want := "Tests: no JUnit reports found\n\nLogs: no matching lines\n"
+	if buf.String() != want {
+		t.Errorf("analyzeDir() = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/clobrano/prow-helper/internal/watcher"
+)
+
+// classification is what the classify_cmd hook decided to do with a
+// finished job.
+type classification struct {
+	Notify   bool // send the job's notifications
+	Download bool // download its artifacts (and run the analysis command)
+}
+
+// String returns the actions of c as the hook would print them.
+func (c classification) String() string {
+	var actions []string
+	if c.Notify {
+		actions = append(actions, "notify")
+	}
+	if c.Download {
+		actions = append(actions, "download")
+	}
+	if len(actions) == 0 {
+		return "skip"
+	}
+	return strings.Join(actions, " ")
+}
+
+// classifySkipExit is the exit code with which the classify command says
+// the job is not interesting, like a false test in jq -e or grep -q.
+const classifySkipExit = 1
+
+// classifyJob runs the classify command cmdStr through sh -c for a job that
+// finished with status, and returns its decision. The command gets the JSON
+// form of result on stdin and its fields in JOB_NAME, BUILD_ID, JOB_RESULT
+// (PASSED or FAILED), JOB_URL and JOB_DURATION, along with PROW_RESULT, the
+// result of finished.json (e.g. ABORTED).
+//
+// Exiting with classifySkipExit skips the job: neither notified nor
+// downloaded. Exiting with 0 and no output keeps def, the decision without a
+// classify command; otherwise the words printed on stdout select the
+// actions: "notify", "download", or "skip" alone. Any other exit code, or an
+// unknown word, is an error.
+func classifyJob(cmdStr string, result watchResult, status *watcher.JobStatus, def classification) (classification, error) {
+	input, err := json.Marshal(result)
+	if err != nil {
+		return def, err
+	}
+	cmd := execCommand("sh", "-c", cmdStr)
+	cmd.Stdin = bytes.NewReader(append(input, '\n'))
+	cmd.Env = append(os.Environ(),
+		"JOB_NAME="+result.Job,
+		"BUILD_ID="+result.Build,
+		"JOB_RESULT="+result.Result,
+		"JOB_URL="+result.URL,
+		"JOB_DURATION="+result.Duration,
+		"PROW_RESULT="+status.Result,
+	)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == classifySkipExit {
+			return classification{}, nil
+		}
+		return def, fmt.Errorf("classify command failed: %w", err)
+	}
+	return parseClassification(stdout.String(), def)
+}
+
+// parseClassification parses the output of the classify command, returning
+// def when it is empty.
+func parseClassification(out string, def classification) (classification, error) {
+	words := strings.Fields(strings.ToLower(out))
+	if len(words) == 0 {
+		return def, nil
+	}
+	var c classification
+	for _, word := range words {
+		switch word {
+		case "notify":
+			c.Notify = true
+		case "download":
+			c.Download = true
+		case "skip":
+			if len(words) > 1 {
+				return def, fmt.Errorf("classify command printed %q: skip cannot be combined with other actions", strings.TrimSpace(out))
+			}
+		default:
+			return def, fmt.Errorf("classify command printed %q, expected notify, download or skip", word)
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/clobrano/prow-helper/internal/notifier"
+	"github.com/clobrano/prow-helper/internal/watcher"
+)
+
+func TestParseClassification(t *testing.T) {
+	def := classification{Notify: true}
+	tests := []struct {
+		out     string
+		want    classification
+		wantErr bool
+	}{
+		{out: "", want: def},
+		{out: "\n", want: def},
+		{out: "notify\n", want: classification{Notify: true}},
+		{out: "download", want: classification{Download: true}},
+		{out: "Notify download\n", want: classification{Notify: true, Download: true}},
+		{out: "skip\n", want: classification{}},
+		{out: "skip notify", want: def, wantErr: true},
+		{out: "maybe", want: def, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.out, func(t *testing.T) {
+			got, err := parseClassification(tt.out, def)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseClassification(%q) error = %v, wantErr %v", tt.out, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseClassification(%q) = %+v, want %+v", tt.out, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyJob(t *testing.T) {
+	result := watchResult{Result: "FAILED", Job: "periodic-ci-e2e", Build: "123", Duration: "1h0m0s"}
+	status := &watcher.JobStatus{Finished: true, Result: "ABORTED"}
+	def := classification{Notify: true}
+
+	tests := []struct {
+		name    string
+		cmd     string
+		want    classification
+		wantErr bool
+	}{
+		{name: "no output keeps the default", cmd: "true", want: def},
+		{name: "exit 1 skips", cmd: "false", want: classification{}},
+		{name: "stdout selects the actions", cmd: "echo notify download", want: classification{Notify: true, Download: true}},
+		{name: "prow result in the environment", cmd: `[ "$PROW_RESULT" != ABORTED ]`, want: classification{}},
+		{name: "fields in the environment", cmd: `[ "$JOB_RESULT $JOB_NAME $BUILD_ID" = "FAILED periodic-ci-e2e 123" ] && echo download`, want: classification{Download: true}},
+		{name: "result as JSON on stdin", cmd: `grep -q '"result":"FAILED"' && echo notify download`, want: classification{Notify: true, Download: true}},
+		{name: "other exit codes are errors", cmd: "exit 2", want: def, wantErr: true},
+		{name: "unknown action", cmd: "echo page-me", want: def, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := classifyJob(tt.cmd, result, status, def)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("classifyJob(%q) error = %v, wantErr %v", tt.cmd, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("classifyJob(%q) = %+v, want %+v", tt.cmd, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendNotificationWithConfig_Muted(t *testing.T) {
+	sent := captureNotifications(t)
+	decision, err := classifyJob("echo download", watchResult{Result: "FAILED"}, &watcher.JobStatus{}, classification{Notify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	muteNotifications = !decision.Notify
+	t.Cleanup(func() { muteNotifications = false })
+
+	sendNotificationWithConfig(notifier.EventJobFailed, "job", "message", false, "channel", true)
+	if len(*sent) != 0 {
+		t.Errorf("notifications sent = %v, want none once classified as %s", *sent, decision)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// statusColumn is a column --columns can add to the monitor status table.
+type statusColumn struct {
+	header string
+	// value returns the cell of an entry; withHost is set when the entries
+	// come from several Prow hosts.
+	value func(e *monitorEntry, withHost bool) string
+}
+
+// knownStatusColumns are the columns of the status table, by name.
+var knownStatusColumns = map[string]statusColumn{
+	"name": {"NAME", func(e *monitorEntry, withHost bool) string {
+		if withHost && e.host != "" {
+			return e.host + " " + e.metadata.JobName
+		}
+		return e.metadata.JobName
+	}},
+	"state":  {"STATE", func(e *monitorEntry, _ bool) string { return entryStatus(e) }},
+	"pr":     {"PR", func(e *monitorEntry, _ bool) string { return orDash(e.prRef) }},
+	"author": {"AUTHOR", func(e *monitorEntry, _ bool) string { return orDash(e.author) }},
+	"start": {"START", func(e *monitorEntry, _ bool) string {
+		if e.startTime.IsZero() {
+			return "-"
+		}
+		return e.startTime.Local().Format("Jan 02 15:04")
+	}},
+	"url": {"URL", func(e *monitorEntry, _ bool) string {
+		if e.url == "" && e.metadata != nil {
+			return orDash(e.metadata.RawURL) // no status page URL: fall back to the parsed one
+		}
+		return orDash(e.url)
+	}},
+}
+
+// statusColumnNames lists the names of knownStatusColumns, for help and
+// error messages.
+var statusColumnNames = []string{"name", "state", "pr", "author", "start", "url"}
+
+// statusColumns are the columns selected with --columns; when empty, the
+// status table has its default layout.
+var statusColumns []statusColumn
+
+// parseColumns parses a comma-separated list of column names. An empty spec
+// selects no columns.
+func parseColumns(spec string) ([]statusColumn, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	var columns []statusColumn
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		c, ok := knownStatusColumns[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q in --columns, expected some of: %s", name, strings.Join(statusColumnNames, ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("column %q appears twice in --columns", name)
+		}
+		seen[name] = true
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
+
+// renderColumns writes a header and one row per entry with the given
+// columns, aligned.
+func renderColumns(w io.Writer, entries []*monitorEntry, columns []statusColumn) {
+	// The states carry color escape sequences of the same length, so they
+	// do not disturb the alignment.
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	withHost := multipleHosts(entries)
+	cells := make([]string, len(columns))
+	for i, c := range columns {
+		cells[i] = c.header
+	}
+	fmt.Fprintf(tw, "  %s\n", strings.Join(cells, "\t"))
+	for _, e := range entries {
+		for i, c := range columns {
+			cells[i] = c.value(e, withHost)
+		}
+		fmt.Fprintf(tw, "  %s\n", strings.Join(cells, "\t"))
+	}
+	tw.Flush()
+}
+
+// orDash returns s, or "-" when it is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
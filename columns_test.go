@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+
+	"github.com/clobrano/prow-helper/internal/prowapi"
+	"github.com/clobrano/prow-helper/internal/watcher"
+)
+
+func TestParseColumns(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []string
+		wantErr bool
+	}{
+		{spec: "", want: nil},
+		{spec: "name,state", want: []string{"NAME", "STATE"}},
+		{spec: " URL , pr,author,start ", want: []string{"URL", "PR", "AUTHOR", "START"}},
+		{spec: "name,duration", wantErr: true},
+		{spec: "name,,state", wantErr: true},
+		{spec: "state,state", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			columns, err := parseColumns(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseColumns(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			var got []string
+			for _, c := range columns {
+				got = append(got, c.header)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("parseColumns(%q) = %v, want %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderColumns(t *testing.T) {
+	orig := color.NoColor
+	color.NoColor = true
+	t.Cleanup(func() { color.NoColor = orig })
+
+	entries, _, err := buildEntriesAndItems([]prowapi.Job{
+		{URL: jobURL("pull-ci-e2e", "100"), PRRef: "[org/repo PR1]", Author: "alice"},
+		{URL: jobURL("periodic-ci-metal", "200")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries[0].status = &watcher.JobStatus{Finished: true, Passed: true}
+	entries[1].url = "" // e.g. a job the status page listed without a URL
+
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{
+			spec: "state,name",
+			want: "  STATE      NAME\n" +
+				"  ✅ PASSED   pull-ci-e2e\n" +
+				"  🔄 RUNNING  periodic-ci-metal\n",
+		},
+		{
+			spec: "author,pr,name",
+			want: "  AUTHOR  PR              NAME\n" +
+				"  alice   [org/repo PR1]  pull-ci-e2e\n" +
+				"  -       -               periodic-ci-metal\n",
+		},
+		{
+			spec: "url",
+			want: "  URL\n" +
+				"  " + jobURL("pull-ci-e2e", "100") + "\n" +
+				"  " + jobURL("periodic-ci-metal", "200") + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			columns, err := parseColumns(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			renderColumns(&buf, entries, columns)
+			if buf.String() != tt.want {
+				t.Errorf("renderColumns(%s) =\n%s\nwant\n%s", tt.spec, buf.String(), tt.want)
+			}
+		})
+	}
+}
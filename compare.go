@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/clobrano/prow-helper/internal/config"
+	"github.com/clobrano/prow-helper/internal/downloader"
+	"github.com/clobrano/prow-helper/internal/junit"
+	"github.com/clobrano/prow-helper/internal/output"
+	"github.com/clobrano/prow-helper/internal/parser"
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare <prow-url-a> <prow-url-b>",
+	Short: "Compare the test results and artifacts of two builds",
+	Long: `compare downloads the artifacts of two builds (reusing earlier downloads in
+the destination directory) and reports how they differ: tests that passed in
+one build and failed in the other, tests that only ran in one of them, and
+files present in only one of them.
+
+Typically A is a passing build and B a failing build of the same job.
+
+Example:
+  prow-helper compare <passing-build-url> <failing-build-url>`,
+	Args: cobra.ExactArgs(2),
+	RunE: runCompare,
+}
+
+func init() {
+	compareCmd.Flags().StringVar(&flagDest, "dest", "", "Download destination directory")
+	rootCmd.AddCommand(compareCmd)
+}
+
+func runCompare(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(&config.Config{Dest: flagDest})
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if reportConfigIssues(config.Validate(cfg)) {
+		return fmt.Errorf("invalid configuration")
+	}
+	applyConfig(cfg)
+
+	dirs := make([]string, len(args))
+	for i, u := range args {
+		dirs[i], err = fetchBuild(u, cfg.Dest)
+		if err != nil {
+			return err
+		}
+	}
+
+	var tests [2]junit.Results
+	var files [2]map[string]downloader.FileInfo
+	for i, dir := range dirs {
+		if tests[i], err = junit.ParseDir(dir); err != nil {
+			return fmt.Errorf("failed to read JUnit reports: %w", err)
+		}
+		if files[i], err = downloader.ScanDir(dir); err != nil {
+			return fmt.Errorf("failed to list artifacts: %w", err)
+		}
+	}
+
+	fmt.Println()
+	printComparison(os.Stdout, junit.Compare(tests[0], tests[1]),
+		downloader.MissingFiles(files[0], files[1]), downloader.MissingFiles(files[1], files[0]))
+	return nil
+}
+
+// fetchBuild downloads the artifacts of the build at prowURL into its
+// destination under baseDest, or reuses them when that folder already
+// exists, and returns the folder.
+func fetchBuild(prowURL, baseDest string) (string, error) {
+	metadata, err := parser.ParseURL(prowURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse URL %s: %w", prowURL, err)
+	}
+	destPath := downloader.BuildDestinationPath(baseDest, metadata)
+
+	exists, err := downloader.CheckDestinationConflict(destPath)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		output.PrintField(os.Stdout, "Using existing", destPath)
+		return destPath, nil
+	}
+
+	output.PrintField(os.Stdout, "Downloading to", destPath)
+	if err := downloadArtifacts(parser.GCSPath(metadata), destPath, os.Stdout, os.Stderr); err != nil {
+		return "", fmt.Errorf("download of %s failed: %w", metadata.JobName, err)
+	}
+	return destPath, nil
+}
+
+// printComparison writes the differences between build A and build B.
+func printComparison(w io.Writer, tests junit.Diff, onlyA, onlyB []string) {
+	if tests.Empty() {
+		fmt.Fprintln(w, "Tests: no differences")
+	} else {
+		fmt.Fprintln(w, "Tests that differ (A -> B):")
+		for _, f := range tests.Flipped {
+			fmt.Fprintf(w, "  %-8s %s -> %s  %s\n", "CHANGED", f.From, f.To, f.ID)
+		}
+		for _, c := range tests.Removed {
+			fmt.Fprintf(w, "  %-8s %s  %s\n", "ONLY A", c.Status, c.ID())
+		}
+		for _, c := range tests.Added {
+			fmt.Fprintf(w, "  %-8s %s  %s\n", "ONLY B", c.Status, c.ID())
+		}
+	}
+
+	if len(onlyA) == 0 && len(onlyB) == 0 {
+		fmt.Fprintln(w, "Files: no differences")
+		return
+	}
+	printFileList(w, "Files only in A:", onlyA)
+	printFileList(w, "Files only in B:", onlyB)
+}
+
+func printFileList(w io.Writer, header string, paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	fmt.Fprintln(w, header)
+	for _, p := range paths {
+		fmt.Fprintf(w, "  %s\n", p)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/clobrano/prow-helper/internal/junit"
+)
+
+func TestPrintComparison(t *testing.T) {
+	diff := junit.Diff{
+		Flipped: []junit.Flip{{ID: "e2e: breaks", From: junit.StatusPassed, To: junit.StatusFailed}},
+		Removed: []junit.TestCase{{Suite: "e2e", Name: "gone", Status: junit.StatusPassed}},
+		Added:   []junit.TestCase{{Suite: "e2e", Name: "new", Status: junit.StatusFailed}},
+	}
+
+	var buf bytes.Buffer
+	printComparison(&buf, diff, nil, []string{"artifacts/must-gather.tar"})
+
+	want := `Tests that differ (A -> B):
+  CHANGED  passed -> failed  e2e: breaks
+  ONLY A   passed  e2e: gone
+  ONLY B   failed  e2e: new
+Files only in B:
+  artifacts/must-gather.tar
+`
+	if buf.String() != want {
+		t.Errorf("printComparison() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestPrintComparison_NoDifferences(t *testing.T) {
+	var buf bytes.Buffer
+	printComparison(&buf, junit.Diff{}, nil, nil)
+
+	want := "Tests: no differences\nFiles: no differences\n"
+	if buf.String() != want {
+		t.Errorf("printComparison() = %q, want %q", buf.String(), want)
+	}
+}
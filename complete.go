@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/clobrano/prow-helper/internal/config"
+	"github.com/clobrano/prow-helper/internal/prowapi"
+)
+
+// completionCacheTTL is how long the job names fetched for shell completion
+// are reused, so that pressing TAB repeatedly does not re-fetch the status
+// page every time.
+const completionCacheTTL = 2 * time.Minute
+
+// completionCachePath returns the file caching the last job names fetched
+// for shell completion. It is a variable so tests can redirect it.
+var completionCachePath = func() (string, error) {
+	dir, err := config.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "completion-jobs.json"), nil
+}
+
+// completionCache is the on-disk form of the cached job names of a page.
+type completionCache struct {
+	URL     string    `json:"url"`
+	Fetched time.Time `json:"fetched"`
+	Names   []string  `json:"names"`
+}
+
+// completeMonitorSelect completes the value of monitor --select with the
+// job names listed on the status pages given as arguments, and with the parts
+// of those names that tell jobs apart (e.g. a platform such as "metal").
+// Without an argument there is nothing to suggest.
+func completeMonitorSelect(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	seen := make(map[string]bool)
+	for _, pageURL := range args {
+		pageNames, err := completionJobNames(pageURL)
+		if err != nil {
+			cobra.CompDebugln("fetching jobs for completion: "+err.Error(), true)
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		for _, name := range pageNames {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return selectCandidates(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completionJobNames returns the distinct job names listed on pageURL,
+// reusing the cached ones when they were fetched for the same page less than
+// completionCacheTTL ago. A cache that cannot be read or written is ignored.
+func completionJobNames(pageURL string) ([]string, error) {
+	path, pathErr := completionCachePath()
+	if pathErr == nil {
+		var cache completionCache
+		if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &cache) == nil &&
+			cache.URL == pageURL && time.Since(cache.Fetched) < completionCacheTTL {
+			return cache.Names, nil
+		}
+	}
+
+	jobs, err := prowapi.FetchJobs(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(jobs))
+	var names []string
+	for _, j := range jobs {
+		if j.Name != "" && !seen[j.Name] {
+			seen[j.Name] = true
+			names = append(names, j.Name)
+		}
+	}
+	sort.Strings(names)
+
+	if pathErr == nil {
+		if data, err := json.Marshal(completionCache{URL: pageURL, Fetched: time.Now(), Names: names}); err == nil {
+			_ = os.WriteFile(path, data, 0644)
+		}
+	}
+	return names, nil
+}
+
+// selectCandidates returns the sorted --select suggestions starting with
+// toComplete: every job name, and every dash-separated part of a name that
+// appears in some job names but not all of them, since those are the ones
+// that narrow a selection.
+func selectCandidates(names []string, toComplete string) []string {
+	partCount := make(map[string]int)
+	for _, name := range names {
+		seen := make(map[string]bool)
+		for _, part := range strings.Split(name, "-") {
+			if part != "" && !seen[part] {
+				seen[part] = true
+				partCount[part]++
+			}
+		}
+	}
+
+	candidates := make(map[string]bool)
+	for _, name := range names {
+		candidates[name] = true
+	}
+	for part, n := range partCount {
+		if n < len(names) {
+			candidates[part] = true
+		}
+	}
+
+	var matches []string
+	for c := range candidates {
+		if strings.HasPrefix(c, toComplete) {
+			matches = append(matches, c)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
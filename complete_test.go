@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/clobrano/prow-helper/internal/httpclient"
+)
+
+var sampleJobNames = []string{
+	"periodic-ci-openshift-release-master-nightly-4.22-e2e-aws",
+	"periodic-ci-openshift-release-master-nightly-4.22-e2e-metal",
+	"pull-ci-openshift-api-master-e2e-metal",
+}
+
+func TestSelectCandidates(t *testing.T) {
+	tests := []struct {
+		name       string
+		toComplete string
+		want       []string
+	}{
+		{
+			name:       "platform parts",
+			toComplete: "m",
+			want:       []string{"metal"},
+		},
+		{
+			name:       "job names and distinguishing parts",
+			toComplete: "p",
+			want: []string{
+				"periodic",
+				"periodic-ci-openshift-release-master-nightly-4.22-e2e-aws",
+				"periodic-ci-openshift-release-master-nightly-4.22-e2e-metal",
+				"pull",
+				"pull-ci-openshift-api-master-e2e-metal",
+			},
+		},
+		{
+			name:       "parts shared by every job are not suggested",
+			toComplete: "e",
+		},
+		{
+			name:       "no match",
+			toComplete: "gcp",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectCandidates(sampleJobNames, tt.toComplete)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectCandidates(%q) = %v, want %v", tt.toComplete, got, tt.want)
+			}
+		})
+	}
+}
+
+// countingTransport wraps fakeProw and counts the prowjobs.js requests.
+type countingTransport struct {
+	fakeProw
+	fetches int
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Path == "/prowjobs.js" {
+		c.fetches++
+	}
+	return c.fakeProw.RoundTrip(req)
+}
+
+func TestCompleteMonitorSelect(t *testing.T) {
+	origTransport := httpclient.Client.Transport
+	defer func() { httpclient.Client.Transport = origTransport }()
+	transport := &countingTransport{}
+	httpclient.Client.Transport = transport
+
+	origPath := completionCachePath
+	defer func() { completionCachePath = origPath }()
+	cacheFile := filepath.Join(t.TempDir(), "completion-jobs.json")
+	completionCachePath = func() (string, error) { return cacheFile, nil }
+
+	got, directive := completeMonitorSelect(monitorCmd, nil, "")
+	if got != nil || directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("without a URL = %v, %v, want no candidates and no file completion", got, directive)
+	}
+
+	page := "https://prow.ci.openshift.org/"
+	for i := 0; i < 2; i++ {
+		got, directive = completeMonitorSelect(monitorCmd, []string{page}, "job-")
+		if want := []string{"job-a", "job-b"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("completeMonitorSelect() = %v, want %v", got, want)
+		}
+		if directive != cobra.ShellCompDirectiveNoFileComp {
+			t.Errorf("completeMonitorSelect() directive = %v, want NoFileComp", directive)
+		}
+	}
+	if transport.fetches != 1 {
+		t.Errorf("prowjobs.js fetched %d times, want 1 (cached)", transport.fetches)
+	}
+
+	// Another page is not served from the cache.
+	completeMonitorSelect(monitorCmd, []string{page + "?state=pending"}, "")
+	if transport.fetches != 2 {
+		t.Errorf("prowjobs.js fetched %d times, want 2", transport.fetches)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/clobrano/prow-helper/internal/config"
+	"github.com/clobrano/prow-helper/internal/output"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show every setting, its effective value and where it came from",
+	Long: `show prints every configuration setting with its effective value and the
+source that set it: cli (a flag), env (an environment variable), config (the
+--config file), project (the nearest .prow-helper.yaml), file (the XDG config
+file) or default. The config files read are listed first.
+
+The flags that set configuration on the main command are accepted, so you can
+check what a given invocation would use.
+
+Example:
+  prow-helper config show
+  prow-helper config show --dest /tmp/artifacts`,
+	Args: cobra.NoArgs,
+	RunE: runConfigShow,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the configuration for invalid settings",
+	Long: `validate loads the configuration and prints every problem found, with the
+setting and its value, without running anything. It exits with an error when
+any problem is not just a warning, so it can guard a shared config in a
+pre-commit hook.
+
+With --config, only that file is checked (over the defaults); otherwise the
+effective configuration, from every config file and the environment, is.
+
+Example:
+  prow-helper config validate
+  prow-helper config validate --config team/prow-helper.yaml`,
+	Args: cobra.NoArgs,
+	RunE: runConfigValidate,
+}
+
+func init() {
+	configShowCmd.Flags().StringVar(&flagDest, "dest", "", "Download destination directory")
+	configShowCmd.Flags().StringArrayVar(&flagAnalyzeCmds, "analyze-cmd", nil, "Command to run after download")
+	configShowCmd.Flags().BoolVar(&flagAnalyzeShell, "analyze-shell", false, "Run the analysis command through sh -c, allowing pipes, redirections and &&")
+	configShowCmd.Flags().StringVar(&flagNtfyChannel, "ntfy-channel", "", "ntfy.sh channel for notifications (comma-separated for several)")
+	configShowCmd.Flags().BoolVar(&flagNoDatePrefix, "no-date-prefix", false, "Keep the <job>/<build> folder name instead of prefixing it with the job's start date")
+	configShowCmd.Flags().StringVar(&flagDestCollision, "dest-collision-suffix", "", "Suffix of the new folder created next to an existing destination: timestamp or counter")
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	_, settings, err := config.LoadWithProvenance(cliConfig())
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	fmt.Println("Config files, highest precedence first:")
+	printConfigPaths(os.Stdout, config.ResolvePaths())
+	fmt.Println()
+
+	return printConfigSettings(os.Stdout, settings)
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	var cfg *config.Config
+	var err error
+	source := "the configuration"
+	if config.ConfigFile != "" {
+		cfg, err = config.LoadFile(config.ConfigFile)
+		source = config.ConfigFile
+	} else {
+		cfg, err = config.Load(nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if reportConfigIssues(config.Validate(cfg)) {
+		return fmt.Errorf("%s is invalid", source)
+	}
+	fmt.Printf("No errors in %s\n", source)
+	return nil
+}
+
+// printConfigPaths prints one "path (origin)" line per config file, noting
+// the files that do not exist.
+func printConfigPaths(w io.Writer, paths []config.ConfigPath) {
+	for _, p := range paths {
+		note := p.Origin
+		if !p.Exists() {
+			note += ", not found"
+		}
+		fmt.Fprintf(w, "%s (%s)\n", p.Path, note)
+	}
+}
+
+// printConfigSettings prints one aligned "key: value (source)" line per
+// setting. Settings no source sets are shown as unset.
+func printConfigSettings(w io.Writer, settings []config.Setting) error {
+	fields := output.NewFieldTable()
+	for _, s := range settings {
+		if s.Source == "" {
+			fields.Add(s.Key, "(unset)")
+			continue
+		}
+		value := s.Value
+		if value == "" {
+			value = `""`
+		}
+		fields.Add(s.Key, fmt.Sprintf("%s (%s)", value, s.Source))
+	}
+	return fields.Flush(w)
+}
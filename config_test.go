@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+
+	"github.com/clobrano/prow-helper/internal/config"
+)
+
+func TestPrintConfigSettings(t *testing.T) {
+	orig := color.NoColor
+	color.NoColor = true
+	t.Cleanup(func() { color.NoColor = orig })
+
+	var buf bytes.Buffer
+	err := printConfigSettings(&buf, []config.Setting{
+		{Key: "dest", Value: "/env/path", Source: config.SourceEnv},
+		{Key: "analyze_cmd", Value: "", Source: ""},
+		{Key: "ntfy_timeout", Value: "10s", Source: config.SourceDefault},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"        dest: /env/path (env)",
+		" analyze_cmd: (unset)",
+		"ntfy_timeout: 10s (default)",
+	}
+	got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("printConfigSettings() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestPrintConfigPaths(t *testing.T) {
+	dir := t.TempDir()
+	team := filepath.Join(dir, "team.yaml")
+	if err := os.WriteFile(team, []byte("dest: /team\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	xdg := filepath.Join(dir, "config.yaml")
+
+	var buf bytes.Buffer
+	printConfigPaths(&buf, []config.ConfigPath{
+		{Path: team, Source: config.SourceConfig, Origin: "--config"},
+		{Path: xdg, Source: config.SourceFile, Origin: "XDG"},
+	})
+	want := team + " (--config)\n" + xdg + " (XDG, not found)\n"
+	if buf.String() != want {
+		t.Errorf("printConfigPaths() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRunConfigValidate(t *testing.T) {
+	orig := config.ConfigFile
+	t.Cleanup(func() { config.ConfigFile = orig })
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "good", content: "dest: /tmp/artifacts\nntfy_timeout: 10s\n"},
+		{name: "warnings only", content: "analyze_cmd: prow-helper-no-such-analyzer\n"},
+		{name: "errors", content: "ntfy_timeout: soon\nntfy_channel: a/b\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.ConfigFile = filepath.Join(t.TempDir(), "team.yaml")
+			if err := os.WriteFile(config.ConfigFile, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := runConfigValidate(configValidateCmd, nil); (err != nil) != tt.wantErr {
+				t.Errorf("runConfigValidate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/clobrano/prow-helper/internal/config"
+	"github.com/clobrano/prow-helper/internal/downloader"
+	"github.com/clobrano/prow-helper/internal/parser"
+)
+
+var flagDownloadTemp bool
+
+// downloadArtifacts downloads the artifacts at a GCS path into a folder; tests
+// replace it to avoid running gsutil.
+var downloadArtifacts = downloader.Download
+
+var downloadCmd = &cobra.Command{
+	Use:   "download <prow-url>",
+	Short: "Download the artifacts of a build, without watching or analyzing it",
+	Long: `download fetches the artifacts of a build into its destination folder
+(reusing an earlier download there) and prints the folder.
+
+With --temp, the artifacts go into a new temporary directory instead, and the
+path of that directory is the only output: there are no prompts, no date
+prefix and no progress messages, so that the command composes with others.
+Removing the directory is up to the caller.
+
+Example:
+  dir=$(prow-helper download --temp <prow-url>)
+  my-tool "$dir"
+  rm -rf "$dir"`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDownload,
+}
+
+func init() {
+	downloadCmd.Flags().StringVar(&flagDest, "dest", "", "Download destination directory")
+	downloadCmd.Flags().BoolVar(&flagDownloadTemp, "temp", false,
+		"Download into a new temporary directory and print only its path")
+	downloadCmd.MarkFlagsMutuallyExclusive("dest", "temp")
+	rootCmd.AddCommand(downloadCmd)
+}
+
+func runDownload(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(&config.Config{Dest: flagDest})
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if reportConfigIssues(config.Validate(cfg)) {
+		return fmt.Errorf("invalid configuration")
+	}
+	applyConfig(cfg)
+
+	if flagDownloadTemp {
+		dir, err := downloadToTemp(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), dir)
+		return nil
+	}
+	dir, err := fetchBuild(args[0], cfg.Dest)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), dir)
+	return nil
+}
+
+// downloadToTemp downloads the artifacts of the build at prowURL into a new
+// directory under os.TempDir and returns it. gsutil's output is discarded:
+// its last lines are part of the error when the download fails, in which
+// case the directory is removed.
+func downloadToTemp(prowURL string) (string, error) {
+	metadata, err := parser.ParseURL(prowURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse URL %s: %w", prowURL, err)
+	}
+	dir, err := os.MkdirTemp("", "prow-helper-"+metadata.BuildID+"-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	if err := downloadArtifacts(parser.GCSPath(metadata), dir, io.Discard, io.Discard); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("download of %s failed: %w", metadata.JobName, err)
+	}
+	return dir, nil
+}
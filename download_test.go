@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clobrano/prow-helper/internal/config"
+)
+
+// fakeDownload replaces downloadArtifacts with a function that writes a
+// build log into the destination, or fails with err when it is not nil.
+func fakeDownload(t *testing.T, err error) {
+	t.Helper()
+	orig := downloadArtifacts
+	downloadArtifacts = func(gcsPath, destPath string, stdout, stderr io.Writer) error {
+		if err != nil {
+			return err
+		}
+		io.WriteString(stdout, "Copying "+gcsPath+"\n")
+		return os.WriteFile(filepath.Join(destPath, "build-log.txt"), []byte("build log\n"), 0644)
+	}
+	t.Cleanup(func() { downloadArtifacts = orig })
+}
+
+// runDownloadTemp runs download --temp on prowURL and returns what it printed.
+func runDownloadTemp(t *testing.T, prowURL string) (string, error) {
+	t.Helper()
+	origConfig, origTemp := config.ConfigFile, flagDownloadTemp
+	t.Cleanup(func() {
+		config.ConfigFile, flagDownloadTemp = origConfig, origTemp
+		downloadCmd.SetOut(nil)
+	})
+	config.ConfigFile = filepath.Join(t.TempDir(), "empty.yaml")
+	if err := os.WriteFile(config.ConfigFile, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	flagDownloadTemp = true
+
+	var out bytes.Buffer
+	downloadCmd.SetOut(&out)
+	err := runDownload(downloadCmd, []string{prowURL})
+	return out.String(), err
+}
+
+func TestRunDownload_Temp(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	fakeDownload(t, nil)
+
+	out, err := runDownloadTemp(t, jobURL("e2e-aws", "12345"))
+	if err != nil {
+		t.Fatalf("runDownload() error = %v", err)
+	}
+	if strings.Count(out, "\n") != 1 {
+		t.Fatalf("runDownload() printed %q, want only the directory", out)
+	}
+	dir := strings.TrimSuffix(out, "\n")
+	if filepath.Dir(dir) != tmp {
+		t.Errorf("directory %s is not under the temporary directory %s", dir, tmp)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "build-log.txt"))
+	if err != nil {
+		t.Fatalf("downloaded file missing: %v", err)
+	}
+	if string(data) != "build log\n" {
+		t.Errorf("build-log.txt = %q, want the downloaded content", data)
+	}
+}
+
+func TestRunDownload_TempFailureRemovesDir(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	fakeDownload(t, errors.New("access denied"))
+
+	out, err := runDownloadTemp(t, jobURL("e2e-aws", "12345"))
+	if err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("runDownload() error = %v, want the download error", err)
+	}
+	if out != "" {
+		t.Errorf("runDownload() printed %q on failure, want nothing", out)
+	}
+	if left, _ := os.ReadDir(tmp); len(left) != 0 {
+		t.Errorf("temporary directory not removed: %v", left)
+	}
+}
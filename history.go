@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/clobrano/prow-helper/internal/history"
+)
+
+var flagHistoryRun int
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List recently processed jobs, or re-run one",
+	Long: `history lists the Prow URLs prow-helper processed most recently, newest first,
+with when they were processed and how it went. The list is kept in the XDG
+state directory (~/.local/state/prow-helper/history.json by default).
+
+Use --run to process one of them again, by its index in the list.
+
+Example:
+  prow-helper history
+  prow-helper history --run 2`,
+	Args: cobra.NoArgs,
+	RunE: runHistory,
+}
+
+func init() {
+	historyCmd.Flags().IntVar(&flagHistoryRun, "run", 0, "Re-run the entry with this index (1 is the most recent)")
+	rootCmd.AddCommand(historyCmd)
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	path, err := history.DefaultPath()
+	if err != nil {
+		return err
+	}
+
+	if flagHistoryRun != 0 {
+		entry, err := history.Get(path, flagHistoryRun)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Re-running %s\n", entry.URL)
+		return executeWorkflow(entry.URL, false)
+	}
+
+	entries, err := history.List(path)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("No history yet.")
+		return nil
+	}
+	printHistory(os.Stdout, entries)
+	return nil
+}
+
+// printHistory writes entries as a numbered list matching history --run.
+func printHistory(w io.Writer, entries []history.Entry) {
+	for i, e := range entries {
+		fmt.Fprintf(w, "%3d  %s  %-16s  %s\n", i+1, e.Time.Local().Format("2006-01-02 15:04"), e.Outcome, e.URL)
+	}
+}
+
+// recordHistory appends the outcome of processing prowURL to the history.
+// Failing to record it never affects the workflow.
+func recordHistory(prowURL, outcome string) {
+	recordHistoryEntry(history.Entry{URL: prowURL, Outcome: outcome})
+}
+
+// recordDownloadHistory is recordHistory for a job whose artifacts were
+// downloaded to dest, recording the analysis command too so that reanalyze
+// can run it again. dest is stored absolute, as reanalyze may run from
+// another directory.
+func recordDownloadHistory(prowURL, outcome, dest, analyzeCmd string) {
+	if abs, err := filepath.Abs(dest); err == nil {
+		dest = abs
+	}
+	recordHistoryEntry(history.Entry{URL: prowURL, Outcome: outcome, Dest: dest, AnalyzeCmd: analyzeCmd})
+}
+
+// recordHistoryEntry appends e to the history, stamped with the current time.
+func recordHistoryEntry(e history.Entry) {
+	e.Time = time.Now()
+	path, err := history.DefaultPath()
+	if err == nil {
+		err = history.Append(path, e, history.MaxEntries)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record history: %v\n", err)
+	}
+}
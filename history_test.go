@@ -0,0 +1,26 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/clobrano/prow-helper/internal/history"
+)
+
+func TestPrintHistory(t *testing.T) {
+	at := time.Date(2026, 3, 4, 5, 6, 0, 0, time.Local)
+	entries := []history.Entry{
+		{URL: "https://prow.example.com/view/gs/b/logs/job/2", Time: at, Outcome: "analyzed"},
+		{URL: "https://prow.example.com/view/gs/b/logs/job/1", Time: at, Outcome: "download failed"},
+	}
+
+	var buf bytes.Buffer
+	printHistory(&buf, entries)
+
+	want := "  1  2026-03-04 05:06  analyzed          https://prow.example.com/view/gs/b/logs/job/2\n" +
+		"  2  2026-03-04 05:06  download failed   https://prow.example.com/view/gs/b/logs/job/1\n"
+	if buf.String() != want {
+		t.Errorf("printHistory() =\n%q\nwant\n%q", buf.String(), want)
+	}
+}
@@ -4,12 +4,20 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"syscall"
 
 	"github.com/mattn/go-shellwords"
+
+	"github.com/clobrano/prow-helper/internal/parser"
 )
 
+// Shell runs the analysis command through sh -c instead of executing it
+// directly, so that it can use pipes, redirections and &&. It is off by
+// default and set from the analyze_shell setting.
+var Shell bool
+
 // ExitError represents an error with an exit code.
 type ExitError struct {
 	ExitCode int
@@ -43,6 +51,39 @@ func ParseAnalyzeCommand(cmd string) (string, []string, error) {
 	return args[0], args[1:], nil
 }
 
+// ArtifactDirEnv is the environment variable holding the artifacts path in
+// the analysis command's environment. OpenShift CI tools read their
+// artifacts from it, so they work unmodified as analysis commands.
+const ArtifactDirEnv = "ARTIFACT_DIR"
+
+// analysisEnv returns the environment of the analysis command: this
+// process's, with ArtifactDirEnv set to artifactsPath.
+func analysisEnv(artifactsPath string) []string {
+	env := []string{ArtifactDirEnv + "=" + artifactsPath}
+	for _, kv := range os.Environ() {
+		// Drop an inherited value: with exec, the first one would win.
+		if !strings.HasPrefix(kv, ArtifactDirEnv+"=") {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
+
+// analysisCommand returns the program and arguments that run cmdStr on
+// artifactsPath: the parsed command with the path as last argument or, with
+// Shell, sh -c with the path (quoted) appended to the command line. An empty
+// name means there is nothing to run.
+func analysisCommand(cmdStr, artifactsPath string) (string, []string, error) {
+	if Shell {
+		return "sh", []string{"-c", cmdStr + " " + parser.QuoteCommand([]string{artifactsPath})}, nil
+	}
+	name, args, err := ParseAnalyzeCommand(cmdStr)
+	if err != nil || name == "" {
+		return "", nil, err
+	}
+	return name, append(args, artifactsPath), nil
+}
+
 // execSyscall is the low-level exec function used to replace the current process.
 // It is a variable so tests can override it without actually replacing the test process.
 var execSyscall = syscall.Exec
@@ -53,10 +94,12 @@ var osChdir = os.Chdir
 // RunAnalysis replaces the current process with the analysis command by using
 // the exec syscall. The artifacts path is appended as the last argument and the
 // working directory is changed to artifactsPath before exec, so any files the
-// analysis command writes land in the same folder as the downloaded data.
+// analysis command writes land in the same folder as the downloaded data. The
+// path, made absolute, is also exported as ARTIFACT_DIR.
 // Because exec replaces the process in-place (same PID, terminal, and process
 // group), the session runs directly in the current shell — plain terminal or
-// tmux pane — with no intermediate child process.
+// tmux pane — with no intermediate child process. With Shell, the process is
+// replaced by sh running the command line.
 //
 // RunAnalysis only returns when the exec itself fails (e.g. command not found).
 func RunAnalysis(cmdStr, artifactsPath string) error {
@@ -65,7 +108,15 @@ func RunAnalysis(cmdStr, artifactsPath string) error {
 		return nil
 	}
 
-	name, args, err := ParseAnalyzeCommand(cmdStr)
+	// The command runs in artifactsPath, where a relative path would not
+	// resolve: pass and export it absolute.
+	artifactsPath, err := filepath.Abs(artifactsPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve artifacts path: %w", err)
+	}
+
+	// The artifacts path is the last argument.
+	name, args, err := analysisCommand(cmdStr, artifactsPath)
 	if err != nil {
 		return err
 	}
@@ -74,9 +125,6 @@ func RunAnalysis(cmdStr, artifactsPath string) error {
 		return nil
 	}
 
-	// Append artifacts path as the last argument
-	args = append(args, artifactsPath)
-
 	// Resolve the full executable path
 	execPath, err := exec.LookPath(name)
 	if err != nil {
@@ -91,17 +139,22 @@ func RunAnalysis(cmdStr, artifactsPath string) error {
 
 	// Replace the current process with the analysis command.
 	// argv[0] is conventionally the program name, followed by the arguments.
-	return execSyscall(execPath, append([]string{name}, args...), os.Environ())
+	return execSyscall(execPath, append([]string{name}, args...), analysisEnv(artifactsPath))
 }
 
-// RunAnalysisWithIO executes the analysis command with custom IO streams.
+// RunAnalysisWithIO executes the analysis command with custom IO streams, in
+// the same directory and environment as RunAnalysis.
 // Useful for testing and background execution.
 func RunAnalysisWithIO(cmdStr, artifactsPath string, stdout, stderr *os.File) error {
 	if strings.TrimSpace(cmdStr) == "" {
 		return nil
 	}
 
-	name, args, err := ParseAnalyzeCommand(cmdStr)
+	artifactsPath, err := filepath.Abs(artifactsPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve artifacts path: %w", err)
+	}
+	name, args, err := analysisCommand(cmdStr, artifactsPath)
 	if err != nil {
 		return err
 	}
@@ -110,10 +163,9 @@ func RunAnalysisWithIO(cmdStr, artifactsPath string, stdout, stderr *os.File) er
 		return nil
 	}
 
-	args = append(args, artifactsPath)
-
 	cmd := exec.Command(name, args...)
 	cmd.Dir = artifactsPath
+	cmd.Env = analysisEnv(artifactsPath)
 	cmd.Stdout = stdout
 	cmd.Stderr = stderr
 
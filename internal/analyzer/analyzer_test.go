@@ -4,6 +4,7 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -294,3 +295,158 @@ echo "$1" > "` + outputFile + `"
 	}
 }
 
+// useShell turns Shell on for the duration of a test.
+func useShell(t *testing.T) {
+	t.Helper()
+	orig := Shell
+	Shell = true
+	t.Cleanup(func() { Shell = orig })
+}
+
+// devNull opens the null device for writing, to discard the output of a
+// command.
+func devNull(t *testing.T) *os.File {
+	t.Helper()
+	f, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestRunAnalysis_Shell(t *testing.T) {
+	useShell(t)
+	gotPath, gotArgv := mockExecSyscall(t, nil)
+	mockOsChdir(t)
+
+	artifactsPath := filepath.Join(t.TempDir(), "job's artifacts")
+	if err := RunAnalysis("my-tool | grep ERROR", artifactsPath); err != nil {
+		t.Fatalf("RunAnalysis() error = %v", err)
+	}
+	if filepath.Base(*gotPath) != "sh" {
+		t.Errorf("exec path = %q, want sh", *gotPath)
+	}
+	want := []string{"sh", "-c", "my-tool | grep ERROR '" + strings.ReplaceAll(artifactsPath, "'", `'\''`) + "'"}
+	if strings.Join(*gotArgv, "\x00") != strings.Join(want, "\x00") {
+		t.Errorf("argv = %q, want %q", *gotArgv, want)
+	}
+}
+
+func TestRunAnalysisWithIO_ShellPipeline(t *testing.T) {
+	useShell(t)
+	artifactsPath := t.TempDir()
+	if err := os.WriteFile(filepath.Join(artifactsPath, "build-log.txt"), []byte("ok\nERROR one\nok\nERROR two\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := devNull(t), devNull(t)
+
+	// The command runs in the artifacts folder, which is appended to the
+	// last command of the line.
+	cmd := "grep ERROR build-log.txt | wc -l > count.txt && ls"
+	if err := RunAnalysisWithIO(cmd, artifactsPath, stdout, stderr); err != nil {
+		t.Fatalf("RunAnalysisWithIO() error = %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(artifactsPath, "count.txt"))
+	if err != nil {
+		t.Fatalf("pipeline did not write its output: %v", err)
+	}
+	if strings.TrimSpace(string(got)) != "2" {
+		t.Errorf("pipeline counted %q, want 2", strings.TrimSpace(string(got)))
+	}
+}
+
+func TestRunAnalysisWithIO_ShellExitStatus(t *testing.T) {
+	useShell(t)
+	stdout, stderr := devNull(t), devNull(t)
+
+	// grep finds no match, so the && chain stops with its exit status.
+	err := RunAnalysisWithIO("echo no errors | grep -q ERROR && test -d", t.TempDir(), stdout, stderr)
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode != 1 {
+		t.Errorf("RunAnalysisWithIO() error = %v, want exit code 1", err)
+	}
+
+	if err := RunAnalysisWithIO("echo ERROR | grep -q ERROR && test -d", t.TempDir(), stdout, stderr); err != nil {
+		t.Errorf("RunAnalysisWithIO() error = %v, want nil", err)
+	}
+}
+
+func TestRunAnalysisWithIO_ArtifactDir(t *testing.T) {
+	t.Setenv(ArtifactDirEnv, "/inherited/value")
+	tmpDir := t.TempDir()
+	outputFile := filepath.Join(tmpDir, "output.txt")
+	scriptPath := filepath.Join(tmpDir, "test-script.sh")
+	script := "#!/bin/sh\nprintf '%s' \"$ARTIFACT_DIR\" > '" + outputFile + "'\n"
+	if err := os.WriteFile(scriptPath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	artifactsPath := t.TempDir()
+	if err := RunAnalysisWithIO(scriptPath, artifactsPath, devNull(t), devNull(t)); err != nil {
+		t.Fatalf("RunAnalysisWithIO() error = %v", err)
+	}
+	got, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != artifactsPath {
+		t.Errorf("ARTIFACT_DIR = %q, want %q", got, artifactsPath)
+	}
+}
+
+func TestRunAnalysis_ArtifactDir(t *testing.T) {
+	t.Setenv(ArtifactDirEnv, "/inherited/value")
+	mockOsChdir(t)
+	var env []string
+	orig := execSyscall
+	t.Cleanup(func() { execSyscall = orig })
+	execSyscall = func(_ string, _ []string, e []string) error {
+		env = e
+		return nil
+	}
+
+	if err := RunAnalysis("echo", "/artifacts/job/1"); err != nil {
+		t.Fatalf("RunAnalysis() error = %v", err)
+	}
+	var values []string
+	for _, kv := range env {
+		if strings.HasPrefix(kv, ArtifactDirEnv+"=") {
+			values = append(values, kv)
+		}
+	}
+	if len(values) != 1 || values[0] != "ARTIFACT_DIR=/artifacts/job/1" {
+		t.Errorf("exec environment has %q, want only ARTIFACT_DIR=/artifacts/job/1", values)
+	}
+}
+
+func TestRunAnalysis_RelativeArtifactsPath(t *testing.T) {
+	gotDir := mockOsChdir(t)
+	var argv, env []string
+	orig := execSyscall
+	t.Cleanup(func() { execSyscall = orig })
+	execSyscall = func(_ string, av []string, e []string) error {
+		argv, env = av, e
+		return nil
+	}
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.MkdirAll(filepath.Join("job", "1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := RunAnalysis("echo", filepath.Join("job", "1")); err != nil {
+		t.Fatalf("RunAnalysis() error = %v", err)
+	}
+
+	want := filepath.Join(dir, "job", "1")
+	if last := argv[len(argv)-1]; last != want {
+		t.Errorf("artifacts path in argv = %q, want the absolute %q", last, want)
+	}
+	if *gotDir != want {
+		t.Errorf("chdir target = %q, want %q", *gotDir, want)
+	}
+	if !slices.Contains(env, ArtifactDirEnv+"="+want) {
+		t.Errorf("exec environment lacks %s=%s", ArtifactDirEnv, want)
+	}
+}
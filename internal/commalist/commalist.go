@@ -0,0 +1,20 @@
+// Package commalist splits comma-separated settings such as ntfy channels,
+// Prow hosts or webhook events.
+//
+// It is shared by the config and notifier packages so that every list setting
+// accepts the same spacing and empty items.
+package commalist
+
+import "strings"
+
+// Split splits a comma-separated value into its trimmed, non-empty items.
+// Returns nil for an empty value.
+func Split(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,27 @@
+package commalist
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{name: "empty", value: "", want: nil},
+		{name: "only separators", value: " , ,", want: nil},
+		{name: "single", value: "alerts", want: []string{"alerts"}},
+		{name: "trimmed", value: " alerts , team-ci ", want: []string{"alerts", "team-ci"}},
+		{name: "empty items dropped", value: "alerts,,team-ci,", want: []string{"alerts", "team-ci"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Split(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
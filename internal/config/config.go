@@ -1,18 +1,86 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/adrg/xdg"
 	"gopkg.in/yaml.v3"
+
+	"github.com/clobrano/prow-helper/internal/commalist"
 )
 
+// ProjectConfigName is the name of the per-project config file, looked up in
+// the current directory and its parents.
+const ProjectConfigName = ".prow-helper.yaml"
+
 // Config holds the application configuration.
 type Config struct {
-	Dest        string `yaml:"dest"`         // Download destination directory
-	AnalyzeCmd  string `yaml:"analyze_cmd"`  // Command to run after download
-	NtfyChannel string `yaml:"ntfy_channel"` // ntfy.sh channel for notifications
+	Dest        string   `yaml:"dest"`         // Download destination directory
+	AnalyzeCmd  string   `yaml:"analyze_cmd"`  // Command to run after download
+	NtfyChannel string   `yaml:"ntfy_channel"` // ntfy.sh channels for notifications, comma-separated
+	ProwHosts   []string `yaml:"prow_hosts"`   // Prow hosts whose job URLs are accepted
+	ProwHost    string   `yaml:"prow_host"`    // Prow host accepted along with ProwHosts and looked up first, e.g. a self-hosted Prow
+	NtfyTimeout string   `yaml:"ntfy_timeout"` // Timeout for each ntfy.sh request (e.g. "10s")
+
+	AnalyzeShell string `yaml:"analyze_shell"` // "true" to run AnalyzeCmd through sh -c, for pipes and redirections
+	ClassifyCmd  string `yaml:"classify_cmd"`  // Shell command deciding whether a watched job is notified and downloaded
+
+	StartedFile  string `yaml:"started_file"`  // Artifact file holding the job start time
+	StartedField string `yaml:"started_field"` // Dot-delimited JSON path of the start time in StartedFile
+
+	DatePrefix      string `yaml:"date_prefix"`      // "false" to keep the <job>/<build> folder name after download
+	CollisionSuffix string `yaml:"collision_suffix"` // Suffix of a new folder next to an existing one: "timestamp" or "counter"
+
+	GCSHost    string `yaml:"gcs_host"`   // Host serving GCS objects, for mirrors (e.g. "gcs-mirror.example.com:8443")
+	GCSBase    string `yaml:"gcs_base"`   // Base URL serving GCS objects, overriding GCSHost (e.g. "https://mirror.example.com/storage")
+	Downloader string `yaml:"downloader"` // Download backend: "gsutil" or "http" (public buckets, no Cloud SDK)
+
+	NtfyEmail        string            `yaml:"ntfy_email"`         // Address ntfy.sh also forwards notifications to
+	NtfyExtraHeaders map[string]string `yaml:"ntfy_extra_headers"` // Additional ntfy.sh request headers (e.g. Tags, Priority, Call)
+
+	WebhookURL string `yaml:"webhook_url"` // URL receiving a JSON POST for each selected notification event
+	WebhookOn  string `yaml:"webhook_on"`  // Events posted to WebhookURL, comma-separated (default: completion and failure events)
+
+	Watchlists map[string]Watchlist `yaml:"watchlists"` // Named groups of jobs for monitor --list
+}
+
+// Watchlist is a named group of jobs that monitor --list watches: the job
+// URLs in URLs, and the jobs listed by the status page URLs in URLs whose
+// name matches Select (all of them when Select is empty).
+type Watchlist struct {
+	URLs   []string `yaml:"urls"`
+	Select string   `yaml:"select"`
+}
+
+// DatePrefixEnabled reports whether downloaded folders get the job's start
+// date as a prefix. Anything but a false boolean value keeps the default.
+func (c *Config) DatePrefixEnabled() bool {
+	enabled, err := strconv.ParseBool(c.DatePrefix)
+	return err != nil || enabled
+}
+
+// AllowedProwHosts returns the Prow hosts whose job URLs are accepted:
+// ProwHosts, preceded by ProwHost when it is set, so that jobs are looked up
+// on it.
+func (c *Config) AllowedProwHosts() []string {
+	if c.ProwHost == "" {
+		return c.ProwHosts
+	}
+	return appendNew([]string{c.ProwHost}, c.ProwHosts)
+}
+
+// AnalyzeShellEnabled reports whether the analysis command runs through a
+// shell. Anything but a true boolean value keeps it off.
+func (c *Config) AnalyzeShellEnabled() bool {
+	enabled, err := strconv.ParseBool(c.AnalyzeShell)
+	return err == nil && enabled
 }
 
 // DefaultConfig returns a Config with default values.
@@ -21,6 +89,19 @@ func DefaultConfig() *Config {
 		Dest:        ".",
 		AnalyzeCmd:  "",
 		NtfyChannel: "",
+		ProwHosts:   []string{"prow.ci.openshift.org"},
+		NtfyTimeout: "10s",
+
+		AnalyzeShell: "false",
+
+		StartedFile:  "started.json",
+		StartedField: "timestamp",
+
+		DatePrefix:      "true",
+		CollisionSuffix: "timestamp",
+
+		GCSHost:    "storage.googleapis.com",
+		Downloader: "gsutil",
 	}
 }
 
@@ -49,80 +130,289 @@ func LoadConfigFile(path string) (*Config, error) {
 	return &cfg, nil
 }
 
-// LoadEnvConfig loads configuration from environment variables.
-func LoadEnvConfig() *Config {
-	return &Config{
-		Dest:        os.Getenv("PROW_HELPER_DEST"),
-		AnalyzeCmd:  os.Getenv("PROW_HELPER_ANALYZE_CMD"),
-		NtfyChannel: os.Getenv("NTFY_CHANNEL"),
+// LoadFile loads the config file at path alone, over the defaults, without
+// the other config files or the environment. Unlike LoadConfigFile, a missing
+// file is an error.
+func LoadFile(path string) (*Config, error) {
+	if !(ConfigPath{Path: path}).Exists() {
+		return nil, fmt.Errorf("config file %s not found", path)
+	}
+	fileConfig, err := LoadConfigFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
 	}
+	cfg := MergeLayers(DefaultConfig(), fileConfig)
+	expandEnv(cfg)
+	return cfg, nil
 }
 
-// MergeConfig merges configurations with priority: cli > env > file > defaults.
-// Non-empty values from higher priority configs override lower priority values.
-func MergeConfig(cli, env, file, defaults *Config) *Config {
-	result := &Config{}
+// listKeys are the string settings that also accept a YAML list, which is
+// read as its comma-separated items.
+var listKeys = map[string]bool{"ntfy_channel": true, "webhook_on": true}
 
-	// Start with defaults
-	if defaults != nil {
-		result.Dest = defaults.Dest
-		result.AnalyzeCmd = defaults.AnalyzeCmd
-		result.NtfyChannel = defaults.NtfyChannel
+// UnmarshalYAML decodes a config file, accepting a YAML list for the
+// settings in listKeys.
+func (c *Config) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			key, val := value.Content[i], value.Content[i+1]
+			if !listKeys[key.Value] || val.Kind != yaml.SequenceNode {
+				continue
+			}
+			var items []string
+			if err := val.Decode(&items); err != nil {
+				return fmt.Errorf("%s: %w", key.Value, err)
+			}
+			value.Content[i+1] = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: strings.Join(items, ",")}
+		}
 	}
+	type plain Config
+	return value.Decode((*plain)(c))
+}
 
-	// Override with file config
-	if file != nil {
-		if file.Dest != "" {
-			result.Dest = file.Dest
-		}
-		if file.AnalyzeCmd != "" {
-			result.AnalyzeCmd = file.AnalyzeCmd
+// DefaultEnvPrefix is the prefix of the environment variables read by
+// LoadEnvConfig unless EnvPrefix is changed.
+const DefaultEnvPrefix = "PROW_HELPER_"
+
+// EnvPrefix is prepended to the upper-cased YAML key of a setting to name
+// its environment variable (e.g. PROW_HELPER_DEST for dest). It is set from
+// the --config-env-prefix flag.
+var EnvPrefix = DefaultEnvPrefix
+
+// legacyEnvNames maps YAML keys to the un-prefixed environment variables
+// they were read from before every name got a prefix. They are still read,
+// with lower priority than the prefixed name, when EnvPrefix is the default.
+var legacyEnvNames = map[string]string{
+	"ntfy_channel": "NTFY_CHANNEL",
+}
+
+// LoadEnvConfig loads configuration from environment variables named after
+// EnvPrefix.
+func LoadEnvConfig() *Config {
+	return LoadEnvConfigWithPrefix(EnvPrefix)
+}
+
+// LoadEnvConfigWithPrefix loads configuration from the environment variables
+// named prefix followed by the upper-cased YAML key of each setting. List
+// settings are comma-separated; map settings are only read from files.
+func LoadEnvConfigWithPrefix(prefix string) *Config {
+	cfg := &Config{}
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		value := os.Getenv(EnvName(prefix, key))
+		if legacy, ok := legacyEnvNames[key]; ok && value == "" && prefix == DefaultEnvPrefix {
+			value = os.Getenv(legacy)
 		}
-		if file.NtfyChannel != "" {
-			result.NtfyChannel = file.NtfyChannel
+		switch f := v.Field(i); f.Kind() {
+		case reflect.String:
+			f.SetString(value)
+		case reflect.Slice:
+			f.Set(reflect.ValueOf(commalist.Split(value)))
 		}
 	}
+	return cfg
+}
 
-	// Override with env config
-	if env != nil {
-		if env.Dest != "" {
-			result.Dest = env.Dest
-		}
-		if env.AnalyzeCmd != "" {
-			result.AnalyzeCmd = env.AnalyzeCmd
-		}
-		if env.NtfyChannel != "" {
-			result.NtfyChannel = env.NtfyChannel
-		}
+// EnvName returns the environment variable a setting is read from:
+// prefix followed by its upper-cased YAML key.
+func EnvName(prefix, key string) string {
+	return prefix + strings.ToUpper(key)
+}
+
+// NormalizeEnvPrefix validates an environment variable prefix and appends
+// the "_" separator when it is missing, so "TRIAGE" reads TRIAGE_DEST.
+func NormalizeEnvPrefix(prefix string) (string, error) {
+	if !envPrefixPattern.MatchString(prefix) {
+		return "", fmt.Errorf("invalid environment variable prefix %q: expected letters, digits and underscores, not starting with a digit", prefix)
+	}
+	if !strings.HasSuffix(prefix, "_") {
+		prefix += "_"
 	}
+	return prefix, nil
+}
 
-	// Override with CLI config
-	if cli != nil {
-		if cli.Dest != "" {
-			result.Dest = cli.Dest
-		}
-		if cli.AnalyzeCmd != "" {
-			result.AnalyzeCmd = cli.AnalyzeCmd
-		}
-		if cli.NtfyChannel != "" {
-			result.NtfyChannel = cli.NtfyChannel
+var envPrefixPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// appendNew returns list followed by the items not already in it, without
+// modifying list.
+func appendNew(list, items []string) []string {
+	list = slices.Clone(list)
+	for _, item := range items {
+		if !slices.Contains(list, item) {
+			list = append(list, item)
 		}
 	}
+	return list
+}
+
+// MergeConfig merges configurations with priority: cli > env > file > defaults.
+// Non-empty values from higher priority configs override lower priority values.
+func MergeConfig(cli, env, file, defaults *Config) *Config {
+	return MergeLayers(defaults, file, env, cli)
+}
 
+// MergeLayers merges configurations given from lowest to highest priority.
+// Non-empty values from later layers override earlier ones; nil layers are skipped.
+func MergeLayers(layers ...*Config) *Config {
+	result := &Config{}
+	for _, layer := range layers {
+		overlay(result, layer)
+	}
 	return result
 }
 
+// overlay copies the non-empty fields of src onto dst.
+func overlay(dst, src *Config) {
+	if src == nil {
+		return
+	}
+	if src.Dest != "" {
+		dst.Dest = src.Dest
+	}
+	if src.AnalyzeCmd != "" {
+		dst.AnalyzeCmd = src.AnalyzeCmd
+	}
+	if src.NtfyChannel != "" {
+		dst.NtfyChannel = src.NtfyChannel
+	}
+	if len(src.ProwHosts) > 0 {
+		// Configured hosts are accepted in addition to the default ones.
+		dst.ProwHosts = appendNew(dst.ProwHosts, src.ProwHosts)
+	}
+	if src.ProwHost != "" {
+		dst.ProwHost = src.ProwHost
+	}
+	if src.NtfyTimeout != "" {
+		dst.NtfyTimeout = src.NtfyTimeout
+	}
+	if src.AnalyzeShell != "" {
+		dst.AnalyzeShell = src.AnalyzeShell
+	}
+	if src.ClassifyCmd != "" {
+		dst.ClassifyCmd = src.ClassifyCmd
+	}
+	if src.StartedFile != "" {
+		dst.StartedFile = src.StartedFile
+	}
+	if src.StartedField != "" {
+		dst.StartedField = src.StartedField
+	}
+	if src.DatePrefix != "" {
+		dst.DatePrefix = src.DatePrefix
+	}
+	if src.CollisionSuffix != "" {
+		dst.CollisionSuffix = src.CollisionSuffix
+	}
+	if src.GCSHost != "" {
+		dst.GCSHost = src.GCSHost
+	}
+	if src.GCSBase != "" {
+		dst.GCSBase = src.GCSBase
+	}
+	if src.Downloader != "" {
+		dst.Downloader = src.Downloader
+	}
+	if src.NtfyEmail != "" {
+		dst.NtfyEmail = src.NtfyEmail
+	}
+	if src.WebhookURL != "" {
+		dst.WebhookURL = src.WebhookURL
+	}
+	if src.WebhookOn != "" {
+		dst.WebhookOn = src.WebhookOn
+	}
+	if len(src.NtfyExtraHeaders) > 0 {
+		dst.NtfyExtraHeaders = src.NtfyExtraHeaders
+	}
+	if len(src.Watchlists) > 0 {
+		dst.Watchlists = src.Watchlists
+	}
+}
+
+// FindProjectConfig looks for a ProjectConfigName file in dir and each of its
+// parent directories, and returns the path of the nearest one, or "" if none
+// exists.
+func FindProjectConfig(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	dir = filepath.Clean(dir)
+	for {
+		path := filepath.Join(dir, ProjectConfigName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
 // Load loads the full configuration by merging all sources.
 // cliConfig should contain values from command-line flags (can be nil).
+//
+// Priority, highest first: CLI flags, environment variables, the explicit
+// config file (ConfigFile or <EnvPrefix>CONFIG), the project config (the
+// nearest .prow-helper.yaml in the current directory or above), the XDG
+// config file, defaults. ResolvePaths lists the files.
 func Load(cliConfig *Config) (*Config, error) {
-	defaults := DefaultConfig()
-	envConfig := LoadEnvConfig()
+	cfg, _, err := LoadWithProvenance(cliConfig)
+	return cfg, err
+}
 
-	configPath := GetConfigPath()
-	fileConfig, err := LoadConfigFile(configPath)
-	if err != nil {
-		return nil, err
+// LoadWithProvenance is Load that also reports, for every setting, its
+// effective value and the source it came from (see MergeWithProvenance).
+func LoadWithProvenance(cliConfig *Config) (*Config, []Setting, error) {
+	return loadWithProvenance(cliConfig, ResolvePaths())
+}
+
+// load is Load with explicit XDG and project config file paths; projectPath
+// may be empty.
+func load(cliConfig *Config, configPath, projectPath string) (*Config, error) {
+	cfg, _, err := loadWithProvenance(cliConfig, resolvePaths(
+		ConfigPath{Path: projectPath, Source: SourceProject},
+		ConfigPath{Path: configPath, Source: SourceFile}))
+	return cfg, err
+}
+
+// loadWithProvenance is LoadWithProvenance with the config files to read,
+// highest precedence first (see ResolvePaths).
+func loadWithProvenance(cliConfig *Config, paths []ConfigPath) (*Config, []Setting, error) {
+	layers := []Layer{{SourceDefault, DefaultConfig()}}
+	for i := len(paths) - 1; i >= 0; i-- {
+		p := paths[i]
+		if p.Source == SourceConfig && !p.Exists() {
+			return nil, nil, fmt.Errorf("config file %s (%s) not found", p.Path, p.Origin)
+		}
+		fileConfig, err := LoadConfigFile(p.Path)
+		if err != nil {
+			if p.Source == SourceFile {
+				return nil, nil, err
+			}
+			return nil, nil, fmt.Errorf("%s: %w", p.Path, err)
+		}
+		layers = append(layers, Layer{p.Source, fileConfig})
 	}
+	layers = append(layers, Layer{SourceEnv, LoadEnvConfig()}, Layer{SourceCLI, cliConfig})
+
+	cfg, settings := MergeWithProvenance(layers...)
+	expandEnv(cfg)
+	for i, s := range fieldSettings(cfg) {
+		settings[i].Value = s.Value
+	}
+	return cfg, settings, nil
+}
 
-	return MergeConfig(cliConfig, envConfig, fileConfig, defaults), nil
+// expandEnv replaces $VAR and ${VAR} references in the string fields of cfg
+// with the corresponding environment variable values, so templated values
+// such as "ci-$USER-alerts" resolve to the intended ntfy topic.
+// AnalyzeCmd is left untouched: it is expanded later by the analyzer's
+// shell-word parser, which honours quoting.
+func expandEnv(cfg *Config) {
+	cfg.Dest = os.ExpandEnv(cfg.Dest)
+	cfg.NtfyChannel = os.ExpandEnv(cfg.NtfyChannel)
 }
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -63,6 +64,33 @@ analyze_cmd: "echo test"
 	}
 }
 
+func TestLoadConfigFile_NtfyChannelList(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"single", "ntfy_channel: me-3f9a81c2d7\n", "me-3f9a81c2d7"},
+		{"comma-separated", "ntfy_channel: me-3f9a81c2d7,team-8b1e44f0a2\n", "me-3f9a81c2d7,team-8b1e44f0a2"},
+		{"yaml list", "ntfy_channel:\n  - me-3f9a81c2d7\n  - team-8b1e44f0a2\ndest: /tmp\n", "me-3f9a81c2d7,team-8b1e44f0a2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := LoadConfigFile(configPath)
+			if err != nil {
+				t.Fatalf("LoadConfigFile() error = %v", err)
+			}
+			if cfg.NtfyChannel != tt.want {
+				t.Errorf("NtfyChannel = %q, want %q", cfg.NtfyChannel, tt.want)
+			}
+		})
+	}
+}
+
 func TestLoadConfigFile_NotExists(t *testing.T) {
 	cfg, err := LoadConfigFile("/nonexistent/path/config.yaml")
 	if err != nil {
@@ -146,6 +174,86 @@ func TestLoadEnvConfig_Empty(t *testing.T) {
 	}
 }
 
+func TestLoadEnvConfigWithPrefix(t *testing.T) {
+	t.Setenv("PROW_HELPER_DEST", "/default/path")
+	t.Setenv("NTFY_CHANNEL", "legacy-channel")
+	t.Setenv("TRIAGE_DEST", "/triage/path")
+	t.Setenv("TRIAGE_NTFY_CHANNEL", "triage-channel")
+	t.Setenv("TRIAGE_PROW_HOSTS", "prow.a.example.com,prow.b.example.com")
+	t.Setenv("TRIAGE_NTFY_EXTRA_HEADERS", "Tags=x")
+
+	cfg := LoadEnvConfigWithPrefix("TRIAGE_")
+	if cfg.Dest != "/triage/path" {
+		t.Errorf("Dest = %q, want /triage/path", cfg.Dest)
+	}
+	if cfg.NtfyChannel != "triage-channel" {
+		t.Errorf("NtfyChannel = %q, want triage-channel", cfg.NtfyChannel)
+	}
+	if strings.Join(cfg.ProwHosts, " ") != "prow.a.example.com prow.b.example.com" {
+		t.Errorf("ProwHosts = %v, want both hosts", cfg.ProwHosts)
+	}
+	if cfg.NtfyExtraHeaders != nil {
+		t.Errorf("NtfyExtraHeaders = %v, want it only read from files", cfg.NtfyExtraHeaders)
+	}
+
+	// The legacy NTFY_CHANNEL is not read under a custom prefix.
+	t.Setenv("TRIAGE_NTFY_CHANNEL", "")
+	if cfg := LoadEnvConfigWithPrefix("TRIAGE_"); cfg.NtfyChannel != "" {
+		t.Errorf("NtfyChannel = %q, want NTFY_CHANNEL ignored under a custom prefix", cfg.NtfyChannel)
+	}
+}
+
+func TestLoadEnvConfig_LegacyNtfyChannel(t *testing.T) {
+	t.Setenv("NTFY_CHANNEL", "legacy-channel")
+	t.Setenv("PROW_HELPER_NTFY_CHANNEL", "")
+	if cfg := LoadEnvConfig(); cfg.NtfyChannel != "legacy-channel" {
+		t.Errorf("NtfyChannel = %q, want the NTFY_CHANNEL fallback", cfg.NtfyChannel)
+	}
+
+	t.Setenv("PROW_HELPER_NTFY_CHANNEL", "prefixed-channel")
+	if cfg := LoadEnvConfig(); cfg.NtfyChannel != "prefixed-channel" {
+		t.Errorf("NtfyChannel = %q, want PROW_HELPER_NTFY_CHANNEL to win", cfg.NtfyChannel)
+	}
+}
+
+func TestLoadEnvConfig_UsesEnvPrefix(t *testing.T) {
+	orig := EnvPrefix
+	t.Cleanup(func() { EnvPrefix = orig })
+	EnvPrefix = "TRIAGE_"
+	t.Setenv("TRIAGE_GCS_HOST", "gcs-mirror.example.com")
+
+	if cfg := LoadEnvConfig(); cfg.GCSHost != "gcs-mirror.example.com" {
+		t.Errorf("GCSHost = %q, want gcs-mirror.example.com", cfg.GCSHost)
+	}
+}
+
+func TestNormalizeEnvPrefix(t *testing.T) {
+	tests := []struct {
+		prefix  string
+		want    string
+		wantErr bool
+	}{
+		{prefix: "PROW_HELPER_", want: "PROW_HELPER_"},
+		{prefix: "TRIAGE", want: "TRIAGE_"},
+		{prefix: "_ci2_", want: "_ci2_"},
+		{prefix: "", wantErr: true},
+		{prefix: "2FAST", wantErr: true},
+		{prefix: "MY-APP", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			got, err := NormalizeEnvPrefix(tt.prefix)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeEnvPrefix(%q) error = %v, wantErr %v", tt.prefix, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeEnvPrefix(%q) = %q, want %q", tt.prefix, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestMergeConfig(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -215,3 +323,298 @@ func TestMergeConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestLoad_ExpandsEnvInNtfyChannel(t *testing.T) {
+	t.Setenv("USER", "tester")
+
+	cfg, err := Load(&Config{NtfyChannel: "ci-$USER"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.NtfyChannel != "ci-tester" {
+		t.Errorf("Load().NtfyChannel = %v, want ci-tester", cfg.NtfyChannel)
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("USER", "tester")
+	t.Setenv("ARTIFACTS_ROOT", "/data")
+
+	cfg := &Config{
+		Dest:        "${ARTIFACTS_ROOT}/prow",
+		AnalyzeCmd:  "echo $USER",
+		NtfyChannel: "ci-${USER}-alerts",
+	}
+	expandEnv(cfg)
+
+	if cfg.Dest != "/data/prow" {
+		t.Errorf("expandEnv().Dest = %v, want /data/prow", cfg.Dest)
+	}
+	if cfg.NtfyChannel != "ci-tester-alerts" {
+		t.Errorf("expandEnv().NtfyChannel = %v, want ci-tester-alerts", cfg.NtfyChannel)
+	}
+	if cfg.AnalyzeCmd != "echo $USER" {
+		t.Errorf("expandEnv().AnalyzeCmd = %v, want it left unexpanded", cfg.AnalyzeCmd)
+	}
+}
+
+func TestProwHostsLayering(t *testing.T) {
+	t.Setenv("PROW_HELPER_PROW_HOSTS", " prow.a.example.com, ,prow.b.example.com ")
+	env := LoadEnvConfig()
+	want := []string{"prow.a.example.com", "prow.b.example.com"}
+	if strings.Join(env.ProwHosts, ",") != strings.Join(want, ",") {
+		t.Errorf("LoadEnvConfig().ProwHosts = %v, want %v", env.ProwHosts, want)
+	}
+
+	merged := MergeConfig(nil, nil, nil, DefaultConfig())
+	if len(merged.ProwHosts) != 1 || merged.ProwHosts[0] != "prow.ci.openshift.org" {
+		t.Errorf("default ProwHosts = %v, want [prow.ci.openshift.org]", merged.ProwHosts)
+	}
+
+	file := &Config{ProwHosts: []string{"prow.ci.openshift.org", "prow.internal.example.com"}}
+	merged = MergeConfig(nil, &Config{}, file, DefaultConfig())
+	if len(merged.ProwHosts) != 2 || merged.ProwHosts[1] != "prow.internal.example.com" {
+		t.Errorf("file ProwHosts not applied: %v", merged.ProwHosts)
+	}
+
+	// Configured hosts are added to the default one, each listed once.
+	file = &Config{ProwHosts: []string{"prow.internal.example.com"}}
+	merged = MergeConfig(nil, env, file, DefaultConfig())
+	want = []string{"prow.ci.openshift.org", "prow.internal.example.com", "prow.a.example.com", "prow.b.example.com"}
+	if strings.Join(merged.ProwHosts, ",") != strings.Join(want, ",") {
+		t.Errorf("merged ProwHosts = %v, want %v", merged.ProwHosts, want)
+	}
+	if strings.Join(DefaultConfig().ProwHosts, ",") != "prow.ci.openshift.org" {
+		t.Errorf("DefaultConfig().ProwHosts modified by the merge: %v", DefaultConfig().ProwHosts)
+	}
+}
+
+func TestAllowedProwHosts(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want []string
+	}{
+		{name: "prow_hosts only", cfg: Config{ProwHosts: []string{"prow.ci.openshift.org", "prow.b.example.com"}},
+			want: []string{"prow.ci.openshift.org", "prow.b.example.com"}},
+		{name: "prow_host first", cfg: Config{ProwHost: "prow.a.example.com", ProwHosts: []string{"prow.ci.openshift.org", "prow.b.example.com"}},
+			want: []string{"prow.a.example.com", "prow.ci.openshift.org", "prow.b.example.com"}},
+		{name: "prow_host listed once", cfg: Config{ProwHost: "prow.b.example.com", ProwHosts: []string{"prow.ci.openshift.org", "prow.b.example.com"}},
+			want: []string{"prow.b.example.com", "prow.ci.openshift.org"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.AllowedProwHosts(); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("AllowedProwHosts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFile_ProwHosts(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	content := "prow_hosts:\n  - prow.ci.openshift.org\n  - prow.internal.example.com\n"
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	cfg, err := LoadConfigFile(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfigFile() error = %v", err)
+	}
+	if len(cfg.ProwHosts) != 2 || cfg.ProwHosts[1] != "prow.internal.example.com" {
+		t.Errorf("LoadConfigFile().ProwHosts = %v", cfg.ProwHosts)
+	}
+}
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create config dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+}
+
+func TestFindProjectConfig(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "team", "repo", "sub")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("Failed to create dirs: %v", err)
+	}
+
+	if got := FindProjectConfig(nested); got != "" {
+		t.Errorf("FindProjectConfig() = %q, want empty when no project config exists", got)
+	}
+
+	teamConfig := filepath.Join(root, "team", ProjectConfigName)
+	writeConfig(t, teamConfig, "dest: /team\n")
+	if got := FindProjectConfig(nested); got != teamConfig {
+		t.Errorf("FindProjectConfig() = %q, want %q", got, teamConfig)
+	}
+
+	// The nearest file wins.
+	repoConfig := filepath.Join(root, "team", "repo", ProjectConfigName)
+	writeConfig(t, repoConfig, "dest: /repo\n")
+	if got := FindProjectConfig(nested); got != repoConfig {
+		t.Errorf("FindProjectConfig() = %q, want %q", got, repoConfig)
+	}
+}
+
+func TestLoad_LayeringOrder(t *testing.T) {
+	t.Setenv("PROW_HELPER_DEST", "")
+	t.Setenv("PROW_HELPER_ANALYZE_CMD", "")
+	t.Setenv("NTFY_CHANNEL", "")
+
+	dir := t.TempDir()
+	xdgPath := filepath.Join(dir, "xdg", "config.yaml")
+	projectPath := filepath.Join(dir, "project", ProjectConfigName)
+	writeConfig(t, xdgPath, "dest: /xdg\nanalyze_cmd: xdg-cmd\nntfy_channel: xdg-channel\n")
+	writeConfig(t, projectPath, "dest: /project\nanalyze_cmd: project-cmd\n")
+
+	// The project file overrides the XDG file; unset fields fall through.
+	cfg, err := load(nil, xdgPath, projectPath)
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if cfg.Dest != "/project" || cfg.AnalyzeCmd != "project-cmd" || cfg.NtfyChannel != "xdg-channel" {
+		t.Errorf("load() = %+v, want project values over XDG ones", cfg)
+	}
+
+	// Environment variables override the project file.
+	t.Setenv("PROW_HELPER_DEST", "/env")
+	cfg, err = load(nil, xdgPath, projectPath)
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if cfg.Dest != "/env" || cfg.AnalyzeCmd != "project-cmd" {
+		t.Errorf("load() = %+v, want env over project", cfg)
+	}
+
+	// CLI flags override everything.
+	cfg, err = load(&Config{Dest: "/cli"}, xdgPath, projectPath)
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if cfg.Dest != "/cli" {
+		t.Errorf("load().Dest = %q, want /cli", cfg.Dest)
+	}
+
+	// Without a project file the XDG file applies.
+	t.Setenv("PROW_HELPER_DEST", "")
+	cfg, err = load(nil, xdgPath, "")
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if cfg.Dest != "/xdg" || cfg.AnalyzeCmd != "xdg-cmd" {
+		t.Errorf("load() = %+v, want XDG values", cfg)
+	}
+}
+
+func TestLoad_InvalidProjectConfig(t *testing.T) {
+	dir := t.TempDir()
+	projectPath := filepath.Join(dir, ProjectConfigName)
+	writeConfig(t, projectPath, "invalid: yaml: content:")
+
+	_, err := load(nil, filepath.Join(dir, "missing.yaml"), projectPath)
+	if err == nil {
+		t.Fatal("load() should fail for an invalid project config")
+	}
+	if !strings.Contains(err.Error(), projectPath) {
+		t.Errorf("load() error = %v, should name the project config", err)
+	}
+}
+
+func TestDatePrefixEnabled(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"", true},
+		{"true", true},
+		{"false", false},
+		{"0", false},
+		{"bogus", true},
+	}
+	for _, tt := range tests {
+		if got := (&Config{DatePrefix: tt.value}).DatePrefixEnabled(); got != tt.want {
+			t.Errorf("DatePrefixEnabled() with %q = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+	if !DefaultConfig().DatePrefixEnabled() {
+		t.Error("date prefix should be enabled by default")
+	}
+}
+
+func TestAnalyzeShellEnabled(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"", false},
+		{"true", true},
+		{"1", true},
+		{"false", false},
+		{"bogus", false},
+	}
+	for _, tt := range tests {
+		if got := (&Config{AnalyzeShell: tt.value}).AnalyzeShellEnabled(); got != tt.want {
+			t.Errorf("AnalyzeShellEnabled() with %q = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+	if DefaultConfig().AnalyzeShellEnabled() {
+		t.Error("analyze_shell should be off by default")
+	}
+}
+
+func TestLoadConfigFile_DatePrefix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("date_prefix: false\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFile() error = %v", err)
+	}
+	if cfg.DatePrefixEnabled() {
+		t.Errorf("date_prefix: false loaded as %q, want disabled", cfg.DatePrefix)
+	}
+}
+
+func TestMergeLayers_GCSHost(t *testing.T) {
+	env := &Config{GCSHost: "gcs-mirror.example.com"}
+	cfg := MergeLayers(DefaultConfig(), &Config{}, env)
+	if cfg.GCSHost != "gcs-mirror.example.com" {
+		t.Errorf("GCSHost = %q, want the environment value", cfg.GCSHost)
+	}
+	if got := MergeLayers(DefaultConfig()).GCSHost; got != "storage.googleapis.com" {
+		t.Errorf("default GCSHost = %q, want storage.googleapis.com", got)
+	}
+}
+
+func TestLoadConfigFile_Watchlists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `watchlists:
+  nightly-metal:
+    urls:
+      - https://prow.ci.openshift.org/?job=*metal*
+    select: e2e
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFile() error = %v", err)
+	}
+	want := map[string]Watchlist{"nightly-metal": {URLs: []string{"https://prow.ci.openshift.org/?job=*metal*"}, Select: "e2e"}}
+	if !reflect.DeepEqual(cfg.Watchlists, want) {
+		t.Errorf("Watchlists = %+v, want %+v", cfg.Watchlists, want)
+	}
+
+	merged := MergeLayers(DefaultConfig(), cfg, &Config{})
+	if !reflect.DeepEqual(merged.Watchlists, want) {
+		t.Errorf("MergeLayers() Watchlists = %+v, want %+v", merged.Watchlists, want)
+	}
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/adrg/xdg"
+)
+
+// appName is the directory name used under every XDG base directory.
+const appName = "prow-helper"
+
+// StateDir returns $XDG_STATE_HOME/prow-helper (by default
+// ~/.local/state/prow-helper), creating it if needed. It holds data that
+// should persist between runs but is not configuration, such as logs and
+// history.
+func StateDir() (string, error) {
+	return ensureDir(filepath.Join(xdg.StateHome, appName))
+}
+
+// CacheDir returns $XDG_CACHE_HOME/prow-helper (by default
+// ~/.cache/prow-helper), creating it if needed. It holds data that can be
+// recomputed or re-downloaded at any time.
+func CacheDir() (string, error) {
+	return ensureDir(filepath.Join(xdg.CacheHome, appName))
+}
+
+// ensureDir creates dir (and its parents) if it does not exist and returns it.
+func ensureDir(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	return dir, nil
+}
+
+// ErrUnsafeDestination is returned by CheckDestination for a download
+// destination that would put artifacts somewhere they do not belong.
+var ErrUnsafeDestination = errors.New("unsafe download destination")
+
+// CheckDestination reports whether dest, the base download directory, is a
+// place where artifacts must not be written: the filesystem root, the home
+// directory itself, the XDG config directory, or prow-helper's own state or
+// cache directory (or anything inside those). A leading "~/" is expanded.
+func CheckDestination(dest string) error {
+	path, err := absPath(dest)
+	if err != nil {
+		return err
+	}
+
+	if path == string(filepath.Separator) {
+		return fmt.Errorf("%w: %s is the filesystem root", ErrUnsafeDestination, path)
+	}
+	if home, err := os.UserHomeDir(); err == nil && path == filepath.Clean(home) {
+		return fmt.Errorf("%w: %s is the home directory", ErrUnsafeDestination, path)
+	}
+
+	guarded := []struct{ dir, what string }{
+		{xdg.ConfigHome, "the configuration directory"},
+		{filepath.Join(xdg.StateHome, appName), "prow-helper's state directory"},
+		{filepath.Join(xdg.CacheHome, appName), "prow-helper's cache directory"},
+	}
+	for _, g := range guarded {
+		if isWithin(path, filepath.Clean(g.dir)) {
+			return fmt.Errorf("%w: %s is inside %s (%s)", ErrUnsafeDestination, dest, g.what, g.dir)
+		}
+	}
+	return nil
+}
+
+// absPath expands a leading "~/" and returns the cleaned absolute form of path.
+func absPath(path string) (string, error) {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+	return filepath.Abs(path)
+}
+
+// isWithin reports whether path is dir or lies below it.
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
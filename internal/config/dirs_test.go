@@ -0,0 +1,107 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adrg/xdg"
+)
+
+// setXDGHome points an XDG base directory variable at a temporary directory
+// and reloads the xdg package, which otherwise reads the environment only once.
+func setXDGHome(t *testing.T, env string) string {
+	t.Helper()
+	dir := t.TempDir()
+	// Registered first so it runs after t.Setenv restores the variable.
+	t.Cleanup(xdg.Reload)
+	t.Setenv(env, dir)
+	xdg.Reload()
+	return dir
+}
+
+func TestStateDir(t *testing.T) {
+	root := setXDGHome(t, "XDG_STATE_HOME")
+
+	dir, err := StateDir()
+	if err != nil {
+		t.Fatalf("StateDir() error = %v", err)
+	}
+	if want := filepath.Join(root, "prow-helper"); dir != want {
+		t.Errorf("StateDir() = %q, want %q", dir, want)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Errorf("StateDir() should create %s", dir)
+	}
+}
+
+func TestCacheDir(t *testing.T) {
+	root := setXDGHome(t, "XDG_CACHE_HOME")
+
+	dir, err := CacheDir()
+	if err != nil {
+		t.Fatalf("CacheDir() error = %v", err)
+	}
+	if want := filepath.Join(root, "prow-helper"); dir != want {
+		t.Errorf("CacheDir() = %q, want %q", dir, want)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Errorf("CacheDir() should create %s", dir)
+	}
+}
+
+func TestStateDir_CreateError(t *testing.T) {
+	// A regular file where the XDG root should be makes creation fail.
+	file := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	t.Cleanup(xdg.Reload)
+	t.Setenv("XDG_STATE_HOME", file)
+	xdg.Reload()
+
+	if _, err := StateDir(); err == nil {
+		t.Error("StateDir() should fail when the directory cannot be created")
+	}
+}
+
+func TestCheckDestination(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	configHome := setXDGHome(t, "XDG_CONFIG_HOME")
+	stateHome := setXDGHome(t, "XDG_STATE_HOME")
+	cacheHome := setXDGHome(t, "XDG_CACHE_HOME")
+
+	tests := []struct {
+		name   string
+		dest   string
+		unsafe bool
+	}{
+		{"filesystem root", "/", true},
+		{"home directory", home, true},
+		{"home directory with tilde", "~", true},
+		{"home directory with trailing slash", home + "/", true},
+		{"config directory", filepath.Join(configHome, "prow-helper"), true},
+		{"config root", configHome, true},
+		{"inside state directory", filepath.Join(stateHome, "prow-helper", "artifacts"), true},
+		{"cache directory", filepath.Join(cacheHome, "prow-helper"), true},
+		{"path resolving to the home directory", home + "/artifacts/..", true},
+		{"directory under home", "~/prow-artifacts", false},
+		{"other application's cache", filepath.Join(cacheHome, "other-app"), false},
+		{"sibling sharing a prefix", configHome + "-backup", false},
+		{"tmp directory", t.TempDir(), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckDestination(tt.dest)
+			if tt.unsafe && !errors.Is(err, ErrUnsafeDestination) {
+				t.Errorf("CheckDestination(%q) = %v, want ErrUnsafeDestination", tt.dest, err)
+			}
+			if !tt.unsafe && err != nil {
+				t.Errorf("CheckDestination(%q) = %v, want nil", tt.dest, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"os"
+)
+
+// ConfigFile is a config file given explicitly, with --config. It takes
+// precedence over the project and XDG config files, and over the file named
+// by the <EnvPrefix>CONFIG environment variable.
+var ConfigFile string
+
+// ConfigPath is a config file Load reads.
+type ConfigPath struct {
+	Path   string
+	Source Source // the layer the file provides: SourceConfig, SourceProject or SourceFile
+	Origin string // how the file was found: "--config", the environment variable, "project" or "XDG"
+}
+
+// Exists reports whether the file exists. Only an explicit config file is
+// required to exist.
+func (p ConfigPath) Exists() bool {
+	info, err := os.Stat(p.Path)
+	return err == nil && !info.IsDir()
+}
+
+// ResolvePaths returns the config files Load reads, highest precedence
+// first: the explicit config file (ConfigFile, or else the file named by
+// <EnvPrefix>CONFIG), the nearest project file and the XDG config file.
+// The XDG file is listed even when it does not exist.
+func ResolvePaths() []ConfigPath {
+	wd, _ := os.Getwd()
+	envName := EnvName(EnvPrefix, "config")
+	explicit := ConfigPath{Path: ConfigFile, Source: SourceConfig, Origin: "--config"}
+	if explicit.Path == "" {
+		explicit = ConfigPath{Path: os.Getenv(envName), Source: SourceConfig, Origin: "$" + envName}
+	}
+	return resolvePaths(explicit,
+		ConfigPath{Path: FindProjectConfig(wd), Source: SourceProject, Origin: "project"},
+		ConfigPath{Path: GetConfigPath(), Source: SourceFile, Origin: "XDG"})
+}
+
+// resolvePaths returns the candidates, given highest precedence first, that
+// have a path. A file found several ways is only read at its highest
+// precedence.
+func resolvePaths(candidates ...ConfigPath) []ConfigPath {
+	var paths []ConfigPath
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		if c.Path == "" || seen[c.Path] {
+			continue
+		}
+		seen[c.Path] = true
+		paths = append(paths, c)
+	}
+	return paths
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolvePaths(t *testing.T) {
+	xdgHome := setXDGHome(t, "XDG_CONFIG_HOME")
+	xdgPath := filepath.Join(xdgHome, "prow-helper", "config.yaml")
+	dir := t.TempDir()
+	projectPath := filepath.Join(dir, ProjectConfigName)
+	writeConfig(t, projectPath, "dest: /project\n")
+	nested := filepath.Join(dir, "sub")
+	writeConfig(t, filepath.Join(nested, "placeholder"), "")
+	t.Chdir(nested)
+
+	origFile := ConfigFile
+	t.Cleanup(func() { ConfigFile = origFile })
+
+	describe := func(paths []ConfigPath) string {
+		var parts []string
+		for _, p := range paths {
+			parts = append(parts, p.Origin+"="+p.Path)
+		}
+		return strings.Join(parts, " ")
+	}
+	tests := []struct {
+		name string
+		flag string
+		env  string
+		want string
+	}{
+		{name: "project and XDG", want: "project=" + projectPath + " XDG=" + xdgPath},
+		{name: "env", env: "/team/env.yaml", want: "$PROW_HELPER_CONFIG=/team/env.yaml project=" + projectPath + " XDG=" + xdgPath},
+		{name: "--config over env", flag: "/team/flag.yaml", env: "/team/env.yaml", want: "--config=/team/flag.yaml project=" + projectPath + " XDG=" + xdgPath},
+		{name: "--config naming the XDG file", flag: xdgPath, want: "--config=" + xdgPath + " project=" + projectPath},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ConfigFile = tt.flag
+			t.Setenv("PROW_HELPER_CONFIG", tt.env)
+			if got := describe(ResolvePaths()); got != tt.want {
+				t.Errorf("ResolvePaths() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_ExplicitConfigFile(t *testing.T) {
+	t.Setenv("PROW_HELPER_DEST", "")
+	setXDGHome(t, "XDG_CONFIG_HOME")
+	dir := t.TempDir()
+	writeConfig(t, filepath.Join(dir, ProjectConfigName), "dest: /project\n")
+	teamPath := filepath.Join(dir, "team.yaml")
+	writeConfig(t, teamPath, "dest: /team\n")
+	t.Chdir(dir)
+
+	origFile := ConfigFile
+	t.Cleanup(func() { ConfigFile = origFile })
+	ConfigFile = teamPath
+
+	cfg, settings, err := LoadWithProvenance(nil)
+	if err != nil {
+		t.Fatalf("LoadWithProvenance() error = %v", err)
+	}
+	if cfg.Dest != "/team" {
+		t.Errorf("Dest = %q, want the --config file over the project one", cfg.Dest)
+	}
+	for _, s := range settings {
+		if s.Key == "dest" && s.Source != SourceConfig {
+			t.Errorf("dest source = %q, want %q", s.Source, SourceConfig)
+		}
+	}
+
+	ConfigFile = filepath.Join(dir, "missing.yaml")
+	if _, err := Load(nil); err == nil || !strings.Contains(err.Error(), "missing.yaml") {
+		t.Errorf("Load() error = %v, want a missing --config file reported", err)
+	}
+}
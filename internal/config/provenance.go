@@ -0,0 +1,99 @@
+package config
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Source names the configuration layer a setting's value came from.
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"    // the XDG config file
+	SourceProject Source = "project" // the nearest .prow-helper.yaml
+	SourceConfig  Source = "config"  // the --config (or <EnvPrefix>CONFIG) file
+	SourceEnv     Source = "env"
+	SourceCLI     Source = "cli"
+)
+
+// Layer is one configuration source for MergeWithProvenance.
+type Layer struct {
+	Source Source
+	Config *Config // nil for a source that is absent
+}
+
+// Setting is the effective value of one configuration field, identified by
+// its YAML key, and the source that set it. List values are comma-separated,
+// and map values are comma-separated key=value pairs sorted by key (only the
+// sorted names for watchlists).
+type Setting struct {
+	Key    string
+	Value  string
+	Source Source
+}
+
+// MergeWithProvenance merges layers given from lowest to highest priority
+// exactly like MergeLayers and also returns, for every field in declaration
+// order, its effective value and the layer it came from. A field no layer
+// sets has an empty Source.
+func MergeWithProvenance(layers ...Layer) (*Config, []Setting) {
+	configs := make([]*Config, len(layers))
+	for i, l := range layers {
+		configs[i] = l.Config
+	}
+	cfg := MergeLayers(configs...)
+
+	settings := fieldSettings(cfg)
+	for _, l := range layers {
+		if l.Config == nil {
+			continue
+		}
+		for i, s := range fieldSettings(l.Config) {
+			if s.Value != "" {
+				settings[i].Source = l.Source
+			}
+		}
+	}
+	return cfg, settings
+}
+
+// fieldSettings returns the YAML key and value of every field of cfg, in
+// declaration order, with no Source.
+func fieldSettings(cfg *Config) []Setting {
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+	settings := make([]Setting, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		var value string
+		switch f := v.Field(i); f.Kind() {
+		case reflect.Slice:
+			value = strings.Join(f.Interface().([]string), ",")
+		case reflect.Map:
+			switch m := f.Interface().(type) {
+			case map[string]string:
+				value = joinMap(m)
+			case map[string]Watchlist:
+				value = strings.Join(slices.Sorted(maps.Keys(m)), ",")
+			}
+		default:
+			value = f.String()
+		}
+		settings = append(settings, Setting{Key: key, Value: value})
+	}
+	return settings
+}
+
+// joinMap formats m as comma-separated key=value pairs sorted by key.
+func joinMap(m map[string]string) string {
+	pairs := make([]string, 0, len(m))
+	for k, v := range m {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMergeWithProvenance(t *testing.T) {
+	file := &Config{Dest: "/file", AnalyzeCmd: "file-cmd", ProwHosts: []string{"a.example.com", "b.example.com"},
+		NtfyExtraHeaders: map[string]string{"Tags": "warning", "Priority": "high"}}
+	env := &Config{AnalyzeCmd: "env-cmd"}
+	cli := &Config{NtfyChannel: "cli-channel-1234"}
+	cfg, settings := MergeWithProvenance(
+		Layer{SourceDefault, DefaultConfig()},
+		Layer{SourceFile, file},
+		Layer{SourceProject, nil},
+		Layer{SourceEnv, env},
+		Layer{SourceCLI, cli},
+	)
+	if want := MergeLayers(DefaultConfig(), file, env, cli); !reflect.DeepEqual(cfg, want) {
+		t.Errorf("MergeWithProvenance() config = %+v, want the MergeLayers result %+v", cfg, want)
+	}
+
+	tests := []struct {
+		key        string
+		wantValue  string
+		wantSource Source
+	}{
+		{"dest", "/file", SourceFile},
+		{"analyze_cmd", "env-cmd", SourceEnv},
+		{"ntfy_channel", "cli-channel-1234", SourceCLI},
+		{"prow_hosts", "prow.ci.openshift.org,a.example.com,b.example.com", SourceFile},
+		{"ntfy_timeout", "10s", SourceDefault},
+		{"started_file", "started.json", SourceDefault},
+		{"ntfy_extra_headers", "Priority=high,Tags=warning", SourceFile},
+	}
+	byKey := make(map[string]Setting)
+	for _, s := range settings {
+		byKey[s.Key] = s
+	}
+	for _, tt := range tests {
+		s, ok := byKey[tt.key]
+		if !ok {
+			t.Errorf("no setting reported for %q", tt.key)
+			continue
+		}
+		if s.Value != tt.wantValue || s.Source != tt.wantSource {
+			t.Errorf("%s = %q from %q, want %q from %q", tt.key, s.Value, s.Source, tt.wantValue, tt.wantSource)
+		}
+	}
+	if settings[0].Key != "dest" {
+		t.Errorf("first setting = %q, want fields in declaration order starting with dest", settings[0].Key)
+	}
+}
+
+func TestMergeWithProvenance_Unset(t *testing.T) {
+	_, settings := MergeWithProvenance(Layer{SourceFile, &Config{Dest: "/file"}})
+	for _, s := range settings {
+		if s.Key == "analyze_cmd" && (s.Value != "" || s.Source != "") {
+			t.Errorf("unset analyze_cmd = %q from %q, want no value and no source", s.Value, s.Source)
+		}
+	}
+}
+
+func TestLoadWithProvenance_Layers(t *testing.T) {
+	for _, env := range []string{"PROW_HELPER_DEST", "PROW_HELPER_ANALYZE_CMD", "NTFY_CHANNEL", "PROW_HELPER_PROW_HOSTS",
+		"PROW_HELPER_NTFY_TIMEOUT", "PROW_HELPER_STARTED_FILE", "PROW_HELPER_STARTED_FIELD", "PROW_HELPER_DATE_PREFIX", "PROW_HELPER_GCS_HOST"} {
+		t.Setenv(env, "")
+	}
+	t.Setenv("NTFY_CHANNEL", "env-$USER-alerts")
+	t.Setenv("USER", "tester")
+
+	dir := t.TempDir()
+	xdgPath := filepath.Join(dir, "xdg", "config.yaml")
+	projectPath := filepath.Join(dir, "project", ProjectConfigName)
+	writeConfig(t, xdgPath, "dest: /xdg\nanalyze_cmd: xdg-cmd\n")
+	writeConfig(t, projectPath, "analyze_cmd: project-cmd\n")
+	explicitPath := filepath.Join(dir, "team.yaml")
+	writeConfig(t, explicitPath, "gcs_host: gcs-mirror.example.com\n")
+
+	_, settings, err := loadWithProvenance(&Config{DatePrefix: "false"}, []ConfigPath{
+		{Path: explicitPath, Source: SourceConfig},
+		{Path: projectPath, Source: SourceProject},
+		{Path: xdgPath, Source: SourceFile},
+	})
+	if err != nil {
+		t.Fatalf("loadWithProvenance() error = %v", err)
+	}
+	want := map[string]Setting{
+		"dest":         {"dest", "/xdg", SourceFile},
+		"analyze_cmd":  {"analyze_cmd", "project-cmd", SourceProject},
+		"ntfy_channel": {"ntfy_channel", "env-tester-alerts", SourceEnv},
+		"date_prefix":  {"date_prefix", "false", SourceCLI},
+		"gcs_host":     {"gcs_host", "gcs-mirror.example.com", SourceConfig},
+	}
+	for _, s := range settings {
+		if w, ok := want[s.Key]; ok && s != w {
+			t.Errorf("setting %+v, want %+v", s, w)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"net/mail"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clobrano/prow-helper/internal/analyzer"
+	"github.com/clobrano/prow-helper/internal/commalist"
+	"github.com/clobrano/prow-helper/internal/downloader"
+	"github.com/clobrano/prow-helper/internal/notifier"
+)
+
+// minPrivateChannelLength is the topic length below which an ntfy channel is
+// considered easy to guess. ntfy.sh topics are public to anyone who knows the
+// name, so short names are likely shared with strangers.
+const minPrivateChannelLength = 12
+
+// ntfyChannelPattern matches the characters ntfy.sh accepts in a topic name.
+var ntfyChannelPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// commonNtfyChannels lists topic names that are very likely used by others.
+var commonNtfyChannels = map[string]bool{
+	"test":          true,
+	"testing":       true,
+	"alerts":        true,
+	"alert":         true,
+	"ci":            true,
+	"prow":          true,
+	"notifications": true,
+	"notify":        true,
+	"jobs":          true,
+	"builds":        true,
+}
+
+// Issue describes a single problem found while validating a Config.
+type Issue struct {
+	Field   string // YAML key of the offending field
+	Value   string // offending value
+	Message string
+	Warning bool // true for advisory issues that do not prevent running
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s %q: %s", i.Field, i.Value, i.Message)
+}
+
+// HasErrors reports whether issues contains at least one non-warning issue.
+func HasErrors(issues []Issue) bool {
+	for _, i := range issues {
+		if !i.Warning {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks cfg for invalid or risky settings and returns every issue
+// found. A nil result means the configuration is fine.
+func Validate(cfg *Config) []Issue {
+	var issues []Issue
+	for _, channel := range commalist.Split(cfg.NtfyChannel) {
+		issues = append(issues, validateNtfyChannel(channel)...)
+	}
+	if !cfg.AnalyzeShellEnabled() {
+		// A shell command line is not checked: sh reports its own errors.
+		issues = append(issues, validateAnalyzeCmd(cfg.AnalyzeCmd)...)
+	}
+	issues = append(issues, validateBool("analyze_shell", cfg.AnalyzeShell)...)
+	issues = append(issues, validateDuration("ntfy_timeout", cfg.NtfyTimeout)...)
+	issues = append(issues, validateStartedFile(cfg.StartedFile)...)
+	issues = append(issues, validateBool("date_prefix", cfg.DatePrefix)...)
+	if cfg.CollisionSuffix != "" && !downloader.IsCollisionSuffix(cfg.CollisionSuffix) {
+		issues = append(issues, Issue{Field: "collision_suffix", Value: cfg.CollisionSuffix,
+			Message: fmt.Sprintf("must be %s or %s", downloader.CollisionTimestamp, downloader.CollisionCounter)})
+	}
+	issues = append(issues, validateHost("gcs_host", cfg.GCSHost)...)
+	issues = append(issues, validateGCSBase(cfg.GCSBase)...)
+	issues = append(issues, validateHost("prow_host", cfg.ProwHost)...)
+	if cfg.Downloader != "" && !downloader.IsBackend(cfg.Downloader) {
+		issues = append(issues, Issue{Field: "downloader", Value: cfg.Downloader,
+			Message: fmt.Sprintf("must be %s or %s", downloader.BackendGsutil, downloader.BackendHTTP)})
+	}
+	issues = append(issues, validateNtfyEmail(cfg.NtfyEmail)...)
+	issues = append(issues, validateNtfyHeaders(cfg.NtfyExtraHeaders)...)
+	issues = append(issues, validateWebhookURL(cfg.WebhookURL)...)
+	issues = append(issues, validateWatchlists(cfg.Watchlists)...)
+	for _, event := range commalist.Split(cfg.WebhookOn) {
+		if !notifier.IsEvent(event) {
+			issues = append(issues, Issue{Field: "webhook_on", Value: event,
+				Message: "unknown event, expected one of " + eventNames()})
+		}
+	}
+	return issues
+}
+
+// validateAnalyzeCmd checks that the analysis command, when set, parses and
+// names a program found in PATH. A missing program is only a warning: it may
+// be installed where the config is used.
+func validateAnalyzeCmd(cmd string) []Issue {
+	name, _, err := analyzer.ParseAnalyzeCommand(cmd)
+	if err != nil {
+		return []Issue{{Field: "analyze_cmd", Value: cmd, Message: "not a valid command line (check the quotes)"}}
+	}
+	if name == "" {
+		return nil
+	}
+	if _, err := exec.LookPath(name); err != nil {
+		return []Issue{{Field: "analyze_cmd", Value: cmd, Message: fmt.Sprintf("command %q not found in PATH", name), Warning: true}}
+	}
+	return nil
+}
+
+// validateBool checks that a boolean setting, when set, parses as one.
+func validateBool(field, value string) []Issue {
+	if value == "" {
+		return nil
+	}
+	if _, err := strconv.ParseBool(value); err != nil {
+		return []Issue{{Field: field, Value: value, Message: "must be true or false"}}
+	}
+	return nil
+}
+
+// validateHost checks that a host setting such as gcs_host, when set, is a
+// bare host with an optional port: the scheme is always https and the path
+// is appended.
+func validateHost(field, host string) []Issue {
+	if host == "" {
+		return nil
+	}
+	if strings.Contains(host, "://") || strings.ContainsAny(host, "/?# ") {
+		return []Issue{{Field: field, Value: host, Message: "must be a host name with an optional port, without scheme or path"}}
+	}
+	return nil
+}
+
+// validateGCSBase checks that gcs_base, when set, is an https:// URL: object
+// URLs are <gcs_base>/<bucket>/<path>, so it takes no query or fragment.
+func validateGCSBase(base string) []Issue {
+	if base == "" {
+		return nil
+	}
+	u, err := url.Parse(base)
+	if err != nil || u.Scheme != "https" || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return []Issue{{Field: "gcs_base", Value: base, Message: "must be an https:// URL without query, e.g. https://mirror.example.com/storage"}}
+	}
+	return nil
+}
+
+// validateWebhookURL checks that webhook_url, when set, is an http(s) URL.
+func validateWebhookURL(raw string) []Issue {
+	if raw == "" {
+		return nil
+	}
+	if u, err := url.Parse(raw); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return []Issue{{Field: "webhook_url", Value: raw, Message: "must be an http:// or https:// URL"}}
+	}
+	return nil
+}
+
+// eventNames returns the comma-separated names of the notification events.
+func eventNames() string {
+	names := make([]string, len(notifier.Events))
+	for i, e := range notifier.Events {
+		names[i] = string(e)
+	}
+	return strings.Join(names, ", ")
+}
+
+// headerNamePattern matches a valid HTTP header name (an RFC 9110 token).
+var headerNamePattern = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+
+// reservedNtfyHeaders are set by prow-helper itself on every ntfy.sh request.
+var reservedNtfyHeaders = map[string]bool{"title": true, "content-type": true}
+
+// validateNtfyEmail checks that ntfy_email, when set, is a plain address.
+func validateNtfyEmail(email string) []Issue {
+	if email == "" {
+		return nil
+	}
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return []Issue{{Field: "ntfy_email", Value: email, Message: "must be an email address (e.g. me@example.com)"}}
+	}
+	return nil
+}
+
+// validateNtfyHeaders checks that every ntfy_extra_headers entry is a valid
+// header that prow-helper does not already set, with a single-line value.
+func validateNtfyHeaders(headers map[string]string) []Issue {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var issues []Issue
+	for _, name := range names {
+		switch {
+		case !headerNamePattern.MatchString(name):
+			issues = append(issues, Issue{Field: "ntfy_extra_headers", Value: name, Message: "not a valid header name"})
+		case reservedNtfyHeaders[strings.ToLower(name)]:
+			issues = append(issues, Issue{Field: "ntfy_extra_headers", Value: name, Message: "is set by prow-helper and cannot be overridden"})
+		case strings.ContainsAny(headers[name], "\r\n"):
+			issues = append(issues, Issue{Field: "ntfy_extra_headers", Value: name, Message: "value must be a single line"})
+		}
+	}
+	return issues
+}
+
+// validateWatchlists checks that every watchlist lists at least one URL, and
+// that its URLs are http(s) URLs.
+func validateWatchlists(watchlists map[string]Watchlist) []Issue {
+	var issues []Issue
+	for _, name := range slices.Sorted(maps.Keys(watchlists)) {
+		w := watchlists[name]
+		if len(w.URLs) == 0 {
+			issues = append(issues, Issue{Field: "watchlists", Value: name, Message: "lists no urls"})
+		}
+		for _, raw := range w.URLs {
+			if u, err := url.Parse(raw); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				issues = append(issues, Issue{Field: "watchlists", Value: name, Message: fmt.Sprintf("%q is not an http(s) URL", raw)})
+			}
+		}
+	}
+	return issues
+}
+
+// validateStartedFile checks that started_file names a file inside the
+// build's artifacts: it is used both in a GCS URL and as a local path.
+func validateStartedFile(name string) []Issue {
+	if name == "" {
+		return nil
+	}
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "/") {
+		return []Issue{{Field: "started_file", Value: name, Message: "must be relative to the build's artifacts"}}
+	}
+	for _, part := range strings.Split(filepath.ToSlash(name), "/") {
+		if part == ".." {
+			return []Issue{{Field: "started_file", Value: name, Message: "must not leave the build's artifacts (\"..\")"}}
+		}
+	}
+	return nil
+}
+
+// validateDuration checks that value, when set, is a positive Go duration.
+func validateDuration(field, value string) []Issue {
+	if value == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return []Issue{{Field: field, Value: value, Message: "not a valid duration (e.g. 10s, 1m)"}}
+	}
+	if d <= 0 {
+		return []Issue{{Field: field, Value: value, Message: "must be greater than zero"}}
+	}
+	return nil
+}
+
+// validateNtfyChannel rejects channel names ntfy.sh would not accept and warns
+// about names that are short or common enough to be shared with other users.
+func validateNtfyChannel(channel string) []Issue {
+	if channel == "" {
+		return nil
+	}
+	if !ntfyChannelPattern.MatchString(channel) {
+		return []Issue{{
+			Field:   "ntfy_channel",
+			Value:   channel,
+			Message: "must be 1-64 characters of letters, digits, '-' or '_' (no spaces or slashes)",
+		}}
+	}
+	if commonNtfyChannels[strings.ToLower(channel)] || len(channel) < minPrivateChannelLength {
+		return []Issue{{
+			Field:   "ntfy_channel",
+			Value:   channel,
+			Message: "ntfy.sh topics are public and this name is easy to guess; use a long random topic instead",
+			Warning: true,
+		}}
+	}
+	return nil
+}
@@ -0,0 +1,338 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate_NtfyChannel(t *testing.T) {
+	tests := []struct {
+		name        string
+		channel     string
+		wantIssues  int
+		wantWarning bool
+	}{
+		{"empty channel", "", 0, false},
+		{"long random channel", "prow-helper-3f9a81c2d7", 0, false},
+		{"space in channel", "my channel", 1, false},
+		{"slash in channel", "team/alerts", 1, false},
+		{"too long channel", strings.Repeat("a", 65), 1, false},
+		{"common name", "test", 1, true},
+		{"common name mixed case", "Alerts", 1, true},
+		{"short name", "clobrano", 1, true},
+		{"channel list", "prow-helper-3f9a81c2d7, team-alerts-8b1e44f0a2", 0, false},
+		{"invalid channel in list", "prow-helper-3f9a81c2d7,team/alerts", 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := Validate(&Config{NtfyChannel: tt.channel})
+			if len(issues) != tt.wantIssues {
+				t.Fatalf("Validate() returned %d issues, want %d: %v", len(issues), tt.wantIssues, issues)
+			}
+			if tt.wantIssues == 0 {
+				return
+			}
+			if issues[0].Field != "ntfy_channel" {
+				t.Errorf("Issue.Field = %v, want ntfy_channel", issues[0].Field)
+			}
+			if issues[0].Warning != tt.wantWarning {
+				t.Errorf("Issue.Warning = %v, want %v", issues[0].Warning, tt.wantWarning)
+			}
+			if tt.wantWarning && !strings.Contains(issues[0].Message, "long random topic") {
+				t.Errorf("warning should suggest a long random topic, got %q", issues[0].Message)
+			}
+		})
+	}
+}
+
+func TestHasErrors(t *testing.T) {
+	if HasErrors(nil) {
+		t.Error("HasErrors(nil) = true, want false")
+	}
+	if HasErrors([]Issue{{Warning: true}}) {
+		t.Error("HasErrors() with only warnings = true, want false")
+	}
+	if !HasErrors([]Issue{{Warning: true}, {}}) {
+		t.Error("HasErrors() with an error = false, want true")
+	}
+}
+
+func TestValidate_NtfyTimeout(t *testing.T) {
+	tests := []struct {
+		value      string
+		wantIssues int
+	}{
+		{"", 0},
+		{"10s", 0},
+		{"1m30s", 0},
+		{"ten seconds", 1},
+		{"0s", 1},
+		{"-5s", 1},
+	}
+	for _, tt := range tests {
+		issues := Validate(&Config{NtfyTimeout: tt.value})
+		if len(issues) != tt.wantIssues {
+			t.Errorf("Validate(ntfy_timeout=%q) returned %d issues, want %d: %v", tt.value, len(issues), tt.wantIssues, issues)
+		}
+		for _, issue := range issues {
+			if issue.Field != "ntfy_timeout" || issue.Warning {
+				t.Errorf("unexpected issue %+v", issue)
+			}
+		}
+	}
+}
+
+func TestValidate_StartedFile(t *testing.T) {
+	tests := []struct {
+		value     string
+		wantIssue bool
+	}{
+		{"", false},
+		{"started.json", false},
+		{"prowjob.json", false},
+		{"artifacts/metadata.json", false},
+		{"/etc/passwd", true},
+		{"../started.json", true},
+		{"artifacts/../../x.json", true},
+	}
+	for _, tt := range tests {
+		issues := Validate(&Config{StartedFile: tt.value})
+		if got := HasErrors(issues); got != tt.wantIssue {
+			t.Errorf("Validate(started_file=%q) errors = %v, want %v (%v)", tt.value, got, tt.wantIssue, issues)
+		}
+	}
+}
+
+func TestValidate_CollisionSuffix(t *testing.T) {
+	for value, wantIssue := range map[string]bool{"": false, "timestamp": false, "counter": false, "random": true} {
+		issues := Validate(&Config{CollisionSuffix: value})
+		if got := len(issues) > 0; got != wantIssue {
+			t.Errorf("Validate(collision_suffix=%q) issues = %v, want issue = %v", value, issues, wantIssue)
+		}
+	}
+}
+
+func TestValidate_Downloader(t *testing.T) {
+	for value, wantIssue := range map[string]bool{"": false, "gsutil": false, "http": false, "curl": true} {
+		issues := Validate(&Config{Downloader: value})
+		if got := len(issues) > 0; got != wantIssue {
+			t.Errorf("Validate(downloader=%q) issues = %v, want issue = %v", value, issues, wantIssue)
+		}
+	}
+}
+
+func TestValidate_DatePrefix(t *testing.T) {
+	for value, wantIssue := range map[string]bool{"": false, "true": false, "false": false, "nope": true} {
+		issues := Validate(&Config{DatePrefix: value})
+		if got := len(issues) > 0; got != wantIssue {
+			t.Errorf("Validate(date_prefix=%q) issues = %v, want issue = %v", value, issues, wantIssue)
+		}
+	}
+}
+
+func TestValidate_GCSHost(t *testing.T) {
+	tests := []struct {
+		value     string
+		wantIssue bool
+	}{
+		{"", false},
+		{"storage.googleapis.com", false},
+		{"gcs-mirror.example.com:8443", false},
+		{"https://gcs-mirror.example.com", true},
+		{"gcs-mirror.example.com/storage", true},
+	}
+	for _, tt := range tests {
+		issues := Validate(&Config{GCSHost: tt.value})
+		if got := HasErrors(issues); got != tt.wantIssue {
+			t.Errorf("Validate(gcs_host=%q) errors = %v, want %v (%v)", tt.value, got, tt.wantIssue, issues)
+		}
+	}
+}
+
+func TestValidate_ProwHostAndGCSBase(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       Config
+		wantIssue string
+	}{
+		{name: "unset", cfg: Config{}},
+		{name: "prow host", cfg: Config{ProwHost: "prow.internal.example.com"}},
+		{name: "prow host URL", cfg: Config{ProwHost: "https://prow.internal.example.com"}, wantIssue: "prow_host"},
+		{name: "gcs base", cfg: Config{GCSBase: "https://gcs-mirror.example.com:8443/"}},
+		{name: "gcs base with path", cfg: Config{GCSBase: "https://gcs-mirror.example.com/storage"}},
+		{name: "gcs base without scheme", cfg: Config{GCSBase: "gcs-mirror.example.com"}, wantIssue: "gcs_base"},
+		{name: "gcs base over http", cfg: Config{GCSBase: "http://gcs-mirror.example.com"}, wantIssue: "gcs_base"},
+		{name: "gcs base with query", cfg: Config{GCSBase: "https://gcs-mirror.example.com/?alt=media"}, wantIssue: "gcs_base"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := Validate(&tt.cfg)
+			var got string
+			if len(issues) > 0 {
+				got = issues[0].Field
+			}
+			if got != tt.wantIssue || len(issues) > 1 {
+				t.Errorf("Validate() = %v, want an issue for %q", issues, tt.wantIssue)
+			}
+		})
+	}
+}
+
+func TestValidate_NtfyEmail(t *testing.T) {
+	for value, wantIssue := range map[string]bool{"": false, "me@example.com": false, "me": true, "Me <me@example.com>": true} {
+		issues := Validate(&Config{NtfyEmail: value})
+		if got := HasErrors(issues); got != wantIssue {
+			t.Errorf("Validate(ntfy_email=%q) errors = %v, want %v (%v)", value, got, wantIssue, issues)
+		}
+	}
+}
+
+func TestValidate_NtfyExtraHeaders(t *testing.T) {
+	tests := []struct {
+		name      string
+		headers   map[string]string
+		wantIssue bool
+	}{
+		{"none", nil, false},
+		{"valid", map[string]string{"Tags": "warning", "X-Priority": "5", "Call": "yes"}, false},
+		{"invalid name", map[string]string{"Bad Header": "x"}, true},
+		{"reserved name", map[string]string{"title": "x"}, true},
+		{"multi-line value", map[string]string{"Tags": "a\nInjected: b"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := Validate(&Config{NtfyExtraHeaders: tt.headers})
+			if got := HasErrors(issues); got != tt.wantIssue {
+				t.Errorf("Validate() errors = %v, want %v (%v)", got, tt.wantIssue, issues)
+			}
+		})
+	}
+}
+
+func TestValidate_Webhook(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        Config
+		wantFields []string
+	}{
+		{name: "unset", cfg: Config{}},
+		{name: "valid", cfg: Config{WebhookURL: "https://hooks.example.com/prow", WebhookOn: "download_complete, job_failed"}},
+		{name: "not a URL", cfg: Config{WebhookURL: "hooks.example.com/prow"}, wantFields: []string{"webhook_url"}},
+		{name: "unknown event", cfg: Config{WebhookOn: "download_complete,job_done"}, wantFields: []string{"webhook_on"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, issue := range Validate(&tt.cfg) {
+				got = append(got, issue.Field)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantFields, ",") {
+				t.Errorf("Validate() issues on %v, want %v", got, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestValidate_AnalyzeCmd(t *testing.T) {
+	tests := []struct {
+		name        string
+		cmd         string
+		wantIssue   bool
+		wantWarning bool
+	}{
+		{name: "unset", cmd: ""},
+		{name: "found in PATH", cmd: "sh -c 'ls \"$1\"' --"},
+		{name: "absolute path", cmd: "/bin/sh -c true"},
+		{name: "not found", cmd: "prow-helper-no-such-analyzer --deep", wantIssue: true, wantWarning: true},
+		{name: "unbalanced quotes", cmd: "claude 'analyze", wantIssue: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := Validate(&Config{AnalyzeCmd: tt.cmd})
+			if (len(issues) > 0) != tt.wantIssue {
+				t.Fatalf("Validate() = %v, want an issue: %v", issues, tt.wantIssue)
+			}
+			if tt.wantIssue && (issues[0].Field != "analyze_cmd" || issues[0].Warning != tt.wantWarning) {
+				t.Errorf("Validate() = %+v, want an analyze_cmd issue with Warning %v", issues[0], tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestValidate_AnalyzeShell(t *testing.T) {
+	// A shell command line is left to sh, whatever it starts with.
+	cfg := &Config{AnalyzeCmd: "prow-helper-no-such-analyzer 2>&1 | tee 'unbalanced", AnalyzeShell: "true"}
+	if issues := Validate(cfg); len(issues) != 0 {
+		t.Errorf("Validate() with analyze_shell = %v, want no issues", issues)
+	}
+	issues := Validate(&Config{AnalyzeShell: "maybe"})
+	if len(issues) != 1 || issues[0].Field != "analyze_shell" {
+		t.Errorf("Validate(analyze_shell=maybe) = %v, want an analyze_shell issue", issues)
+	}
+}
+
+func TestLoadFile_Validate(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		wantIssues []string // "field" for errors, "field (warning)" for warnings
+	}{
+		{name: "good", content: "dest: /tmp/artifacts\nntfy_channel: prow-helper-team-7f3a9c\nntfy_timeout: 10s\n"},
+		{name: "unparseable duration", content: "ntfy_timeout: ten seconds\n", wantIssues: []string{"ntfy_timeout"}},
+		{name: "bad channel characters", content: "ntfy_channel: my team/alerts\n", wantIssues: []string{"ntfy_channel"}},
+		{name: "unresolvable analyze binary", content: "analyze_cmd: prow-helper-no-such-analyzer --deep\n", wantIssues: []string{"analyze_cmd (warning)"}},
+		{name: "several", content: "ntfy_timeout: 0s\ndate_prefix: maybe\n", wantIssues: []string{"ntfy_timeout", "date_prefix"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			writeConfig(t, path, tt.content)
+			cfg, err := LoadFile(path)
+			if err != nil {
+				t.Fatalf("LoadFile() error = %v", err)
+			}
+			var got []string
+			for _, issue := range Validate(cfg) {
+				if issue.Warning {
+					got = append(got, issue.Field+" (warning)")
+				} else {
+					got = append(got, issue.Field)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantIssues, ",") {
+				t.Errorf("Validate() issues = %v, want %v", got, tt.wantIssues)
+			}
+		})
+	}
+}
+
+func TestLoadFile_Errors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadFile(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("LoadFile() of a missing file: error = nil")
+	}
+	path := filepath.Join(dir, "broken.yaml")
+	writeConfig(t, path, "dest: [unterminated\n")
+	if _, err := LoadFile(path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("LoadFile() of a broken file: error = %v, want it to name the file", err)
+	}
+}
+
+func TestValidate_Watchlists(t *testing.T) {
+	cfg := &Config{Watchlists: map[string]Watchlist{
+		"ok":    {URLs: []string{"https://prow.ci.openshift.org/?job=*metal*"}, Select: "e2e"},
+		"empty": {Select: "e2e"},
+		"bad":   {URLs: []string{"prow.ci.openshift.org/view/gs/b/logs/job/1"}},
+	}}
+	issues := Validate(cfg)
+	var got []string
+	for _, i := range issues {
+		got = append(got, i.Field+" "+i.Value)
+	}
+	if want := []string{"watchlists bad", "watchlists empty"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Validate() = %v, want issues for %v", issues, want)
+	}
+}
@@ -0,0 +1,81 @@
+package downloader
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// IncompleteMarker is the file Download keeps in the destination folder while
+// gsutil runs. A folder that still holds it is a partial download.
+const IncompleteMarker = ".prow-helper-incomplete"
+
+// now returns the current time; tests replace it to get stable ages.
+var now = time.Now
+
+// describeDir summarizes the folder at path for the conflict prompt: when it
+// was last modified, its total size and file count, and whether it is a
+// partial download (see IncompleteMarker). It returns "" if path cannot be
+// read.
+func describeDir(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+
+	var size int64
+	files := 0
+	incomplete := false
+	filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if p == filepath.Join(path, IncompleteMarker) {
+			incomplete = true
+			return nil
+		}
+		if fi, err := d.Info(); err == nil {
+			size += fi.Size()
+			files++
+		}
+		return nil
+	})
+
+	parts := []string{
+		fmt.Sprintf("modified %s (%s ago)", info.ModTime().Format("2006-01-02 15:04"), formatAge(now().Sub(info.ModTime()))),
+		fmt.Sprintf("%s in %d files", formatSize(size), files),
+	}
+	if incomplete {
+		parts = append(parts, "INCOMPLETE: a previous download did not finish")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatAge formats d with the largest unit that fits: minutes, hours or days.
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
+
+// formatSize formats n bytes with a binary unit (B, KiB, MiB, GiB, …).
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
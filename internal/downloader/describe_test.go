@@ -0,0 +1,75 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDescribeDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "artifacts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]int{"build-log.txt": 1024, "artifacts/junit.xml": 1024, "artifacts/e2e.log": 2048}
+	for name, size := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	modified := time.Date(2026, 1, 2, 10, 30, 0, 0, time.Local)
+	if err := os.Chtimes(dir, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	origNow := now
+	now = func() time.Time { return modified.Add(3 * time.Hour) }
+	t.Cleanup(func() { now = origNow })
+
+	want := "modified 2026-01-02 10:30 (3h ago), 4.0 KiB in 3 files"
+	if got := describeDir(dir); got != want {
+		t.Errorf("describeDir() = %q, want %q", got, want)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, IncompleteMarker), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(dir, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	want += ", INCOMPLETE: a previous download did not finish"
+	if got := describeDir(dir); got != want {
+		t.Errorf("describeDir() with marker = %q, want %q", got, want)
+	}
+}
+
+func TestDescribeDir_Missing(t *testing.T) {
+	if got := describeDir(filepath.Join(t.TempDir(), "missing")); got != "" {
+		t.Errorf("describeDir() = %q, want empty for a missing folder", got)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{0: "0 B", 1023: "1023 B", 1024: "1.0 KiB", 1536: "1.5 KiB", 5 << 30: "5.0 GiB"}
+	for n, want := range tests {
+		if got := formatSize(n); got != want {
+			t.Errorf("formatSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestPromptConflictResolution_DescribesFolder(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, IncompleteMarker), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout strings.Builder
+	if _, err := PromptConflictResolution(dir, strings.NewReader("s\n"), &stdout); err != nil {
+		t.Fatalf("PromptConflictResolution() error = %v", err)
+	}
+	if !strings.Contains(stdout.String(), "INCOMPLETE") || !strings.Contains(stdout.String(), "0 B in 0 files") {
+		t.Errorf("prompt = %q, want the folder description", stdout.String())
+	}
+}
@@ -0,0 +1,91 @@
+package downloader
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+)
+
+// FileInfo describes an artifact for comparison between two downloads.
+type FileInfo struct {
+	Size int64
+	MD5  string // hex-encoded MD5 digest; empty when unknown
+}
+
+// FilesToDownload compares the artifacts of a build (remote) with those of a
+// reference download (reference), both keyed by path relative to the build
+// root, and returns the sorted paths that must be fetched: files missing from
+// reference, and files whose size differs or whose MD5 differs when both
+// sides know it.
+func FilesToDownload(remote, reference map[string]FileInfo) []string {
+	var paths []string
+	for path, r := range remote {
+		ref, ok := reference[path]
+		if !ok || ref.Size != r.Size || (ref.MD5 != "" && r.MD5 != "" && ref.MD5 != r.MD5) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// unchangedFiles returns the destinations of the transfers whose copy in the
+// reference download dir is the same (see FilesToDownload).
+func unchangedFiles(dir string, transfers []Transfer) (map[string]bool, error) {
+	reference, err := ScanDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	remote := make(map[string]FileInfo, len(transfers))
+	for _, tr := range transfers {
+		remote[tr.Dest] = FileInfo{Size: tr.Object.Size}
+	}
+	unchanged := make(map[string]bool, len(remote))
+	for path := range remote {
+		unchanged[path] = true
+	}
+	for _, path := range FilesToDownload(remote, reference) {
+		delete(unchanged, path)
+	}
+	return unchanged, nil
+}
+
+// ScanDir returns the regular files under dir keyed by their slash-separated
+// path relative to dir, with their sizes. Hashes are not computed.
+func ScanDir(dir string) (map[string]FileInfo, error) {
+	files := make(map[string]FileInfo)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = FileInfo{Size: info.Size()}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// MissingFiles returns the sorted paths present in a but not in b.
+func MissingFiles(a, b map[string]FileInfo) []string {
+	var paths []string
+	for path := range a {
+		if _, ok := b[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,151 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFilesToDownload(t *testing.T) {
+	tests := []struct {
+		name      string
+		remote    map[string]FileInfo
+		reference map[string]FileInfo
+		want      []string
+	}{
+		{
+			name: "identical sets",
+			remote: map[string]FileInfo{
+				"build-log.txt":   {Size: 100},
+				"artifacts/a.xml": {Size: 20},
+			},
+			reference: map[string]FileInfo{
+				"build-log.txt":   {Size: 100},
+				"artifacts/a.xml": {Size: 20},
+			},
+			want: nil,
+		},
+		{
+			name: "new files",
+			remote: map[string]FileInfo{
+				"build-log.txt":   {Size: 100},
+				"artifacts/b.xml": {Size: 5},
+				"artifacts/c.xml": {Size: 6},
+			},
+			reference: map[string]FileInfo{
+				"build-log.txt": {Size: 100},
+			},
+			want: []string{"artifacts/b.xml", "artifacts/c.xml"},
+		},
+		{
+			name: "changed size",
+			remote: map[string]FileInfo{
+				"build-log.txt": {Size: 101},
+				"finished.json": {Size: 50},
+			},
+			reference: map[string]FileInfo{
+				"build-log.txt": {Size: 100},
+				"finished.json": {Size: 50},
+			},
+			want: []string{"build-log.txt"},
+		},
+		{
+			name: "same size, different hash",
+			remote: map[string]FileInfo{
+				"finished.json": {Size: 50, MD5: "aaaa"},
+				"started.json":  {Size: 40, MD5: "cccc"},
+			},
+			reference: map[string]FileInfo{
+				"finished.json": {Size: 50, MD5: "bbbb"},
+				"started.json":  {Size: 40, MD5: "cccc"},
+			},
+			want: []string{"finished.json"},
+		},
+		{
+			name: "hash unknown on one side falls back to size",
+			remote: map[string]FileInfo{
+				"finished.json": {Size: 50, MD5: "aaaa"},
+			},
+			reference: map[string]FileInfo{
+				"finished.json": {Size: 50},
+			},
+			want: nil,
+		},
+		{
+			name: "overlapping, new and changed together",
+			remote: map[string]FileInfo{
+				"same.txt":    {Size: 1},
+				"changed.txt": {Size: 3},
+				"new.txt":     {Size: 4},
+			},
+			reference: map[string]FileInfo{
+				"same.txt":     {Size: 1},
+				"changed.txt":  {Size: 2},
+				"only-old.txt": {Size: 9},
+			},
+			want: []string{"changed.txt", "new.txt"},
+		},
+		{
+			name:      "empty reference downloads everything",
+			remote:    map[string]FileInfo{"b": {Size: 1}, "a": {Size: 1}},
+			reference: nil,
+			want:      []string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FilesToDownload(tt.remote, tt.reference)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilesToDownload() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "artifacts", "junit"), 0755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "build-log.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "artifacts", "junit", "a.xml"), []byte("<x/>"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	got, err := ScanDir(dir)
+	if err != nil {
+		t.Fatalf("ScanDir() error = %v", err)
+	}
+	want := map[string]FileInfo{
+		"build-log.txt":         {Size: 5},
+		"artifacts/junit/a.xml": {Size: 4},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ScanDir() = %v, want %v", got, want)
+	}
+}
+
+func TestScanDir_Missing(t *testing.T) {
+	if _, err := ScanDir(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("ScanDir() should fail for a missing directory")
+	}
+}
+
+func TestMissingFiles(t *testing.T) {
+	a := map[string]FileInfo{"build-log.txt": {Size: 1}, "artifacts/junit.xml": {Size: 2}, "only-a.txt": {Size: 3}}
+	b := map[string]FileInfo{"build-log.txt": {Size: 9}, "artifacts/junit.xml": {Size: 2}, "only-b.txt": {Size: 4}}
+
+	if got, want := MissingFiles(a, b), []string{"only-a.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MissingFiles(a, b) = %v, want %v", got, want)
+	}
+	if got, want := MissingFiles(b, a), []string{"only-b.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MissingFiles(b, a) = %v, want %v", got, want)
+	}
+	if got := MissingFiles(a, a); got != nil {
+		t.Errorf("MissingFiles(a, a) = %v, want nil", got)
+	}
+}
@@ -2,6 +2,7 @@ package downloader
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -9,17 +10,56 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/clobrano/prow-helper/internal/logtail"
 	"github.com/clobrano/prow-helper/internal/parser"
+	"github.com/clobrano/prow-helper/internal/prompt"
 )
 
 var (
 	ErrGsutilNotFound    = errors.New("gsutil command not found. Please install Google Cloud SDK")
 	ErrDownloadFailed    = errors.New("failed to download artifacts")
 	ErrDestinationExists = errors.New("destination folder already exists")
+
+	// ErrAccessDenied and ErrNotFound refine ErrDownloadFailed when gsutil's
+	// stderr shows why the copy failed; errors.Is matches both against
+	// ErrDownloadFailed too.
+	ErrAccessDenied = fmt.Errorf("%w: access denied (check `gcloud auth login` and bucket permissions)", ErrDownloadFailed)
+	ErrNotFound     = fmt.Errorf("%w: no artifacts found at the GCS path", ErrDownloadFailed)
+
+	// ErrNoArtifacts refines ErrNotFound when the GCS path matched no object
+	// at all, which usually means the URL is wrong. The returned error names
+	// the path that was tried.
+	ErrNoArtifacts = fmt.Errorf("%w (no object matched)", ErrNotFound)
 )
 
+// gsutilCommand is the gsutil binary Download runs; tests point it at a fake.
+var gsutilCommand = "gsutil"
+
+// Quiet discards gsutil's output instead of streaming it to the writers given
+// to Download; a failure still reports the last lines gsutil wrote to stderr.
+var Quiet bool
+
+// stderrTailLines is how many trailing gsutil stderr lines a download error
+// includes.
+const stderrTailLines = 5
+
+// gsutilErrorSignatures maps substrings of gsutil's stderr to the error they
+// indicate, checked in order.
+var gsutilErrorSignatures = []struct {
+	substr string
+	err    error
+}{
+	{"AccessDeniedException", ErrAccessDenied},
+	{"does not have storage.objects", ErrAccessDenied},
+	{"Anonymous caller", ErrAccessDenied},
+	{"401 Unauthorized", ErrAccessDenied},
+	{"NotFoundException", ErrNotFound},
+	{"No URLs matched", ErrNoArtifacts},
+}
+
 // ConflictResolution represents the user's choice when destination exists.
 type ConflictResolution int
 
@@ -32,14 +72,18 @@ const (
 // BuildDestinationPath constructs the full destination path for artifacts.
 // Format: <baseDest>/<job-name>/<build-id>/
 func BuildDestinationPath(baseDest string, metadata *parser.ProwMetadata) string {
-	// Expand ~ to home directory if present
-	if strings.HasPrefix(baseDest, "~/") {
+	return filepath.Join(expandHome(baseDest), metadata.JobName, metadata.BuildID)
+}
+
+// expandHome expands a leading ~/ in path to the user's home directory.
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
 		home, err := os.UserHomeDir()
 		if err == nil {
-			baseDest = filepath.Join(home, baseDest[2:])
+			path = filepath.Join(home, path[2:])
 		}
 	}
-	return filepath.Join(baseDest, metadata.JobName, metadata.BuildID)
+	return path
 }
 
 // CheckDestinationConflict checks if the destination folder already exists.
@@ -60,18 +104,70 @@ func CreateTimestampedPath(basePath string) string {
 	return basePath + "-" + timestamp
 }
 
+// Strategies naming the new folder created next to an existing one.
+const (
+	CollisionTimestamp = "timestamp" // <path>-YYYYMMDD-HHMMSS
+	CollisionCounter   = "counter"   // <path>-N, N the lowest unused integer from 1
+)
+
+// CollisionSuffix is the strategy used when a new folder is chosen for an
+// existing destination. It is set from the collision_suffix setting.
+var CollisionSuffix = CollisionTimestamp
+
+// IsCollisionSuffix reports whether strategy is CollisionTimestamp or
+// CollisionCounter.
+func IsCollisionSuffix(strategy string) bool {
+	return strategy == CollisionTimestamp || strategy == CollisionCounter
+}
+
+// nextAvailablePath returns a path next to base for a new folder, named by
+// strategy: base with a timestamp suffix, or with the lowest integer suffix
+// not taken by an existing file.
+func nextAvailablePath(base string, strategy string) (string, error) {
+	switch strategy {
+	case CollisionTimestamp:
+		return CreateTimestampedPath(base), nil
+	case CollisionCounter:
+		for n := 1; ; n++ {
+			path := fmt.Sprintf("%s-%d", base, n)
+			if _, err := os.Lstat(path); os.IsNotExist(err) {
+				return path, nil
+			} else if err != nil {
+				return "", err
+			}
+		}
+	default:
+		return "", fmt.Errorf("unknown collision suffix %q (want %s or %s)", strategy, CollisionTimestamp, CollisionCounter)
+	}
+}
+
 // CheckGsutilAvailable verifies that gsutil is installed and accessible.
 func CheckGsutilAvailable() error {
-	_, err := exec.LookPath("gsutil")
+	_, err := exec.LookPath(gsutilCommand)
 	if err != nil {
 		return ErrGsutilNotFound
 	}
 	return nil
 }
 
-// Download executes the gsutil command to download artifacts.
-// It streams output to the provided writers for progress indication.
+// Download executes the gsutil command to download artifacts, or downloads
+// them over HTTP with the http Backend, DiffAgainst, FollowLinks or Verify.
+// It streams output to the provided writers for progress indication. On
+// failure the returned error wraps ErrDownloadFailed (or the more specific
+// ErrAccessDenied / ErrNotFound / ErrNoArtifacts) and ends with the last lines gsutil wrote to
+// stderr.
 func Download(gcsPath, destPath string, stdout, stderr io.Writer) error {
+	return DownloadWithFilter(gcsPath, destPath, Filter{}, stdout, stderr)
+}
+
+// DownloadWithFilter is Download restricted to the files selected by filter.
+// With the http Backend, DiffAgainst, FollowLinks or Verify it runs
+// DownloadHTTPWithFilter instead of gsutil.
+func DownloadWithFilter(gcsPath, destPath string, filter Filter, stdout, stderr io.Writer) error {
+	if Backend == BackendHTTP || DiffAgainst != "" || FollowLinks || Verify {
+		bucket, path := splitGCSPath(gcsPath)
+		return DownloadHTTPWithFilter(bucket, path, destPath, filter, stdout, stderr)
+	}
 	if err := CheckGsutilAvailable(); err != nil {
 		return err
 	}
@@ -80,10 +176,17 @@ func Download(gcsPath, destPath string, stdout, stderr io.Writer) error {
 	if err := os.MkdirAll(destPath, 0755); err != nil {
 		return fmt.Errorf("failed to create destination directory: %w", err)
 	}
+	// Flag the folder as partial until gsutil succeeds, so that a later run
+	// can tell an interrupted download from a complete one.
+	marker := filepath.Join(destPath, IncompleteMarker)
+	if err := os.WriteFile(marker, nil, 0644); err != nil {
+		return fmt.Errorf("failed to create %s: %w", IncompleteMarker, err)
+	}
 
 	// Build gsutil command
-	// gsutil -m cp -r gs://<bucket>/<path>/* <dest>
-	cmd := exec.Command("gsutil", "-m", "cp", "-r", gcsPath+"/*", destPath)
+	// gsutil -m cp -r gs://<bucket>/<path>/* <dest>, or rsync with a filter
+	args := GsutilArgs(gcsPath, destPath, filter)
+	cmd := exec.Command(gsutilCommand, args[1:]...)
 
 	// Set up pipes for output
 	stdoutPipe, err := cmd.StdoutPipe()
@@ -100,16 +203,51 @@ func Download(gcsPath, destPath string, stdout, stderr io.Writer) error {
 		return fmt.Errorf("failed to start gsutil: %w", err)
 	}
 
-	// Stream output
-	go streamOutput(stdoutPipe, stdout)
-	go streamOutput(stderrPipe, stderr)
+	// Stream output, keeping a copy of stderr for the error message. The
+	// pipes must be drained before Wait closes them.
+	if Quiet {
+		stdout, stderr = io.Discard, io.Discard
+	}
+	var stderrBuf bytes.Buffer
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		streamOutput(stdoutPipe, stdout)
+	}()
+	go func() {
+		defer wg.Done()
+		streamOutput(stderrPipe, io.MultiWriter(stderr, &stderrBuf))
+	}()
+	wg.Wait()
 
 	// Wait for command to complete
 	if err := cmd.Wait(); err != nil {
-		return fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+		return downloadError(gcsPath, err, stderrBuf.Bytes())
 	}
 
-	return nil
+	return os.Remove(marker)
+}
+
+// downloadError builds the error for a failed gsutil run of gcsPath from its
+// exit error and captured stderr.
+func downloadError(gcsPath string, err error, stderr []byte) error {
+	base := ErrDownloadFailed
+	for _, sig := range gsutilErrorSignatures {
+		if bytes.Contains(stderr, []byte(sig.substr)) {
+			base = sig.err
+			break
+		}
+	}
+	if base == ErrNoArtifacts {
+		base = fmt.Errorf("%w: %s", ErrNoArtifacts, gcsPath)
+	}
+
+	lines := logtail.TailBytes(stderr, stderrTailLines)
+	if len(lines) == 0 {
+		return fmt.Errorf("%w: %v", base, err)
+	}
+	return fmt.Errorf("%w: %v\n  %s", base, err, strings.Join(lines, "\n  "))
 }
 
 // streamOutput reads from reader and writes to writer line by line.
@@ -124,6 +262,9 @@ func streamOutput(reader io.Reader, writer io.Writer) {
 // Returns the user's choice.
 func PromptConflictResolution(path string, stdin io.Reader, stdout io.Writer) (ConflictResolution, error) {
 	fmt.Fprintf(stdout, "Folder exists: %s\n", path)
+	if desc := describeDir(path); desc != "" {
+		fmt.Fprintf(stdout, "  %s\n", desc)
+	}
 	fmt.Fprint(stdout, "[O]verwrite, [S]kip download, [N]ew timestamped folder? ")
 
 	reader := bufio.NewReader(stdin)
@@ -148,6 +289,14 @@ func PromptConflictResolution(path string, stdin io.Reader, stdout io.Writer) (C
 
 // ResolveDestination handles the full destination resolution including conflict handling.
 func ResolveDestination(baseDest string, metadata *parser.ProwMetadata, stdin io.Reader, stdout io.Writer) (string, bool, error) {
+	return ResolveDestinationWithPolicy(baseDest, metadata, prompt.Ask, stdin, stdout)
+}
+
+// ResolveDestinationWithPolicy is ResolveDestination that prompts about an
+// existing folder according to policy: prompt.Yes picks the safe default, a
+// new timestamped folder, which keeps the existing download; prompt.Never
+// returns an error matching prompt.ErrDisabled.
+func ResolveDestinationWithPolicy(baseDest string, metadata *parser.ProwMetadata, policy prompt.Policy, stdin io.Reader, stdout io.Writer) (string, bool, error) {
 	destPath := BuildDestinationPath(baseDest, metadata)
 
 	exists, err := CheckDestinationConflict(destPath)
@@ -159,16 +308,29 @@ func ResolveDestination(baseDest string, metadata *parser.ProwMetadata, stdin io
 		return destPath, false, nil
 	}
 
-	resolution, err := PromptConflictResolution(destPath, stdin, stdout)
-	if err != nil {
-		return "", false, err
+	var resolution ConflictResolution
+	switch policy {
+	case prompt.Never:
+		return "", false, prompt.Disabled("folder exists: " + destPath)
+	case prompt.Yes:
+		fmt.Fprintf(stdout, "Folder exists: %s, downloading to a new timestamped folder\n", destPath)
+		resolution = NewTimestamped
+	default:
+		resolution, err = PromptConflictResolution(destPath, stdin, stdout)
+		if err != nil {
+			return "", false, err
+		}
 	}
 
 	switch resolution {
 	case Skip:
 		return destPath, true, nil
 	case NewTimestamped:
-		return CreateTimestampedPath(destPath), false, nil
+		newPath, err := nextAvailablePath(destPath, CollisionSuffix)
+		if err != nil {
+			return "", false, err
+		}
+		return newPath, false, nil
 	default: // Overwrite
 		// Remove existing directory
 		if err := os.RemoveAll(destPath); err != nil {
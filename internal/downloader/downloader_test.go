@@ -2,12 +2,16 @@ package downloader
 
 import (
 	"bytes"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 
 	"github.com/clobrano/prow-helper/internal/parser"
+	"github.com/clobrano/prow-helper/internal/prompt"
 )
 
 func TestBuildDestinationPath(t *testing.T) {
@@ -113,6 +117,64 @@ func TestCreateTimestampedPath(t *testing.T) {
 	}
 }
 
+func TestNextAvailablePath(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "test-job", "123")
+	for _, dir := range []string{base, base + "-1", base + "-2", base + "-4"} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := nextAvailablePath(base, CollisionCounter)
+	if err != nil {
+		t.Fatalf("nextAvailablePath(counter) error = %v", err)
+	}
+	if want := base + "-3"; got != want {
+		t.Errorf("nextAvailablePath(counter) = %v, want %v", got, want)
+	}
+
+	// A file, not only a folder, takes the suffix.
+	if err := os.WriteFile(base+"-3", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := nextAvailablePath(base, CollisionCounter); got != base+"-5" {
+		t.Errorf("nextAvailablePath(counter) = %v, want %v", got, base+"-5")
+	}
+
+	got, err = nextAvailablePath(base, CollisionTimestamp)
+	if err != nil {
+		t.Fatalf("nextAvailablePath(timestamp) error = %v", err)
+	}
+	if len(got) != len(base)+16 || !strings.HasPrefix(got, base+"-") {
+		t.Errorf("nextAvailablePath(timestamp) = %v, want a timestamp suffix", got)
+	}
+
+	if _, err := nextAvailablePath(base, "random"); err == nil {
+		t.Error("nextAvailablePath(random) error = nil, want an error for an unknown strategy")
+	}
+}
+
+func TestResolveDestination_CounterSuffix(t *testing.T) {
+	orig := CollisionSuffix
+	t.Cleanup(func() { CollisionSuffix = orig })
+	CollisionSuffix = CollisionCounter
+
+	baseDest := t.TempDir()
+	metadata := &parser.ProwMetadata{JobName: "test-job", BuildID: "123"}
+	existing := BuildDestinationPath(baseDest, metadata)
+	if err := os.MkdirAll(existing, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	got, skip, err := ResolveDestinationWithPolicy(baseDest, metadata, prompt.Yes, strings.NewReader(""), io.Discard)
+	if err != nil {
+		t.Fatalf("ResolveDestinationWithPolicy() error = %v", err)
+	}
+	if skip || got != existing+"-1" {
+		t.Errorf("ResolveDestinationWithPolicy() = %v, %v, want %v-1", got, skip, existing)
+	}
+}
+
 func TestPromptConflictResolution(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -228,3 +290,187 @@ func TestResolveDestination_NewTimestamped(t *testing.T) {
 		t.Errorf("ResolveDestination() = %v, should be timestamped version", destPath)
 	}
 }
+
+func TestResolveDestinationWithPolicy_NoPrompt(t *testing.T) {
+	tmpDir := t.TempDir()
+	metadata := &parser.ProwMetadata{JobName: "existing-job", BuildID: "333"}
+	if err := os.MkdirAll(filepath.Join(tmpDir, "existing-job", "333"), 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+
+	// An empty stdin would make a prompt default to overwrite: it must not be read.
+	_, _, err := ResolveDestinationWithPolicy(tmpDir, metadata, prompt.Never, strings.NewReader(""), &bytes.Buffer{})
+	if !errors.Is(err, prompt.ErrDisabled) {
+		t.Fatalf("ResolveDestinationWithPolicy() error = %v, want prompt.ErrDisabled", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "existing-job", "333")); err != nil {
+		t.Errorf("existing folder was touched: %v", err)
+	}
+}
+
+func TestResolveDestinationWithPolicy_Yes(t *testing.T) {
+	tmpDir := t.TempDir()
+	metadata := &parser.ProwMetadata{JobName: "existing-job", BuildID: "444"}
+	existingPath := filepath.Join(tmpDir, "existing-job", "444")
+	if err := os.MkdirAll(existingPath, 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+
+	destPath, skip, err := ResolveDestinationWithPolicy(tmpDir, metadata, prompt.Yes, strings.NewReader(""), &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("ResolveDestinationWithPolicy() error = %v", err)
+	}
+	if skip {
+		t.Error("ResolveDestinationWithPolicy() skip = true, want the download to proceed")
+	}
+	if !strings.HasPrefix(destPath, existingPath+"-") {
+		t.Errorf("ResolveDestinationWithPolicy() = %v, want a timestamped version of %v", destPath, existingPath)
+	}
+	if _, err := os.Stat(existingPath); err != nil {
+		t.Errorf("existing folder was removed: %v", err)
+	}
+}
+
+// fakeGsutil points gsutilCommand at a script that writes stderr and exits
+// with exitCode.
+func fakeGsutil(t *testing.T, stderr string, exitCode int) {
+	t.Helper()
+	script := filepath.Join(t.TempDir(), "gsutil")
+	content := "#!/bin/sh\nprintf '%s' '" + strings.ReplaceAll(stderr, "'", `'\''`) + "' >&2\nexit " + strconv.Itoa(exitCode) + "\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	orig := gsutilCommand
+	gsutilCommand = script
+	t.Cleanup(func() { gsutilCommand = orig })
+}
+
+func TestDownload_GsutilErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		stderr    string
+		exitCode  int
+		wantErr   error
+		wantInMsg string
+	}{
+		{
+			name:     "success",
+			stderr:   "Copying gs://bucket/path/build-log.txt...\n",
+			exitCode: 0,
+		},
+		{
+			name:      "access denied",
+			stderr:    "Copying gs://bucket/path/build-log.txt...\nAccessDeniedException: 403 user@example.com does not have storage.objects.list access\n",
+			exitCode:  1,
+			wantErr:   ErrAccessDenied,
+			wantInMsg: "AccessDeniedException: 403",
+		},
+		{
+			name:      "not found",
+			stderr:    "CommandException: No URLs matched: gs://bucket/path/*\n",
+			exitCode:  1,
+			wantErr:   ErrNotFound,
+			wantInMsg: "No URLs matched",
+		},
+		{
+			name:      "unrecognized failure",
+			stderr:    "line 1\nline 2\nline 3\nline 4\nline 5\nServiceException: 503 backend error\n",
+			exitCode:  1,
+			wantErr:   ErrDownloadFailed,
+			wantInMsg: "ServiceException: 503",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeGsutil(t, tt.stderr, tt.exitCode)
+
+			var stderr bytes.Buffer
+			dest := t.TempDir()
+			err := Download("gs://bucket/path", dest, &bytes.Buffer{}, &stderr)
+			if stderr.String() != tt.stderr {
+				t.Errorf("streamed stderr = %q, want %q", stderr.String(), tt.stderr)
+			}
+			_, statErr := os.Stat(filepath.Join(dest, IncompleteMarker))
+			if markerLeft := statErr == nil; markerLeft != (tt.wantErr != nil) {
+				t.Errorf("%s left behind = %v, want %v", IncompleteMarker, markerLeft, tt.wantErr != nil)
+			}
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("Download() error = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Download() error = %v, want %v", err, tt.wantErr)
+			}
+			if !errors.Is(err, ErrDownloadFailed) {
+				t.Errorf("Download() error = %v, want it to match ErrDownloadFailed", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantInMsg) {
+				t.Errorf("Download() error = %q, want it to contain %q", err, tt.wantInMsg)
+			}
+		})
+	}
+}
+
+func TestDownload_StderrTail(t *testing.T) {
+	fakeGsutil(t, "old 1\nold 2\nkeep 1\nkeep 2\nkeep 3\nkeep 4\nkeep 5\n", 1)
+
+	err := Download("gs://bucket/path", t.TempDir(), &bytes.Buffer{}, &bytes.Buffer{})
+	if err == nil {
+		t.Fatal("Download() error = nil, want failure")
+	}
+	if strings.Contains(err.Error(), "old") {
+		t.Errorf("Download() error = %q, want only the last %d stderr lines", err, stderrTailLines)
+	}
+	if !strings.Contains(err.Error(), "keep 1") || !strings.Contains(err.Error(), "keep 5") {
+		t.Errorf("Download() error = %q, want the last stderr lines", err)
+	}
+}
+
+func TestDownload_NoArtifacts(t *testing.T) {
+	fakeGsutil(t, "CommandException: No URLs matched: gs://bucket/logs/job/12/*\n", 1)
+
+	err := Download("gs://bucket/logs/job/12/", t.TempDir(), &bytes.Buffer{}, &bytes.Buffer{})
+	if !errors.Is(err, ErrNoArtifacts) {
+		t.Fatalf("Download() error = %v, want ErrNoArtifacts", err)
+	}
+	if !strings.Contains(err.Error(), "(no object matched): gs://bucket/logs/job/12/") {
+		t.Errorf("Download() error = %q, want it to name the GCS path", err)
+	}
+}
+
+func TestDownload_Quiet(t *testing.T) {
+	orig := Quiet
+	Quiet = true
+	t.Cleanup(func() { Quiet = orig })
+
+	script := filepath.Join(t.TempDir(), "gsutil")
+	content := "#!/bin/sh\necho 'Copying gs://bucket/path/build-log.txt...'\necho 'Operation completed over 1 objects.' >&2\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	origCmd := gsutilCommand
+	gsutilCommand = script
+	t.Cleanup(func() { gsutilCommand = origCmd })
+
+	var stdout, stderr bytes.Buffer
+	if err := Download("gs://bucket/path", t.TempDir(), &stdout, &stderr); err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if stdout.Len() != 0 || stderr.Len() != 0 {
+		t.Errorf("Download() wrote stdout %q and stderr %q, want nothing from gsutil", stdout.String(), stderr.String())
+	}
+
+	// A failure still explains itself.
+	fakeGsutil(t, "AccessDeniedException: 403 denied\n", 1)
+	stderr.Reset()
+	err := Download("gs://bucket/path", t.TempDir(), &stdout, &stderr)
+	if !errors.Is(err, ErrAccessDenied) || !strings.Contains(err.Error(), "AccessDeniedException: 403 denied") {
+		t.Errorf("Download() error = %v, want ErrAccessDenied with gsutil's stderr", err)
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("Download() streamed stderr %q, want nothing", stderr.String())
+	}
+}
@@ -0,0 +1,143 @@
+package downloader
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/clobrano/prow-helper/internal/parser"
+)
+
+// Filter selects the artifacts of a build to download with glob patterns
+// matched against the slash-separated path of each file relative to the
+// build root. A pattern without a slash matches the file name at any depth
+// (junit*.xml); one with a slash matches from the build root
+// (artifacts/e2e/*.log). "*" and "?" do not cross a slash, "**" does, and a
+// trailing slash selects everything under a folder.
+//
+// A file is downloaded when it matches an Include pattern, or Include is
+// empty, and it matches no Exclude pattern.
+type Filter struct {
+	Include []string
+	Exclude []string
+}
+
+// DefaultPreset is the preset that downloads every artifact.
+const DefaultPreset = "all"
+
+// Presets are the named filters selectable with --preset.
+var Presets = map[string]Filter{
+	DefaultPreset: {},
+	"logs":        {Include: []string{"build-log.txt", "junit*.xml", "finished.json", "started.json"}},
+	"junit":       {Include: []string{"junit*.xml"}},
+}
+
+// PresetNames returns the names of the Presets, sorted.
+func PresetNames() []string {
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveFilter expands preset and extends it with explicit include and
+// exclude patterns: include adds to the files the preset selects (with the
+// "all" preset, it restricts the download to them) and exclude removes files
+// from the result.
+func ResolveFilter(preset string, include, exclude []string) (Filter, error) {
+	base, ok := Presets[preset]
+	if !ok {
+		return Filter{}, fmt.Errorf("unknown preset %q (valid: %s)", preset, strings.Join(PresetNames(), ", "))
+	}
+	f := Filter{
+		Include: append(append([]string(nil), base.Include...), include...),
+		Exclude: append(append([]string(nil), base.Exclude...), exclude...),
+	}
+	for _, p := range append(append([]string(nil), f.Include...), f.Exclude...) {
+		if strings.TrimSpace(p) == "" {
+			return Filter{}, fmt.Errorf("empty include/exclude pattern")
+		}
+	}
+	return f, nil
+}
+
+// Empty reports whether f selects every file.
+func (f Filter) Empty() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// Match reports whether the file at the slash-separated path, relative to
+// the build root, is selected by f.
+func (f Filter) Match(path string) bool {
+	if len(f.Include) > 0 && !regexp.MustCompile(anchored(f.Include)).MatchString(path) {
+		return false
+	}
+	return len(f.Exclude) == 0 || !regexp.MustCompile(anchored(f.Exclude)).MatchString(path)
+}
+
+// ExcludeRegex returns the Python regular expression for gsutil rsync -x that
+// skips every file f does not select, or "" when f is empty. Includes are
+// expressed with a negative lookahead, which gsutil's Python regular
+// expressions support.
+func (f Filter) ExcludeRegex() string {
+	var alts []string
+	if len(f.Include) > 0 {
+		alts = append(alts, "^(?!"+anchored(f.Include)[1:]+").*$")
+	}
+	if len(f.Exclude) > 0 {
+		alts = append(alts, anchored(f.Exclude))
+	}
+	return strings.Join(alts, "|")
+}
+
+// anchored returns a regular expression matching a whole path against any of
+// the glob patterns.
+func anchored(patterns []string) string {
+	alts := make([]string, len(patterns))
+	for i, p := range patterns {
+		alts[i] = globRegex(p)
+	}
+	return "^(?:" + strings.Join(alts, "|") + ")$"
+}
+
+// globRegex translates a Filter glob pattern into a regular expression
+// (without anchors) valid in both Go and Python.
+func globRegex(pattern string) string {
+	var b strings.Builder
+	if !strings.Contains(strings.TrimSuffix(pattern, "/"), "/") {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '*' && strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case c == '*' && strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if strings.HasSuffix(pattern, "/") {
+		b.WriteString(".*")
+	}
+	return b.String()
+}
+
+// GsutilArgs returns the argv of the gsutil command that downloads the files
+// of gcsPath selected by filter into dest: a recursive copy for an empty
+// filter, an rsync with an exclusion regex otherwise.
+func GsutilArgs(gcsPath, dest string, filter Filter) []string {
+	if filter.Empty() {
+		return parser.GsutilCopyArgs(gcsPath, dest)
+	}
+	return parser.GsutilSyncArgs(gcsPath, dest, filter.ExcludeRegex())
+}
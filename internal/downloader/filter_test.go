@@ -0,0 +1,133 @@
+package downloader
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestResolveFilter_Presets(t *testing.T) {
+	tests := []struct {
+		preset string
+		want   Filter
+	}{
+		{"all", Filter{}},
+		{"logs", Filter{Include: []string{"build-log.txt", "junit*.xml", "finished.json", "started.json"}}},
+		{"junit", Filter{Include: []string{"junit*.xml"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.preset, func(t *testing.T) {
+			got, err := ResolveFilter(tt.preset, nil, nil)
+			if err != nil {
+				t.Fatalf("ResolveFilter() error = %v", err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("ResolveFilter(%q) = %+v, want %+v", tt.preset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveFilter_Combined(t *testing.T) {
+	got, err := ResolveFilter("junit", []string{"artifacts/e2e/*.log"}, []string{"**/junit_skipped*.xml"})
+	if err != nil {
+		t.Fatalf("ResolveFilter() error = %v", err)
+	}
+	want := Filter{
+		Include: []string{"junit*.xml", "artifacts/e2e/*.log"},
+		Exclude: []string{"**/junit_skipped*.xml"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ResolveFilter() = %+v, want %+v", got, want)
+	}
+	if Presets["junit"].Include[0] != "junit*.xml" || len(Presets["junit"].Include) != 1 {
+		t.Errorf("ResolveFilter() modified the preset: %+v", Presets["junit"])
+	}
+
+	tests := map[string]bool{
+		"artifacts/junit_e2e.xml":             true,
+		"artifacts/e2e/node.log":              true,
+		"artifacts/e2e/sub/node.log":          false,
+		"artifacts/junit_skipped_upgrade.xml": false,
+		"build-log.txt":                       false,
+	}
+	for path, want := range tests {
+		if got := got.Match(path); got != want {
+			t.Errorf("Match(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestResolveFilter_Errors(t *testing.T) {
+	if _, err := ResolveFilter("everything", nil, nil); err == nil {
+		t.Error("ResolveFilter() error = nil for an unknown preset")
+	}
+	if _, err := ResolveFilter("all", []string{""}, nil); err == nil {
+		t.Error("ResolveFilter() error = nil for an empty pattern")
+	}
+}
+
+func TestFilter_Match(t *testing.T) {
+	logs := Presets["logs"]
+	tests := []struct {
+		name   string
+		filter Filter
+		path   string
+		want   bool
+	}{
+		{"empty filter matches all", Filter{}, "artifacts/must-gather.tar", true},
+		{"preset at root", logs, "build-log.txt", true},
+		{"preset at any depth", logs, "artifacts/e2e/build-log.txt", true},
+		{"preset glob", logs, "artifacts/e2e/junit/junit_e2e_20240224.xml", true},
+		{"preset skips others", logs, "artifacts/must-gather.tar", false},
+		{"star does not cross slash", Filter{Include: []string{"artifacts/*.log"}}, "artifacts/e2e/a.log", false},
+		{"double star crosses slash", Filter{Include: []string{"artifacts/**/*.log"}}, "artifacts/e2e/a.log", true},
+		{"double star matches no folder", Filter{Include: []string{"artifacts/**/*.log"}}, "artifacts/a.log", true},
+		{"folder pattern", Filter{Include: []string{"artifacts/e2e/"}}, "artifacts/e2e/gather/x.txt", true},
+		{"question mark", Filter{Include: []string{"started.jso?"}}, "started.json", true},
+		{"dot is literal", Filter{Include: []string{"finished.json"}}, "finishedxjson", false},
+		{"exclude only", Filter{Exclude: []string{"*.tar"}}, "artifacts/must-gather.tar", false},
+		{"exclude only keeps others", Filter{Exclude: []string{"*.tar"}}, "build-log.txt", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(tt.path); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter_ExcludeRegex(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		want   string
+	}{
+		{"empty", Filter{}, ""},
+		{"include", Filter{Include: []string{"junit*.xml"}}, `^(?!(?:(?:.*/)?junit[^/]*\.xml)$).*$`},
+		{"exclude", Filter{Exclude: []string{"artifacts/**"}}, `^(?:artifacts/.*)$`},
+		{"both", Filter{Include: []string{"*.log"}, Exclude: []string{"a.log"}}, `^(?!(?:(?:.*/)?[^/]*\.log)$).*$|^(?:(?:.*/)?a\.log)$`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.ExcludeRegex(); got != tt.want {
+				t.Errorf("ExcludeRegex() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGsutilArgs(t *testing.T) {
+	got := GsutilArgs("gs://bucket/logs/job/1", "/dest", Filter{})
+	want := []string{"gsutil", "-m", "cp", "-r", "gs://bucket/logs/job/1/*", "/dest"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GsutilArgs() = %q, want %q", got, want)
+	}
+
+	got = GsutilArgs("gs://bucket/logs/job/1", "/dest", Filter{Exclude: []string{"*.tar"}})
+	want = []string{"gsutil", "-m", "rsync", "-r", "-x", `^(?:(?:.*/)?[^/]*\.tar)$`, "gs://bucket/logs/job/1", "/dest"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GsutilArgs() with filter = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,183 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/clobrano/prow-helper/internal/httpclient"
+)
+
+// Download backends.
+const (
+	BackendGsutil = "gsutil" // gsutil -m cp -r, or rsync with a filter
+	BackendHTTP   = "http"   // the GCS JSON API listing and plain HTTPS reads, no Cloud SDK needed
+)
+
+// Backend is how Download and DownloadWithFilter fetch the artifacts. It is
+// set from the downloader setting.
+var Backend = BackendGsutil
+
+// IsBackend reports whether name is BackendGsutil or BackendHTTP.
+func IsBackend(name string) bool {
+	return name == BackendGsutil || name == BackendHTTP
+}
+
+// HTTPWorkers is how many objects DownloadHTTP fetches at once.
+var HTTPWorkers = 8
+
+// DiffAgainst, when set, is the folder of an earlier download of a related
+// build: DownloadHTTPWithFilter then skips the objects whose file there has
+// the same path and size, fetching only the new and changed artifacts.
+var DiffAgainst string
+
+// FollowLinks makes DownloadHTTPWithFilter fetch the targets of Prow symlink
+// markers instead of the markers themselves, so the download is
+// self-contained.
+var FollowLinks bool
+
+// Verify makes DownloadHTTPWithFilter compare the CRC32C of the files already
+// in the destination with their object's before keeping them, rather than
+// only their size (see shouldDownload).
+var Verify bool
+
+// DownloadHTTP downloads the objects under gs://<bucket>/<path> into destPath
+// without gsutil: it lists them with the GCS JSON API and fetches them over
+// HTTPS, HTTPWorkers at a time, so it only works for publicly readable
+// buckets. The folder layout is the one gsutil produces. A "Copying ..."
+// line is written to stdout for each object and failures to stderr; the
+// returned error wraps ErrDownloadFailed, or ErrNoArtifacts when there is
+// no object at all.
+func DownloadHTTP(bucket, path, destPath string, stdout, stderr io.Writer) error {
+	return DownloadHTTPWithFilter(bucket, path, destPath, Filter{}, stdout, stderr)
+}
+
+// DownloadHTTPWithFilter is DownloadHTTP restricted to the files selected by
+// filter. Files already in destPath with the size of their object, and with
+// Verify its checksum, are kept.
+// With FollowLinks, Prow symlink markers are replaced by their targets (see
+// ResolveLinks).
+func DownloadHTTPWithFilter(bucket, path, destPath string, filter Filter, stdout, stderr io.Writer) error {
+	objects, err := ListArtifacts("gs://" + bucket + "/" + path)
+	if errors.Is(err, ErrDownloadFailed) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+	}
+
+	transfers := plainTransfers(bucket, path, objects)
+	if FollowLinks {
+		if transfers, err = ResolveLinks(bucket, path, objects); err != nil {
+			return fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+		}
+	}
+	var unchanged map[string]bool
+	if DiffAgainst != "" {
+		if unchanged, err = unchangedFiles(DiffAgainst, transfers); err != nil {
+			return fmt.Errorf("failed to read the reference download: %w", err)
+		}
+	}
+
+	// Destinations come from object names and, through symlink markers, from
+	// object contents: check them all before writing anything.
+	var selected []Transfer
+	var errs []error
+	for _, tr := range transfers {
+		// Names ending with a slash are folder placeholders.
+		if tr.Dest == "" || strings.HasSuffix(tr.Dest, "/") || !filter.Match(tr.Dest) || unchanged[tr.Dest] {
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(tr.Dest)) {
+			errs = append(errs, fmt.Errorf("refusing to write gs://%s/%s outside %s", tr.Bucket, tr.Object.Name, destPath))
+			continue
+		}
+		selected = append(selected, tr)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrDownloadFailed, errors.Join(errs...))
+	}
+
+	if err := os.MkdirAll(destPath, 0755); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+	marker := filepath.Join(destPath, IncompleteMarker)
+	if err := os.WriteFile(marker, nil, 0644); err != nil {
+		return fmt.Errorf("failed to create %s: %w", IncompleteMarker, err)
+	}
+
+	if Quiet {
+		stdout, stderr = io.Discard, io.Discard
+	}
+	queue := make(chan Transfer)
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for range max(HTTPWorkers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for tr := range queue {
+				mu.Lock()
+				fmt.Fprintf(stdout, "Copying gs://%s/%s...\n", tr.Bucket, tr.Object.Name)
+				mu.Unlock()
+				target := filepath.Join(destPath, filepath.FromSlash(tr.Dest))
+				if err := fetchObject(tr.Bucket, tr.Object.Name, target); err != nil {
+					mu.Lock()
+					fmt.Fprintln(stderr, err)
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, tr := range selected {
+		existing, err := statExisting(filepath.Join(destPath, filepath.FromSlash(tr.Dest)), Verify)
+		if err == nil && !shouldDownload(existing, tr.Object, Verify) {
+			continue
+		}
+		queue <- tr
+	}
+	close(queue)
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %d of the objects failed: %w", ErrDownloadFailed, len(errs), errors.Join(errs...))
+	}
+	return os.Remove(marker)
+}
+
+// fetchObject writes the content of the object name of bucket to the file
+// at target, creating its folder. A partial file is removed.
+func fetchObject(bucket, name, target string) error {
+	objectURL := gcsBaseURL() + (&url.URL{Path: "/" + bucket + "/" + name}).EscapedPath()
+	resp, err := httpclient.Get(objectURL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch gs://%s/%s: %w", bucket, name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching gs://%s/%s returned HTTP %d", bucket, name, resp.StatusCode)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		os.Remove(target)
+		return fmt.Errorf("failed to fetch gs://%s/%s: %w", bucket, name, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,321 @@
+package downloader
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeGCS serves a GCS JSON API listing of objects, a map of object names in
+// bucket to their content, and the objects themselves. It returns the names
+// of the objects fetched.
+func fakeGCS(t *testing.T, bucket string, objects map[string]string) func() []string {
+	t.Helper()
+	var mu sync.Mutex
+	var fetched []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/storage/v1/b/"+bucket+"/o" {
+			var page gcsListResponse
+			for name, content := range objects {
+				if strings.HasPrefix(name, r.URL.Query().Get("prefix")) {
+					page.Items = append(page.Items, gcsObject{Name: name, Size: strconv.Itoa(len(content)), CRC32C: crc32cOf(content)})
+				}
+			}
+			json.NewEncoder(w).Encode(page)
+			return
+		}
+		name := strings.TrimPrefix(r.URL.Path, "/"+bucket+"/")
+		content, ok := objects[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		fetched = append(fetched, name)
+		mu.Unlock()
+		w.Write([]byte(content))
+	}))
+	t.Cleanup(server.Close)
+	orig := gcsBaseURL
+	gcsBaseURL = func() string { return server.URL }
+	t.Cleanup(func() { gcsBaseURL = orig })
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return fetched
+	}
+}
+
+// crc32cOf returns the CRC32C of content as GCS reports it.
+func crc32cOf(content string) string {
+	sum := crc32.Checksum([]byte(content), crc32.MakeTable(crc32.Castagnoli))
+	return base64.StdEncoding.EncodeToString(binary.BigEndian.AppendUint32(nil, sum))
+}
+
+func TestDownloadHTTP(t *testing.T) {
+	fetched := fakeGCS(t, "bucket", map[string]string{
+		"logs/job/1/build-log.txt":                    "log",
+		"logs/job/1/artifacts/e2e/junit_e2e.xml":      "<testsuite/>",
+		"logs/job/1/artifacts/with space/file #1.txt": "x",
+		"logs/job/1/artifacts/":                       "",
+		"logs/job/12/build-log.txt":                   "other build",
+	})
+
+	dest := t.TempDir()
+	var stdout bytes.Buffer
+	if err := DownloadHTTP("bucket", "logs/job/1", dest, &stdout, &bytes.Buffer{}); err != nil {
+		t.Fatalf("DownloadHTTP() error = %v", err)
+	}
+	for rel, want := range map[string]string{
+		"build-log.txt":                    "log",
+		"artifacts/e2e/junit_e2e.xml":      "<testsuite/>",
+		"artifacts/with space/file #1.txt": "x",
+	} {
+		got, err := os.ReadFile(filepath.Join(dest, rel))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v, want %q", rel, got, err, want)
+		}
+	}
+	if len(fetched()) != 3 {
+		t.Errorf("fetched %v, want the 3 files of build 1", fetched())
+	}
+	if !strings.Contains(stdout.String(), "Copying gs://bucket/logs/job/1/build-log.txt...\n") {
+		t.Errorf("stdout = %q, want a line per object", stdout.String())
+	}
+	if _, err := os.Stat(filepath.Join(dest, IncompleteMarker)); err == nil {
+		t.Errorf("%s left behind after a successful download", IncompleteMarker)
+	}
+}
+
+func TestDownloadHTTPWithFilter_SkipsExisting(t *testing.T) {
+	fetched := fakeGCS(t, "bucket", map[string]string{
+		"logs/job/1/build-log.txt":        "log",
+		"logs/job/1/finished.json":        "{}",
+		"logs/job/1/artifacts/junit.xml":  "<testsuite/>",
+		"logs/job/1/artifacts/gather.tar": "big",
+	})
+
+	dest := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dest, "build-log.txt"), []byte("LOG"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	filter := Filter{Include: []string{"build-log.txt", "junit*.xml", "finished.json"}}
+	if err := DownloadHTTPWithFilter("bucket", "logs/job/1/", dest, filter, &bytes.Buffer{}, &bytes.Buffer{}); err != nil {
+		t.Fatalf("DownloadHTTPWithFilter() error = %v", err)
+	}
+	names := fetched()
+	slices.Sort(names)
+	got := strings.Join(names, ",")
+	if want := "logs/job/1/artifacts/junit.xml,logs/job/1/finished.json"; got != want {
+		t.Errorf("fetched %s, want %s (filtered, and build-log.txt already there)", got, want)
+	}
+}
+
+func TestDownloadHTTPWithFilter_Verify(t *testing.T) {
+	for _, verify := range []bool{false, true} {
+		t.Run(fmt.Sprintf("verify=%v", verify), func(t *testing.T) {
+			fetched := fakeGCS(t, "bucket", map[string]string{
+				"logs/job/1/build-log.txt": "log",
+				"logs/job/1/junit.xml":     "<testsuite/>",
+			})
+			orig := Verify
+			Verify = verify
+			t.Cleanup(func() { Verify = orig })
+
+			// Both files have the size of their object; only junit.xml
+			// has its content.
+			dest := t.TempDir()
+			for name, content := range map[string]string{"build-log.txt": "LOG", "junit.xml": "<testsuite/>"} {
+				if err := os.WriteFile(filepath.Join(dest, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := DownloadHTTP("bucket", "logs/job/1", dest, &bytes.Buffer{}, &bytes.Buffer{}); err != nil {
+				t.Fatalf("DownloadHTTP() error = %v", err)
+			}
+			want := ""
+			if verify {
+				want = "logs/job/1/build-log.txt"
+			}
+			if got := strings.Join(fetched(), ","); got != want {
+				t.Errorf("fetched %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestDownloadHTTP_DiffAgainst(t *testing.T) {
+	fetched := fakeGCS(t, "bucket", map[string]string{
+		"logs/job/2/build-log.txt":            "same",
+		"logs/job/2/artifacts/junit.xml":      "<testsuite failures=\"1\"/>",
+		"logs/job/2/artifacts/new/events.log": "new",
+	})
+
+	reference := t.TempDir()
+	for rel, content := range map[string]string{
+		"build-log.txt":       "SAME",
+		"artifacts/junit.xml": "<testsuite/>",
+	} {
+		path := filepath.Join(reference, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	orig := DiffAgainst
+	DiffAgainst = reference
+	t.Cleanup(func() { DiffAgainst = orig })
+
+	dest := t.TempDir()
+	if err := DownloadHTTP("bucket", "logs/job/2", dest, &bytes.Buffer{}, &bytes.Buffer{}); err != nil {
+		t.Fatalf("DownloadHTTP() error = %v", err)
+	}
+	names := fetched()
+	slices.Sort(names)
+	got := strings.Join(names, ",")
+	if want := "logs/job/2/artifacts/junit.xml,logs/job/2/artifacts/new/events.log"; got != want {
+		t.Errorf("fetched %s, want %s (build-log.txt has the same size in the reference)", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "build-log.txt")); err == nil {
+		t.Error("build-log.txt downloaded although unchanged")
+	}
+}
+
+func TestDownloadHTTP_Errors(t *testing.T) {
+	fakeGCS(t, "bucket", map[string]string{"logs/job/1/build-log.txt": "log"})
+
+	err := DownloadHTTP("bucket", "logs/job/2", t.TempDir(), &bytes.Buffer{}, &bytes.Buffer{})
+	if !errors.Is(err, ErrNoArtifacts) || !strings.Contains(err.Error(), "gs://bucket/logs/job/2") {
+		t.Errorf("DownloadHTTP() of an empty listing error = %v, want ErrNoArtifacts naming the path", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/storage/") {
+			w.Write([]byte(`{"items":[{"name":"logs/job/1/build-log.txt","size":"3"}]}`))
+			return
+		}
+		http.Error(w, "denied", http.StatusForbidden)
+	}))
+	t.Cleanup(server.Close)
+	gcsBaseURL = func() string { return server.URL }
+
+	dest := t.TempDir()
+	var stderr bytes.Buffer
+	err = DownloadHTTP("bucket", "logs/job/1", dest, &bytes.Buffer{}, &stderr)
+	if !errors.Is(err, ErrDownloadFailed) || !strings.Contains(err.Error(), "HTTP 403") {
+		t.Errorf("DownloadHTTP() error = %v, want ErrDownloadFailed with the HTTP status", err)
+	}
+	if !strings.Contains(stderr.String(), "gs://bucket/logs/job/1/build-log.txt") {
+		t.Errorf("stderr = %q, want the failed object", stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(dest, IncompleteMarker)); err != nil {
+		t.Errorf("%s missing after a failed download: %v", IncompleteMarker, err)
+	}
+}
+
+func TestDownloadWithFilter_HTTPBackend(t *testing.T) {
+	fakeGCS(t, "bucket", map[string]string{"logs/job/1/build-log.txt": "log"})
+	origBackend, origCmd := Backend, gsutilCommand
+	Backend, gsutilCommand = BackendHTTP, "gsutil-not-installed"
+	t.Cleanup(func() { Backend, gsutilCommand = origBackend, origCmd })
+
+	dest := t.TempDir()
+	if err := Download("gs://bucket/logs/job/1", dest, &bytes.Buffer{}, &bytes.Buffer{}); err != nil {
+		t.Fatalf("Download() error = %v, want the HTTP backend to run without gsutil", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "build-log.txt")); err != nil {
+		t.Errorf("build-log.txt not downloaded: %v", err)
+	}
+}
+
+func TestDownload_DiffAgainstUsesHTTP(t *testing.T) {
+	fakeGCS(t, "bucket", map[string]string{"logs/job/1/build-log.txt": "log"})
+	orig := DiffAgainst
+	DiffAgainst = t.TempDir()
+	t.Cleanup(func() { DiffAgainst = orig })
+
+	dest := t.TempDir()
+	if err := Download("gs://bucket/logs/job/1", dest, &bytes.Buffer{}, &bytes.Buffer{}); err != nil {
+		t.Fatalf("Download() error = %v, want the HTTP download to run without gsutil", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "build-log.txt")); err != nil {
+		t.Errorf("build-log.txt not downloaded: %v", err)
+	}
+}
+
+func TestDownloadHTTP_OutsideDest(t *testing.T) {
+	fakeGCS(t, "bucket", map[string]string{
+		"logs/job/1/build-log.txt":           "log",
+		"logs/job/1/../../../../escaped.txt": "owned",
+	})
+
+	dest := filepath.Join(t.TempDir(), "a", "b")
+	err := DownloadHTTP("bucket", "logs/job/1", dest, &bytes.Buffer{}, &bytes.Buffer{})
+	if !errors.Is(err, ErrDownloadFailed) || !strings.Contains(err.Error(), "refusing to write") {
+		t.Errorf("DownloadHTTP() error = %v, want the object refused", err)
+	}
+	if _, err := os.Stat(dest); err == nil {
+		t.Errorf("%s created, want nothing written", dest)
+	}
+}
+
+func TestDownloadHTTP_FollowLinks(t *testing.T) {
+	fakeBucket(t, map[string]map[string]string{
+		"bucket": {
+			"logs/job/1/build-log.txt":           "a long build log that is not a link",
+			"logs/job/1/artifacts/file-link.txt": "gs://shared/cache/must-gather.tar\n",
+		},
+		"shared": {"cache/must-gather.tar": "tar"},
+	})
+	orig := FollowLinks
+	FollowLinks = true
+	t.Cleanup(func() { FollowLinks = orig })
+
+	dest := t.TempDir()
+	if err := DownloadHTTP("bucket", "logs/job/1", dest, &bytes.Buffer{}, &bytes.Buffer{}); err != nil {
+		t.Fatalf("DownloadHTTP() error = %v", err)
+	}
+	if got, err := os.ReadFile(filepath.Join(dest, "artifacts", "must-gather.tar")); err != nil || string(got) != "tar" {
+		t.Errorf("link target = %q, %v, want it downloaded next to the marker", got, err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "artifacts", "file-link.txt")); err == nil {
+		t.Error("file-link.txt downloaded, want it replaced by its target")
+	}
+}
+
+func TestDownloadHTTP_FollowLinksOutsideDest(t *testing.T) {
+	fakeBucket(t, map[string]map[string]string{
+		"bucket": {
+			"logs/job/1/build-log.txt":     "a long build log that is not a link",
+			"logs/job/1/artifacts/dir.txt": "gs://shared/gather\n",
+		},
+		"shared": {"gather/../../../../../escaped.txt": "owned"},
+	})
+	orig := FollowLinks
+	FollowLinks = true
+	t.Cleanup(func() { FollowLinks = orig })
+
+	dest := filepath.Join(t.TempDir(), "a", "b")
+	err := DownloadHTTP("bucket", "logs/job/1", dest, &bytes.Buffer{}, &bytes.Buffer{})
+	if !errors.Is(err, ErrDownloadFailed) || !strings.Contains(err.Error(), "refusing to write") {
+		t.Errorf("DownloadHTTP() error = %v, want the link target refused", err)
+	}
+	if _, err := os.Stat(dest); err == nil {
+		t.Errorf("%s created, want nothing written", dest)
+	}
+}
@@ -0,0 +1,147 @@
+package downloader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/clobrano/prow-helper/internal/httpclient"
+	"github.com/clobrano/prow-helper/internal/parser"
+)
+
+// ErrNoBuilds is returned when no build of a job can be found in GCS.
+var ErrNoBuilds = errors.New("no builds found for job")
+
+// gcsBaseURL returns the HTTP endpoint used for direct GCS reads.
+// It is a variable so tests can point it at a local server.
+var gcsBaseURL = parser.GCSBaseURL
+
+// gcsListResponse is the subset of the GCS JSON API object listing we use.
+type gcsListResponse struct {
+	Items         []gcsObject `json:"items"`
+	Prefixes      []string    `json:"prefixes"`
+	NextPageToken string      `json:"nextPageToken"`
+}
+
+// gcsObject is the subset of a GCS JSON API object resource we use. The API
+// encodes sizes as strings.
+type gcsObject struct {
+	Name   string `json:"name"`
+	Size   string `json:"size"`
+	CRC32C string `json:"crc32c"`
+}
+
+// ResolveLatestBuild returns the ID of the newest build of the job stored
+// under gs://<bucket>/<jobPath>/. It reads the latest-build.txt file Prow
+// publishes next to the builds and, when that is missing, falls back to
+// listing the build directories and picking the highest numeric ID.
+func ResolveLatestBuild(bucket, jobPath string) (string, error) {
+	jobPath = strings.Trim(jobPath, "/")
+
+	buildID, err := readLatestBuildFile(bucket, jobPath)
+	if err == nil && buildID != "" {
+		return buildID, nil
+	}
+
+	buildIDs, listErr := ListBuildIDs(bucket, jobPath)
+	if listErr != nil {
+		return "", listErr
+	}
+	if len(buildIDs) == 0 {
+		return "", fmt.Errorf("%w: gs://%s/%s/", ErrNoBuilds, bucket, jobPath)
+	}
+	return buildIDs[len(buildIDs)-1], nil
+}
+
+// readLatestBuildFile fetches <jobPath>/latest-build.txt and returns its
+// trimmed content. Returns an empty string if the file does not exist.
+func readLatestBuildFile(bucket, jobPath string) (string, error) {
+	latestURL := fmt.Sprintf("%s/%s/%s/latest-build.txt", gcsBaseURL(), bucket, jobPath)
+	resp, err := httpclient.Get(latestURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch latest-build.txt: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("latest-build.txt returned HTTP %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read latest-build.txt: %w", err)
+	}
+	buildID := strings.TrimSpace(string(body))
+	if _, err := strconv.ParseUint(buildID, 10, 64); err != nil {
+		return "", fmt.Errorf("unexpected latest-build.txt content %q", buildID)
+	}
+	return buildID, nil
+}
+
+// ListBuildIDs lists the numeric build directories under gs://<bucket>/<jobPath>/
+// using the GCS JSON API and returns their IDs sorted from oldest to newest.
+func ListBuildIDs(bucket, jobPath string) ([]string, error) {
+	jobPath = strings.Trim(jobPath, "/")
+
+	var ids []uint64
+	pageToken := ""
+	for {
+		q := url.Values{}
+		q.Set("prefix", jobPath+"/")
+		q.Set("delimiter", "/")
+		if pageToken != "" {
+			q.Set("pageToken", pageToken)
+		}
+		listURL := fmt.Sprintf("%s/storage/v1/b/%s/o?%s", gcsBaseURL(), url.PathEscape(bucket), q.Encode())
+
+		page, err := fetchListing(listURL)
+		if err != nil {
+			return nil, err
+		}
+		for _, prefix := range page.Prefixes {
+			name := strings.TrimSuffix(strings.TrimPrefix(prefix, jobPath+"/"), "/")
+			if id, err := strconv.ParseUint(name, 10, 64); err == nil {
+				ids = append(ids, id)
+			}
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		pageToken = page.NextPageToken
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	result := make([]string, len(ids))
+	for i, id := range ids {
+		result[i] = strconv.FormatUint(id, 10)
+	}
+	return result, nil
+}
+
+// fetchListing performs a single GCS listing request.
+func fetchListing(listURL string) (*gcsListResponse, error) {
+	resp, err := httpclient.Get(listURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list GCS objects: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GCS listing returned HTTP %d", resp.StatusCode)
+	}
+
+	var page gcsListResponse
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("failed to parse GCS listing: %w", err)
+	}
+	return &page, nil
+}
@@ -0,0 +1,94 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newGCSServer serves latest-build.txt (when latest is non-empty) and a
+// listing with the given build prefixes, paginated one prefix per page.
+func newGCSServer(t *testing.T, latest string, builds []string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bucket/logs/my-job/latest-build.txt":
+			if latest == "" {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprintln(w, latest)
+		case "/storage/v1/b/bucket/o":
+			if got := r.URL.Query().Get("prefix"); got != "logs/my-job/" {
+				t.Errorf("listing prefix = %q, want logs/my-job/", got)
+			}
+			page := 0
+			fmt.Sscanf(r.URL.Query().Get("pageToken"), "%d", &page)
+			if page >= len(builds) {
+				fmt.Fprint(w, `{}`)
+				return
+			}
+			next := ""
+			if page+1 < len(builds) {
+				next = fmt.Sprintf("%d", page+1)
+			}
+			fmt.Fprintf(w, `{"prefixes":["logs/my-job/%s/"],"nextPageToken":%q}`, builds[page], next)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	orig := gcsBaseURL
+	gcsBaseURL = func() string { return server.URL }
+	t.Cleanup(func() { gcsBaseURL = orig })
+	return server
+}
+
+func TestResolveLatestBuild_FromLatestBuildFile(t *testing.T) {
+	newGCSServer(t, "1700000000000000003", []string{"1"})
+
+	got, err := ResolveLatestBuild("bucket", "logs/my-job")
+	if err != nil {
+		t.Fatalf("ResolveLatestBuild() error = %v", err)
+	}
+	if got != "1700000000000000003" {
+		t.Errorf("ResolveLatestBuild() = %v, want 1700000000000000003", got)
+	}
+}
+
+func TestResolveLatestBuild_ListingFallback(t *testing.T) {
+	newGCSServer(t, "", []string{"998", "1002", "latest", "1001"})
+
+	got, err := ResolveLatestBuild("bucket", "logs/my-job/")
+	if err != nil {
+		t.Fatalf("ResolveLatestBuild() error = %v", err)
+	}
+	if got != "1002" {
+		t.Errorf("ResolveLatestBuild() = %v, want 1002 (highest numeric build)", got)
+	}
+}
+
+func TestResolveLatestBuild_NoBuilds(t *testing.T) {
+	newGCSServer(t, "", nil)
+
+	_, err := ResolveLatestBuild("bucket", "logs/my-job")
+	if !errors.Is(err, ErrNoBuilds) {
+		t.Errorf("ResolveLatestBuild() error = %v, want ErrNoBuilds", err)
+	}
+}
+
+func TestListBuildIDs_Sorted(t *testing.T) {
+	newGCSServer(t, "", []string{"30", "4", "200"})
+
+	got, err := ListBuildIDs("bucket", "logs/my-job")
+	if err != nil {
+		t.Fatalf("ListBuildIDs() error = %v", err)
+	}
+	want := []string{"4", "30", "200"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("ListBuildIDs() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,120 @@
+package downloader
+
+import (
+	"bytes"
+	"path"
+	"strings"
+)
+
+// maxLinkSize bounds the size of an object considered as a symlink marker, so
+// ordinary .txt artifacts (logs) are never fetched just to be inspected.
+const maxLinkSize = 1024
+
+// Transfer is one object to fetch for a download, and where to save it.
+type Transfer struct {
+	Bucket string
+	Object ObjectInfo // Object.Name is the full object name within Bucket
+	Dest   string     // slash-separated path relative to the download directory
+}
+
+// plainTransfers returns the transfers saving each of objects at its path
+// relative to root in bucket, with no symlink resolution.
+func plainTransfers(bucket, root string, objects []ObjectInfo) []Transfer {
+	root = strings.Trim(root, "/") + "/"
+	transfers := make([]Transfer, 0, len(objects))
+	for _, obj := range objects {
+		transfers = append(transfers, Transfer{Bucket: bucket, Object: obj, Dest: strings.TrimPrefix(obj.Name, root)})
+	}
+	return transfers
+}
+
+// ParseLink reports whether content is a Prow symlink marker: a .txt object
+// whose whole content is a single gs://<bucket>/<path> URL naming the
+// object, or the directory of objects, it stands for. It returns the
+// target's bucket and path.
+func ParseLink(content []byte) (bucket, target string, ok bool) {
+	line := string(bytes.TrimSpace(content))
+	if strings.ContainsAny(line, "\n\r\t ") {
+		return "", "", false
+	}
+	rest, found := strings.CutPrefix(line, "gs://")
+	if !found {
+		return "", "", false
+	}
+	bucket, target, _ = strings.Cut(rest, "/")
+	target = strings.Trim(target, "/")
+	if bucket == "" || target == "" {
+		return "", "", false
+	}
+	return bucket, target, true
+}
+
+// isLinkCandidate reports whether obj could be a symlink marker and is
+// worth fetching to find out.
+func isLinkCandidate(obj ObjectInfo) bool {
+	return strings.HasSuffix(obj.Name, ".txt") && obj.Size > 0 && obj.Size <= maxLinkSize
+}
+
+// ResolveLinks turns the objects listed under root in bucket into the
+// transfers of a self-contained download: every object is saved at its path
+// relative to root, except symlink markers (see ParseLink), which are
+// replaced by their target. A target object is saved next to the marker
+// under its own name; the objects of a target directory are saved in a
+// directory named after the marker without ".txt". Links are followed one
+// level deep, and a marker whose target does not exist is kept as is.
+func ResolveLinks(bucket, root string, objects []ObjectInfo) ([]Transfer, error) {
+	root = strings.Trim(root, "/") + "/"
+
+	var transfers []Transfer
+	for _, obj := range objects {
+		rel := strings.TrimPrefix(obj.Name, root)
+		plain := Transfer{Bucket: bucket, Object: obj, Dest: rel}
+		if !isLinkCandidate(obj) {
+			transfers = append(transfers, plain)
+			continue
+		}
+
+		content, err := ReadObject(bucket, obj.Name)
+		if err != nil {
+			return nil, err
+		}
+		linkBucket, target, ok := ParseLink(content)
+		if !ok {
+			transfers = append(transfers, plain)
+			continue
+		}
+
+		resolved, err := linkTransfers(linkBucket, target, rel)
+		if err != nil {
+			return nil, err
+		}
+		if len(resolved) == 0 {
+			transfers = append(transfers, plain)
+			continue
+		}
+		transfers = append(transfers, resolved...)
+	}
+	return transfers, nil
+}
+
+// linkTransfers returns the transfers that replace the marker saved at rel
+// with the object or directory gs://<bucket>/<target>.
+func linkTransfers(bucket, target, rel string) ([]Transfer, error) {
+	objects, err := ListObjects(bucket, target)
+	if err != nil {
+		return nil, err
+	}
+
+	var transfers []Transfer
+	for _, obj := range objects {
+		switch {
+		case obj.Name == target:
+			dest := path.Join(path.Dir(rel), path.Base(target))
+			transfers = append(transfers, Transfer{Bucket: bucket, Object: obj, Dest: dest})
+		case strings.HasPrefix(obj.Name, target+"/"):
+			dest := path.Join(strings.TrimSuffix(rel, ".txt"), strings.TrimPrefix(obj.Name, target+"/"))
+			transfers = append(transfers, Transfer{Bucket: bucket, Object: obj, Dest: dest})
+		}
+	}
+	return transfers, nil
+}
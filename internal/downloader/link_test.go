@@ -0,0 +1,132 @@
+package downloader
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeBucket serves objects (bucket -> name -> content) through the GCS JSON
+// API listing and plain object reads, and points gcsBaseURL at itself.
+func fakeBucket(t *testing.T, buckets map[string]map[string]string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rest, ok := strings.CutPrefix(r.URL.Path, "/storage/v1/b/"); ok {
+			bucket := strings.TrimSuffix(rest, "/o")
+			prefix := r.URL.Query().Get("prefix")
+			var page gcsListResponse
+			for name, content := range buckets[bucket] {
+				if strings.HasPrefix(name, prefix) {
+					page.Items = append(page.Items, gcsObject{Name: name, Size: strconv.Itoa(len(content))})
+				}
+			}
+			json.NewEncoder(w).Encode(page)
+			return
+		}
+		bucket, name, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		content, ok := buckets[bucket][name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	t.Cleanup(server.Close)
+
+	orig := gcsBaseURL
+	gcsBaseURL = func() string { return server.URL }
+	t.Cleanup(func() { gcsBaseURL = orig })
+}
+
+func TestParseLink(t *testing.T) {
+	tests := []struct {
+		content    string
+		wantBucket string
+		wantTarget string
+		wantOK     bool
+	}{
+		{"gs://bucket/logs/job/1/artifacts/must-gather.tar\n", "bucket", "logs/job/1/artifacts/must-gather.tar", true},
+		{"gs://other-bucket/pr-logs/pull/org_repo/1/job/2/", "other-bucket", "pr-logs/pull/org_repo/1/job/2", true},
+		{"1700000000000000001\n", "", "", false},
+		{"see gs://bucket/path for details", "", "", false},
+		{"gs://bucket/a\ngs://bucket/b", "", "", false},
+		{"gs://bucket", "", "", false},
+		{"gs:///path", "", "", false},
+	}
+	for _, tt := range tests {
+		bucket, target, ok := ParseLink([]byte(tt.content))
+		if bucket != tt.wantBucket || target != tt.wantTarget || ok != tt.wantOK {
+			t.Errorf("ParseLink(%q) = %q, %q, %v, want %q, %q, %v",
+				tt.content, bucket, target, ok, tt.wantBucket, tt.wantTarget, tt.wantOK)
+		}
+	}
+}
+
+func TestResolveLinks(t *testing.T) {
+	fakeBucket(t, map[string]map[string]string{
+		"bucket": {
+			"logs/job/1/build-log.txt":           "a long build log that is not a link",
+			"logs/job/1/artifacts/junit.xml":     "<testsuite/>",
+			"logs/job/1/artifacts/file-link.txt": "gs://shared/cache/must-gather.tar\n",
+			"logs/job/1/artifacts/dir-link.txt":  "gs://shared/gather/run-7\n",
+			"logs/job/1/artifacts/dangling.txt":  "gs://shared/missing\n",
+			"logs/job/1/artifacts/notes.txt":     "just some notes",
+		},
+		"shared": {
+			"cache/must-gather.tar":       "tar",
+			"gather/run-7/nodes.log":      "nodes",
+			"gather/run-7/pods/pods.log":  "pods",
+			"gather/run-70/unrelated.log": "not part of run-7",
+		},
+	})
+
+	objects, err := ListObjects("bucket", "logs/job/1/")
+	if err != nil {
+		t.Fatalf("ListObjects() error = %v", err)
+	}
+	got, err := ResolveLinks("bucket", "logs/job/1", objects)
+	if err != nil {
+		t.Fatalf("ResolveLinks() error = %v", err)
+	}
+
+	// Bucket and object name of each transfer, by destination.
+	want := map[string][2]string{
+		"build-log.txt":                    {"bucket", "logs/job/1/build-log.txt"},
+		"artifacts/junit.xml":              {"bucket", "logs/job/1/artifacts/junit.xml"},
+		"artifacts/must-gather.tar":        {"shared", "cache/must-gather.tar"},
+		"artifacts/dir-link/nodes.log":     {"shared", "gather/run-7/nodes.log"},
+		"artifacts/dir-link/pods/pods.log": {"shared", "gather/run-7/pods/pods.log"},
+		"artifacts/dangling.txt":           {"bucket", "logs/job/1/artifacts/dangling.txt"},
+		"artifacts/notes.txt":              {"bucket", "logs/job/1/artifacts/notes.txt"},
+	}
+	gotByDest := make(map[string][2]string)
+	for _, tr := range got {
+		gotByDest[tr.Dest] = [2]string{tr.Bucket, tr.Object.Name}
+		if tr.Dest == "artifacts/must-gather.tar" && tr.Object.Size != int64(len("tar")) {
+			t.Errorf("%s size = %d, want the size of the target", tr.Dest, tr.Object.Size)
+		}
+	}
+	if !reflect.DeepEqual(gotByDest, want) {
+		t.Errorf("ResolveLinks() =\n%v\nwant\n%v", gotByDest, want)
+	}
+}
+
+func TestResolveLinks_LargeTxtNotFetched(t *testing.T) {
+	// No server: fetching anything would fail.
+	orig := gcsBaseURL
+	gcsBaseURL = func() string { return "http://127.0.0.1:0" }
+	t.Cleanup(func() { gcsBaseURL = orig })
+
+	objects := []ObjectInfo{{Name: "logs/job/1/build-log.txt", Size: maxLinkSize + 1}}
+	got, err := ResolveLinks("bucket", "logs/job/1/", objects)
+	if err != nil {
+		t.Fatalf("ResolveLinks() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Dest != "build-log.txt" {
+		t.Errorf("ResolveLinks() = %v, want build-log.txt kept as is", got)
+	}
+}
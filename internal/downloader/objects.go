@@ -0,0 +1,87 @@
+package downloader
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/clobrano/prow-helper/internal/httpclient"
+)
+
+// ObjectInfo describes a GCS object.
+type ObjectInfo struct {
+	Name   string // full object name within its bucket
+	Size   int64
+	CRC32C string // base64-encoded big-endian CRC32C, as reported by GCS; empty when unknown
+}
+
+// ListObjects lists every object of bucket whose name starts with prefix,
+// using the GCS JSON API, in the order the API returns them (by name).
+func ListObjects(bucket, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	pageToken := ""
+	for {
+		q := url.Values{}
+		q.Set("prefix", prefix)
+		if pageToken != "" {
+			q.Set("pageToken", pageToken)
+		}
+		listURL := fmt.Sprintf("%s/storage/v1/b/%s/o?%s", gcsBaseURL(), url.PathEscape(bucket), q.Encode())
+
+		page, err := fetchListing(listURL)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			size, _ := strconv.ParseInt(item.Size, 10, 64)
+			objects = append(objects, ObjectInfo{Name: item.Name, Size: size, CRC32C: item.CRC32C})
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		pageToken = page.NextPageToken
+	}
+	return objects, nil
+}
+
+// ListArtifacts lists the objects under gcsPath, a gs://<bucket>/<path> URL,
+// over HTTP. When there are none it returns an error wrapping ErrNoArtifacts
+// that names gcsPath, rather than an empty list: a build always stores some
+// artifacts, so an empty listing means the path is wrong.
+func ListArtifacts(gcsPath string) ([]ObjectInfo, error) {
+	bucket, prefix := splitGCSPath(gcsPath)
+	if prefix = strings.TrimSuffix(prefix, "/"); prefix != "" {
+		prefix += "/"
+	}
+	objects, err := ListObjects(bucket, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoArtifacts, gcsPath)
+	}
+	return objects, nil
+}
+
+// splitGCSPath splits a gs://<bucket>/<path> URL into its bucket and path.
+func splitGCSPath(gcsPath string) (bucket, path string) {
+	bucket, path, _ = strings.Cut(strings.TrimPrefix(gcsPath, "gs://"), "/")
+	return bucket, path
+}
+
+// ReadObject fetches the content of the GCS object name of bucket over HTTP.
+func ReadObject(bucket, name string) ([]byte, error) {
+	resp, err := httpclient.Get(gcsBaseURL() + "/" + bucket + "/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch gs://%s/%s: %w", bucket, name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching gs://%s/%s returned HTTP %d", bucket, name, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
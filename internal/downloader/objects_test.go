@@ -0,0 +1,88 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestListObjects_Pages(t *testing.T) {
+	var prefixes []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefixes = append(prefixes, r.URL.Query().Get("prefix"))
+		if r.URL.Query().Get("pageToken") == "" {
+			fmt.Fprint(w, `{"items":[{"name":"logs/job/1/a.txt","size":"3","crc32c":"4waSgw=="}],"nextPageToken":"p2"}`)
+			return
+		}
+		fmt.Fprint(w, `{"items":[{"name":"logs/job/1/b/c.xml","size":"1024"}]}`)
+	}))
+	t.Cleanup(server.Close)
+	orig := gcsBaseURL
+	gcsBaseURL = func() string { return server.URL }
+	t.Cleanup(func() { gcsBaseURL = orig })
+
+	got, err := ListObjects("bucket", "logs/job/1/")
+	if err != nil {
+		t.Fatalf("ListObjects() error = %v", err)
+	}
+	want := []ObjectInfo{{Name: "logs/job/1/a.txt", Size: 3, CRC32C: "4waSgw=="}, {Name: "logs/job/1/b/c.xml", Size: 1024}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListObjects() = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(prefixes, []string{"logs/job/1/", "logs/job/1/"}) {
+		t.Errorf("listing prefixes = %q, want the prefix on every page", prefixes)
+	}
+}
+
+func TestListObjects_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "denied", http.StatusForbidden)
+	}))
+	t.Cleanup(server.Close)
+	orig := gcsBaseURL
+	gcsBaseURL = func() string { return server.URL }
+	t.Cleanup(func() { gcsBaseURL = orig })
+
+	if _, err := ListObjects("bucket", "logs/job/1/"); err == nil {
+		t.Error("ListObjects() error = nil, want an error for HTTP 403")
+	}
+}
+
+func TestListArtifacts(t *testing.T) {
+	var prefix string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix = r.URL.Query().Get("prefix")
+		if strings.HasPrefix(prefix, "logs/job/1/") {
+			fmt.Fprint(w, `{"items":[{"name":"logs/job/1/build-log.txt","size":"3"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"kind":"storage#objects"}`)
+	}))
+	t.Cleanup(server.Close)
+	orig := gcsBaseURL
+	gcsBaseURL = func() string { return server.URL }
+	t.Cleanup(func() { gcsBaseURL = orig })
+
+	got, err := ListArtifacts("gs://bucket/logs/job/1")
+	if err != nil {
+		t.Fatalf("ListArtifacts() error = %v", err)
+	}
+	if prefix != "logs/job/1/" {
+		t.Errorf("listing prefix = %q, want %q", prefix, "logs/job/1/")
+	}
+	if len(got) != 1 || got[0].Name != "logs/job/1/build-log.txt" {
+		t.Errorf("ListArtifacts() = %v, want build-log.txt", got)
+	}
+
+	_, err = ListArtifacts("gs://bucket/logs/job/12/")
+	if !errors.Is(err, ErrNoArtifacts) {
+		t.Fatalf("ListArtifacts() on an empty listing error = %v, want ErrNoArtifacts", err)
+	}
+	if !strings.Contains(err.Error(), "gs://bucket/logs/job/12/") {
+		t.Errorf("ListArtifacts() error = %q, want it to name the GCS path", err)
+	}
+}
@@ -0,0 +1,55 @@
+package downloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/clobrano/prow-helper/internal/parser"
+)
+
+// PRLatestLinkPath returns the path of the convenience symlink pointing at
+// the most recently downloaded build of a presubmit job:
+// <baseDest>/<org>_<repo>/PR<num>/<job-name>/latest. ok is false for jobs
+// that do not test a pull request.
+func PRLatestLinkPath(baseDest string, metadata *parser.ProwMetadata) (path string, ok bool) {
+	if metadata.Org == "" || metadata.Repo == "" || metadata.PRNumber == "" {
+		return "", false
+	}
+	return filepath.Join(expandHome(baseDest), metadata.Org+"_"+metadata.Repo, "PR"+metadata.PRNumber, metadata.JobName, "latest"), true
+}
+
+// UpdateLatestLink points the symlink at linkPath to target, creating its
+// parent folders as needed. The link is replaced atomically, so readers see
+// either the previous or the new build, never a missing link. target is
+// stored relative to the link when possible, so the tree can be moved.
+func UpdateLatestLink(linkPath, target string) error {
+	dir := filepath.Dir(linkPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return err
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	linkTarget := absTarget
+	if rel, err := filepath.Rel(absDir, absTarget); err == nil {
+		linkTarget = rel
+	}
+
+	tmp := fmt.Sprintf("%s.tmp-%d", linkPath, os.Getpid())
+	os.Remove(tmp)
+	if err := os.Symlink(linkTarget, tmp); err != nil {
+		return fmt.Errorf("failed to create symlink: %w", err)
+	}
+	if err := os.Rename(tmp, linkPath); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to update %s: %w", linkPath, err)
+	}
+	return nil
+}
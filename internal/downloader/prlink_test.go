@@ -0,0 +1,64 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/clobrano/prow-helper/internal/parser"
+)
+
+func TestPRLatestLinkPath(t *testing.T) {
+	pr := &parser.ProwMetadata{JobName: "pull-ci-openshift-api-master-unit", Org: "openshift", Repo: "api", PRNumber: "1234"}
+	path, ok := PRLatestLinkPath("/tmp/artifacts", pr)
+	if !ok {
+		t.Fatal("PRLatestLinkPath() ok = false for a PR job")
+	}
+	if want := "/tmp/artifacts/openshift_api/PR1234/pull-ci-openshift-api-master-unit/latest"; path != want {
+		t.Errorf("PRLatestLinkPath() = %q, want %q", path, want)
+	}
+
+	if _, ok := PRLatestLinkPath("/tmp/artifacts", &parser.ProwMetadata{JobName: "periodic-job"}); ok {
+		t.Error("PRLatestLinkPath() ok = true for a periodic job")
+	}
+}
+
+func TestUpdateLatestLink_TwoBuilds(t *testing.T) {
+	base := t.TempDir()
+	pr := &parser.ProwMetadata{JobName: "pull-ci-openshift-api-master-unit", Org: "openshift", Repo: "api", PRNumber: "1234"}
+	linkPath, _ := PRLatestLinkPath(base, pr)
+
+	for _, build := range []string{"1001", "1002"} {
+		pr.BuildID = build
+		dest := BuildDestinationPath(base, pr)
+		if err := os.MkdirAll(dest, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dest, "build-id"), []byte(build), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := UpdateLatestLink(linkPath, dest); err != nil {
+			t.Fatalf("UpdateLatestLink(%s) error = %v", build, err)
+		}
+
+		got, err := os.ReadFile(filepath.Join(linkPath, "build-id"))
+		if err != nil {
+			t.Fatalf("reading through link after build %s: %v", build, err)
+		}
+		if string(got) != build {
+			t.Errorf("latest points at build %s, want %s", got, build)
+		}
+	}
+
+	target, err := os.Readlink(linkPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.IsAbs(target) {
+		t.Errorf("link target %q is absolute, want relative", target)
+	}
+	if matches, _ := filepath.Glob(linkPath + ".tmp-*"); len(matches) != 0 {
+		t.Errorf("temporary links left behind: %v", matches)
+	}
+}
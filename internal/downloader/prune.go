@@ -0,0 +1,105 @@
+package downloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// PruneCandidate is a build folder a PrunePlan removes.
+type PruneCandidate struct {
+	Path    string
+	ModTime time.Time
+	Bytes   int64 // total size of the files in the folder
+}
+
+func (c PruneCandidate) String() string {
+	return fmt.Sprintf("%s  %s  (modified %s, %s ago)", c.Path, formatSize(c.Bytes),
+		c.ModTime.Format("2006-01-02 15:04"), formatAge(now().Sub(c.ModTime)))
+}
+
+// PrunePlan lists the build folders to remove, oldest first.
+type PrunePlan struct {
+	Dirs []PruneCandidate
+}
+
+// Total returns the bytes reclaimed by removing every folder of the plan.
+func (p PrunePlan) Total() int64 {
+	var total int64
+	for _, c := range p.Dirs {
+		total += c.Bytes
+	}
+	return total
+}
+
+// TotalString formats Total with a binary unit.
+func (p PrunePlan) TotalString() string {
+	return formatSize(p.Total())
+}
+
+// buildFolderPattern matches the names the downloader gives build folders:
+// the build ID, optionally after the job's start date (see
+// RenameWithDatePrefix) and before a collision suffix (see nextAvailablePath).
+var buildFolderPattern = regexp.MustCompile(`^(\d{8}-\d{4}-)?\d+(-\d{8}-\d{6}|-\d+)?$`)
+
+// PlanPrune returns the build folders under baseDest, laid out as
+// <baseDest>/<job-name>/<build-folder>, that were last modified more than
+// olderThan ago. Their sizes are computed as for download summaries.
+// Folders not named like a download (see buildFolderPattern) and symlinks
+// are never followed nor removed.
+func PlanPrune(baseDest string, olderThan time.Duration) (PrunePlan, error) {
+	baseDest = expandHome(baseDest)
+	jobs, err := os.ReadDir(baseDest)
+	if err != nil {
+		return PrunePlan{}, fmt.Errorf("failed to read %s: %w", baseDest, err)
+	}
+
+	cutoff := now().Add(-olderThan)
+	var plan PrunePlan
+	for _, job := range jobs {
+		if !job.IsDir() {
+			continue
+		}
+		builds, err := os.ReadDir(filepath.Join(baseDest, job.Name()))
+		if err != nil {
+			return PrunePlan{}, err
+		}
+		for _, build := range builds {
+			if !build.IsDir() || !buildFolderPattern.MatchString(build.Name()) {
+				continue
+			}
+			info, err := build.Info()
+			if err != nil {
+				return PrunePlan{}, err
+			}
+			if !info.ModTime().Before(cutoff) {
+				continue
+			}
+			path := filepath.Join(baseDest, job.Name(), build.Name())
+			summary, err := SummarizeDownload(path, 0)
+			if err != nil {
+				return PrunePlan{}, fmt.Errorf("failed to size %s: %w", path, err)
+			}
+			plan.Dirs = append(plan.Dirs, PruneCandidate{Path: path, ModTime: info.ModTime(), Bytes: summary.Bytes})
+		}
+	}
+	sort.SliceStable(plan.Dirs, func(i, j int) bool {
+		return plan.Dirs[i].ModTime.Before(plan.Dirs[j].ModTime)
+	})
+	return plan, nil
+}
+
+// Execute removes the folders of the plan, then the job folders left empty.
+func (p PrunePlan) Execute() error {
+	for _, c := range p.Dirs {
+		if err := os.RemoveAll(c.Path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", c.Path, err)
+		}
+		// Only succeeds once the job folder is empty.
+		os.Remove(filepath.Dir(c.Path))
+	}
+	return nil
+}
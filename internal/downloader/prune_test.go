@@ -0,0 +1,138 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// makeBuild creates the build folder dest/job/build holding files of the
+// given sizes, last modified at modTime.
+func makeBuild(t *testing.T, dest, job, build string, modTime time.Time, sizes ...int) string {
+	t.Helper()
+	dir := filepath.Join(dest, job, build)
+	if err := os.MkdirAll(filepath.Join(dir, "artifacts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for i, size := range sizes {
+		name := filepath.Join(dir, "artifacts", string(rune('a'+i))+".txt")
+		if err := os.WriteFile(name, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chtimes(dir, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestPlanPrune(t *testing.T) {
+	current := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	origNow := now
+	now = func() time.Time { return current }
+	t.Cleanup(func() { now = origNow })
+
+	dest := t.TempDir()
+	old1 := makeBuild(t, dest, "job-a", "20260101-1000-100", current.Add(-60*24*time.Hour), 1000, 24)
+	makeBuild(t, dest, "job-a", "200", current.Add(-2*time.Hour), 4096)
+	old2 := makeBuild(t, dest, "job-b", "300", current.Add(-40*24*time.Hour), 2048)
+	// A symlink to a build, such as those of --dest-per-pr-latest, is kept.
+	if err := os.Symlink(old2, filepath.Join(dest, "job-b", "latest")); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := PlanPrune(dest, 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("PlanPrune() error = %v", err)
+	}
+	if len(plan.Dirs) != 2 || plan.Dirs[0].Path != old1 || plan.Dirs[1].Path != old2 {
+		t.Fatalf("PlanPrune() = %+v, want %s then %s", plan.Dirs, old1, old2)
+	}
+
+	var sum int64
+	for _, c := range plan.Dirs {
+		sum += c.Bytes
+	}
+	if plan.Dirs[0].Bytes != 1024 || plan.Dirs[1].Bytes != 2048 {
+		t.Errorf("planned sizes = %d, %d, want 1024, 2048", plan.Dirs[0].Bytes, plan.Dirs[1].Bytes)
+	}
+	if plan.Total() != sum || plan.Total() != 3072 {
+		t.Errorf("Total() = %d, want the sum of the planned folders %d", plan.Total(), sum)
+	}
+	if got := plan.TotalString(); got != "3.0 KiB" {
+		t.Errorf("TotalString() = %q, want %q", got, "3.0 KiB")
+	}
+	if got := plan.Dirs[0].String(); !strings.Contains(got, "1.0 KiB") || !strings.Contains(got, "60d ago") {
+		t.Errorf("String() = %q, want the size and age", got)
+	}
+}
+
+func TestPlanPrune_OnlyBuildFolders(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+	dest := t.TempDir()
+	var want []string
+	for _, name := range []string{"100", "20260101-1000-100", "100-2", "100-20260102-103000", "20260101-1000-100-1"} {
+		want = append(want, makeBuild(t, dest, "job-a", name, old, 10))
+	}
+	kept := []string{
+		makeBuild(t, dest, "job-a", "notes", old, 10),
+		makeBuild(t, dest, "job-a", "20260101-backup", old, 10),
+		makeBuild(t, dest, "projects", "my-repo", old, 10),
+	}
+
+	plan, err := PlanPrune(dest, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("PlanPrune() error = %v", err)
+	}
+	var got []string
+	for _, c := range plan.Dirs {
+		got = append(got, c.Path)
+	}
+	slices.Sort(got)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Errorf("PlanPrune() = %v, want only the build folders %v", got, want)
+	}
+	if err := plan.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	for _, dir := range kept {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("%s removed, want folders not named like a build kept: %v", dir, err)
+		}
+	}
+}
+
+func TestPrunePlanExecute(t *testing.T) {
+	current := time.Now()
+	dest := t.TempDir()
+	old := makeBuild(t, dest, "job-a", "100", current.Add(-48*time.Hour), 10)
+	recent := makeBuild(t, dest, "job-a", "200", current, 10)
+	lone := makeBuild(t, dest, "job-b", "300", current.Add(-48*time.Hour), 10)
+
+	plan, err := PlanPrune(dest, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("PlanPrune() error = %v", err)
+	}
+	if err := plan.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	for _, gone := range []string{old, lone, filepath.Dir(lone)} {
+		if _, err := os.Stat(gone); !os.IsNotExist(err) {
+			t.Errorf("%s still exists after Execute()", gone)
+		}
+	}
+	if _, err := os.Stat(recent); err != nil {
+		t.Errorf("recent build removed: %v", err)
+	}
+}
+
+func TestPlanPrune_MissingDest(t *testing.T) {
+	if _, err := PlanPrune(filepath.Join(t.TempDir(), "missing"), time.Hour); err == nil {
+		t.Error("PlanPrune() error = nil, want an error for a missing destination")
+	}
+}
@@ -0,0 +1,68 @@
+package downloader
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// checksummedFileInfo is the os.FileInfo of a local file along with the
+// CRC32C of its content, in the encoding GCS reports (see ObjectInfo).
+type checksummedFileInfo struct {
+	os.FileInfo
+	crc32c string
+}
+
+// statExisting returns what shouldDownload needs to know about the local copy
+// of an object at path: nil when there is none, and its checksum along with
+// its os.FileInfo when verify is set.
+func statExisting(path string, verify bool) (os.FileInfo, error) {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil || !verify || !info.Mode().IsRegular() {
+		return info, err
+	}
+	sum, err := fileCRC32C(path)
+	if err != nil {
+		return nil, err
+	}
+	return checksummedFileInfo{FileInfo: info, crc32c: sum}, nil
+}
+
+// fileCRC32C returns the CRC32C of the file at path, base64-encoded in big
+// endian order like the crc32c of GCS objects.
+func fileCRC32C(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(binary.BigEndian.AppendUint32(nil, h.Sum32())), nil
+}
+
+// shouldDownload decides whether the object remote must be fetched over its
+// local copy existing (nil when there is none), so that merging into an
+// earlier download only pulls what is missing or changed. A regular file of
+// the same size is kept; with verify, its CRC32C (see statExisting) must also
+// match the object's, when GCS reports one.
+func shouldDownload(existing os.FileInfo, remote ObjectInfo, verify bool) bool {
+	if existing == nil || !existing.Mode().IsRegular() || existing.Size() != remote.Size {
+		return true
+	}
+	if !verify || remote.CRC32C == "" {
+		return false
+	}
+	local, ok := existing.(checksummedFileInfo)
+	return !ok || local.crc32c != remote.CRC32C
+}
@@ -0,0 +1,75 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileCRC32C(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "check")
+	if err := os.WriteFile(path, []byte("123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// The CRC32C check value, 0xE3069283, base64-encoded as GCS reports it.
+	if got, err := fileCRC32C(path); err != nil || got != "4waSgw==" {
+		t.Errorf("fileCRC32C() = %q, %v, want 4waSgw==", got, err)
+	}
+}
+
+func TestShouldDownload(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "build-log.txt"), []byte("123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "artifacts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	const crc = "4waSgw==" // CRC32C of "123456789"
+
+	tests := []struct {
+		name   string
+		file   string
+		remote ObjectInfo
+		verify bool
+		want   bool
+	}{
+		{name: "missing", file: "finished.json", remote: ObjectInfo{Size: 9}, want: true},
+		{name: "missing with verify", file: "finished.json", remote: ObjectInfo{Size: 9, CRC32C: crc}, verify: true, want: true},
+		{name: "size match", file: "build-log.txt", remote: ObjectInfo{Size: 9}, want: false},
+		{name: "size match ignores hash without verify", file: "build-log.txt", remote: ObjectInfo{Size: 9, CRC32C: "AAAAAA=="}, want: false},
+		{name: "size mismatch", file: "build-log.txt", remote: ObjectInfo{Size: 10}, want: true},
+		{name: "size mismatch with matching hash", file: "build-log.txt", remote: ObjectInfo{Size: 10, CRC32C: crc}, verify: true, want: true},
+		{name: "hash match", file: "build-log.txt", remote: ObjectInfo{Size: 9, CRC32C: crc}, verify: true, want: false},
+		{name: "hash mismatch", file: "build-log.txt", remote: ObjectInfo{Size: 9, CRC32C: "AAAAAA=="}, verify: true, want: true},
+		{name: "remote hash unknown", file: "build-log.txt", remote: ObjectInfo{Size: 9}, verify: true, want: false},
+		{name: "directory in the way", file: "artifacts", remote: ObjectInfo{Size: 9}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existing, err := statExisting(filepath.Join(dir, tt.file), tt.verify)
+			if err != nil {
+				t.Fatalf("statExisting() error = %v", err)
+			}
+			if got := shouldDownload(existing, tt.remote, tt.verify); got != tt.want {
+				t.Errorf("shouldDownload() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldDownload_VerifyWithoutLocalChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "build-log.txt")
+	if err := os.WriteFile(path, []byte("123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A plain os.FileInfo carries no checksum: with verify, the file cannot
+	// be trusted and is fetched again.
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !shouldDownload(info, ObjectInfo{Size: 9, CRC32C: "4waSgw=="}, true) {
+		t.Error("shouldDownload() = false, want true without a local checksum")
+	}
+}
@@ -0,0 +1,60 @@
+package downloader
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// DownloadSummary describes a finished download.
+type DownloadSummary struct {
+	Files   int
+	Bytes   int64
+	Elapsed time.Duration
+}
+
+// SummarizeDownload counts the files under destPath and their total size,
+// for a download that took elapsed. The IncompleteMarker is not counted.
+func SummarizeDownload(destPath string, elapsed time.Duration) (DownloadSummary, error) {
+	summary := DownloadSummary{Elapsed: elapsed}
+	err := filepath.WalkDir(destPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || d.Name() == IncompleteMarker {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		summary.Files++
+		summary.Bytes += info.Size()
+		return nil
+	})
+	if err != nil {
+		return DownloadSummary{}, err
+	}
+	return summary, nil
+}
+
+// Rate returns the average download speed in bytes per second, or 0 when no
+// time elapsed.
+func (s DownloadSummary) Rate() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) / s.Elapsed.Seconds()
+}
+
+// String formats the summary on one line, e.g.
+// "345 files, 1.2 GiB in 2m3s (10.2 MiB/s)".
+func (s DownloadSummary) String() string {
+	files := "files"
+	if s.Files == 1 {
+		files = "file"
+	}
+	return fmt.Sprintf("%d %s, %s in %s (%s/s)", s.Files, files, formatSize(s.Bytes),
+		s.Elapsed.Round(time.Second), formatSize(int64(s.Rate())))
+}
@@ -0,0 +1,61 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSummarizeDownload(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "artifacts", "e2e"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]int{
+		"build-log.txt":            3 << 20,
+		"artifacts/junit.xml":      512 << 10,
+		"artifacts/e2e/gather.tar": 6<<20 + 512<<10,
+		"artifacts/e2e/empty.txt":  0,
+		IncompleteMarker:           0,
+	}
+	for name, size := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := SummarizeDownload(dir, 5*time.Second)
+	if err != nil {
+		t.Fatalf("SummarizeDownload() error = %v", err)
+	}
+	want := DownloadSummary{Files: 4, Bytes: 10 << 20, Elapsed: 5 * time.Second}
+	if got != want {
+		t.Errorf("SummarizeDownload() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSummarizeDownload_Missing(t *testing.T) {
+	if _, err := SummarizeDownload(filepath.Join(t.TempDir(), "missing"), time.Second); err == nil {
+		t.Error("SummarizeDownload() error = nil, want an error for a missing folder")
+	}
+}
+
+func TestDownloadSummary_String(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary DownloadSummary
+		want    string
+	}{
+		{"typical", DownloadSummary{Files: 345, Bytes: 10 << 20, Elapsed: 5 * time.Second}, "345 files, 10.0 MiB in 5s (2.0 MiB/s)"},
+		{"single file", DownloadSummary{Files: 1, Bytes: 1536, Elapsed: 1500 * time.Millisecond}, "1 file, 1.5 KiB in 2s (1.0 KiB/s)"},
+		{"no elapsed time", DownloadSummary{Files: 2, Bytes: 100}, "2 files, 100 B in 0s (0 B/s)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.summary.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
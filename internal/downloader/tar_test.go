@@ -0,0 +1,103 @@
+package downloader
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// readTarball returns the entries of a .tar.gz: file contents by name,
+// "dir" for directories and "-> target" for symlinks.
+func readTarball(t *testing.T, path string) map[string]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	entries := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			entries[hdr.Name] = "dir"
+		case tar.TypeSymlink:
+			entries[hdr.Name] = "-> " + hdr.Linkname
+		default:
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries[hdr.Name] = string(data)
+		}
+	}
+	return entries
+}
+
+func TestTarDir(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "job-12345")
+	if err := os.MkdirAll(filepath.Join(src, "artifacts", "e2e"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"build-log.txt":             "build log\n",
+		"artifacts/junit.xml":       "<testsuites/>",
+		"artifacts/e2e/empty.txt":   "",
+		IncompleteMarker:            "",
+		"artifacts/e2e/gather.json": `{"ok":true}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("build-log.txt", filepath.Join(src, "latest-log")); err != nil {
+		t.Fatal(err)
+	}
+	// A tarball written inside the directory does not include itself.
+	out := filepath.Join(src, "artifacts.tar.gz")
+
+	if err := TarDir(src, out); err != nil {
+		t.Fatalf("TarDir() error = %v", err)
+	}
+
+	want := map[string]string{
+		"job-12345/":                          "dir",
+		"job-12345/build-log.txt":             "build log\n",
+		"job-12345/latest-log":                "-> build-log.txt",
+		"job-12345/artifacts/":                "dir",
+		"job-12345/artifacts/junit.xml":       "<testsuites/>",
+		"job-12345/artifacts/e2e/":            "dir",
+		"job-12345/artifacts/e2e/empty.txt":   "",
+		"job-12345/artifacts/e2e/gather.json": `{"ok":true}`,
+	}
+	if got := readTarball(t, out); !reflect.DeepEqual(got, want) {
+		t.Errorf("TarDir() archived %v, want %v", got, want)
+	}
+}
+
+func TestTarDir_MissingSource(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out.tar.gz")
+	if err := TarDir(filepath.Join(dir, "missing"), out); err == nil {
+		t.Fatal("TarDir() error = nil, want an error for a missing directory")
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("TarDir() left %s behind after failing", out)
+	}
+}
@@ -1,38 +1,36 @@
 package downloader
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"time"
-)
 
-// StartedMetadata represents the structure of the started.json file
-type StartedMetadata struct {
-	Timestamp int64 `json:"timestamp"`
-}
+	"github.com/clobrano/prow-helper/internal/jsonpath"
+	"github.com/clobrano/prow-helper/internal/watcher"
+)
 
-// ReadStartedTimestamp reads the started.json file and extracts the timestamp
+// ReadStartedTimestamp reads the job's start time from the downloaded
+// artifacts: by default the timestamp field of started.json, or the file and
+// field configured in watcher.StartedFile and watcher.StartedField.
 func ReadStartedTimestamp(artifactPath string) (time.Time, error) {
-	startedFilePath := filepath.Join(artifactPath, "started.json")
+	startedFile := watcher.StartedFile
+	startedFilePath := filepath.Join(artifactPath, startedFile)
 
 	data, err := os.ReadFile(startedFilePath)
 	if err != nil {
-		return time.Time{}, fmt.Errorf("failed to read started.json: %w", err)
+		return time.Time{}, fmt.Errorf("failed to read %s: %w", startedFile, err)
 	}
 
-	var metadata StartedMetadata
-	if err := json.Unmarshal(data, &metadata); err != nil {
-		return time.Time{}, fmt.Errorf("failed to parse started.json: %w", err)
+	timestamp, err := jsonpath.LookupTime(data, watcher.StartedField)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read %s from %s: %w", watcher.StartedField, startedFile, err)
 	}
 
-	if metadata.Timestamp == 0 {
-		return time.Time{}, fmt.Errorf("timestamp field is missing or zero in started.json")
+	if timestamp.IsZero() {
+		return time.Time{}, fmt.Errorf("%s field is zero in %s", watcher.StartedField, startedFile)
 	}
 
-	// Convert Unix timestamp to time.Time
-	timestamp := time.Unix(metadata.Timestamp, 0)
 	return timestamp, nil
 }
 
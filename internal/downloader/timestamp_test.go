@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/clobrano/prow-helper/internal/watcher"
 )
 
 func TestReadStartedTimestamp(t *testing.T) {
@@ -173,3 +175,33 @@ func TestRenameWithDatePrefix_MissingStartedJSON(t *testing.T) {
 		t.Error("Expected error when started.json is missing, got nil")
 	}
 }
+
+func TestReadStartedTimestamp_AlternativeFileAndField(t *testing.T) {
+	origFile, origField := watcher.StartedFile, watcher.StartedField
+	watcher.StartedFile, watcher.StartedField = "prowjob.json", "status.startTime"
+	defer func() { watcher.StartedFile, watcher.StartedField = origFile, origField }()
+
+	dir := t.TempDir()
+	content := `{"kind":"ProwJob","status":{"startTime":"2020-07-20T20:54:00Z"}}`
+	if err := os.WriteFile(filepath.Join(dir, "prowjob.json"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write prowjob.json: %v", err)
+	}
+
+	got, err := ReadStartedTimestamp(dir)
+	if err != nil {
+		t.Fatalf("ReadStartedTimestamp() error = %v", err)
+	}
+	want, _ := time.Parse(time.RFC3339, "2020-07-20T20:54:00Z")
+	if !got.Equal(want) {
+		t.Errorf("ReadStartedTimestamp() = %v, want %v", got, want)
+	}
+
+	// started.json alone is not consulted once another file is configured.
+	other := t.TempDir()
+	if err := os.WriteFile(filepath.Join(other, "started.json"), []byte(`{"timestamp": 1595278440}`), 0644); err != nil {
+		t.Fatalf("Failed to write started.json: %v", err)
+	}
+	if _, err := ReadStartedTimestamp(other); err == nil {
+		t.Error("ReadStartedTimestamp() should fail when the configured file is missing")
+	}
+}
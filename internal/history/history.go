@@ -0,0 +1,114 @@
+// Package history keeps a small record of the Prow URLs prow-helper
+// processed, newest last on disk, so recent jobs can be listed and re-run.
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/clobrano/prow-helper/internal/config"
+)
+
+// MaxEntries is how many entries Append keeps; older ones are evicted.
+const MaxEntries = 50
+
+// fileName is the history file inside the XDG state directory.
+const fileName = "history.json"
+
+// ErrNoEntry is returned by Get for an index outside the history.
+var ErrNoEntry = errors.New("no such history entry")
+
+// Entry is one processed URL. Dest and AnalyzeCmd are set once the
+// artifacts were downloaded: where they went and the analysis command run on
+// them, if any.
+type Entry struct {
+	URL        string    `json:"url"`
+	Time       time.Time `json:"time"`
+	Outcome    string    `json:"outcome"`
+	Dest       string    `json:"dest,omitempty"`
+	AnalyzeCmd string    `json:"analyze_cmd,omitempty"`
+}
+
+// DefaultPath returns the history file under prow-helper's XDG state
+// directory, creating the directory if needed.
+func DefaultPath() (string, error) {
+	dir, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
+// Append adds e to the history file at path, keeping at most max entries
+// (the oldest are dropped). A missing file is created.
+func Append(path string, e Entry, max int) error {
+	entries, err := read(path)
+	if err != nil {
+		return err
+	}
+	entries = append(entries, e)
+	if len(entries) > max {
+		entries = entries[len(entries)-max:]
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file and rename so a crash never leaves a
+	// truncated history behind.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// List returns the entries of the history file at path, newest first. A
+// missing file is an empty history.
+func List(path string) ([]Entry, error) {
+	entries, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// Get returns the entry at index in List order, starting from 1 for the most
+// recent one.
+func Get(path string, index int) (Entry, error) {
+	entries, err := List(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	if index < 1 || index > len(entries) {
+		return Entry{}, fmt.Errorf("%w: %d (history has %d entries)", ErrNoEntry, index, len(entries))
+	}
+	return entries[index-1], nil
+}
+
+// read returns the entries stored at path, oldest first.
+func read(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse history %s: %w", path, err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,112 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func entry(i int) Entry {
+	return Entry{
+		URL:     fmt.Sprintf("https://prow.ci.openshift.org/view/gs/bucket/logs/job/%d", i),
+		Time:    time.Date(2026, 1, 1, 0, i, 0, 0, time.UTC),
+		Outcome: "downloaded",
+	}
+}
+
+func TestAppendAndList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+
+	for i := 1; i <= 3; i++ {
+		if err := Append(path, entry(i), MaxEntries); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	got, err := List(path)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("List() returned %d entries, want 3", len(got))
+	}
+	for i, e := range got {
+		if want := entry(3 - i); !e.Time.Equal(want.Time) || e.URL != want.URL {
+			t.Errorf("List()[%d] = %+v, want %+v", i, e, want)
+		}
+	}
+}
+
+func TestAppend_EvictsOldest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+
+	for i := 1; i <= 5; i++ {
+		if err := Append(path, entry(i), 3); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	got, err := List(path)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var urls []string
+	for _, e := range got {
+		urls = append(urls, e.URL)
+	}
+	want := []string{entry(5).URL, entry(4).URL, entry(3).URL}
+	if fmt.Sprint(urls) != fmt.Sprint(want) {
+		t.Errorf("List() = %v, want %v", urls, want)
+	}
+}
+
+func TestGet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	for i := 1; i <= 3; i++ {
+		if err := Append(path, entry(i), MaxEntries); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		index   int
+		wantURL string
+		wantErr error
+	}{
+		{index: 1, wantURL: entry(3).URL},
+		{index: 3, wantURL: entry(1).URL},
+		{index: 0, wantErr: ErrNoEntry},
+		{index: 4, wantErr: ErrNoEntry},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.index), func(t *testing.T) {
+			got, err := Get(path, tt.index)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Get(%d) error = %v, want %v", tt.index, err, tt.wantErr)
+			}
+			if got.URL != tt.wantURL {
+				t.Errorf("Get(%d) = %q, want %q", tt.index, got.URL, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestList_MissingFile(t *testing.T) {
+	got, err := List(filepath.Join(t.TempDir(), "history.json"))
+	if err != nil || len(got) != 0 {
+		t.Errorf("List() = %v, %v; want empty history", got, err)
+	}
+}
+
+func TestList_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	if err := os.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := List(path); err == nil {
+		t.Error("List() error = nil, want parse error")
+	}
+}
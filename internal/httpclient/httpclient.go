@@ -0,0 +1,45 @@
+// Package httpclient holds the HTTP client shared by every request prow-helper
+// makes to Prow, GCS and GitHub.
+//
+// Routing those requests through a single client lets the transport be swapped,
+// e.g. to record the responses to a directory or to replay them from one
+// instead of hitting the network (see Recorder and Replayer).
+package httpclient
+
+import "net/http"
+
+// Client is the client used by Get and Do. Its Transport may be replaced to
+// record or replay traffic.
+var Client = &http.Client{}
+
+// UserAgent is sent with every request, so that Prow and GCS operators can
+// tell prow-helper's traffic apart. It is set to prow-helper/<version>, or
+// from --user-agent.
+var UserAgent = "prow-helper"
+
+// SetUserAgent sets the User-Agent header of req to UserAgent, unless req
+// already has one. Get and Do call it; requests sent with another client
+// (e.g. notifications, which have their own timeout) must call it too.
+func SetUserAgent(req *http.Request) {
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", UserAgent)
+	}
+}
+
+// Get issues a GET to url with Client. Network and TLS errors come with
+// guidance on their likely cause (see WrapNetError).
+func Get(url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return Do(req)
+}
+
+// Do sends req with Client. Network and TLS errors come with guidance on
+// their likely cause (see WrapNetError).
+func Do(req *http.Request) (*http.Response, error) {
+	SetUserAgent(req)
+	resp, err := Client.Do(req)
+	return resp, WrapNetError(err)
+}
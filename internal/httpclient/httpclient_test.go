@@ -0,0 +1,75 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUserAgent(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("User-Agent"))
+	}))
+	defer server.Close()
+
+	orig := UserAgent
+	t.Cleanup(func() { UserAgent = orig })
+	UserAgent = "prow-helper/1.2.3"
+
+	resp, err := Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL, nil)
+	resp, err = Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	resp.Body.Close()
+
+	// A User-Agent set by the caller is kept.
+	req, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("User-Agent", "custom/1")
+	resp, err = Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	resp.Body.Close()
+
+	want := []string{"prow-helper/1.2.3", "prow-helper/1.2.3", "custom/1"}
+	if len(got) != len(want) {
+		t.Fatalf("server saw %d requests, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("request %d User-Agent = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestUserAgent_ReplacedTransport(t *testing.T) {
+	var got string
+	origTransport, origUA := Client.Transport, UserAgent
+	t.Cleanup(func() { Client.Transport, UserAgent = origTransport, origUA })
+	UserAgent = "prow-helper/dev"
+	Client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		got = req.Header.Get("User-Agent")
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+
+	resp, err := Get("https://storage.googleapis.com/bucket/finished.json")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+	if got != "prow-helper/dev" {
+		t.Errorf("User-Agent = %q, want it set before the transport", got)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
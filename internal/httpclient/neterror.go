@@ -0,0 +1,78 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+// NetError is a request error with guidance on its likely cause. It wraps the
+// original error, which errors.Is and errors.As still reach.
+type NetError struct {
+	Err  error
+	Hint string
+}
+
+func (e *NetError) Error() string {
+	return e.Err.Error() + " (" + e.Hint + ")"
+}
+
+func (e *NetError) Unwrap() error {
+	return e.Err
+}
+
+// WrapNetError returns err wrapped in a NetError when its cause is a common
+// network or TLS failure, and err unchanged otherwise (including nil). Get
+// and Do apply it; requests made with another client should too.
+func WrapNetError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if hint := classifyNetError(err); hint != "" {
+		return &NetError{Err: err, Hint: hint}
+	}
+	return err
+}
+
+// classifyNetError returns actionable guidance for a network or TLS error, or
+// "" when err is not one it recognizes.
+func classifyNetError(err error) string {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "proxyconnect" {
+		return "could not connect to the proxy, check your HTTPS_PROXY/HTTP_PROXY settings"
+	}
+
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCert x509.CertificateInvalidError
+	var verifyErr *tls.CertificateVerificationError
+	if errors.As(err, &unknownAuthority) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidCert) || errors.As(err, &verifyErr) {
+		return "the server certificate is not trusted, check your proxy/CA settings (e.g. SSL_CERT_FILE)"
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && !dnsErr.IsTimeout {
+		return "host name could not be resolved, check your network connection and DNS, or whether you need a VPN"
+	}
+
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connection refused, check the host and port and your proxy settings"
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return "host unreachable, are you on VPN?"
+	case errors.Is(err, syscall.ECONNRESET):
+		return "connection reset, a proxy or firewall may be interfering"
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) ||
+		(errors.As(err, &netErr) && netErr.Timeout()) {
+		return "request timed out, the host may be unreachable (are you on VPN?) or blocked by a proxy"
+	}
+	return ""
+}
@@ -0,0 +1,99 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// urlErr wraps err the way net/http reports a failed GET.
+func urlErr(err error) error {
+	return &url.Error{Op: "Get", URL: "https://storage.googleapis.com/bucket/finished.json", Err: err}
+}
+
+// dialErr wraps a syscall error the way the dialer reports it.
+func dialErr(op string, errno syscall.Errno) error {
+	return &net.OpError{Op: op, Net: "tcp", Err: os.NewSyscallError("connect", errno)}
+}
+
+func TestClassifyNetError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string // substring of the hint; "" for no hint
+	}{
+		{"unknown CA", urlErr(x509.UnknownAuthorityError{}), "proxy/CA settings"},
+		{"wrong host name", urlErr(x509.HostnameError{Host: "example.com", Certificate: &x509.Certificate{}}), "proxy/CA settings"},
+		{"expired certificate", urlErr(x509.CertificateInvalidError{Reason: x509.Expired}), "proxy/CA settings"},
+		{"DNS failure", urlErr(&net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "storage.googleapis.com", IsNotFound: true}}), "could not be resolved"},
+		{"connection refused", urlErr(dialErr("dial", syscall.ECONNREFUSED)), "connection refused"},
+		{"host unreachable", urlErr(dialErr("dial", syscall.EHOSTUNREACH)), "are you on VPN?"},
+		{"network unreachable", urlErr(dialErr("dial", syscall.ENETUNREACH)), "are you on VPN?"},
+		{"connection reset", urlErr(dialErr("read", syscall.ECONNRESET)), "proxy or firewall"},
+		{"proxy unreachable", urlErr(dialErr("proxyconnect", syscall.ECONNREFUSED)), "HTTPS_PROXY"},
+		{"dial timeout", urlErr(&net.OpError{Op: "dial", Err: os.ErrDeadlineExceeded}), "timed out"},
+		{"client timeout", urlErr(context.DeadlineExceeded), "timed out"},
+		{"DNS timeout", urlErr(&net.OpError{Op: "dial", Err: &net.DNSError{Err: "i/o timeout", IsTimeout: true}}), "timed out"},
+		{"unrelated error", urlErr(errors.New("unsupported protocol scheme")), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyNetError(tt.err)
+			if tt.want == "" {
+				if got != "" {
+					t.Errorf("classifyNetError() = %q, want no hint", got)
+				}
+				return
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("classifyNetError() = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrapNetError(t *testing.T) {
+	if WrapNetError(nil) != nil {
+		t.Error("WrapNetError(nil) should be nil")
+	}
+
+	plain := errors.New("boom")
+	if got := WrapNetError(plain); got != plain {
+		t.Errorf("WrapNetError() = %v, want an unclassified error unchanged", got)
+	}
+
+	orig := urlErr(dialErr("dial", syscall.ECONNREFUSED))
+	err := WrapNetError(orig)
+	var netErr *NetError
+	if !errors.As(err, &netErr) {
+		t.Fatalf("WrapNetError() = %T, want *NetError", err)
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Error("wrapped error should still match the original cause")
+	}
+	if !strings.HasPrefix(err.Error(), orig.Error()) || !strings.Contains(err.Error(), netErr.Hint) {
+		t.Errorf("Error() = %q, want the original message followed by the hint", err.Error())
+	}
+}
+
+func TestGet_WrapsNetworkErrors(t *testing.T) {
+	server := httptest.NewServer(nil)
+	addr := server.URL
+	server.Close() // nothing listens there anymore
+
+	_, err := Get(addr)
+	var netErr *NetError
+	if !errors.As(err, &netErr) {
+		t.Fatalf("Get() error = %v, want a *NetError", err)
+	}
+	if !strings.Contains(netErr.Hint, "connection refused") {
+		t.Errorf("hint = %q, want connection refused guidance", netErr.Hint)
+	}
+}
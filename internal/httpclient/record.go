@@ -0,0 +1,162 @@
+package httpclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrNoRecording is returned by a Replayer for a request that was never recorded.
+var ErrNoRecording = errors.New("no recorded response")
+
+// recording is the on-disk form of one recorded response.
+type recording struct {
+	Method      string `json:"method"`
+	URL         string `json:"url"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body"`
+}
+
+// recordingPath returns the file holding the n-th response recorded for
+// method and url. Responses to the same request are numbered in the order
+// they were received, so a replay sees them in the same sequence.
+func recordingPath(dir, method, url string, n int) string {
+	sum := sha256.Sum256([]byte(method + " " + url))
+	return filepath.Join(dir, fmt.Sprintf("%s-%03d.json", hex.EncodeToString(sum[:8]), n))
+}
+
+// Recorder is an http.RoundTripper that forwards requests to another
+// transport and saves every response it receives to a directory.
+type Recorder struct {
+	dir  string
+	next http.RoundTripper
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// NewRecorder returns a Recorder writing to dir, which is created if needed.
+// Requests are sent with next, or http.DefaultTransport if next is nil.
+func NewRecorder(dir string, next http.RoundTripper) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create record directory: %w", err)
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{dir: dir, next: next, counts: make(map[string]int)}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	url := req.URL.String()
+	r.mu.Lock()
+	key := req.Method + " " + url
+	n := r.counts[key]
+	r.counts[key]++
+	r.mu.Unlock()
+
+	data, err := json.MarshalIndent(recording{
+		Method:      req.Method,
+		URL:         url,
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        string(body),
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(recordingPath(r.dir, req.Method, url, n), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to record response: %w", err)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// Replayer is an http.RoundTripper that serves the responses saved by a
+// Recorder instead of using the network. Repeated requests get the recorded
+// responses in order; once they are exhausted the last one is served again.
+type Replayer struct {
+	dir string
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// NewReplayer returns a Replayer reading from dir.
+func NewReplayer(dir string) (*Replayer, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replay directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("replay path %s is not a directory", dir)
+	}
+	return &Replayer{dir: dir, counts: make(map[string]int)}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	url := req.URL.String()
+	key := req.Method + " " + url
+
+	r.mu.Lock()
+	n := r.counts[key]
+	path := recordingPath(r.dir, req.Method, url, n)
+	if _, err := os.Stat(path); err == nil {
+		r.counts[key]++
+	} else if n > 0 {
+		path = recordingPath(r.dir, req.Method, url, n-1)
+	}
+	r.mu.Unlock()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w for %s %s", ErrNoRecording, req.Method, url)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rec recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to parse recording %s: %w", path, err)
+	}
+
+	header := make(http.Header)
+	if rec.ContentType != "" {
+		header.Set("Content-Type", rec.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status)),
+		StatusCode:    rec.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(rec.Body))),
+		ContentLength: int64(len(rec.Body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,95 @@
+package httpclient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func get(t *testing.T, client *http.Client, url string) (int, string) {
+	t.Helper()
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/finished.json" {
+			calls++
+			if calls == 1 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(`{"passed":true}`))
+			return
+		}
+		w.Write([]byte("other"))
+	}))
+	dir := t.TempDir()
+
+	recorder, err := NewRecorder(dir, nil)
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+	recordClient := &http.Client{Transport: recorder}
+
+	urls := []string{server.URL + "/finished.json", server.URL + "/other", server.URL + "/finished.json"}
+	type result struct {
+		status int
+		body   string
+	}
+	var recorded []result
+	for _, u := range urls {
+		status, body := get(t, recordClient, u)
+		recorded = append(recorded, result{status, body})
+	}
+	server.Close()
+
+	replayer, err := NewReplayer(dir)
+	if err != nil {
+		t.Fatalf("NewReplayer() error = %v", err)
+	}
+	replayClient := &http.Client{Transport: replayer}
+
+	for i, u := range urls {
+		status, body := get(t, replayClient, u)
+		if status != recorded[i].status || body != recorded[i].body {
+			t.Errorf("replay %d of %s = (%d, %q), want (%d, %q)", i, u, status, body, recorded[i].status, recorded[i].body)
+		}
+	}
+
+	// Exhausted sequences keep serving the last recorded response.
+	if status, body := get(t, replayClient, urls[0]); status != http.StatusOK || body != `{"passed":true}` {
+		t.Errorf("replay after exhaustion = (%d, %q), want the last recording", status, body)
+	}
+}
+
+func TestReplayer_UnknownRequest(t *testing.T) {
+	replayer, err := NewReplayer(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewReplayer() error = %v", err)
+	}
+	client := &http.Client{Transport: replayer}
+
+	_, err = client.Get("https://storage.googleapis.com/bucket/missing/finished.json")
+	if !errors.Is(err, ErrNoRecording) {
+		t.Errorf("Get() error = %v, want ErrNoRecording", err)
+	}
+}
+
+func TestNewReplayer_MissingDir(t *testing.T) {
+	if _, err := NewReplayer("/nonexistent/replay/dir"); err == nil {
+		t.Error("NewReplayer() should fail for a missing directory")
+	}
+}
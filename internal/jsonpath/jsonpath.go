@@ -0,0 +1,94 @@
+// Package jsonpath extracts values from JSON documents with dot-delimited
+// paths such as "status.startTime" or "items.0.name".
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound    = errors.New("field not found")
+	ErrNotATime    = errors.New("value is not a timestamp")
+	ErrInvalidJSON = errors.New("invalid JSON")
+)
+
+// Lookup decodes data and returns the value at path. Each dot-separated
+// segment selects an object key, or an element when applied to an array and
+// numeric. Numbers are returned as json.Number.
+func Lookup(data []byte, path string) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+	}
+
+	if path == "" {
+		return value, nil
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			next, ok := v[key]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrNotFound, path)
+			}
+			value = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("%w: %s", ErrNotFound, path)
+			}
+			value = v[i]
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, path)
+		}
+	}
+	return value, nil
+}
+
+// Time converts a value returned by Lookup to a time: numbers (and numeric
+// strings) are Unix seconds, other strings must be RFC 3339. A zero Unix
+// timestamp yields the zero time.
+func Time(value any) (time.Time, error) {
+	var seconds json.Number
+	switch v := value.(type) {
+	case json.Number:
+		seconds = v
+	case string:
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t, nil
+		}
+		seconds = json.Number(v)
+	default:
+		return time.Time{}, fmt.Errorf("%w: %v", ErrNotATime, value)
+	}
+
+	secs, err := seconds.Int64()
+	if err != nil {
+		f, ferr := seconds.Float64()
+		if ferr != nil {
+			return time.Time{}, fmt.Errorf("%w: %v", ErrNotATime, value)
+		}
+		secs = int64(f)
+	}
+	if secs == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(secs, 0), nil
+}
+
+// LookupTime is Lookup followed by Time.
+func LookupTime(data []byte, path string) (time.Time, error) {
+	value, err := Lookup(data, path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return Time(value)
+}
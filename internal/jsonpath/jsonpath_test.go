@@ -0,0 +1,86 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+const prowJobJSON = `{
+  "kind": "ProwJob",
+  "spec": {"job": "periodic-ci-e2e"},
+  "status": {
+    "startTime": "2024-02-24T10:30:00Z",
+    "pod_names": ["pod-a", "pod-b"]
+  }
+}`
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		path    string
+		want    any
+		wantErr error
+	}{
+		{"top-level number", `{"timestamp": 1700000000}`, "timestamp", json.Number("1700000000"), nil},
+		{"nested string", prowJobJSON, "status.startTime", "2024-02-24T10:30:00Z", nil},
+		{"array index", prowJobJSON, "status.pod_names.1", "pod-b", nil},
+		{"missing key", prowJobJSON, "status.completionTime", nil, ErrNotFound},
+		{"index out of range", prowJobJSON, "status.pod_names.5", nil, ErrNotFound},
+		{"descend into scalar", prowJobJSON, "kind.name", nil, ErrNotFound},
+		{"invalid JSON", `<html>`, "timestamp", nil, ErrInvalidJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Lookup([]byte(tt.data), tt.path)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Lookup() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Lookup() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Lookup() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupTime(t *testing.T) {
+	rfc, _ := time.Parse(time.RFC3339, "2024-02-24T10:30:00Z")
+
+	tests := []struct {
+		name    string
+		data    string
+		path    string
+		want    time.Time
+		wantErr bool
+	}{
+		{"started.json timestamp", `{"timestamp": 1700000000, "node": "x"}`, "timestamp", time.Unix(1700000000, 0), false},
+		{"prowjob.json startTime", prowJobJSON, "status.startTime", rfc, false},
+		{"numeric string", `{"ts": "1700000000"}`, "ts", time.Unix(1700000000, 0), false},
+		{"float seconds", `{"ts": 1700000000.5}`, "ts", time.Unix(1700000000, 0), false},
+		{"zero timestamp", `{"timestamp": 0}`, "timestamp", time.Time{}, false},
+		{"not a time", prowJobJSON, "spec.job", time.Time{}, true},
+		{"object", prowJobJSON, "status", time.Time{}, true},
+		{"missing", `{}`, "timestamp", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LookupTime([]byte(tt.data), tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LookupTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("LookupTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
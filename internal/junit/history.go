@@ -0,0 +1,79 @@
+package junit
+
+import (
+	"regexp"
+	"sort"
+)
+
+// History holds the statuses of a set of tests across several builds of a
+// job, to spot flaky tests.
+type History struct {
+	Builds []string            // build IDs, in the order given to NewHistory
+	Tests  []string            // test IDs, sorted
+	Status map[string][]Status // per test ID, parallel to Builds; "" where the test did not run
+}
+
+// NewHistory builds the History of the tests whose ID matches re across the
+// builds buildIDs, whose results are given in the parallel slice results.
+// A nil re selects the tests that failed in at least one build.
+func NewHistory(buildIDs []string, results []Results, re *regexp.Regexp) History {
+	h := History{Builds: buildIDs, Status: make(map[string][]Status)}
+	for i, r := range results {
+		for id, c := range r {
+			if re != nil && !re.MatchString(id) {
+				continue
+			}
+			if _, ok := h.Status[id]; !ok {
+				h.Status[id] = make([]Status, len(buildIDs))
+			}
+			h.Status[id][i] = c.Status
+		}
+	}
+	for id, statuses := range h.Status {
+		if re == nil && !contains(statuses, StatusFailed) {
+			delete(h.Status, id)
+			continue
+		}
+		h.Tests = append(h.Tests, id)
+	}
+	sort.Strings(h.Tests)
+	return h
+}
+
+// Counts returns how many builds passed and failed the test.
+func (h History) Counts(test string) (passed, failed int) {
+	for _, s := range h.Status[test] {
+		switch s {
+		case StatusPassed:
+			passed++
+		case StatusFailed:
+			failed++
+		}
+	}
+	return passed, failed
+}
+
+// FlakeRate returns the fraction of the builds that ran the test (passed or
+// failed it) in which it failed, or 0 when no build ran it.
+func (h History) FlakeRate(test string) float64 {
+	passed, failed := h.Counts(test)
+	if passed+failed == 0 {
+		return 0
+	}
+	return float64(failed) / float64(passed+failed)
+}
+
+// Flaky reports whether the test both passed and failed across the builds.
+func (h History) Flaky(test string) bool {
+	passed, failed := h.Counts(test)
+	return passed > 0 && failed > 0
+}
+
+func contains(statuses []Status, s Status) bool {
+	for _, st := range statuses {
+		if st == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package junit
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// historyResults are the results of three builds of a job.
+var historyResults = []Results{
+	results(
+		TestCase{Suite: "e2e", Name: "fencing", Status: StatusPassed},
+		TestCase{Suite: "e2e", Name: "upgrade", Status: StatusPassed},
+		TestCase{Suite: "e2e", Name: "stable", Status: StatusPassed},
+	),
+	results(
+		TestCase{Suite: "e2e", Name: "fencing", Status: StatusFailed},
+		TestCase{Suite: "e2e", Name: "upgrade", Status: StatusSkipped},
+		TestCase{Suite: "e2e", Name: "stable", Status: StatusPassed},
+	),
+	results(
+		TestCase{Suite: "e2e", Name: "fencing", Status: StatusFailed},
+		TestCase{Suite: "e2e", Name: "stable", Status: StatusPassed},
+	),
+}
+
+func TestNewHistory(t *testing.T) {
+	builds := []string{"1", "2", "3"}
+
+	h := NewHistory(builds, historyResults, regexp.MustCompile(`fencing|upgrade`))
+	if want := []string{"e2e: fencing", "e2e: upgrade"}; !reflect.DeepEqual(h.Tests, want) {
+		t.Errorf("Tests = %v, want %v", h.Tests, want)
+	}
+	want := map[string][]Status{
+		"e2e: fencing": {StatusPassed, StatusFailed, StatusFailed},
+		"e2e: upgrade": {StatusPassed, StatusSkipped, ""},
+	}
+	if !reflect.DeepEqual(h.Status, want) {
+		t.Errorf("Status = %v, want %v", h.Status, want)
+	}
+
+	// Without a pattern, only the tests that failed at least once.
+	h = NewHistory(builds, historyResults, nil)
+	if want := []string{"e2e: fencing"}; !reflect.DeepEqual(h.Tests, want) {
+		t.Errorf("Tests without a pattern = %v, want %v", h.Tests, want)
+	}
+}
+
+func TestHistoryFlakeRate(t *testing.T) {
+	h := NewHistory([]string{"1", "2", "3"}, historyResults, regexp.MustCompile(`.`))
+
+	tests := []struct {
+		test      string
+		wantRate  float64
+		wantFlaky bool
+	}{
+		{test: "e2e: fencing", wantRate: 2.0 / 3, wantFlaky: true},
+		{test: "e2e: upgrade", wantRate: 0},
+		{test: "e2e: stable", wantRate: 0},
+		{test: "e2e: unknown", wantRate: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.test, func(t *testing.T) {
+			if got := h.FlakeRate(tt.test); got != tt.wantRate {
+				t.Errorf("FlakeRate() = %v, want %v", got, tt.wantRate)
+			}
+			if got := h.Flaky(tt.test); got != tt.wantFlaky {
+				t.Errorf("Flaky() = %v, want %v", got, tt.wantFlaky)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+// Package junit reads JUnit XML test reports and compares the results of two
+// builds.
+package junit
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Status is the outcome of a single test case.
+type Status string
+
+const (
+	StatusPassed  Status = "passed"
+	StatusFailed  Status = "failed"
+	StatusSkipped Status = "skipped"
+)
+
+// rank orders statuses when a test appears more than once in a build: any
+// failure wins, then any pass.
+var rank = map[Status]int{StatusSkipped: 0, StatusPassed: 1, StatusFailed: 2}
+
+// TestCase is one test case from a report.
+type TestCase struct {
+	Suite   string
+	Name    string
+	Status  Status
+	Message string // failure or error message, if any
+}
+
+// ID identifies the test case across builds.
+func (c TestCase) ID() string {
+	if c.Suite == "" {
+		return c.Name
+	}
+	return c.Suite + ": " + c.Name
+}
+
+// Results holds the test cases of a build keyed by ID.
+type Results map[string]TestCase
+
+// Add records c, keeping the worst status when the test is already known
+// (e.g. a retried test that failed once).
+func (r Results) Add(c TestCase) {
+	if prev, ok := r[c.ID()]; ok && rank[prev.Status] >= rank[c.Status] {
+		return
+	}
+	r[c.ID()] = c
+}
+
+type xmlSuites struct {
+	Suites []xmlSuite `xml:"testsuite"`
+}
+
+type xmlSuite struct {
+	Name   string     `xml:"name,attr"`
+	Cases  []xmlCase  `xml:"testcase"`
+	Suites []xmlSuite `xml:"testsuite"`
+}
+
+type xmlCase struct {
+	Name    string  `xml:"name,attr"`
+	Failure *xmlMsg `xml:"failure"`
+	Error   *xmlMsg `xml:"error"`
+	Skipped *xmlMsg `xml:"skipped"`
+}
+
+type xmlMsg struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// message returns the message attribute or, when a report leaves it out, the
+// element's text.
+func (m *xmlMsg) message() string {
+	if m.Message != "" {
+		return m.Message
+	}
+	return strings.TrimSpace(m.Text)
+}
+
+// Parse reads a JUnit report whose root element is <testsuites> or
+// <testsuite> and adds its test cases to a new Results.
+func Parse(r io.Reader) (Results, error) {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("invalid JUnit report: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		var suites []xmlSuite
+		switch start.Name.Local {
+		case "testsuites":
+			var doc xmlSuites
+			if err := dec.DecodeElement(&doc, &start); err != nil {
+				return nil, fmt.Errorf("invalid JUnit report: %w", err)
+			}
+			suites = doc.Suites
+		case "testsuite":
+			var suite xmlSuite
+			if err := dec.DecodeElement(&suite, &start); err != nil {
+				return nil, fmt.Errorf("invalid JUnit report: %w", err)
+			}
+			suites = []xmlSuite{suite}
+		default:
+			return nil, fmt.Errorf("invalid JUnit report: unexpected root element <%s>", start.Name.Local)
+		}
+
+		results := make(Results)
+		addSuites(results, suites)
+		return results, nil
+	}
+}
+
+func addSuites(results Results, suites []xmlSuite) {
+	for _, s := range suites {
+		for _, c := range s.Cases {
+			tc := TestCase{Suite: s.Name, Name: c.Name, Status: StatusPassed}
+			switch {
+			case c.Failure != nil:
+				tc.Status, tc.Message = StatusFailed, c.Failure.message()
+			case c.Error != nil:
+				tc.Status, tc.Message = StatusFailed, c.Error.message()
+			case c.Skipped != nil:
+				tc.Status = StatusSkipped
+			}
+			results.Add(tc)
+		}
+		addSuites(results, s.Suites)
+	}
+}
+
+// Filter returns the test cases whose ID (suite and name) matches re.
+func (r Results) Filter(re *regexp.Regexp) Results {
+	filtered := make(Results)
+	for id, c := range r {
+		if re.MatchString(id) {
+			filtered[id] = c
+		}
+	}
+	return filtered
+}
+
+// Sorted returns the test cases sorted by ID.
+func (r Results) Sorted() []TestCase {
+	cases := make([]TestCase, 0, len(r))
+	for _, c := range r {
+		cases = append(cases, c)
+	}
+	sort.Slice(cases, func(i, j int) bool { return cases[i].ID() < cases[j].ID() })
+	return cases
+}
+
+// IsReport reports whether a file name follows the Prow convention for JUnit
+// reports (junit*.xml).
+func IsReport(name string) bool {
+	return strings.HasPrefix(name, "junit") && strings.HasSuffix(name, ".xml")
+}
+
+// ParseDir parses every JUnit report under dir and merges their test cases.
+func ParseDir(dir string) (Results, error) {
+	results := make(Results)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || !IsReport(d.Name()) {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		parsed, err := Parse(f)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		for _, c := range parsed {
+			results.Add(c)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Flip is a test whose status differs between two builds.
+type Flip struct {
+	ID       string
+	From, To Status
+}
+
+// Diff lists the tests that differ between two builds, each sorted by ID.
+type Diff struct {
+	Added   []TestCase // only in the second build
+	Removed []TestCase // only in the first build
+	Flipped []Flip     // in both, with different statuses
+}
+
+// Empty reports whether the two builds ran the same tests with the same
+// results.
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Flipped) == 0
+}
+
+// Compare returns how the results of build b differ from those of build a.
+func Compare(a, b Results) Diff {
+	var d Diff
+	for id, ca := range a {
+		cb, ok := b[id]
+		if !ok {
+			d.Removed = append(d.Removed, ca)
+			continue
+		}
+		if ca.Status != cb.Status {
+			d.Flipped = append(d.Flipped, Flip{ID: id, From: ca.Status, To: cb.Status})
+		}
+	}
+	for id, cb := range b {
+		if _, ok := a[id]; !ok {
+			d.Added = append(d.Added, cb)
+		}
+	}
+	sort.Slice(d.Added, func(i, j int) bool { return d.Added[i].ID() < d.Added[j].ID() })
+	sort.Slice(d.Removed, func(i, j int) bool { return d.Removed[i].ID() < d.Removed[j].ID() })
+	sort.Slice(d.Flipped, func(i, j int) bool { return d.Flipped[i].ID < d.Flipped[j].ID })
+	return d
+}
@@ -0,0 +1,169 @@
+package junit
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const suitesReport = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="e2e" tests="4">
+    <testcase name="passes"></testcase>
+    <testcase name="fails"><failure message="timeout">stack</failure></testcase>
+    <testcase name="errors"><error message="panic"></error></testcase>
+    <testcase name="skips"><skipped message="not on this platform"></skipped></testcase>
+  </testsuite>
+</testsuites>`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		report  string
+		want    Results
+		wantErr bool
+	}{
+		{
+			name:   "testsuites root",
+			report: suitesReport,
+			want: Results{
+				"e2e: passes": {Suite: "e2e", Name: "passes", Status: StatusPassed},
+				"e2e: fails":  {Suite: "e2e", Name: "fails", Status: StatusFailed, Message: "timeout"},
+				"e2e: errors": {Suite: "e2e", Name: "errors", Status: StatusFailed, Message: "panic"},
+				"e2e: skips":  {Suite: "e2e", Name: "skips", Status: StatusSkipped},
+			},
+		},
+		{
+			name:   "testsuite root",
+			report: `<testsuite name="unit"><testcase name="a"/></testsuite>`,
+			want:   Results{"unit: a": {Suite: "unit", Name: "a", Status: StatusPassed}},
+		},
+		{
+			name:   "retried test keeps the failure",
+			report: `<testsuite name="s"><testcase name="flaky"><failure/></testcase><testcase name="flaky"/></testsuite>`,
+			want:   Results{"s: flaky": {Suite: "s", Name: "flaky", Status: StatusFailed}},
+		},
+		{
+			name: "failure text without a message attribute",
+			report: `<testsuite name="s"><testcase name="t"><failure>
+  expected 3 replicas, got 2
+</failure></testcase></testsuite>`,
+			want: Results{"s: t": {Suite: "s", Name: "t", Status: StatusFailed, Message: "expected 3 replicas, got 2"}},
+		},
+		{
+			name:    "not junit",
+			report:  `<html></html>`,
+			wantErr: true,
+		},
+		{
+			name:    "not xml",
+			report:  `{"passed": true}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.report))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"artifacts/junit_e2e.xml":      suitesReport,
+		"artifacts/nested/junit_1.xml": `<testsuite name="unit"><testcase name="a"/></testsuite>`,
+		"artifacts/not-a-report.xml":   `<html/>`,
+		"artifacts/junit-notes.txt":    `ignored`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := ParseDir(dir)
+	if err != nil {
+		t.Fatalf("ParseDir() error = %v", err)
+	}
+	if len(got) != 5 {
+		t.Errorf("ParseDir() found %d test cases, want 5: %+v", len(got), got)
+	}
+	if _, ok := got["unit: a"]; !ok {
+		t.Errorf("ParseDir() did not read the nested report")
+	}
+}
+
+func results(cases ...TestCase) Results {
+	r := make(Results)
+	for _, c := range cases {
+		r.Add(c)
+	}
+	return r
+}
+
+func TestFilter(t *testing.T) {
+	r := results(
+		TestCase{Suite: "e2e", Name: "fencing reboots the node", Status: StatusFailed, Message: "timeout"},
+		TestCase{Suite: "e2e", Name: "fencing with a bad BMC", Status: StatusPassed},
+		TestCase{Suite: "e2e", Name: "creates a pod", Status: StatusPassed},
+		TestCase{Suite: "unit", Name: "fencing config", Status: StatusPassed},
+	)
+
+	var got []string
+	for _, c := range r.Filter(regexp.MustCompile(`e2e.*fencing`)).Sorted() {
+		got = append(got, c.ID()+" "+string(c.Status))
+	}
+	want := []string{"e2e: fencing reboots the node failed", "e2e: fencing with a bad BMC passed"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Filter().Sorted() = %v, want %v", got, want)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	a := results(
+		TestCase{Suite: "s", Name: "stable", Status: StatusPassed},
+		TestCase{Suite: "s", Name: "breaks", Status: StatusPassed},
+		TestCase{Suite: "s", Name: "heals", Status: StatusFailed},
+		TestCase{Suite: "s", Name: "removed", Status: StatusPassed},
+	)
+	b := results(
+		TestCase{Suite: "s", Name: "stable", Status: StatusPassed},
+		TestCase{Suite: "s", Name: "breaks", Status: StatusFailed},
+		TestCase{Suite: "s", Name: "heals", Status: StatusPassed},
+		TestCase{Suite: "s", Name: "added", Status: StatusSkipped},
+	)
+
+	got := Compare(a, b)
+	want := Diff{
+		Added:   []TestCase{{Suite: "s", Name: "added", Status: StatusSkipped}},
+		Removed: []TestCase{{Suite: "s", Name: "removed", Status: StatusPassed}},
+		Flipped: []Flip{
+			{ID: "s: breaks", From: StatusPassed, To: StatusFailed},
+			{ID: "s: heals", From: StatusFailed, To: StatusPassed},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Compare() = %+v, want %+v", got, want)
+	}
+	if got.Empty() {
+		t.Error("Diff.Empty() = true, want false")
+	}
+	if !Compare(a, a).Empty() {
+		t.Errorf("Compare(a, a) = %+v, want empty", Compare(a, a))
+	}
+}
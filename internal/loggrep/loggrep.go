@@ -0,0 +1,91 @@
+// Package loggrep searches the log files of downloaded artifacts for lines
+// matching a pattern.
+package loggrep
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DefaultPattern matches the lines that usually explain a failure.
+const DefaultPattern = `(?i)\berror\b|\bfail(ed|ure)?\b|\bpanic:`
+
+// maxLineSize bounds the length of a scanned line: a file is only searched up
+// to its first longer line.
+const maxLineSize = 1024 * 1024
+
+// Match is a matching line of a log file.
+type Match struct {
+	File string // slash-separated path relative to the searched directory
+	Line int    // 1-based line number
+	Text string
+}
+
+// IsLog reports whether a file name is a log searched by Grep: the job's
+// build-log.txt or any *.log file.
+func IsLog(name string) bool {
+	return name == "build-log.txt" || strings.HasSuffix(name, ".log")
+}
+
+// Grep returns the lines of the log files under dir (see IsLog) that match
+// re, in file path order, stopping after max matches when max > 0.
+func Grep(dir string, re *regexp.Regexp, max int) ([]Match, error) {
+	var matches []Match
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || !IsLog(d.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		found, err := grepFile(path, filepath.ToSlash(rel), re, max-len(matches))
+		matches = append(matches, found...)
+		if err != nil {
+			return err
+		}
+		if max > 0 && len(matches) >= max {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// grepFile returns the lines of the file at path that match re, reported
+// under the name rel, stopping after max matches when max > 0.
+func grepFile(path, rel string, re *regexp.Regexp, max int) ([]Match, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var matches []Match
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if !re.MatchString(text) {
+			continue
+		}
+		matches = append(matches, Match{File: rel, Line: line, Text: strings.TrimRight(text, "\r")})
+		if max > 0 && len(matches) >= max {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil && err != bufio.ErrTooLong {
+		return matches, err
+	}
+	return matches, nil
+}
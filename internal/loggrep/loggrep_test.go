@@ -0,0 +1,80 @@
+package loggrep
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestIsLog(t *testing.T) {
+	tests := map[string]bool{
+		"build-log.txt":   true,
+		"kubelet.log":     true,
+		"notes.txt":       false,
+		"junit_e2e.xml":   false,
+		"build-log.txt.1": false,
+	}
+	for name, want := range tests {
+		if got := IsLog(name); got != want {
+			t.Errorf("IsLog(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestGrep(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"build-log.txt":                  "starting\nerror: cluster install failed\ndone\n",
+		"artifacts/e2e/gather/nodes.log": "ok\r\npanic: runtime error\r\n",
+		"artifacts/e2e/notes.txt":        "error in a file that is not a log\n",
+	})
+
+	got, err := Grep(dir, regexp.MustCompile(DefaultPattern), 0)
+	if err != nil {
+		t.Fatalf("Grep() error = %v", err)
+	}
+	want := []Match{
+		{File: "artifacts/e2e/gather/nodes.log", Line: 2, Text: "panic: runtime error"},
+		{File: "build-log.txt", Line: 2, Text: "error: cluster install failed"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Grep() = %+v, want %+v", got, want)
+	}
+}
+
+func TestGrep_Max(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.log": "x1\nx2\nx3\n",
+		"b.log": "x4\n",
+	})
+
+	got, err := Grep(dir, regexp.MustCompile(`x`), 2)
+	if err != nil {
+		t.Fatalf("Grep() error = %v", err)
+	}
+	if len(got) != 2 || got[0].Text != "x1" || got[1].Text != "x2" {
+		t.Errorf("Grep() with max 2 = %+v, want the first two matches", got)
+	}
+}
+
+func TestGrep_MissingDir(t *testing.T) {
+	if _, err := Grep(filepath.Join(t.TempDir(), "missing"), regexp.MustCompile(`x`), 0); err == nil {
+		t.Error("Grep() error = nil, want an error for a missing directory")
+	}
+}
@@ -0,0 +1,77 @@
+// Package logtail returns the last lines of logs, either from a local file or
+// from bytes already fetched (e.g. a range of a remote build log).
+package logtail
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// chunkSize is how many bytes TailFile reads at a time, walking back from the
+// end of the file.
+const chunkSize = 4096
+
+// TailBytes returns the last n lines of b, without their line terminators.
+// A trailing newline does not start an extra empty line. It returns nil when
+// n <= 0 or b is empty.
+func TailBytes(b []byte, n int) []string {
+	if n <= 0 || len(b) == 0 {
+		return nil
+	}
+	b = bytes.TrimSuffix(b, []byte("\n"))
+
+	// Find the n-th newline from the end so only the tail is split; -1 means
+	// the tail starts at the beginning of b.
+	start := len(b)
+	for i := 0; i < n && start >= 0; i++ {
+		start = bytes.LastIndexByte(b[:start], '\n')
+	}
+
+	var lines []string
+	for _, line := range bytes.Split(b[start+1:], []byte("\n")) {
+		lines = append(lines, string(bytes.TrimSuffix(line, []byte("\r"))))
+	}
+	return lines
+}
+
+// TailFile returns the last n lines of the file at path, reading it backwards
+// from the end so only the tail of a large log is loaded.
+func TailFile(path string, n int) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf []byte
+	pos := size
+	for pos > 0 {
+		readSize := int64(chunkSize)
+		if pos < readSize {
+			readSize = pos
+		}
+		pos -= readSize
+
+		chunk := make([]byte, readSize)
+		if _, err := f.ReadAt(chunk, pos); err != nil {
+			return nil, err
+		}
+		buf = append(chunk, buf...)
+
+		// n complete lines need n separating newlines before them, not
+		// counting the file's own trailing newline.
+		if bytes.Count(bytes.TrimSuffix(buf, []byte("\n")), []byte("\n")) >= n {
+			break
+		}
+	}
+	return TailBytes(buf, n), nil
+}
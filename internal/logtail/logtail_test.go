@@ -0,0 +1,81 @@
+package logtail
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var tailTests = []struct {
+	name    string
+	content string
+	n       int
+	want    []string
+}{
+	{name: "empty", content: "", n: 3, want: nil},
+	{name: "trailing newline", content: "a\nb\nc\n", n: 2, want: []string{"b", "c"}},
+	{name: "no trailing newline", content: "a\nb\nc", n: 2, want: []string{"b", "c"}},
+	{name: "n larger than line count", content: "a\nb\n", n: 10, want: []string{"a", "b"}},
+	{name: "n equals line count", content: "a\nb", n: 2, want: []string{"a", "b"}},
+	{name: "single line without newline", content: "only", n: 1, want: []string{"only"}},
+	{name: "blank lines kept", content: "a\n\nb\n", n: 2, want: []string{"", "b"}},
+	{name: "only a newline", content: "\n", n: 2, want: []string{""}},
+	{name: "crlf", content: "a\r\nb\r\n", n: 1, want: []string{"b"}},
+	{name: "zero lines", content: "a\nb\n", n: 0, want: nil},
+}
+
+func TestTailBytes(t *testing.T) {
+	for _, tt := range tailTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TailBytes([]byte(tt.content), tt.n)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TailBytes(%q, %d) = %q, want %q", tt.content, tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTailFile(t *testing.T) {
+	for _, tt := range tailTests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "build-log.txt")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := TailFile(path, tt.n)
+			if err != nil {
+				t.Fatalf("TailFile() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TailFile(%q, %d) = %q, want %q", tt.content, tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTailFile_SpansChunks(t *testing.T) {
+	// Lines longer than a chunk force several backward reads.
+	long := strings.Repeat("x", chunkSize+10)
+	content := "first\n" + long + "\n" + long + "\nlast\n"
+	path := filepath.Join(t.TempDir(), "build-log.txt")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := TailFile(path, 3)
+	if err != nil {
+		t.Fatalf("TailFile() error = %v", err)
+	}
+	want := []string{long, long, "last"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TailFile() returned %d lines, want %d ending in %q", len(got), len(want), "last")
+	}
+}
+
+func TestTailFile_Missing(t *testing.T) {
+	if _, err := TailFile(filepath.Join(t.TempDir(), "missing"), 5); !os.IsNotExist(err) {
+		t.Errorf("TailFile() error = %v, want not-exist error", err)
+	}
+}
@@ -0,0 +1,75 @@
+package notifier
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Senders used by Multi; replaced in tests.
+var (
+	sendNtfy    = NotifyNtfy
+	sendDesktop = Notify
+	sendWebhook = NotifyWebhook
+)
+
+// Multi delivers a notification over ntfy.sh and/or the desktop.
+//
+// Ntfy and Desktop select the channels that are always used. When Fallback is
+// set, a channel that fails hands the notification over to the other one:
+// a failed ntfy.sh send triggers a desktop notification, and a failed desktop
+// notification is sent to NtfyChannel if ntfy.sh was not used already.
+//
+// Independently, the notification is posted to WebhookURL when Event is one
+// of WebhookEvents.
+type Multi struct {
+	NtfyChannel string // ntfy.sh topic, used by Ntfy and by the fallback
+	Ntfy        bool   // send to NtfyChannel
+	Desktop     bool   // send a desktop notification
+	Fallback    bool   // chain to the other channel when one fails
+	Event       Event  // what happened, for the webhook
+}
+
+// Send delivers the notification and returns the errors of the channels that
+// failed, or nil if every attempted channel succeeded. The error of a channel
+// whose fallback succeeded is still reported, so callers can warn about it.
+func (m Multi) Send(title, message string, success bool) error {
+	var errs []error
+	ntfyTried := false
+	ntfyFailed := false
+
+	if m.Ntfy && m.NtfyChannel != "" {
+		ntfyTried = true
+		if err := sendNtfy(m.NtfyChannel, fullTitle(title, success), message); err != nil {
+			ntfyFailed = true
+			errs = append(errs, fmt.Errorf("ntfy notification failed: %w", err))
+		}
+	}
+
+	if m.Desktop || (m.Fallback && ntfyFailed) {
+		if err := sendDesktop(title, message, success); err != nil {
+			errs = append(errs, fmt.Errorf("desktop notification failed: %w", err))
+			if m.Fallback && !ntfyTried && m.NtfyChannel != "" {
+				if err := sendNtfy(m.NtfyChannel, fullTitle(title, success), message); err != nil {
+					errs = append(errs, fmt.Errorf("ntfy notification failed: %w", err))
+				}
+			}
+		}
+	}
+
+	if WebhookURL != "" && webhookWanted(m.Event) {
+		if err := sendWebhook(WebhookURL, m.Event, fullTitle(title, success), message, success); err != nil {
+			errs = append(errs, fmt.Errorf("webhook notification failed: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// fullTitle prefixes title with the application name and the outcome.
+func fullTitle(title string, success bool) string {
+	statusIcon := "Success"
+	if !success {
+		statusIcon = "Failed"
+	}
+	return fmt.Sprintf("prow-helper: %s - %s", title, statusIcon)
+}
@@ -0,0 +1,116 @@
+package notifier
+
+import (
+	"errors"
+	"testing"
+)
+
+// mockSenders replaces the ntfy and desktop senders for the duration of a test.
+// The returned slice records which channels were attempted, in order.
+func mockSenders(t *testing.T, ntfyErr, desktopErr error) *[]string {
+	t.Helper()
+	var calls []string
+
+	origNtfy, origDesktop := sendNtfy, sendDesktop
+	t.Cleanup(func() { sendNtfy, sendDesktop = origNtfy, origDesktop })
+
+	sendNtfy = func(channel, title, message string) error {
+		calls = append(calls, "ntfy:"+channel)
+		return ntfyErr
+	}
+	sendDesktop = func(title, message string, success bool) error {
+		calls = append(calls, "desktop")
+		return desktopErr
+	}
+	return &calls
+}
+
+func TestMultiSend(t *testing.T) {
+	failure := errors.New("boom")
+
+	tests := []struct {
+		name       string
+		multi      Multi
+		ntfyErr    error
+		desktopErr error
+		wantCalls  []string
+		wantErr    bool
+	}{
+		{
+			name:      "ntfy and desktop both succeed",
+			multi:     Multi{NtfyChannel: "chan", Ntfy: true, Desktop: true},
+			wantCalls: []string{"ntfy:chan", "desktop"},
+		},
+		{
+			name:      "ntfy failure without fallback stays silent",
+			multi:     Multi{NtfyChannel: "chan", Ntfy: true},
+			ntfyErr:   failure,
+			wantCalls: []string{"ntfy:chan"},
+			wantErr:   true,
+		},
+		{
+			name:      "ntfy failure falls back to desktop",
+			multi:     Multi{NtfyChannel: "chan", Ntfy: true, Fallback: true},
+			ntfyErr:   failure,
+			wantCalls: []string{"ntfy:chan", "desktop"},
+			wantErr:   true,
+		},
+		{
+			name:      "ntfy success does not trigger desktop fallback",
+			multi:     Multi{NtfyChannel: "chan", Ntfy: true, Fallback: true},
+			wantCalls: []string{"ntfy:chan"},
+		},
+		{
+			name:       "desktop failure falls back to ntfy",
+			multi:      Multi{NtfyChannel: "chan", Desktop: true, Fallback: true},
+			desktopErr: failure,
+			wantCalls:  []string{"desktop", "ntfy:chan"},
+			wantErr:    true,
+		},
+		{
+			name:       "desktop failure without channel has no fallback",
+			multi:      Multi{Desktop: true, Fallback: true},
+			desktopErr: failure,
+			wantCalls:  []string{"desktop"},
+			wantErr:    true,
+		},
+		{
+			name:       "desktop failure does not resend ntfy already attempted",
+			multi:      Multi{NtfyChannel: "chan", Ntfy: true, Desktop: true, Fallback: true},
+			ntfyErr:    failure,
+			desktopErr: failure,
+			wantCalls:  []string{"ntfy:chan", "desktop"},
+			wantErr:    true,
+		},
+		{
+			name:       "desktop failure without fallback",
+			multi:      Multi{NtfyChannel: "chan", Desktop: true},
+			desktopErr: failure,
+			wantCalls:  []string{"desktop"},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := mockSenders(t, tt.ntfyErr, tt.desktopErr)
+
+			err := tt.multi.Send("job", "message", true)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, failure) {
+				t.Errorf("Send() error = %v, want it to wrap %v", err, failure)
+			}
+			if len(*calls) != len(tt.wantCalls) {
+				t.Fatalf("Send() calls = %v, want %v", *calls, tt.wantCalls)
+			}
+			for i := range tt.wantCalls {
+				if (*calls)[i] != tt.wantCalls[i] {
+					t.Errorf("Send() calls = %v, want %v", *calls, tt.wantCalls)
+					break
+				}
+			}
+		})
+	}
+}
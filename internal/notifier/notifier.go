@@ -1,17 +1,40 @@
 package notifier
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/gen2brain/beeep"
+
+	"github.com/clobrano/prow-helper/internal/commalist"
+	"github.com/clobrano/prow-helper/internal/httpclient"
 )
 
 const (
+	// DefaultNtfyTimeout bounds how long a single ntfy.sh request may take.
+	DefaultNtfyTimeout = 10 * time.Second
+)
+
+var (
 	// NtfyBaseURL is the base URL for ntfy.sh
 	NtfyBaseURL = "https://ntfy.sh"
+
+	// NtfyTimeout is the timeout applied to every ntfy.sh request, so a hung
+	// server cannot block the workflow. It is set from the ntfy_timeout setting.
+	NtfyTimeout = DefaultNtfyTimeout
+
+	// NtfyEmail, when set, asks ntfy.sh to also forward every notification
+	// to this address (the Email header). It is set from the ntfy_email
+	// setting.
+	NtfyEmail string
+
+	// NtfyExtraHeaders are added to every ntfy.sh request, e.g. Tags,
+	// Priority or Call. They are set from the ntfy_extra_headers setting.
+	NtfyExtraHeaders map[string]string
 )
 
 func init() {
@@ -24,20 +47,16 @@ func init() {
 // If beeep fails, falls back to notify-send command directly.
 func Notify(title, message string, success bool) error {
 	// Add status indicator to title
-	statusIcon := "Success"
-	if !success {
-		statusIcon = "Failed"
-	}
-	fullTitle := fmt.Sprintf("prow-helper: %s - %s", title, statusIcon)
+	title = fullTitle(title, success)
 
 	// Try beeep first (AppName is set in init())
-	err := beeep.Notify(fullTitle, message, "")
+	err := beeep.Notify(title, message, "")
 	if err == nil {
 		return nil
 	}
 
 	// Fallback to notify-send for Linux
-	return notifySendFallback(fullTitle, message)
+	return notifySendFallback(title, message)
 }
 
 // notifySendFallback uses the notify-send command directly.
@@ -82,8 +101,26 @@ func FormatAnalysisStartMessage(jobName, analyzeCmd string) string {
 }
 
 // NotifyNtfy sends a notification via ntfy.sh.
-// channel is the ntfy.sh topic/channel name.
+// channel is the ntfy.sh topic/channel name, or a comma-separated list of
+// them: the notification is sent to each, and a failure on one channel does
+// not prevent sending to the others. The errors of every failed channel are
+// joined.
 func NotifyNtfy(channel, title, message string) error {
+	channels := commalist.Split(channel)
+	if len(channels) == 1 {
+		return notifyNtfyChannel(channels[0], title, message)
+	}
+	var errs []error
+	for _, c := range channels {
+		if err := notifyNtfyChannel(c, title, message); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// notifyNtfyChannel sends a notification to a single ntfy.sh channel.
+func notifyNtfyChannel(channel, title, message string) error {
 	url := fmt.Sprintf("%s/%s", NtfyBaseURL, channel)
 
 	req, err := http.NewRequest("POST", url, strings.NewReader(message))
@@ -91,13 +128,20 @@ func NotifyNtfy(channel, title, message string) error {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
+	for name, value := range NtfyExtraHeaders {
+		req.Header.Set(name, value)
+	}
+	if NtfyEmail != "" {
+		req.Header.Set("Email", NtfyEmail)
+	}
 	req.Header.Set("Title", title)
 	req.Header.Set("Content-Type", "text/plain")
+	httpclient.SetUserAgent(req)
 
-	client := &http.Client{}
+	client := &http.Client{Timeout: NtfyTimeout}
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send notification: %w", err)
+		return fmt.Errorf("failed to send notification: %w", httpclient.WrapNetError(err))
 	}
 	defer resp.Body.Close()
 
@@ -138,3 +182,32 @@ func FormatJobStatusMessage(jobName string, passed bool) string {
 	}
 	return fmt.Sprintf("Job %s has completed with status: %s", jobName, status)
 }
+
+// FormatJobCompletionMessage is FormatJobStatusMessage followed by how long
+// the job ran, e.g. "(ran 1h12m)", when duration is known (greater than 0).
+func FormatJobCompletionMessage(jobName string, passed bool, duration time.Duration) string {
+	msg := FormatJobStatusMessage(jobName, passed)
+	if duration <= 0 {
+		return msg
+	}
+	return fmt.Sprintf("%s (ran %s)", msg, formatRunTime(duration))
+}
+
+// FormatJobRecoveredMessage formats the message for a job that passed after
+// failing in its last failures builds, e.g. a flaky job that was retested.
+func FormatJobRecoveredMessage(jobName string, failures int) string {
+	plural := "s"
+	if failures == 1 {
+		plural = ""
+	}
+	return fmt.Sprintf("Job %s recovered: now PASSING after %d failure%s", jobName, failures, plural)
+}
+
+// formatRunTime formats d to the minute, or to the second when shorter than
+// a minute: "1h12m", "45m", "40s".
+func formatRunTime(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(time.Second).String()
+	}
+	return strings.TrimSuffix(d.Round(time.Minute).String(), "0s")
+}
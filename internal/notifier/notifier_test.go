@@ -4,8 +4,12 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/clobrano/prow-helper/internal/httpclient"
 )
 
 func TestFormatSuccessMessage(t *testing.T) {
@@ -135,6 +139,68 @@ func TestFormatJobStatusMessage(t *testing.T) {
 	}
 }
 
+func TestFormatJobCompletionMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		passed   bool
+		duration time.Duration
+		want     string
+	}{
+		{
+			name:     "passed with duration",
+			passed:   true,
+			duration: time.Hour + 12*time.Minute + 3*time.Second,
+			want:     "Job test-job has completed with status: PASSED (ran 1h12m)",
+		},
+		{
+			name:     "failed with duration",
+			passed:   false,
+			duration: 45*time.Minute + 40*time.Second,
+			want:     "Job test-job has completed with status: FAILED (ran 46m)",
+		},
+		{
+			name:     "under a minute",
+			passed:   true,
+			duration: 40 * time.Second,
+			want:     "Job test-job has completed with status: PASSED (ran 40s)",
+		},
+		{
+			name:     "whole hours",
+			passed:   true,
+			duration: 2 * time.Hour,
+			want:     "Job test-job has completed with status: PASSED (ran 2h0m)",
+		},
+		{
+			name:   "unknown duration",
+			passed: true,
+			want:   "Job test-job has completed with status: PASSED",
+		},
+		{
+			name:     "negative duration",
+			passed:   false,
+			duration: -time.Minute,
+			want:     "Job test-job has completed with status: FAILED",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatJobCompletionMessage("test-job", tt.passed, tt.duration); got != tt.want {
+				t.Errorf("FormatJobCompletionMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatJobRecoveredMessage(t *testing.T) {
+	if got, want := FormatJobRecoveredMessage("test-job", 2), "Job test-job recovered: now PASSING after 2 failures"; got != want {
+		t.Errorf("FormatJobRecoveredMessage(2) = %q, want %q", got, want)
+	}
+	if got, want := FormatJobRecoveredMessage("test-job", 1), "Job test-job recovered: now PASSING after 1 failure"; got != want {
+		t.Errorf("FormatJobRecoveredMessage(1) = %q, want %q", got, want)
+	}
+}
+
 func TestNotifyNtfy(t *testing.T) {
 	// Test with mock server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -156,3 +222,120 @@ func TestNotifyNtfy(t *testing.T) {
 	// We can't easily test NotifyNtfy directly because it uses hardcoded URL
 	// This test documents the expected behavior
 }
+
+func TestNotifyNtfy_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	origURL, origTimeout := NtfyBaseURL, NtfyTimeout
+	NtfyBaseURL, NtfyTimeout = server.URL, 50*time.Millisecond
+	defer func() { NtfyBaseURL, NtfyTimeout = origURL, origTimeout }()
+
+	start := time.Now()
+	err := NotifyNtfy("my-channel", "title", "message")
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatal("NotifyNtfy() should fail when the server does not answer in time")
+	}
+	var netErr interface{ Timeout() bool }
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("NotifyNtfy() error = %v, want a timeout error", err)
+	}
+	if elapsed > time.Second {
+		t.Errorf("NotifyNtfy() took %v, want it bounded by the timeout", elapsed)
+	}
+}
+
+func TestNotifyNtfy_PostsToChannel(t *testing.T) {
+	var gotPath, gotTitle string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotTitle = r.Header.Get("Title")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	origURL := NtfyBaseURL
+	NtfyBaseURL = server.URL
+	defer func() { NtfyBaseURL = origURL }()
+
+	if err := NotifyNtfy("my-channel", "the title", "message"); err != nil {
+		t.Fatalf("NotifyNtfy() error = %v", err)
+	}
+	if gotPath != "/my-channel" {
+		t.Errorf("request path = %q, want /my-channel", gotPath)
+	}
+	if gotTitle != "the title" {
+		t.Errorf("Title header = %q, want %q", gotTitle, "the title")
+	}
+}
+
+func TestNotifyNtfy_ExtraHeaders(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	origURL, origEmail, origHeaders := NtfyBaseURL, NtfyEmail, NtfyExtraHeaders
+	NtfyBaseURL = server.URL
+	NtfyEmail = "me@example.com"
+	NtfyExtraHeaders = map[string]string{"Tags": "warning,rotating_light", "Priority": "high"}
+	defer func() { NtfyBaseURL, NtfyEmail, NtfyExtraHeaders = origURL, origEmail, origHeaders }()
+
+	if err := NotifyNtfy("my-channel", "the title", "message"); err != nil {
+		t.Fatalf("NotifyNtfy() error = %v", err)
+	}
+	want := map[string]string{
+		"Email":      "me@example.com",
+		"Tags":       "warning,rotating_light",
+		"Priority":   "high",
+		"Title":      "the title",
+		"User-Agent": httpclient.UserAgent,
+	}
+	for name, value := range want {
+		if got.Get(name) != value {
+			t.Errorf("%s header = %q, want %q", name, got.Get(name), value)
+		}
+	}
+}
+
+func TestNotifyNtfy_MultipleChannels(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.URL.Path)
+		if r.URL.Path == "/broken-channel" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	origURL := NtfyBaseURL
+	NtfyBaseURL = server.URL
+	defer func() { NtfyBaseURL = origURL }()
+
+	if err := NotifyNtfy("mine, team", "title", "message"); err != nil {
+		t.Fatalf("NotifyNtfy() error = %v", err)
+	}
+	if want := []string{"/mine", "/team"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("requests = %v, want %v", got, want)
+	}
+
+	got = nil
+	err := NotifyNtfy("broken-channel,team", "title", "message")
+	if err == nil || !strings.Contains(err.Error(), "broken-channel") {
+		t.Errorf("NotifyNtfy() error = %v, want the failure of broken-channel", err)
+	}
+	if want := []string{"/broken-channel", "/team"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("requests = %v, want %v (a failed channel must not block the others)", got, want)
+	}
+}
@@ -0,0 +1,125 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/clobrano/prow-helper/internal/httpclient"
+)
+
+// Event names a point of the workflow that sends a notification. Webhooks
+// receive it and are only called for the events in WebhookEvents.
+type Event string
+
+const (
+	EventJobStateChanged  Event = "job_state_changed"
+	EventJobPassed        Event = "job_passed"
+	EventJobFailed        Event = "job_failed"
+	EventWatchFailed      Event = "watch_failed"
+	EventDownloadStart    Event = "download_start"
+	EventDownloadComplete Event = "download_complete"
+	EventDownloadFailed   Event = "download_failed"
+	EventAnalysisStart    Event = "analysis_start"
+	EventAnalysisComplete Event = "analysis_complete"
+	EventAnalysisFailed   Event = "analysis_failed"
+)
+
+// Events lists every event, in workflow order.
+var Events = []Event{
+	EventJobStateChanged, EventJobPassed, EventJobFailed, EventWatchFailed,
+	EventDownloadStart, EventDownloadComplete, EventDownloadFailed,
+	EventAnalysisStart, EventAnalysisComplete, EventAnalysisFailed,
+}
+
+// DefaultWebhookEvents are the completion and failure events: the
+// intermediate start and state change events are left out.
+var DefaultWebhookEvents = []Event{
+	EventJobPassed, EventJobFailed, EventWatchFailed,
+	EventDownloadComplete, EventDownloadFailed,
+	EventAnalysisComplete, EventAnalysisFailed,
+}
+
+var (
+	// WebhookURL receives a JSON POST for every notification whose event is
+	// in WebhookEvents. It is set from the webhook_url setting.
+	WebhookURL string
+
+	// WebhookEvents selects the events posted to WebhookURL. It is set from
+	// the webhook_on setting.
+	WebhookEvents = DefaultWebhookEvents
+)
+
+// IsEvent reports whether name is a known event.
+func IsEvent(name string) bool {
+	for _, e := range Events {
+		if string(e) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseEvents parses a comma-separated list of event names.
+func ParseEvents(list string) ([]Event, error) {
+	var events []Event
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !IsEvent(name) {
+			return nil, fmt.Errorf("unknown event %q", name)
+		}
+		events = append(events, Event(name))
+	}
+	return events, nil
+}
+
+// webhookWanted reports whether event is one of WebhookEvents.
+func webhookWanted(event Event) bool {
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookPayload is the JSON body posted to WebhookURL.
+type webhookPayload struct {
+	Event   Event  `json:"event"`
+	Title   string `json:"title"`
+	Message string `json:"message"`
+	Success bool   `json:"success"`
+}
+
+// NotifyWebhook posts the notification as a JSON object (event, title,
+// message, success) to url. Any 2xx status is a success. Like ntfy.sh
+// requests, it is bounded by NtfyTimeout.
+func NotifyWebhook(url string, event Event, title, message string, success bool) error {
+	body, err := json.Marshal(webhookPayload{Event: event, Title: title, Message: message, Success: success})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	httpclient.SetUserAgent(req)
+
+	client := &http.Client{Timeout: NtfyTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", httpclient.WrapNetError(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/clobrano/prow-helper/internal/httpclient"
+)
+
+// webhookServer records the payloads posted to it.
+func webhookServer(t *testing.T, status int) (string, *[]webhookPayload) {
+	t.Helper()
+	var got []webhookPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("request %s with Content-Type %q, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+		}
+		if ua := r.Header.Get("User-Agent"); ua != httpclient.UserAgent {
+			t.Errorf("User-Agent = %q, want %q", ua, httpclient.UserAgent)
+		}
+		var p webhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		got = append(got, p)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, &got
+}
+
+// setWebhook configures WebhookURL and WebhookEvents for a test.
+func setWebhook(t *testing.T, url string, events []Event) {
+	t.Helper()
+	origURL, origEvents := WebhookURL, WebhookEvents
+	t.Cleanup(func() { WebhookURL, WebhookEvents = origURL, origEvents })
+	WebhookURL, WebhookEvents = url, events
+}
+
+func TestMultiSend_WebhookOnSelectedEvents(t *testing.T) {
+	url, got := webhookServer(t, http.StatusNoContent)
+	setWebhook(t, url, []Event{EventDownloadComplete, EventJobFailed})
+
+	for _, event := range Events {
+		if err := (Multi{Event: event}).Send("job", "message", event != EventJobFailed); err != nil {
+			t.Errorf("Send(%s) error = %v", event, err)
+		}
+	}
+
+	want := []webhookPayload{
+		{Event: EventJobFailed, Title: "prow-helper: job - Failed", Message: "message", Success: false},
+		{Event: EventDownloadComplete, Title: "prow-helper: job - Success", Message: "message", Success: true},
+	}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("webhook received %+v, want %+v", *got, want)
+	}
+}
+
+func TestMultiSend_WebhookDefaultEvents(t *testing.T) {
+	url, got := webhookServer(t, http.StatusOK)
+	setWebhook(t, url, DefaultWebhookEvents)
+
+	for _, event := range []Event{EventDownloadStart, EventAnalysisStart, EventJobStateChanged, EventAnalysisComplete} {
+		if err := (Multi{Event: event}).Send("job", "message", true); err != nil {
+			t.Errorf("Send(%s) error = %v", event, err)
+		}
+	}
+	if len(*got) != 1 || (*got)[0].Event != EventAnalysisComplete {
+		t.Errorf("webhook received %+v, want only analysis_complete", *got)
+	}
+}
+
+func TestMultiSend_WebhookError(t *testing.T) {
+	url, _ := webhookServer(t, http.StatusInternalServerError)
+	setWebhook(t, url, DefaultWebhookEvents)
+
+	if err := (Multi{Event: EventJobFailed}).Send("job", "message", false); err == nil {
+		t.Error("Send() error = nil, want the webhook failure")
+	}
+}
+
+func TestParseEvents(t *testing.T) {
+	got, err := ParseEvents(" download_complete, job_failed ,")
+	if err != nil {
+		t.Fatalf("ParseEvents() error = %v", err)
+	}
+	if want := []Event{EventDownloadComplete, EventJobFailed}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseEvents() = %v, want %v", got, want)
+	}
+	if _, err := ParseEvents("download_complete,job_done"); err == nil {
+		t.Error("ParseEvents() with an unknown event: error = nil")
+	}
+}
@@ -3,6 +3,7 @@ package output
 import (
 	"fmt"
 	"io"
+	"text/tabwriter"
 
 	"github.com/fatih/color"
 )
@@ -53,6 +54,35 @@ func PrintField(w io.Writer, label, value string) {
 	fmt.Fprintln(w, value)
 }
 
+// FieldTable collects label/value pairs and prints them like PrintField, but
+// with the labels right-aligned so the colons line up. Nothing is written
+// until Flush.
+type FieldTable struct {
+	rows [][2]string
+}
+
+// NewFieldTable returns an empty FieldTable.
+func NewFieldTable() *FieldTable {
+	return &FieldTable{}
+}
+
+// Add appends a field to the table.
+func (t *FieldTable) Add(label, value string) {
+	t.rows = append(t.rows, [2]string{label, value})
+}
+
+// Flush writes the table to w and empties it.
+func (t *FieldTable) Flush(w io.Writer) error {
+	// Every label carries the same color escape sequences, so their extra
+	// width does not disturb the alignment.
+	tw := tabwriter.NewWriter(w, 0, 0, 0, ' ', tabwriter.AlignRight)
+	for _, row := range t.rows {
+		fmt.Fprintf(tw, "%s\t%s\n", Bold.Sprintf("%s: ", row[0]), row[1])
+	}
+	t.rows = nil
+	return tw.Flush()
+}
+
 // PrintStatus prints a status with emoji and color
 func PrintStatus(w io.Writer, status Status) {
 	info := GetStatusInfo(status)
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"strings"
 	"testing"
+
+	"github.com/fatih/color"
 )
 
 func TestGetStatusInfo(t *testing.T) {
@@ -131,3 +133,52 @@ func TestFormatJobStatusMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestFieldTable(t *testing.T) {
+	for _, noColor := range []bool{true, false} {
+		t.Run(map[bool]string{true: "plain", false: "bold"}[noColor], func(t *testing.T) {
+			orig := color.NoColor
+			color.NoColor = noColor
+			t.Cleanup(func() { color.NoColor = orig })
+
+			table := NewFieldTable()
+			table.Add("Job", "test-job")
+			table.Add("Build ID", "12345")
+			table.Add("Downloading to", "/tmp/artifacts")
+
+			var buf bytes.Buffer
+			if err := table.Flush(&buf); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+
+			lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			if len(lines) != 3 {
+				t.Fatalf("Flush() wrote %d lines, want 3:\n%s", len(lines), buf.String())
+			}
+			col := strings.Index(lines[0], ":")
+			for _, line := range lines {
+				if got := strings.Index(line, ":"); got != col {
+					t.Errorf("separator at column %d in %q, want %d:\n%s", got, line, col, buf.String())
+				}
+			}
+			if noColor && lines[2] != "Downloading to: /tmp/artifacts" {
+				t.Errorf("longest label line = %q, want no padding", lines[2])
+			}
+			if noColor && lines[0] != "           Job: test-job" {
+				t.Errorf("short label line = %q, want right-aligned label", lines[0])
+			}
+		})
+	}
+}
+
+func TestFieldTable_FlushEmpties(t *testing.T) {
+	table := NewFieldTable()
+	table.Add("Job", "test-job")
+	table.Flush(&bytes.Buffer{})
+
+	var buf bytes.Buffer
+	table.Flush(&buf)
+	if buf.Len() != 0 {
+		t.Errorf("second Flush() wrote %q, want nothing", buf.String())
+	}
+}
@@ -0,0 +1,40 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Mode selects how a command reports its result.
+type Mode int
+
+const (
+	ModeHuman     Mode = iota // colored, decorated text
+	ModeJSON                  // a single JSON object
+	ModePorcelain             // stable key=value lines, see WritePorcelain
+)
+
+// WritePorcelain writes one key=value line per field, in order, with no
+// color or decoration. Values are quoted so the output can be eval'd by a
+// POSIX shell; keys must be valid shell variable names.
+func WritePorcelain(w io.Writer, fields [][2]string) error {
+	for _, f := range fields {
+		if _, err := fmt.Fprintf(w, "%s=%s\n", f[0], quotePorcelain(f[1])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// quotePorcelain returns v unchanged when it only contains characters the
+// shell does not interpret, and single-quoted otherwise.
+func quotePorcelain(v string) string {
+	safe := strings.IndexFunc(v, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:@%+,", r))
+	}) < 0
+	if safe {
+		return v
+	}
+	return "'" + strings.ReplaceAll(v, "'", `'\''`) + "'"
+}
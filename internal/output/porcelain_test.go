@@ -0,0 +1,49 @@
+package output
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestWritePorcelain(t *testing.T) {
+	var buf bytes.Buffer
+	err := WritePorcelain(&buf, [][2]string{
+		{"job", "periodic-ci-job"},
+		{"dest", "/tmp/my artifacts/it's"},
+		{"status", ""},
+		{"note", "$(rm -rf ~) `x` \"y\";z"},
+	})
+	if err != nil {
+		t.Fatalf("WritePorcelain() error = %v", err)
+	}
+
+	want := "job=periodic-ci-job\n" +
+		"dest='/tmp/my artifacts/it'\\''s'\n" +
+		"status=\n" +
+		"note='$(rm -rf ~) `x` \"y\";z'\n"
+	if buf.String() != want {
+		t.Errorf("WritePorcelain() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWritePorcelain_EvalRoundTrip(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	values := []string{"plain", "with space", "it's", "$HOME", "a\nb", "`id`", "*"}
+
+	var buf bytes.Buffer
+	for _, v := range values {
+		buf.Reset()
+		WritePorcelain(&buf, [][2]string{{"v", v}})
+		out, err := exec.Command("sh", "-c", buf.String()+`printf '%s' "$v"`).Output()
+		if err != nil {
+			t.Fatalf("eval of %q failed: %v", buf.String(), err)
+		}
+		if string(out) != v {
+			t.Errorf("eval of %q gave %q, want %q", strings.TrimSpace(buf.String()), out, v)
+		}
+	}
+}
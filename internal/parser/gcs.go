@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// DefaultGCSHost is the public Google Cloud Storage endpoint.
+const DefaultGCSHost = "storage.googleapis.com"
+
+// GCSHost is the host (optionally with a port) GCS objects are read from,
+// both over HTTP and by gsutil. It defaults to DefaultGCSHost and is replaced
+// from the gcs_host setting to go through a mirror where
+// storage.googleapis.com is blocked. Bucket and object paths are unchanged.
+var GCSHost = DefaultGCSHost
+
+// GCSBase is the https:// base URL GCS objects are read from over HTTP,
+// path included, for mirrors that serve buckets under a prefix (e.g.
+// "https://mirror.example.com/storage"). It is set from the gcs_base setting
+// and takes precedence over GCSHost; gsutil is pointed at its host and port.
+var GCSBase string
+
+// GCSBaseURL returns GCSBase, or the base URL of GCSHost when it is unset.
+// Every HTTP URL of a GCS object is composed from it.
+func GCSBaseURL() string {
+	if GCSBase != "" {
+		return strings.TrimSuffix(GCSBase, "/")
+	}
+	return "https://" + GCSHost
+}
+
+// gcsServer returns the host (optionally with a port) serving GCS objects:
+// the host of GCSBase when it is set, and GCSHost otherwise.
+func gcsServer() string {
+	if u, err := url.Parse(GCSBase); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return GCSHost
+}
+
+// GCSObjectURL returns the HTTP URL of the object at path in bucket:
+// <GCSBaseURL>/<bucket>/<path>.
+func GCSObjectURL(bucket, path string) string {
+	return GCSBaseURL() + "/" + bucket + "/" + strings.TrimPrefix(path, "/")
+}
+
+// gsutilHostOptions returns the gsutil -o options pointing its JSON API at
+// the GCS server, or nil when it is the default.
+func gsutilHostOptions() []string {
+	server := gcsServer()
+	if server == DefaultGCSHost {
+		return nil
+	}
+	host, port, err := net.SplitHostPort(server)
+	if err != nil {
+		return []string{"-o", "Credentials:gs_json_host=" + server}
+	}
+	return []string{
+		"-o", "Credentials:gs_json_host=" + host,
+		"-o", "Credentials:gs_json_port=" + port,
+	}
+}
@@ -0,0 +1,116 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGCSObjectURL(t *testing.T) {
+	tests := []struct {
+		name string
+		host string
+		want string
+	}{
+		{
+			name: "default host",
+			host: DefaultGCSHost,
+			want: "https://storage.googleapis.com/bucket/logs/job/1/finished.json",
+		},
+		{
+			name: "mirror",
+			host: "gcs-mirror.example.com",
+			want: "https://gcs-mirror.example.com/bucket/logs/job/1/finished.json",
+		},
+		{
+			name: "mirror with port",
+			host: "gcs-mirror.example.com:8443",
+			want: "https://gcs-mirror.example.com:8443/bucket/logs/job/1/finished.json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := GCSHost
+			defer func() { GCSHost = orig }()
+			GCSHost = tt.host
+
+			if got := GCSObjectURL("bucket", "logs/job/1/finished.json"); got != tt.want {
+				t.Errorf("GCSObjectURL() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGsutilCopyArgs_GCSHost(t *testing.T) {
+	tests := []struct {
+		name string
+		host string
+		want []string
+	}{
+		{
+			name: "default host",
+			host: DefaultGCSHost,
+			want: []string{"gsutil", "-m", "cp", "-r", "gs://bucket/logs/job/1/*", "/dest"},
+		},
+		{
+			name: "mirror",
+			host: "gcs-mirror.example.com",
+			want: []string{"gsutil", "-o", "Credentials:gs_json_host=gcs-mirror.example.com",
+				"-m", "cp", "-r", "gs://bucket/logs/job/1/*", "/dest"},
+		},
+		{
+			name: "mirror with port",
+			host: "gcs-mirror.example.com:8443",
+			want: []string{"gsutil", "-o", "Credentials:gs_json_host=gcs-mirror.example.com",
+				"-o", "Credentials:gs_json_port=8443",
+				"-m", "cp", "-r", "gs://bucket/logs/job/1/*", "/dest"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := GCSHost
+			defer func() { GCSHost = orig }()
+			GCSHost = tt.host
+
+			if got := GsutilCopyArgs("gs://bucket/logs/job/1", "/dest"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GsutilCopyArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGCSBase(t *testing.T) {
+	origHost, origBase := GCSHost, GCSBase
+	defer func() { GCSHost, GCSBase = origHost, origBase }()
+	GCSHost = "gcs-a.example.com"
+	GCSBase = "https://gcs-b.example.com:8443/storage/"
+
+	if got, want := GCSObjectURL("bucket", "logs/job/1/finished.json"), "https://gcs-b.example.com:8443/storage/bucket/logs/job/1/finished.json"; got != want {
+		t.Errorf("GCSObjectURL() = %s, want %s", got, want)
+	}
+	want := []string{"gsutil", "-o", "Credentials:gs_json_host=gcs-b.example.com",
+		"-o", "Credentials:gs_json_port=8443",
+		"-m", "cp", "-r", "gs://bucket/logs/job/1/*", "/dest"}
+	if got := GsutilCopyArgs("gs://bucket/logs/job/1", "/dest"); !reflect.DeepEqual(got, want) {
+		t.Errorf("GsutilCopyArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestGsutilSyncArgs(t *testing.T) {
+	orig := GCSHost
+	defer func() { GCSHost = orig }()
+	GCSHost = "gcs-mirror.example.com"
+
+	want := []string{"gsutil", "-o", "Credentials:gs_json_host=gcs-mirror.example.com",
+		"-m", "rsync", "-r", "-x", `^(?:.*\.tar)$`, "gs://bucket/logs/job/1", "/dest"}
+	if got := GsutilSyncArgs("gs://bucket/logs/job/1", "/dest", `^(?:.*\.tar)$`); !reflect.DeepEqual(got, want) {
+		t.Errorf("GsutilSyncArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestQuoteCommand(t *testing.T) {
+	got := QuoteCommand([]string{"gsutil", "-x", `^(?:.*\.tar)$`, "/tmp/a b"})
+	want := `gsutil -x '^(?:.*\.tar)$' '/tmp/a b'`
+	if got != want {
+		t.Errorf("QuoteCommand() = %q, want %q", got, want)
+	}
+}
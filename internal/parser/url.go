@@ -2,56 +2,160 @@ package parser
 
 import (
 	"errors"
+	"fmt"
 	"net/url"
 	"strings"
 )
 
 const (
-	prowHost   = "prow.ci.openshift.org"
+	// DefaultProwHost is the Prow deployment accepted when no other hosts are configured.
+	DefaultProwHost = "prow.ci.openshift.org"
+
 	pathPrefix = "/view/gs/"
+
+	// gcswebPathPrefix starts the path of a gcsweb artifact browser URL:
+	// https://gcsweb-<...>/gcs/<bucket>/<path>.
+	gcswebPathPrefix = "/gcs/"
+
+	// defaultGCSWebHost is the gcsweb artifact browser of DefaultProwHost.
+	defaultGCSWebHost = "gcsweb-ci.apps.ci.l2s4.p1.openshiftapps.com"
 )
 
+// AllowedHosts lists the Prow hosts whose /view/gs/ URLs are accepted.
+// It holds DefaultProwHost and the hosts of the prow_host and prow_hosts
+// settings.
+var AllowedHosts = []string{DefaultProwHost}
+
+// ProwHost is the Prow host set with the prow_host setting, empty when it is
+// unset. It is one of AllowedHosts.
+var ProwHost string
+
+// PrimaryHost returns the Prow host jobs are looked up on and gcsweb links
+// are mapped to: ProwHost when it is set, even to DefaultProwHost, otherwise
+// the first of AllowedHosts other than DefaultProwHost, so a self-hosted Prow
+// listed in prow_hosts takes over, or DefaultProwHost.
+func PrimaryHost() string {
+	if ProwHost != "" {
+		return ProwHost
+	}
+	for _, host := range AllowedHosts {
+		if host != DefaultProwHost {
+			return host
+		}
+	}
+	return DefaultProwHost
+}
+
 var (
-	ErrEmptyURL        = errors.New("URL cannot be empty")
-	ErrInvalidURL      = errors.New("invalid URL format")
-	ErrInvalidHost     = errors.New("invalid host: expected prow.ci.openshift.org")
-	ErrInvalidScheme   = errors.New("invalid scheme: expected https")
-	ErrInvalidPath     = errors.New("invalid path: expected /view/gs/<bucket>/<path>")
-	ErrMissingPath     = errors.New("missing required path components")
+	ErrEmptyURL       = errors.New("URL cannot be empty")
+	ErrInvalidURL     = errors.New("invalid URL format")
+	ErrInvalidHost    = errors.New("invalid host: not an accepted Prow host")
+	ErrInvalidScheme  = errors.New("invalid scheme: expected https")
+	ErrInvalidPath    = errors.New("invalid path: expected /view/gs/<bucket>/<path>")
+	ErrMissingPath    = errors.New("missing required path components")
+	ErrInvalidBuildID = errors.New("invalid build ID: expected a number")
+)
+
+// JobKind is the type of a Prow job, inferred from its path and name.
+type JobKind string
+
+const (
+	KindPeriodic   JobKind = "periodic"
+	KindPresubmit  JobKind = "presubmit"
+	KindPostsubmit JobKind = "postsubmit"
+	KindRehearsal  JobKind = "rehearsal"
 )
 
 // ProwMetadata contains the extracted information from a PROW URL.
 type ProwMetadata struct {
-	Bucket   string // GCS bucket name (e.g., "test-platform-results")
-	Path     string // Full GCS path after bucket (e.g., "logs/job-name/build-id")
-	JobName  string // Job name extracted from path
-	BuildID  string // Build ID (last component of path)
-	PRRef    string // "[org/repo PR<num>]" for PR jobs, empty for others
-	RawURL   string // Original URL
+	Bucket   string  // GCS bucket name (e.g., "test-platform-results")
+	Path     string  // Full GCS path after bucket (e.g., "logs/job-name/build-id")
+	JobName  string  // Job name extracted from path
+	BuildID  string  // Build ID (last component of path)
+	PRRef    string  // "[org/repo PR<num>]" for PR jobs, empty for others
+	Org      string  // GitHub org of a PR job (e.g. "openshift"), empty for others
+	Repo     string  // GitHub repo of a PR job (e.g. "api"), empty for others
+	PRNumber string  // Pull request number of a PR job, empty for others
+	Kind     JobKind // Job type, empty when it cannot be inferred
+	Host     string  // Prow host the URL was served from (one of AllowedHosts)
+	RawURL   string  // Original URL, or the build's job URL for other links to it
+}
+
+// IsAllowedHost reports whether host is one of AllowedHosts.
+func IsAllowedHost(host string) bool {
+	for _, h := range AllowedHosts {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeURL returns the Prow job URL of rawURL when it is one of the
+// other links to a build's artifacts: a gcsweb URL
+// (https://gcsweb-<...>/gcs/<bucket>/<path>) or a direct GCS URL
+// (https://storage.googleapis.com/<bucket>/<path>, GCSHost or GCSBase). The result
+// is https://<prow-host>/view/gs/<bucket>/<path>, where the Prow host is
+// DefaultProwHost for OpenShift's gcsweb and storage.googleapis.com, and
+// PrimaryHost for a configured GCS mirror or another gcsweb. Any other URL
+// is returned unchanged.
+func normalizeURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Scheme != "https" {
+		return rawURL
+	}
+
+	host := strings.ToLower(parsed.Host)
+	var gcsPath string
+	prowHost := PrimaryHost()
+	switch {
+	case GCSBase != "" && strings.HasPrefix(rawURL, GCSBaseURL()+"/"):
+		gcsPath = strings.TrimPrefix(rawURL, GCSBaseURL()+"/")
+		if i := strings.IndexAny(gcsPath, "?#"); i >= 0 {
+			gcsPath = gcsPath[:i]
+		}
+	case strings.HasPrefix(host, "gcsweb") && strings.HasPrefix(parsed.Path, gcswebPathPrefix):
+		gcsPath = strings.TrimPrefix(parsed.Path, gcswebPathPrefix)
+		if host == defaultGCSWebHost {
+			prowHost = DefaultProwHost
+		}
+	case host == DefaultGCSHost:
+		gcsPath = strings.TrimPrefix(parsed.Path, "/")
+		prowHost = DefaultProwHost
+	case strings.EqualFold(host, GCSHost):
+		gcsPath = strings.TrimPrefix(parsed.Path, "/")
+	default:
+		return rawURL
+	}
+
+	return (&url.URL{Scheme: "https", Host: prowHost, Path: pathPrefix + gcsPath}).String()
 }
 
 // ValidateURL validates that the given URL is a valid PROW URL.
-// Expected format: https://prow.ci.openshift.org/view/gs/<bucket>/<path>/<build-id>
+// Expected format: https://<allowed-host>/view/gs/<bucket>/<path>/<build-id>
+// gcsweb and direct GCS links to the build's artifacts are accepted too (see
+// normalizeURL). The returned error matches, with errors.Is, one of the Err* values above;
+// it may wrap it with the offending part of the URL.
 func ValidateURL(rawURL string) error {
 	if rawURL == "" {
 		return ErrEmptyURL
 	}
 
-	parsed, err := url.Parse(rawURL)
+	parsed, err := url.Parse(normalizeURL(rawURL))
 	if err != nil {
-		return ErrInvalidURL
+		return fmt.Errorf("%w: %v", ErrInvalidURL, err)
 	}
 
 	if parsed.Scheme != "https" {
-		return ErrInvalidScheme
+		return fmt.Errorf("%w, got %q", ErrInvalidScheme, parsed.Scheme)
 	}
 
-	if parsed.Host != prowHost {
-		return ErrInvalidHost
+	if !IsAllowedHost(parsed.Host) {
+		return fmt.Errorf("%w: %q", ErrInvalidHost, parsed.Host)
 	}
 
 	if !strings.HasPrefix(parsed.Path, pathPrefix) {
-		return ErrInvalidPath
+		return fmt.Errorf("%w, got %q", ErrInvalidPath, parsed.Path)
 	}
 
 	// Extract the path after /view/gs/
@@ -60,13 +164,8 @@ func ValidateURL(rawURL string) error {
 
 	// Need at least bucket/path/build-id (3 components minimum)
 	parts := strings.Split(gcsPath, "/")
-	if len(parts) < 3 {
-		return ErrMissingPath
-	}
-
-	// Check that bucket is not empty
-	if parts[0] == "" {
-		return ErrMissingPath
+	if len(parts) < 3 || parts[0] == "" {
+		return fmt.Errorf("%w: %q has no <bucket>/<path>/<build-id>", ErrMissingPath, parsed.Path)
 	}
 
 	return nil
@@ -74,12 +173,15 @@ func ValidateURL(rawURL string) error {
 
 // ParseURL parses a PROW URL and extracts metadata.
 // Returns a ProwMetadata struct with bucket, path, job name, and build ID.
+// A gcsweb or direct GCS URL is parsed as the Prow URL it maps to, and
+// RawURL is then the job page of the build.
 func ParseURL(rawURL string) (*ProwMetadata, error) {
 	if err := ValidateURL(rawURL); err != nil {
 		return nil, err
 	}
+	normalized := normalizeURL(rawURL)
 
-	parsed, _ := url.Parse(rawURL) // Already validated, ignore error
+	parsed, _ := url.Parse(normalized) // Already validated, ignore error
 
 	// Extract the path after /view/gs/
 	gcsPath := strings.TrimPrefix(parsed.Path, pathPrefix)
@@ -87,6 +189,14 @@ func ParseURL(rawURL string) (*ProwMetadata, error) {
 
 	parts := strings.Split(gcsPath, "/")
 
+	// Drop anything below the build directory (e.g. a link to one of the
+	// build's artifacts), so the build ID is the last component.
+	truncated := false
+	if job := jobIndex(parts); job > 0 && len(parts) > job+2 && isNumeric(parts[job+1]) {
+		parts = parts[:job+2]
+		truncated = true
+	}
+
 	// First part is the bucket
 	bucket := parts[0]
 
@@ -101,28 +211,179 @@ func ParseURL(rawURL string) (*ProwMetadata, error) {
 
 	// Extract PR reference for pr-logs paths:
 	// pr-logs/pull/<org_repo>/<pr_num>/<job_name>/<build_id>
-	var prRef string
+	var prRef, org, repo, prNumber string
 	if len(parts) >= 6 && parts[1] == "pr-logs" && parts[2] == "pull" {
 		orgRepo := parts[3]
 		prNum := parts[4]
 		orgRepoParts := strings.SplitN(orgRepo, "_", 2)
 		if len(orgRepoParts) == 2 && prNum != "" {
-			prRef = "[" + orgRepoParts[0] + "/" + orgRepoParts[1] + " PR" + prNum + "]"
+			org, repo, prNumber = orgRepoParts[0], orgRepoParts[1], prNum
+			prRef = "[" + org + "/" + repo + " PR" + prNumber + "]"
 		}
 	}
 
+	// RawURL is the build's job URL, also for links to its artifacts.
+	if normalized != rawURL || truncated {
+		rawURL = "https://" + parsed.Host + pathPrefix + bucket + "/" + path
+	}
+
 	return &ProwMetadata{
-		Bucket:  bucket,
-		Path:    path,
-		JobName: jobName,
-		BuildID: buildID,
-		PRRef:   prRef,
-		RawURL:  rawURL,
+		Bucket:   bucket,
+		Path:     path,
+		JobName:  jobName,
+		BuildID:  buildID,
+		PRRef:    prRef,
+		Org:      org,
+		Repo:     repo,
+		PRNumber: prNumber,
+		Kind:     jobKind(parts, jobName),
+		Host:     parsed.Host,
+		RawURL:   rawURL,
 	}, nil
 }
 
-// BuildGsutilCommand constructs the gsutil command to download artifacts.
-// Returns the full command string: gsutil -m cp -r gs://<bucket>/<path>/ <dest>
+// jobIndex returns the index in parts (bucket first) of the job name for the
+// known Prow path layouts, or -1 when the layout is not recognized:
+//
+//	<bucket>/logs/<job>/<build>
+//	<bucket>/pr-logs/pull/<org_repo>/<pr>/<job>/<build>
+//	<bucket>/pr-logs/pull/batch/<job>/<build>
+func jobIndex(parts []string) int {
+	switch {
+	case len(parts) > 2 && parts[1] == "logs":
+		return 2
+	case len(parts) > 4 && parts[1] == "pr-logs" && parts[2] == "pull" && parts[3] == "batch":
+		return 4
+	case len(parts) > 5 && parts[1] == "pr-logs" && parts[2] == "pull":
+		return 5
+	}
+	return -1
+}
+
+// jobKind infers the kind of job from its name prefix and, for presubmits,
+// from the pr-logs path. Rehearsals run as presubmits of openshift/release,
+// so they are checked first. It returns "" when the kind is unknown.
+func jobKind(parts []string, jobName string) JobKind {
+	switch {
+	case strings.HasPrefix(jobName, "rehearse-"):
+		return KindRehearsal
+	case strings.HasPrefix(jobName, "pull-") || len(parts) > 1 && parts[1] == "pr-logs":
+		return KindPresubmit
+	case strings.HasPrefix(jobName, "branch-"):
+		return KindPostsubmit
+	case strings.HasPrefix(jobName, "periodic-"):
+		return KindPeriodic
+	}
+	return ""
+}
+
+// WithBuildID returns a copy of metadata that points at build buildID.
+// If the parsed URL already ends with a numeric build ID, that ID is replaced;
+// otherwise the URL is assumed to have lost its build ID (so the parser took
+// the job name for it) and buildID is appended. The result is re-parsed so
+// every derived field (Path, JobName, PRRef, RawURL) stays consistent.
+func WithBuildID(metadata *ProwMetadata, buildID string) (*ProwMetadata, error) {
+	if !isNumeric(buildID) {
+		return nil, fmt.Errorf("%w, got %q", ErrInvalidBuildID, buildID)
+	}
+
+	parsed, err := url.Parse(metadata.RawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	viewPath := pathPrefix + metadata.Bucket + "/" + metadata.Path
+	if isNumeric(metadata.BuildID) {
+		viewPath = strings.TrimSuffix(viewPath, metadata.BuildID)
+		viewPath = strings.TrimSuffix(viewPath, "/")
+	}
+	parsed.Path = viewPath + "/" + buildID
+	parsed.RawPath = ""
+
+	return ParseURL(parsed.String())
+}
+
+// isNumeric reports whether s is a non-empty string of ASCII digits.
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// SplitViewPath splits a Prow /view/gs/ URL into its GCS bucket and the path
+// that follows it, without interpreting the path. It is useful for URLs that
+// point at a job rather than a specific build (e.g. .../logs/<job-name>).
+func SplitViewPath(rawURL string) (bucket, path string, err error) {
+	if err := ValidateURL(rawURL); err != nil {
+		return "", "", err
+	}
+	parsed, _ := url.Parse(normalizeURL(rawURL)) // Already validated, ignore error
+
+	gcsPath := strings.Trim(strings.TrimPrefix(parsed.Path, pathPrefix), "/")
+	parts := strings.SplitN(gcsPath, "/", 2)
+	return parts[0], parts[1], nil
+}
+
+// GCSPath returns the gs:// URL of the build's artifacts (gs://<bucket>/<path>).
+func GCSPath(metadata *ProwMetadata) string {
+	return "gs://" + metadata.Bucket + "/" + metadata.Path
+}
+
+// GsutilCopyArgs returns the argv of the gsutil command that copies the
+// contents of gcsPath into dest. It is the single source of truth for both
+// the command that is executed and the one that is printed. A non-default
+// GCS server (GCSHost, or the host of GCSBase) is passed to gsutil as -o
+// options.
+func GsutilCopyArgs(gcsPath, dest string) []string {
+	args := append([]string{"gsutil"}, gsutilHostOptions()...)
+	return append(args, "-m", "cp", "-r", gcsPath+"/*", dest)
+}
+
+// GsutilSyncArgs returns the argv of the gsutil command that copies the
+// contents of gcsPath into dest except the objects whose relative path
+// matches the Python regular expression exclude.
+func GsutilSyncArgs(gcsPath, dest, exclude string) []string {
+	args := append([]string{"gsutil"}, gsutilHostOptions()...)
+	return append(args, "-m", "rsync", "-r", "-x", exclude, gcsPath, dest)
+}
+
+// BuildGsutilCommand constructs the gsutil command to download artifacts,
+// quoted so it can be pasted into a shell.
+// Returns the full command string: gsutil -m cp -r 'gs://<bucket>/<path>/*' <dest>
 func BuildGsutilCommand(metadata *ProwMetadata, dest string) string {
-	return "gsutil -m cp -r gs://" + metadata.Bucket + "/" + metadata.Path + "/ " + dest
+	return QuoteCommand(GsutilCopyArgs(GCSPath(metadata), dest))
+}
+
+// QuoteCommand joins args into a command line that can be pasted into a
+// shell.
+func QuoteCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote single-quotes s unless it only contains characters that are
+// safe in a POSIX shell word. A leading ~ is left alone so it still expands.
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	safe := true
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@%+,~", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
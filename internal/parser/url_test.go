@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"errors"
+	"strings"
 	"testing"
 )
 
@@ -163,7 +165,7 @@ func TestBuildGsutilCommand(t *testing.T) {
 				Path:   "logs/periodic-ci-openshift-release-master-nightly-4.22-e2e-metal-ovn-two-node-fencing-recovery-techpreview/2013057817195319296",
 			},
 			dest: "/tmp/artifacts",
-			want: "gsutil -m cp -r gs://test-platform-results/logs/periodic-ci-openshift-release-master-nightly-4.22-e2e-metal-ovn-two-node-fencing-recovery-techpreview/2013057817195319296/ /tmp/artifacts",
+			want: "gsutil -m cp -r 'gs://test-platform-results/logs/periodic-ci-openshift-release-master-nightly-4.22-e2e-metal-ovn-two-node-fencing-recovery-techpreview/2013057817195319296/*' /tmp/artifacts",
 		},
 		{
 			name: "path with home directory",
@@ -172,7 +174,7 @@ func TestBuildGsutilCommand(t *testing.T) {
 				Path:   "logs/pull-ci-openshift-origin-master-e2e-aws/12345",
 			},
 			dest: "~/prow-artifacts",
-			want: "gsutil -m cp -r gs://origin-ci-test/logs/pull-ci-openshift-origin-master-e2e-aws/12345/ ~/prow-artifacts",
+			want: "gsutil -m cp -r 'gs://origin-ci-test/logs/pull-ci-openshift-origin-master-e2e-aws/12345/*' ~/prow-artifacts",
 		},
 		{
 			name: "current directory",
@@ -181,7 +183,16 @@ func TestBuildGsutilCommand(t *testing.T) {
 				Path:   "path/to/artifacts/123",
 			},
 			dest: ".",
-			want: "gsutil -m cp -r gs://bucket/path/to/artifacts/123/ .",
+			want: "gsutil -m cp -r 'gs://bucket/path/to/artifacts/123/*' .",
+		},
+		{
+			name: "destination with spaces and quotes",
+			metadata: &ProwMetadata{
+				Bucket: "bucket",
+				Path:   "logs/job/1",
+			},
+			dest: "/tmp/it's here",
+			want: `gsutil -m cp -r 'gs://bucket/logs/job/1/*' '/tmp/it'\''s here'`,
 		},
 	}
 
@@ -194,3 +205,489 @@ func TestBuildGsutilCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateURL_AllowedHosts(t *testing.T) {
+	orig := AllowedHosts
+	defer func() { AllowedHosts = orig }()
+	AllowedHosts = []string{DefaultProwHost, "prow.internal.example.com"}
+
+	tests := []struct {
+		name    string
+		url     string
+		wantErr error
+	}{
+		{
+			name: "default host",
+			url:  "https://prow.ci.openshift.org/view/gs/test-platform-results/logs/job/123",
+		},
+		{
+			name: "additional host",
+			url:  "https://prow.internal.example.com/view/gs/internal-results/logs/job/456",
+		},
+		{
+			name:    "unknown host",
+			url:     "https://prow.unknown.example.com/view/gs/bucket/logs/job/789",
+			wantErr: ErrInvalidHost,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateURL(tt.url)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateURL() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseURL_RecordsMatchedHost(t *testing.T) {
+	orig := AllowedHosts
+	defer func() { AllowedHosts = orig }()
+	AllowedHosts = []string{DefaultProwHost, "prow.internal.example.com"}
+
+	metadata, err := ParseURL("https://prow.internal.example.com/view/gs/internal-results/logs/my-job/456")
+	if err != nil {
+		t.Fatalf("ParseURL() error = %v", err)
+	}
+	if metadata.Host != "prow.internal.example.com" {
+		t.Errorf("ParseURL() Host = %v, want prow.internal.example.com", metadata.Host)
+	}
+	if metadata.Bucket != "internal-results" || metadata.JobName != "my-job" || metadata.BuildID != "456" {
+		t.Errorf("ParseURL() = %+v, unexpected bucket/job/build", metadata)
+	}
+}
+
+func TestSplitViewPath(t *testing.T) {
+	bucket, path, err := SplitViewPath("https://prow.ci.openshift.org/view/gs/test-platform-results/logs/periodic-ci-job/")
+	if err != nil {
+		t.Fatalf("SplitViewPath() error = %v", err)
+	}
+	if bucket != "test-platform-results" {
+		t.Errorf("SplitViewPath() bucket = %v, want test-platform-results", bucket)
+	}
+	if path != "logs/periodic-ci-job" {
+		t.Errorf("SplitViewPath() path = %v, want logs/periodic-ci-job", path)
+	}
+
+	if _, _, err := SplitViewPath("https://example.com/view/gs/bucket/logs/job"); err == nil {
+		t.Error("SplitViewPath() should reject unknown hosts")
+	}
+}
+
+func TestWithBuildID(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		buildID     string
+		wantJobName string
+		wantPath    string
+		wantPRRef   string
+		wantErr     error
+	}{
+		{
+			name:        "replaces existing build ID",
+			url:         "https://prow.ci.openshift.org/view/gs/test-platform-results/logs/periodic-ci-job/111",
+			buildID:     "222",
+			wantJobName: "periodic-ci-job",
+			wantPath:    "logs/periodic-ci-job/222",
+		},
+		{
+			name:        "fills missing build ID",
+			url:         "https://prow.ci.openshift.org/view/gs/test-platform-results/logs/periodic-ci-job/",
+			buildID:     "333",
+			wantJobName: "periodic-ci-job",
+			wantPath:    "logs/periodic-ci-job/333",
+		},
+		{
+			name:        "fills missing build ID of a PR job",
+			url:         "https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_api/1234/pull-ci-job",
+			buildID:     "444",
+			wantJobName: "pull-ci-job",
+			wantPath:    "pr-logs/pull/openshift_api/1234/pull-ci-job/444",
+			wantPRRef:   "[openshift/api PR1234]",
+		},
+		{
+			name:        "replaces build ID of an artifact URL",
+			url:         "https://prow.ci.openshift.org/view/gs/test-platform-results/logs/periodic-ci-job/111/artifacts/e2e/build-log.txt",
+			buildID:     "555",
+			wantJobName: "periodic-ci-job",
+			wantPath:    "logs/periodic-ci-job/555",
+		},
+		{
+			name:    "rejects non-numeric build ID",
+			url:     "https://prow.ci.openshift.org/view/gs/test-platform-results/logs/periodic-ci-job/111",
+			buildID: "abc/def",
+			wantErr: ErrInvalidBuildID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, err := ParseURL(tt.url)
+			if err != nil {
+				t.Fatalf("ParseURL() error = %v", err)
+			}
+			got, err := WithBuildID(metadata, tt.buildID)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("WithBuildID() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("WithBuildID() error = %v", err)
+			}
+			if got.BuildID != tt.buildID {
+				t.Errorf("WithBuildID() BuildID = %v, want %v", got.BuildID, tt.buildID)
+			}
+			if got.JobName != tt.wantJobName {
+				t.Errorf("WithBuildID() JobName = %v, want %v", got.JobName, tt.wantJobName)
+			}
+			if got.Path != tt.wantPath {
+				t.Errorf("WithBuildID() Path = %v, want %v", got.Path, tt.wantPath)
+			}
+			if got.PRRef != tt.wantPRRef {
+				t.Errorf("WithBuildID() PRRef = %v, want %v", got.PRRef, tt.wantPRRef)
+			}
+			wantCmd := "gsutil -m cp -r 'gs://test-platform-results/" + tt.wantPath + "/*' /tmp/dest"
+			if cmd := BuildGsutilCommand(got, "/tmp/dest"); cmd != wantCmd {
+				t.Errorf("BuildGsutilCommand() = %v, want %v", cmd, wantCmd)
+			}
+		})
+	}
+}
+
+func TestParseURL_PRFields(t *testing.T) {
+	tests := []struct {
+		name         string
+		url          string
+		wantOrg      string
+		wantRepo     string
+		wantPRNumber string
+	}{
+		{
+			name:         "pr-logs URL",
+			url:          "https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_cluster-network-operator/2417/pull-ci-openshift-cluster-network-operator-master-e2e-aws/1790000000000000000",
+			wantOrg:      "openshift",
+			wantRepo:     "cluster-network-operator",
+			wantPRNumber: "2417",
+		},
+		{
+			name:         "repo name containing an underscore",
+			url:          "https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift-priv_my_repo/7/pull-ci-job/123",
+			wantOrg:      "openshift-priv",
+			wantRepo:     "my_repo",
+			wantPRNumber: "7",
+		},
+		{
+			name: "periodic URL",
+			url:  "https://prow.ci.openshift.org/view/gs/test-platform-results/logs/periodic-ci-openshift-release-master-nightly-4.22-e2e-metal/2013057817195319296",
+		},
+		{
+			name: "postsubmit URL",
+			url:  "https://prow.ci.openshift.org/view/gs/origin-ci-test/logs/branch-ci-openshift-api-master-images/12345",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, err := ParseURL(tt.url)
+			if err != nil {
+				t.Fatalf("ParseURL() error = %v", err)
+			}
+			if metadata.Org != tt.wantOrg {
+				t.Errorf("ParseURL() Org = %q, want %q", metadata.Org, tt.wantOrg)
+			}
+			if metadata.Repo != tt.wantRepo {
+				t.Errorf("ParseURL() Repo = %q, want %q", metadata.Repo, tt.wantRepo)
+			}
+			if metadata.PRNumber != tt.wantPRNumber {
+				t.Errorf("ParseURL() PRNumber = %q, want %q", metadata.PRNumber, tt.wantPRNumber)
+			}
+		})
+	}
+}
+
+func TestParseURL_Kind(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		wantKind    JobKind
+		wantJobName string
+		wantBuildID string
+		wantPath    string
+	}{
+		{
+			name:        "periodic",
+			url:         "https://prow.ci.openshift.org/view/gs/test-platform-results/logs/periodic-ci-openshift-release-master-nightly-4.22-e2e-metal/2013057817195319296",
+			wantKind:    KindPeriodic,
+			wantJobName: "periodic-ci-openshift-release-master-nightly-4.22-e2e-metal",
+			wantBuildID: "2013057817195319296",
+			wantPath:    "logs/periodic-ci-openshift-release-master-nightly-4.22-e2e-metal/2013057817195319296",
+		},
+		{
+			name:        "presubmit",
+			url:         "https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_api/1234/pull-ci-openshift-api-master-unit/1790000000000000000",
+			wantKind:    KindPresubmit,
+			wantJobName: "pull-ci-openshift-api-master-unit",
+			wantBuildID: "1790000000000000000",
+			wantPath:    "pr-logs/pull/openshift_api/1234/pull-ci-openshift-api-master-unit/1790000000000000000",
+		},
+		{
+			name:        "batch presubmit",
+			url:         "https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/batch/pull-ci-openshift-api-master-unit/1790000000000000000",
+			wantKind:    KindPresubmit,
+			wantJobName: "pull-ci-openshift-api-master-unit",
+			wantBuildID: "1790000000000000000",
+			wantPath:    "pr-logs/pull/batch/pull-ci-openshift-api-master-unit/1790000000000000000",
+		},
+		{
+			name:        "postsubmit",
+			url:         "https://prow.ci.openshift.org/view/gs/origin-ci-test/logs/branch-ci-openshift-api-master-images/12345",
+			wantKind:    KindPostsubmit,
+			wantJobName: "branch-ci-openshift-api-master-images",
+			wantBuildID: "12345",
+			wantPath:    "logs/branch-ci-openshift-api-master-images/12345",
+		},
+		{
+			name:        "rehearsal",
+			url:         "https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_release/56789/rehearse-56789-pull-ci-openshift-api-master-unit/1800000000000000000",
+			wantKind:    KindRehearsal,
+			wantJobName: "rehearse-56789-pull-ci-openshift-api-master-unit",
+			wantBuildID: "1800000000000000000",
+			wantPath:    "pr-logs/pull/openshift_release/56789/rehearse-56789-pull-ci-openshift-api-master-unit/1800000000000000000",
+		},
+		{
+			name:        "rehearsal artifact URL",
+			url:         "https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_release/56789/rehearse-56789-periodic-ci-openshift-release-master-e2e/1800000000000000000/artifacts/e2e/gather-extra/",
+			wantKind:    KindRehearsal,
+			wantJobName: "rehearse-56789-periodic-ci-openshift-release-master-e2e",
+			wantBuildID: "1800000000000000000",
+			wantPath:    "pr-logs/pull/openshift_release/56789/rehearse-56789-periodic-ci-openshift-release-master-e2e/1800000000000000000",
+		},
+		{
+			name:        "unknown prefix",
+			url:         "https://prow.ci.openshift.org/view/gs/test-platform-results/logs/release-openshift-origin-installer-e2e/42",
+			wantJobName: "release-openshift-origin-installer-e2e",
+			wantBuildID: "42",
+			wantPath:    "logs/release-openshift-origin-installer-e2e/42",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, err := ParseURL(tt.url)
+			if err != nil {
+				t.Fatalf("ParseURL() error = %v", err)
+			}
+			if metadata.Kind != tt.wantKind {
+				t.Errorf("ParseURL() Kind = %q, want %q", metadata.Kind, tt.wantKind)
+			}
+			if metadata.JobName != tt.wantJobName {
+				t.Errorf("ParseURL() JobName = %q, want %q", metadata.JobName, tt.wantJobName)
+			}
+			if metadata.BuildID != tt.wantBuildID {
+				t.Errorf("ParseURL() BuildID = %q, want %q", metadata.BuildID, tt.wantBuildID)
+			}
+			if metadata.Path != tt.wantPath {
+				t.Errorf("ParseURL() Path = %q, want %q", metadata.Path, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestParseURL_ArtifactRawURL(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{
+			name: "artifact file",
+			url:  "https://prow.ci.openshift.org/view/gs/test-platform-results/logs/periodic-ci-job/111/artifacts/e2e/build-log.txt",
+			want: "https://prow.ci.openshift.org/view/gs/test-platform-results/logs/periodic-ci-job/111",
+		},
+		{
+			name: "artifact folder of a PR job",
+			url:  "https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_api/42/pull-ci-openshift-api-master-unit/222/artifacts/",
+			want: "https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_api/42/pull-ci-openshift-api-master-unit/222",
+		},
+		{
+			name: "build URL kept as given",
+			url:  "https://prow.ci.openshift.org/view/gs/test-platform-results/logs/periodic-ci-job/111/",
+			want: "https://prow.ci.openshift.org/view/gs/test-platform-results/logs/periodic-ci-job/111/",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, err := ParseURL(tt.url)
+			if err != nil {
+				t.Fatalf("ParseURL() error = %v", err)
+			}
+			if metadata.RawURL != tt.want {
+				t.Errorf("ParseURL() RawURL = %q, want the build URL %q", metadata.RawURL, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateURL_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		wantErr error
+	}{
+		{name: "empty", url: "", wantErr: ErrEmptyURL},
+		{name: "unparsable", url: "https://prow.ci.openshift.org/view/gs/%zz", wantErr: ErrInvalidURL},
+		{name: "http", url: "http://prow.ci.openshift.org/view/gs/bucket/logs/job/123", wantErr: ErrInvalidScheme},
+		{name: "unknown host", url: "https://example.com/view/gs/bucket/logs/job/123", wantErr: ErrInvalidHost},
+		{name: "not a view URL", url: "https://prow.ci.openshift.org/?author=clobrano", wantErr: ErrInvalidPath},
+		{name: "too short", url: "https://prow.ci.openshift.org/view/gs/bucket/logs", wantErr: ErrMissingPath},
+		{name: "empty bucket", url: "https://prow.ci.openshift.org/view/gs//logs/job", wantErr: ErrMissingPath},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateURL(tt.url)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateURL() error = %v, want it to match %v", err, tt.wantErr)
+			}
+			if _, err := ParseURL(tt.url); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ParseURL() error = %v, want it to match %v", err, tt.wantErr)
+			}
+			if _, _, err := SplitViewPath(tt.url); !errors.Is(err, tt.wantErr) {
+				t.Errorf("SplitViewPath() error = %v, want it to match %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateURL_ErrorContext(t *testing.T) {
+	err := ValidateURL("https://example.com/view/gs/bucket/logs/job/123")
+	if err == nil || !strings.Contains(err.Error(), `"example.com"`) {
+		t.Errorf("ValidateURL() error = %v, want it to name the rejected host", err)
+	}
+}
+
+func TestParseURL_ArtifactLinks(t *testing.T) {
+	const want = "https://prow.ci.openshift.org/view/gs/test-platform-results/logs/periodic-ci-job/1234567890"
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{"prow", want},
+		{"gcsweb", "https://gcsweb-ci.apps.ci.l2s4.p1.openshiftapps.com/gcs/test-platform-results/logs/periodic-ci-job/1234567890/"},
+		{"gcsweb artifact", "https://gcsweb-ci.apps.ci.l2s4.p1.openshiftapps.com/gcs/test-platform-results/logs/periodic-ci-job/1234567890/artifacts/e2e/"},
+		{"storage", "https://storage.googleapis.com/test-platform-results/logs/periodic-ci-job/1234567890"},
+		{"storage object", "https://storage.googleapis.com/test-platform-results/logs/periodic-ci-job/1234567890/build-log.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, err := ParseURL(tt.url)
+			if err != nil {
+				t.Fatalf("ParseURL(%q) error = %v", tt.url, err)
+			}
+			if metadata.Bucket != "test-platform-results" || metadata.Path != "logs/periodic-ci-job/1234567890" ||
+				metadata.JobName != "periodic-ci-job" || metadata.BuildID != "1234567890" {
+				t.Errorf("ParseURL(%q) = %+v, unexpected bucket/path/job/build", tt.url, metadata)
+			}
+			if metadata.RawURL != want {
+				t.Errorf("ParseURL(%q) RawURL = %q, want the Prow job URL %q", tt.url, metadata.RawURL, want)
+			}
+			if metadata.Host != DefaultProwHost {
+				t.Errorf("ParseURL(%q) Host = %q, want %q", tt.url, metadata.Host, DefaultProwHost)
+			}
+		})
+	}
+}
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://gcsweb-ci.example.com/gcs/bucket/logs/job/1", "https://prow.ci.openshift.org/view/gs/bucket/logs/job/1"},
+		{"https://storage.googleapis.com/bucket/logs/job/1?alt=media", "https://prow.ci.openshift.org/view/gs/bucket/logs/job/1"},
+		{"http://storage.googleapis.com/bucket/logs/job/1", "http://storage.googleapis.com/bucket/logs/job/1"},
+		{"https://gcsweb-ci.example.com/other/bucket/logs/job/1", "https://gcsweb-ci.example.com/other/bucket/logs/job/1"},
+		{"https://example.com/bucket/logs/job/1", "https://example.com/bucket/logs/job/1"},
+	}
+	for _, tt := range tests {
+		if got := normalizeURL(tt.url); got != tt.want {
+			t.Errorf("normalizeURL(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeURL_GCSBase(t *testing.T) {
+	orig := GCSBase
+	defer func() { GCSBase = orig }()
+	GCSBase = "https://mirror.example.com/storage/"
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://mirror.example.com/storage/bucket/logs/job/1/build-log.txt", "https://prow.ci.openshift.org/view/gs/bucket/logs/job/1/build-log.txt"},
+		{"https://mirror.example.com/storage/bucket/logs/job/1?alt=media", "https://prow.ci.openshift.org/view/gs/bucket/logs/job/1"},
+		{"https://mirror.example.com/other/bucket/logs/job/1", "https://mirror.example.com/other/bucket/logs/job/1"},
+	}
+	for _, tt := range tests {
+		if got := normalizeURL(tt.url); got != tt.want {
+			t.Errorf("normalizeURL(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeURL_SeveralProwHosts(t *testing.T) {
+	origHosts, origGCS := AllowedHosts, GCSHost
+	defer func() { AllowedHosts, GCSHost = origHosts, origGCS }()
+	AllowedHosts = []string{DefaultProwHost, "prow.internal.example.com", "prow.other.example.com"}
+	GCSHost = "gcs.internal.example.com"
+
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{"OpenShift gcsweb", "https://gcsweb-ci.apps.ci.l2s4.p1.openshiftapps.com/gcs/test-platform-results/logs/job/1/",
+			"https://prow.ci.openshift.org/view/gs/test-platform-results/logs/job/1/"},
+		{"public GCS", "https://storage.googleapis.com/test-platform-results/logs/job/1",
+			"https://prow.ci.openshift.org/view/gs/test-platform-results/logs/job/1"},
+		{"configured GCS mirror", "https://gcs.internal.example.com/internal-results/logs/job/1",
+			"https://prow.internal.example.com/view/gs/internal-results/logs/job/1"},
+		{"another gcsweb", "https://gcsweb.internal.example.com/gcs/internal-results/logs/job/1",
+			"https://prow.internal.example.com/view/gs/internal-results/logs/job/1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeURL(tt.url); got != tt.want {
+				t.Errorf("normalizeURL(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrimaryHost(t *testing.T) {
+	origHosts, origProwHost := AllowedHosts, ProwHost
+	defer func() { AllowedHosts, ProwHost = origHosts, origProwHost }()
+
+	tests := []struct {
+		hosts    []string
+		prowHost string
+		want     string
+	}{
+		{hosts: []string{DefaultProwHost}, want: DefaultProwHost},
+		{hosts: nil, want: DefaultProwHost},
+		{hosts: []string{DefaultProwHost, "prow.internal.example.com", "prow.other.example.com"}, want: "prow.internal.example.com"},
+		{hosts: []string{"prow.other.example.com", DefaultProwHost, "prow.internal.example.com"}, prowHost: "prow.other.example.com", want: "prow.other.example.com"},
+		{hosts: []string{DefaultProwHost, "prow.internal.example.com"}, prowHost: DefaultProwHost, want: DefaultProwHost},
+	}
+	for _, tt := range tests {
+		AllowedHosts, ProwHost = tt.hosts, tt.prowHost
+		if got := PrimaryHost(); got != tt.want {
+			t.Errorf("PrimaryHost() with %v and prow_host %q = %q, want %q", tt.hosts, tt.prowHost, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// Package prompt defines how interactive prompts behave when prow-helper runs
+// unattended.
+package prompt
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Policy tells a prompt whether to ask on the terminal.
+type Policy int
+
+const (
+	// Ask prompts on the terminal; this is the default.
+	Ask Policy = iota
+	// Yes accepts each prompt's safe default without asking (--yes).
+	Yes
+	// Never fails with ErrDisabled wherever a prompt would be needed
+	// (--no-prompt), so automation notices unexpected interactive points.
+	Never
+)
+
+// ErrDisabled is returned, wrapped, by prompts that cannot ask under the
+// current policy.
+var ErrDisabled = errors.New("an interactive prompt is needed but prompts are disabled")
+
+// FromFlags returns the policy selected by the --yes and --no-prompt flags,
+// which are mutually exclusive.
+func FromFlags(yes, noPrompt bool) Policy {
+	switch {
+	case noPrompt:
+		return Never
+	case yes:
+		return Yes
+	default:
+		return Ask
+	}
+}
+
+// Disabled returns ErrDisabled wrapped with a description of the question
+// that could not be asked.
+func Disabled(question string) error {
+	return fmt.Errorf("%w: %s", ErrDisabled, question)
+}
@@ -0,0 +1,33 @@
+package prompt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFromFlags(t *testing.T) {
+	tests := []struct {
+		yes, noPrompt bool
+		want          Policy
+	}{
+		{false, false, Ask},
+		{true, false, Yes},
+		{false, true, Never},
+	}
+	for _, tt := range tests {
+		if got := FromFlags(tt.yes, tt.noPrompt); got != tt.want {
+			t.Errorf("FromFlags(%v, %v) = %v, want %v", tt.yes, tt.noPrompt, got, tt.want)
+		}
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	err := Disabled("folder exists: /tmp/job/1")
+	if !errors.Is(err, ErrDisabled) {
+		t.Errorf("Disabled() = %v, want it to match ErrDisabled", err)
+	}
+	if !strings.Contains(err.Error(), "folder exists: /tmp/job/1") {
+		t.Errorf("Disabled() = %q, want the question in the message", err)
+	}
+}
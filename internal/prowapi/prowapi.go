@@ -7,13 +7,18 @@
 package prowapi
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/clobrano/prow-helper/internal/httpclient"
 )
 
 // Job holds the fields of a ProwJob that are relevant for monitoring.
@@ -21,12 +26,33 @@ type Job struct {
 	Name           string
 	State          string
 	URL            string
+	Host           string // host of the status page that listed the job
 	Author         string
 	PRRef          string    // "[org/repo PR<num>]" for presubmit jobs, "" otherwise
+	PR             int       // pull request number for presubmit jobs, 0 otherwise
 	StartTime      time.Time // zero if not yet started
 	CompletionTime time.Time // zero if still running
 }
 
+// terminalStates are the Prow states of jobs that have completed.
+var terminalStates = map[string]bool{
+	"success": true,
+	"failure": true,
+	"aborted": true,
+	"error":   true,
+}
+
+// IsTerminalState reports whether state is the state of a completed job:
+// success, failure, aborted or error.
+func IsTerminalState(state string) bool {
+	return terminalStates[state]
+}
+
+// Finished reports whether the job is in a terminal state.
+func (j Job) Finished() bool {
+	return IsTerminalState(j.State)
+}
+
 // prowJobList is the top-level structure returned by /prowjobs.js.
 type prowJobList struct {
 	Items []prowJob `json:"items"`
@@ -62,7 +88,7 @@ type prowJobStatus struct {
 }
 
 // FetchJobs calls <host>/prowjobs.js and returns the jobs that match the
-// filter query parameters found in pageURL (author, job, state).
+// filter query parameters found in pageURL (author, job, pull, state).
 //
 // The /prowjobs.js endpoint returns a JavaScript assignment of the form
 //
@@ -75,6 +101,64 @@ func FetchJobs(pageURL string) ([]Job, error) {
 		return nil, fmt.Errorf("invalid URL: %w", err)
 	}
 
+	jobs, err := fetchAll(u)
+	if err != nil {
+		return nil, err
+	}
+	return filter(jobs, u.Query()), nil
+}
+
+// ErrJobNotFound is returned by FetchJob when the job is not listed by
+// /prowjobs.js, which only keeps recent jobs.
+var ErrJobNotFound = errors.New("job not found in prowjobs.js")
+
+// FetchJob calls /prowjobs.js on the host of jobURL, a Prow job URL
+// (.../view/gs/<bucket>/<path>), and returns the job listed with that URL.
+// URLs are matched on their path, so a trailing slash or query string does
+// not matter. Returns an error wrapping ErrJobNotFound if no job matches.
+func FetchJob(jobURL string) (*Job, error) {
+	u, err := url.Parse(jobURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	jobs, err := fetchAll(u)
+	if err != nil {
+		return nil, err
+	}
+	if j := findJob(jobs, jobURL); j != nil {
+		return j, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrJobNotFound, jobURL)
+}
+
+// findJob returns the job of jobs whose URL has the same path as jobURL, or
+// nil.
+func findJob(jobs []Job, jobURL string) *Job {
+	want := urlPath(jobURL)
+	if want == "" {
+		return nil
+	}
+	for i := range jobs {
+		if urlPath(jobs[i].URL) == want {
+			return &jobs[i]
+		}
+	}
+	return nil
+}
+
+// urlPath returns the path of rawURL without surrounding slashes, or "" if
+// it cannot be parsed.
+func urlPath(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.Trim(u.Path, "/")
+}
+
+// fetchAll calls /prowjobs.js on the host of u and returns every job it lists.
+func fetchAll(u *url.URL) ([]Job, error) {
 	apiURL := &url.URL{
 		Scheme:   u.Scheme,
 		Host:     u.Host,
@@ -82,7 +166,7 @@ func FetchJobs(pageURL string) ([]Job, error) {
 		RawQuery: "omit=annotations,labels,decoration_config,pod_spec",
 	}
 
-	resp, err := http.Get(apiURL.String()) //nolint:noctx
+	resp, err := httpclient.Get(apiURL.String())
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch prowjobs.js: %w", err)
 	}
@@ -101,24 +185,17 @@ func FetchJobs(pageURL string) ([]Job, error) {
 	if err != nil {
 		return nil, err
 	}
-
-	return filter(jobs, u.Query()), nil
+	for i := range jobs {
+		jobs[i].Host = u.Host
+	}
+	return jobs, nil
 }
 
 // parse strips the JavaScript variable prefix and decodes the ProwJobList JSON.
 func parse(body []byte) ([]Job, error) {
-	data := strings.TrimSpace(string(body))
-
-	// Strip "var <name> = " prefix — find the first '{'.
-	if idx := strings.Index(data, "{"); idx > 0 {
-		data = data[idx:]
-	}
-	// Strip trailing semicolon.
-	data = strings.TrimRight(data, "; \t\n\r")
-
-	var list prowJobList
-	if err := json.Unmarshal([]byte(data), &list); err != nil {
-		return nil, fmt.Errorf("failed to parse prowjobs.js JSON: %w", err)
+	list, err := decodeJobList(body)
+	if err != nil {
+		return nil, err
 	}
 
 	jobs := make([]Job, 0, len(list.Items))
@@ -133,6 +210,7 @@ func parse(body []byte) ([]Job, error) {
 		}
 		if pj.Spec.Refs != nil && len(pj.Spec.Refs.Pulls) > 0 {
 			j.Author = pj.Spec.Refs.Pulls[0].Author
+			j.PR = pj.Spec.Refs.Pulls[0].Number
 			if pj.Spec.Refs.Org != "" && pj.Spec.Refs.Repo != "" && pj.Spec.Refs.Pulls[0].Number > 0 {
 				j.PRRef = fmt.Sprintf("[%s/%s PR%d]", pj.Spec.Refs.Org, pj.Spec.Refs.Repo, pj.Spec.Refs.Pulls[0].Number)
 			}
@@ -148,14 +226,49 @@ func parse(body []byte) ([]Job, error) {
 	return jobs, nil
 }
 
+// decodeJobList finds the ProwJobList object in a prowjobs.js body and
+// decodes it. Rather than assuming the object starts at the first '{', it
+// tries each '{' in turn and keeps the first one that decodes to an object
+// with an "items" list, so braces in the variable declaration or in comments
+// before it do not matter. Whatever follows the object (a semicolon, a
+// comment) is ignored.
+func decodeJobList(body []byte) (*prowJobList, error) {
+	var firstErr error
+	for offset := 0; ; offset++ {
+		i := bytes.IndexByte(body[offset:], '{')
+		if i < 0 {
+			break
+		}
+		offset += i
+
+		var list prowJobList
+		err := json.NewDecoder(bytes.NewReader(body[offset:])).Decode(&list)
+		if err == nil && list.Items != nil {
+			return &list, nil
+		}
+		if err == nil {
+			err = errors.New(`object has no "items" list`)
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		firstErr = errors.New("no JSON object found")
+	}
+	return nil, fmt.Errorf("failed to parse prowjobs.js JSON: %w", firstErr)
+}
+
 // filter applies query-parameter-based filters to a job list.
-// Recognised parameters: author, job (substring match), state.
+// Recognised parameters: author, job (substring match), pull (pull request
+// number), state.
 func filter(jobs []Job, q url.Values) []Job {
 	authorFilter := q.Get("author")
 	stateFilter := q.Get("state")
 	jobFilter := q.Get("job")
+	pullFilter := q.Get("pull")
 
-	if authorFilter == "" && stateFilter == "" && jobFilter == "" {
+	if authorFilter == "" && stateFilter == "" && jobFilter == "" && pullFilter == "" {
 		return jobs
 	}
 
@@ -170,6 +283,9 @@ func filter(jobs []Job, q url.Values) []Job {
 		if jobFilter != "" && !strings.Contains(j.Name, jobFilter) {
 			continue
 		}
+		if pullFilter != "" && strconv.Itoa(j.PR) != pullFilter {
+			continue
+		}
 		result = append(result, j)
 	}
 	return result
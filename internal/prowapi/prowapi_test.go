@@ -1,7 +1,11 @@
 package prowapi
 
 import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 )
@@ -87,6 +91,9 @@ func TestParse(t *testing.T) {
 	if j.PRRef != "[openshift/cno PR42]" {
 		t.Errorf("unexpected PRRef: %s", j.PRRef)
 	}
+	if j.PR != 42 {
+		t.Errorf("unexpected PR: %d", j.PR)
+	}
 
 	// Periodic job has no pulls so Author and PRRef must be empty.
 	if jobs[2].Author != "" {
@@ -95,6 +102,9 @@ func TestParse(t *testing.T) {
 	if jobs[2].PRRef != "" {
 		t.Errorf("periodic job should have empty PRRef, got: %s", jobs[2].PRRef)
 	}
+	if jobs[2].PR != 0 {
+		t.Errorf("periodic job should have no PR, got: %d", jobs[2].PR)
+	}
 }
 
 func TestParseStripsJSPrefix(t *testing.T) {
@@ -115,6 +125,43 @@ func TestParseStripsJSPrefix(t *testing.T) {
 	}
 }
 
+func TestParseTrickyPrefixes(t *testing.T) {
+	const list = `{"items":[{"spec":{"job":"j"},"status":{"state":"pending","url":"https://prow.example.com/view/gs/b/logs/j/1"}}]}`
+	variants := map[string]string{
+		"comment with braces":          "/* generated {by deck} */\nvar allBuilds = " + list + ";",
+		"line comment with JSON":       "// allBuilds = {\"items\": \"see below\"}\nvar allBuilds = " + list,
+		"comment with an object":       "/* {\"note\": 1} */ var allBuilds = " + list,
+		"quotes and braces in comment": "var allBuilds /* \"}{\" */ = " + list + ";",
+		"trailing comment":             "var allBuilds = " + list + ";\n// end {",
+		"trailing whitespace and semi": "var allBuilds = " + list + " ;\n\t",
+		"no prefix":                    list,
+	}
+	for name, body := range variants {
+		t.Run(name, func(t *testing.T) {
+			jobs, err := parse([]byte(body))
+			if err != nil {
+				t.Fatalf("parse() error = %v", err)
+			}
+			if len(jobs) != 1 || jobs[0].Name != "j" || jobs[0].State != "pending" {
+				t.Errorf("parse() = %+v, want the single job j", jobs)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, body := range []string{
+		"",
+		"var allBuilds = ;",
+		"var allBuilds = {\"items\": [",
+		`var allBuilds = {"kind": "List"}`,
+	} {
+		if _, err := parse([]byte(body)); err == nil {
+			t.Errorf("parse(%q) error = nil, want an error", body)
+		}
+	}
+}
+
 func TestFilter(t *testing.T) {
 	jobs, _ := parse([]byte(sampleProwJobsJS))
 
@@ -128,6 +175,9 @@ func TestFilter(t *testing.T) {
 		{"job substring filter", "job=unit", 1},
 		{"no filter", "", 3},
 		{"author not found", "author=nobody", 0},
+		{"pull filter", "pull=43", 1},
+		{"pull and job filters", "pull=42&job=e2e", 1},
+		{"pull not found", "pull=44", 0},
 	}
 
 	for _, tt := range tests {
@@ -140,3 +190,125 @@ func TestFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestFindJob(t *testing.T) {
+	jobs, err := parse([]byte(sampleProwJobsJS))
+	if err != nil {
+		t.Fatalf("parse() error = %v", err)
+	}
+
+	base := "https://prow.ci.openshift.org/view/gs/test-platform-results/logs/"
+	tests := []struct {
+		name      string
+		url       string
+		wantName  string
+		wantState string
+	}{
+		{"exact URL", base + "periodic-nightly/1111111111", "periodic-nightly", "triggered"},
+		{"trailing slash", base + "pull-ci-openshift-cno-master-unit/9876543210/", "pull-ci-openshift-cno-master-unit", "success"},
+		{"query string", base + "pull-ci-openshift-cno-master-e2e-aws-ovn/1234567890?foo=bar", "pull-ci-openshift-cno-master-e2e-aws-ovn", "pending"},
+		{"other build of a listed job", base + "periodic-nightly/2222222222", "", ""},
+		{"build ID prefix only", base + "periodic-nightly/111111111", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findJob(jobs, tt.url)
+			if tt.wantName == "" {
+				if got != nil {
+					t.Errorf("findJob() = %+v, want no match", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("findJob() = nil, want a match")
+			}
+			if got.Name != tt.wantName || got.State != tt.wantState {
+				t.Errorf("findJob() = %s (%s), want %s (%s)", got.Name, got.State, tt.wantName, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestFetchJob(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/prowjobs.js" {
+			http.NotFound(w, r)
+			return
+		}
+		// The sample lists the jobs under prow.ci.openshift.org: only the
+		// path has to match.
+		w.Write([]byte(sampleProwJobsJS))
+	}))
+	defer server.Close()
+
+	job, err := FetchJob(server.URL + "/view/gs/test-platform-results/logs/periodic-nightly/1111111111")
+	if err != nil {
+		t.Fatalf("FetchJob() error = %v", err)
+	}
+	if job.Name != "periodic-nightly" || job.State != "triggered" {
+		t.Errorf("FetchJob() = %s (%s), want periodic-nightly (triggered)", job.Name, job.State)
+	}
+	if want := strings.TrimPrefix(server.URL, "http://"); job.Host != want {
+		t.Errorf("FetchJob() Host = %q, want %q", job.Host, want)
+	}
+
+	_, err = FetchJob(server.URL + "/view/gs/test-platform-results/logs/periodic-nightly/42")
+	if !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("FetchJob() error = %v, want ErrJobNotFound", err)
+	}
+}
+
+func TestFetchJob_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		jobURL  string
+	}{
+		{
+			name:    "HTTP error",
+			handler: func(w http.ResponseWriter, r *http.Request) { http.Error(w, "boom", http.StatusBadGateway) },
+			jobURL:  "/view/gs/test-platform-results/logs/periodic-nightly/1111111111",
+		},
+		{
+			name:    "malformed payload",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("var allBuilds = {not json")) },
+			jobURL:  "/view/gs/test-platform-results/logs/periodic-nightly/1111111111",
+		},
+		{
+			name:    "URL without a path never matches a job without URL",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(sampleProwJobsJS)) },
+			jobURL:  "/",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			job, err := FetchJob(server.URL + tt.jobURL)
+			if err == nil {
+				t.Errorf("FetchJob() = %+v, want an error", job)
+			}
+		})
+	}
+}
+
+func TestJobFinished(t *testing.T) {
+	tests := []struct {
+		state string
+		want  bool
+	}{
+		{"success", true},
+		{"failure", true},
+		{"aborted", true},
+		{"error", true},
+		{"triggered", false},
+		{"pending", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := (Job{State: tt.state}).Finished(); got != tt.want {
+			t.Errorf("Job{State: %q}.Finished() = %v, want %v", tt.state, got, tt.want)
+		}
+	}
+}
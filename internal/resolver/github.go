@@ -0,0 +1,104 @@
+package resolver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/clobrano/prow-helper/internal/httpclient"
+)
+
+// GitHubAPIBaseURL is the GitHub REST API endpoint used to read PR comments.
+var GitHubAPIBaseURL = "https://api.github.com"
+
+var (
+	ErrNotPRURL = errors.New("not a GitHub pull request URL")
+
+	// prURLPattern matches https://github.com/<org>/<repo>/pull/<number>[/...]
+	prURLPattern = regexp.MustCompile(`^https://github\.com/([^/]+)/([^/]+)/pull/(\d+)(?:/.*)?$`)
+)
+
+// commentsPerPage is the page size requested from the GitHub comments API.
+const commentsPerPage = 100
+
+type issueComment struct {
+	Body string `json:"body"`
+}
+
+// ParsePRURL extracts the organisation, repository and PR number from a
+// GitHub pull request URL.
+func ParsePRURL(prURL string) (org, repo, number string, err error) {
+	m := prURLPattern.FindStringSubmatch(strings.TrimSpace(prURL))
+	if m == nil {
+		return "", "", "", fmt.Errorf("%w: %s", ErrNotPRURL, prURL)
+	}
+	return m[1], m[2], m[3], nil
+}
+
+// FindPRJobLinks reads the comments of a GitHub pull request through the
+// GitHub API and returns the Prow job links they contain (CI bots post one
+// per job run), deduplicated in the order they appear. GITHUB_TOKEN, when
+// set, is used to authenticate and avoid the anonymous rate limit.
+// Returns ErrNoProwLinks if no comment links to a Prow job.
+func FindPRJobLinks(prURL string) ([]string, error) {
+	org, repo, number, err := ParsePRURL(prURL)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern := prowLinkPattern()
+	var matches []string
+	for page := 1; ; page++ {
+		comments, err := fetchPRComments(org, repo, number, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range comments {
+			matches = append(matches, pattern.FindAllString(c.Body, -1)...)
+		}
+		if len(comments) < commentsPerPage {
+			break
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil, ErrNoProwLinks
+	}
+	return deduplicate(matches), nil
+}
+
+// fetchPRComments fetches one page of comments of the given pull request.
+func fetchPRComments(org, repo, number string, page int) ([]issueComment, error) {
+	apiURL := fmt.Sprintf("%s/repos/%s/%s/issues/%s/comments?per_page=%d&page=%d",
+		GitHubAPIBaseURL, url.PathEscape(org), url.PathEscape(repo), number, commentsPerPage, page)
+
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFetchFailed, err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := httpclient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFetchFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: GitHub API returned HTTP %d", ErrFetchFailed, resp.StatusCode)
+	}
+
+	var comments []issueComment
+	if err := json.NewDecoder(resp.Body).Decode(&comments); err != nil {
+		return nil, fmt.Errorf("%w: parsing comments: %v", ErrFetchFailed, err)
+	}
+	return comments, nil
+}
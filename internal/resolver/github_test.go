@@ -0,0 +1,90 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParsePRURL(t *testing.T) {
+	org, repo, number, err := ParsePRURL("https://github.com/openshift/api/pull/1234/files")
+	if err != nil {
+		t.Fatalf("ParsePRURL() unexpected error: %v", err)
+	}
+	if org != "openshift" || repo != "api" || number != "1234" {
+		t.Errorf("ParsePRURL() = %s/%s#%s, want openshift/api#1234", org, repo, number)
+	}
+
+	if _, _, _, err := ParsePRURL("https://github.com/openshift/api/issues/1"); !errors.Is(err, ErrNotPRURL) {
+		t.Errorf("ParsePRURL() error = %v, want ErrNotPRURL", err)
+	}
+}
+
+func TestFindPRJobLinks(t *testing.T) {
+	// Page 1 is full (commentsPerPage entries) so a second page is requested.
+	page1 := make([]string, commentsPerPage)
+	for i := range page1 {
+		page1[i] = `{"body":"lgtm"}`
+	}
+	page1[0] = `{"body":"[Full PR test history](https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_api/1234/pull-ci-unit/111)"}`
+	page1[1] = `{"body":"/retest"}`
+	page2 := []string{
+		`{"body":"ci/prow/e2e failed: https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_api/1234/pull-ci-e2e/222 and https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_api/1234/pull-ci-unit/111"}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/openshift/api/issues/1234/comments" {
+			t.Errorf("unexpected API path %s", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		switch r.URL.Query().Get("page") {
+		case "1":
+			fmt.Fprintf(w, "[%s]", strings.Join(page1, ","))
+		case "2":
+			fmt.Fprintf(w, "[%s]", strings.Join(page2, ","))
+		default:
+			fmt.Fprint(w, "[]")
+		}
+	}))
+	defer server.Close()
+
+	orig := GitHubAPIBaseURL
+	GitHubAPIBaseURL = server.URL
+	defer func() { GitHubAPIBaseURL = orig }()
+
+	links, err := FindPRJobLinks("https://github.com/openshift/api/pull/1234")
+	if err != nil {
+		t.Fatalf("FindPRJobLinks() unexpected error: %v", err)
+	}
+	want := []string{
+		"https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_api/1234/pull-ci-unit/111",
+		"https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_api/1234/pull-ci-e2e/222",
+	}
+	if len(links) != len(want) {
+		t.Fatalf("FindPRJobLinks() = %v, want %v", links, want)
+	}
+	for i := range want {
+		if links[i] != want[i] {
+			t.Errorf("FindPRJobLinks()[%d] = %q, want %q", i, links[i], want[i])
+		}
+	}
+}
+
+func TestFindPRJobLinks_NoLinks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"body":"lgtm"}]`)
+	}))
+	defer server.Close()
+
+	orig := GitHubAPIBaseURL
+	GitHubAPIBaseURL = server.URL
+	defer func() { GitHubAPIBaseURL = orig }()
+
+	if _, err := FindPRJobLinks("https://github.com/openshift/api/pull/1"); !errors.Is(err, ErrNoProwLinks) {
+		t.Errorf("FindPRJobLinks() error = %v, want ErrNoProwLinks", err)
+	}
+}
@@ -6,20 +6,31 @@ import (
 	"io"
 	"net/http"
 	"regexp"
+	"strings"
+
+	"github.com/clobrano/prow-helper/internal/httpclient"
+	"github.com/clobrano/prow-helper/internal/parser"
 )
 
 var (
-	ErrFetchFailed   = errors.New("failed to fetch URL")
-	ErrNoProwLinks   = errors.New("no prow job links found on page")
-
-	// prowLinkPattern matches prow.ci.openshift.org /view/gs/ URLs embedded in HTML
-	prowLinkPattern = regexp.MustCompile(`https://prow\.ci\.openshift\.org/view/gs/[^\s"'<>]+`)
+	ErrFetchFailed = errors.New("failed to fetch URL")
+	ErrNoProwLinks = errors.New("no prow job links found on page")
 )
 
+// prowLinkPattern returns a regexp matching /view/gs/ URLs of any of the
+// accepted Prow hosts (parser.AllowedHosts) embedded in HTML or Markdown.
+func prowLinkPattern() *regexp.Regexp {
+	hosts := make([]string, len(parser.AllowedHosts))
+	for i, h := range parser.AllowedHosts {
+		hosts[i] = regexp.QuoteMeta(h)
+	}
+	return regexp.MustCompile(`https://(?:` + strings.Join(hosts, "|") + `)/view/gs/[^\s"'<>()\[\]]+`)
+}
+
 // FindProwJobLinks fetches the given URL and returns all prow job links found on the page.
 // Returns ErrNoProwLinks if the page contains no recognizable prow job URLs.
 func FindProwJobLinks(url string) ([]string, error) {
-	resp, err := http.Get(url) //nolint:noctx
+	resp, err := httpclient.Get(url)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrFetchFailed, err)
 	}
@@ -34,7 +45,7 @@ func FindProwJobLinks(url string) ([]string, error) {
 		return nil, fmt.Errorf("%w: reading body: %v", ErrFetchFailed, err)
 	}
 
-	matches := prowLinkPattern.FindAllString(string(body), -1)
+	matches := prowLinkPattern().FindAllString(string(body), -1)
 	if len(matches) == 0 {
 		return nil, ErrNoProwLinks
 	}
@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/clobrano/prow-helper/internal/parser"
 )
 
 func TestFindProwJobLinks(t *testing.T) {
@@ -105,3 +107,33 @@ func TestFindProwJobLinks(t *testing.T) {
 		})
 	}
 }
+
+func TestFindProwJobLinks_AllowedHosts(t *testing.T) {
+	orig := parser.AllowedHosts
+	defer func() { parser.AllowedHosts = orig }()
+	parser.AllowedHosts = []string{parser.DefaultProwHost, "prow.internal.example.com"}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<a href="https://prow.ci.openshift.org/view/gs/b/logs/job-a/111">A</a>
+			<a href="https://prow.internal.example.com/view/gs/b/logs/job-b/222">B</a>
+			<a href="https://prow.other.example.com/view/gs/b/logs/job-c/333">C</a>`))
+	}))
+	defer server.Close()
+
+	links, err := FindProwJobLinks(server.URL)
+	if err != nil {
+		t.Fatalf("FindProwJobLinks() unexpected error: %v", err)
+	}
+	want := []string{
+		"https://prow.ci.openshift.org/view/gs/b/logs/job-a/111",
+		"https://prow.internal.example.com/view/gs/b/logs/job-b/222",
+	}
+	if len(links) != len(want) {
+		t.Fatalf("FindProwJobLinks() = %v, want %v", links, want)
+	}
+	for i := range want {
+		if links[i] != want[i] {
+			t.Errorf("FindProwJobLinks()[%d] = %q, want %q", i, links[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Package retry runs an operation repeatedly with exponential backoff.
+//
+// It is shared by the packages that talk to the network (GCS, the Prow API,
+// ntfy.sh) so that their retry behaviour is defined and tested in one place.
+package retry
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Policy describes how an operation is retried.
+type Policy struct {
+	// MaxAttempts is the total number of calls, including the first one.
+	// Values below 1 are treated as 1 (no retry).
+	MaxAttempts int
+
+	// BaseDelay is the wait before the second attempt. Each following wait
+	// doubles, up to MaxDelay.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the wait between attempts. Zero means no cap.
+	MaxDelay time.Duration
+
+	// Jitter randomises each wait by up to ±Jitter of its value (0.2 = ±20%).
+	// Zero disables jitter.
+	Jitter float64
+
+	// Retryable reports whether err is worth retrying. A nil Retryable
+	// retries every error.
+	Retryable func(err error) bool
+}
+
+// DefaultPolicy is a conservative policy suitable for idempotent HTTP GETs.
+var DefaultPolicy = Policy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Second,
+	MaxDelay:    10 * time.Second,
+	Jitter:      0.2,
+}
+
+// randFloat returns a value in [0, 1). It is a variable so tests can make
+// jitter deterministic.
+var randFloat = rand.Float64
+
+// Delay returns the wait before attempt number attempt+1, where attempt is the
+// 1-based number of the attempt that just failed. Jitter is not applied.
+func (p Policy) Delay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if p.MaxDelay > 0 && d >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return d
+}
+
+// jittered applies the policy's jitter to d.
+func (p Policy) jittered(d time.Duration) time.Duration {
+	if p.Jitter <= 0 || d <= 0 {
+		return d
+	}
+	delta := (randFloat()*2 - 1) * p.Jitter * float64(d)
+	return d + time.Duration(delta)
+}
+
+// Do calls fn until it succeeds, returns a non-retryable error, the policy's
+// attempts are exhausted, or ctx is done. It returns nil on success, the last
+// error returned by fn otherwise, or ctx.Err() if the context ends while
+// waiting between attempts.
+func Do(ctx context.Context, p Policy, fn func() error) error {
+	attempts := p.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if attempt >= attempts {
+			return err
+		}
+		if p.Retryable != nil && !p.Retryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(p.jittered(p.Delay(attempt)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errTransient = errors.New("transient")
+
+func TestDo_SucceedsFirstTry(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), Policy{MaxAttempts: 3}, func() error {
+		calls++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("fn called %d times, want 1", calls)
+	}
+}
+
+func TestDo_RetriesUntilSuccess(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), Policy{MaxAttempts: 5, BaseDelay: time.Millisecond}, func() error {
+		calls++
+		if calls < 3 {
+			return errTransient
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("fn called %d times, want 3", calls)
+	}
+}
+
+func TestDo_AttemptCounts(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxAttempts int
+		wantCalls   int
+	}{
+		{"zero means one attempt", 0, 1},
+		{"single attempt", 1, 1},
+		{"three attempts", 3, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := Do(context.Background(), Policy{MaxAttempts: tt.maxAttempts, BaseDelay: time.Millisecond}, func() error {
+				calls++
+				return errTransient
+			})
+			if !errors.Is(err, errTransient) {
+				t.Errorf("Do() error = %v, want %v", err, errTransient)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("fn called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestPolicy_Delay(t *testing.T) {
+	p := Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	want := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for i, w := range want {
+		if got := p.Delay(i + 1); got != w {
+			t.Errorf("Delay(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+
+	uncapped := Policy{BaseDelay: time.Second}
+	if got := uncapped.Delay(4); got != 8*time.Second {
+		t.Errorf("uncapped Delay(4) = %v, want 8s", got)
+	}
+}
+
+func TestPolicy_Jitter(t *testing.T) {
+	orig := randFloat
+	defer func() { randFloat = orig }()
+
+	p := Policy{Jitter: 0.5}
+	d := time.Second
+
+	randFloat = func() float64 { return 0 }
+	if got := p.jittered(d); got != 500*time.Millisecond {
+		t.Errorf("jittered() at lower bound = %v, want 500ms", got)
+	}
+	randFloat = func() float64 { return 0.5 }
+	if got := p.jittered(d); got != time.Second {
+		t.Errorf("jittered() at midpoint = %v, want 1s", got)
+	}
+
+	if got := (Policy{}).jittered(d); got != d {
+		t.Errorf("jittered() without jitter = %v, want %v", got, d)
+	}
+}
+
+func TestDo_RetryablePredicate(t *testing.T) {
+	errPermanent := errors.New("permanent")
+	p := Policy{
+		MaxAttempts: 5,
+		BaseDelay:   time.Millisecond,
+		Retryable:   func(err error) bool { return errors.Is(err, errTransient) },
+	}
+
+	calls := 0
+	err := Do(context.Background(), p, func() error {
+		calls++
+		if calls == 1 {
+			return errTransient
+		}
+		return errPermanent
+	})
+	if !errors.Is(err, errPermanent) {
+		t.Errorf("Do() error = %v, want %v", err, errPermanent)
+	}
+	if calls != 2 {
+		t.Errorf("fn called %d times, want 2 (stop at first non-retryable error)", calls)
+	}
+}
+
+func TestDo_ContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	start := time.Now()
+	err := Do(ctx, Policy{MaxAttempts: 5, BaseDelay: time.Hour}, func() error {
+		calls++
+		cancel()
+		return errTransient
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Do() error = %v, want context.Canceled", err)
+	}
+	if calls != 1 {
+		t.Errorf("fn called %d times, want 1", calls)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Do() should return promptly when the context is cancelled")
+	}
+}
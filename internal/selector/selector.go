@@ -38,8 +38,11 @@ type model struct {
 	refreshing  bool
 	refreshErr  error
 	lastRefresh time.Time
-	height      int // terminal height (0 = unknown)
-	width       int // terminal width (0 = unknown)
+	height      int    // terminal height (0 = unknown)
+	width       int    // terminal width (0 = unknown)
+	minSelect   int    // ENTER needs at least this many selected items (0 = no minimum)
+	maxSelect   int    // ENTER needs at most this many selected items (0 = no maximum)
+	warning     string // shown in the footer until the next key press
 }
 
 func newModel(items []Item, refreshFn func() ([]Item, error)) model {
@@ -153,6 +156,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tea.KeyMsg:
+		m.warning = ""
 		switch msg.Type {
 
 		case tea.KeyCtrlC:
@@ -170,6 +174,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case tea.KeyEnter:
+			if w := m.selectionWarning(); w != "" {
+				m.warning = w
+				return m, nil
+			}
 			m.done = true
 			return m, tea.Quit
 
@@ -229,6 +237,30 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// selectedCount returns how many items are selected.
+func (m model) selectedCount() int {
+	n := 0
+	for _, v := range m.selected {
+		if v {
+			n++
+		}
+	}
+	return n
+}
+
+// selectionWarning explains why the current selection cannot be confirmed,
+// or returns "" when it satisfies the min/max constraints.
+func (m model) selectionWarning() string {
+	n := m.selectedCount()
+	switch {
+	case m.minSelect > 0 && n < m.minSelect:
+		return fmt.Sprintf("select at least %d (%d selected)", m.minSelect, n)
+	case m.maxSelect > 0 && n > m.maxSelect:
+		return fmt.Sprintf("select at most %d (%d selected)", m.maxSelect, n)
+	}
+	return ""
+}
+
 func (m model) View() string {
 	var sb strings.Builder
 
@@ -261,12 +293,7 @@ func (m model) View() string {
 	}
 
 	// Footer.
-	nSel := 0
-	for _, v := range m.selected {
-		if v {
-			nSel++
-		}
-	}
+	nSel := m.selectedCount()
 
 	var refreshStatus string
 	switch {
@@ -278,23 +305,50 @@ func (m model) View() string {
 		refreshStatus = fmt.Sprintf("  [last refresh: %s]", m.lastRefresh.Local().Format("15:04:05"))
 	}
 
+	if m.warning != "" {
+		refreshStatus += "  [" + m.warning + "]"
+	}
+
 	fmt.Fprintf(&sb, "\n  %d/%d shown  %d selected  |  ↑↓ navigate  SPACE toggle  Ctrl+A all  Ctrl+R refresh  ENTER confirm  ESC cancel%s\n",
 		len(m.filtered), len(m.items), nSel, refreshStatus)
 
 	return sb.String()
 }
 
+// Options tunes the behaviour of Run.
+type Options struct {
+	// AutoSelectSingle makes Run return the only item immediately, without
+	// starting the TUI, when items contains exactly one entry.
+	AutoSelectSingle bool
+
+	// MinSelect and MaxSelect, when positive, bound how many items may be
+	// selected: ENTER is refused with a footer warning outside that range.
+	MinSelect int
+	MaxSelect int
+}
+
+// runProgram starts the bubbletea program for m and returns its final model.
+// It is a variable so tests can verify whether the TUI was started.
+var runProgram = func(m model) (tea.Model, error) {
+	return tea.NewProgram(m, tea.WithAltScreen()).Run()
+}
+
 // Run presents the interactive fuzzy multi-select UI and returns the indices
 // (into the original items slice) that the user selected.
 // Returns nil without an error if the user cancels (ESC or Ctrl+C).
 // refreshFn, if non-nil, is called when the user presses Ctrl+R to reload
 // the item list; previously-selected items are re-selected by Key.
-func Run(items []Item, refreshFn func() ([]Item, error)) ([]int, error) {
+func Run(items []Item, refreshFn func() ([]Item, error), opts Options) ([]int, error) {
 	if len(items) == 0 {
 		return nil, nil
 	}
-	p := tea.NewProgram(newModel(items, refreshFn), tea.WithAltScreen())
-	final, err := p.Run()
+	if len(items) == 1 && opts.AutoSelectSingle {
+		return []int{0}, nil
+	}
+	m := newModel(items, refreshFn)
+	m.minSelect = opts.MinSelect
+	m.maxSelect = opts.MaxSelect
+	final, err := runProgram(m)
 	if err != nil {
 		return nil, fmt.Errorf("selector: %w", err)
 	}
@@ -2,7 +2,10 @@ package selector
 
 import (
 	"fmt"
+	"strings"
 	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
 )
 
 func TestFuzzyMatch(t *testing.T) {
@@ -168,3 +171,98 @@ func TestViewportStart(t *testing.T) {
 		t.Errorf("cursor=19, vis=5: viewportStart=%d, want 15", got)
 	}
 }
+
+func TestRunAutoSelectSingle(t *testing.T) {
+	orig := runProgram
+	defer func() { runProgram = orig }()
+	started := false
+	runProgram = func(m model) (tea.Model, error) {
+		started = true
+		return m, nil
+	}
+
+	got, err := Run([]Item{{Label: "only-job", Key: "k"}}, nil, Options{AutoSelectSingle: true})
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if started {
+		t.Error("Run() started the TUI for a single item with AutoSelectSingle set")
+	}
+	if len(got) != 1 || got[0] != 0 {
+		t.Errorf("Run() = %v, want [0]", got)
+	}
+}
+
+func TestRunSingleItemWithoutAutoSelect(t *testing.T) {
+	orig := runProgram
+	defer func() { runProgram = orig }()
+	started := false
+	runProgram = func(m model) (tea.Model, error) {
+		started = true
+		m.quit = true
+		return m, nil
+	}
+
+	got, err := Run([]Item{{Label: "only-job"}}, nil, Options{})
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if !started {
+		t.Error("Run() should start the TUI when AutoSelectSingle is not set")
+	}
+	if got != nil {
+		t.Errorf("Run() = %v, want nil after cancel", got)
+	}
+}
+
+func TestEnterSelectionConstraints(t *testing.T) {
+	items := []Item{{Label: "a"}, {Label: "b"}, {Label: "c"}}
+	tests := []struct {
+		name        string
+		min, max    int
+		selected    []int
+		wantDone    bool
+		wantWarning string
+	}{
+		{name: "below min", min: 1, max: 2, selected: nil, wantWarning: "select at least 1 (0 selected)"},
+		{name: "above max", min: 1, max: 2, selected: []int{0, 1, 2}, wantWarning: "select at most 2 (3 selected)"},
+		{name: "within range", min: 1, max: 2, selected: []int{0, 2}, wantDone: true},
+		{name: "no constraints", selected: nil, wantDone: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newModel(items, nil)
+			m.minSelect, m.maxSelect = tt.min, tt.max
+			for _, i := range tt.selected {
+				m.selected[i] = true
+			}
+
+			next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+			got := next.(model)
+			if got.done != tt.wantDone {
+				t.Errorf("done = %v, want %v", got.done, tt.wantDone)
+			}
+			if got.warning != tt.wantWarning {
+				t.Errorf("warning = %q, want %q", got.warning, tt.wantWarning)
+			}
+			if !tt.wantDone && cmd != nil {
+				t.Error("refused ENTER should not quit the program")
+			}
+			if tt.wantWarning != "" && !strings.Contains(got.View(), tt.wantWarning) {
+				t.Errorf("View() footer does not show %q", tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestWarningClearedOnNextKey(t *testing.T) {
+	m := newModel([]Item{{Label: "a"}}, nil)
+	m.minSelect = 1
+
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	next, _ = next.(model).Update(tea.KeyMsg{Type: tea.KeySpace})
+	if w := next.(model).warning; w != "" {
+		t.Errorf("warning = %q after the next key, want it cleared", w)
+	}
+}
@@ -0,0 +1,176 @@
+// Package stats summarizes the outcome, duration and test results of a job's
+// recent builds.
+package stats
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/clobrano/prow-helper/internal/downloader"
+	"github.com/clobrano/prow-helper/internal/junit"
+	"github.com/clobrano/prow-helper/internal/parser"
+	"github.com/clobrano/prow-helper/internal/watcher"
+)
+
+// Summary aggregates the statuses of several builds of a job.
+type Summary struct {
+	Builds   int // builds considered
+	Finished int // builds with a finished.json
+	Passed   int // finished builds that passed
+
+	// Durations are computed over finished builds whose start time is known;
+	// Timed is how many those are. All are zero when Timed is zero.
+	Timed            int
+	Min, Median, Max time.Duration
+}
+
+// PassRate returns the fraction of finished builds that passed, or 0 when
+// none finished.
+func (s Summary) PassRate() float64 {
+	if s.Finished == 0 {
+		return 0
+	}
+	return float64(s.Passed) / float64(s.Finished)
+}
+
+// Summarize aggregates statuses, where a nil status is a build that has not
+// finished yet.
+func Summarize(statuses []*watcher.JobStatus) Summary {
+	s := Summary{Builds: len(statuses)}
+	var durations []time.Duration
+	for _, st := range statuses {
+		if st == nil || !st.Finished {
+			continue
+		}
+		s.Finished++
+		if st.Passed {
+			s.Passed++
+		}
+		if d := st.Duration(); d > 0 {
+			durations = append(durations, d)
+		}
+	}
+
+	s.Timed = len(durations)
+	if s.Timed == 0 {
+		return s
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	s.Min = durations[0]
+	s.Max = durations[len(durations)-1]
+	if mid := len(durations) / 2; len(durations)%2 == 1 {
+		s.Median = durations[mid]
+	} else {
+		s.Median = (durations[mid-1] + durations[mid]) / 2
+	}
+	return s
+}
+
+// Workers is how many builds Collect and CollectJUnit fetch at once.
+var Workers = 8
+
+// forEachBuild calls fn with each index in [0, n), running at most Workers
+// calls at a time, and returns when all of them have.
+func forEachBuild(n int, fn func(i int)) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(Workers, 1))
+	for i := range n {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}()
+	}
+	wg.Wait()
+}
+
+// Collect fetches finished.json and the start time of each build of the job
+// stored under gs://<bucket>/<jobPath>/, Workers builds at a time. The returned
+// slice is parallel to buildIDs, with nil for builds still running. A build
+// whose status cannot be fetched makes Collect fail; a missing start time only
+// leaves that build's duration unknown.
+func Collect(bucket, jobPath string, buildIDs []string) ([]*watcher.JobStatus, error) {
+	statuses := make([]*watcher.JobStatus, len(buildIDs))
+	errs := make([]error, len(buildIDs))
+
+	forEachBuild(len(buildIDs), func(i int) {
+		id := buildIDs[i]
+		metadata := &parser.ProwMetadata{Bucket: bucket, Path: jobPath + "/" + id, BuildID: id}
+		status, err := watcher.CheckJobStatus(watcher.BuildFinishedJSONURL(metadata))
+		if errors.Is(err, watcher.ErrIncompleteStatus) {
+			return // being written: count it as still running
+		}
+		if err != nil {
+			errs[i] = fmt.Errorf("build %s: %w", id, err)
+			return
+		}
+		if status != nil {
+			status.StartTime, _ = watcher.FetchJobStartTime(watcher.BuildStartedJSONURL(metadata))
+		}
+		statuses[i] = status
+	})
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return statuses, nil
+}
+
+// CollectJUnit fetches and parses the JUnit reports (junit*.xml) of each
+// build of the job stored under gs://<bucket>/<jobPath>/, Workers builds at a
+// time. The returned slice is parallel to buildIDs; a build without reports
+// (e.g. one still running) has empty Results.
+func CollectJUnit(bucket, jobPath string, buildIDs []string) ([]junit.Results, error) {
+	results := make([]junit.Results, len(buildIDs))
+	errs := make([]error, len(buildIDs))
+
+	forEachBuild(len(buildIDs), func(i int) {
+		id := buildIDs[i]
+		results[i], errs[i] = buildJUnit(bucket, jobPath+"/"+id+"/")
+		if errs[i] != nil {
+			errs[i] = fmt.Errorf("build %s: %w", id, errs[i])
+		}
+	})
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// buildJUnit merges the JUnit reports found under the build prefix.
+func buildJUnit(bucket, prefix string) (junit.Results, error) {
+	objects, err := downloader.ListObjects(bucket, prefix)
+	if err != nil {
+		return nil, err
+	}
+	results := make(junit.Results)
+	for _, obj := range objects {
+		if !junit.IsReport(path.Base(obj.Name)) {
+			continue
+		}
+		content, err := downloader.ReadObject(bucket, obj.Name)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := junit.Parse(bytes.NewReader(content))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", obj.Name, err)
+		}
+		for _, c := range parsed {
+			results.Add(c)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,207 @@
+package stats
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/clobrano/prow-helper/internal/httpclient"
+	"github.com/clobrano/prow-helper/internal/junit"
+	"github.com/clobrano/prow-helper/internal/watcher"
+)
+
+var t0 = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// finished returns the status of a finished build that ran for d (unknown
+// start time when d is zero).
+func finished(passed bool, d time.Duration) *watcher.JobStatus {
+	s := &watcher.JobStatus{Finished: true, Passed: passed, Timestamp: t0.Add(d)}
+	if d > 0 {
+		s.StartTime = t0
+	}
+	return s
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []*watcher.JobStatus
+		want     Summary
+	}{
+		{
+			name: "odd count",
+			statuses: []*watcher.JobStatus{
+				finished(true, 50*time.Minute),
+				finished(false, 70*time.Minute),
+				finished(true, 60*time.Minute),
+			},
+			want: Summary{Builds: 3, Finished: 3, Passed: 2, Timed: 3,
+				Min: 50 * time.Minute, Median: 60 * time.Minute, Max: 70 * time.Minute},
+		},
+		{
+			name: "even count averages the middle pair",
+			statuses: []*watcher.JobStatus{
+				finished(true, 40*time.Minute),
+				finished(true, 90*time.Minute),
+				finished(true, 60*time.Minute),
+				finished(true, 50*time.Minute),
+			},
+			want: Summary{Builds: 4, Finished: 4, Passed: 4, Timed: 4,
+				Min: 40 * time.Minute, Median: 55 * time.Minute, Max: 90 * time.Minute},
+		},
+		{
+			name: "running and untimed builds",
+			statuses: []*watcher.JobStatus{
+				nil,
+				finished(false, 0),
+				finished(true, 30*time.Minute),
+			},
+			want: Summary{Builds: 3, Finished: 2, Passed: 1, Timed: 1,
+				Min: 30 * time.Minute, Median: 30 * time.Minute, Max: 30 * time.Minute},
+		},
+		{
+			name:     "nothing finished",
+			statuses: []*watcher.JobStatus{nil, nil},
+			want:     Summary{Builds: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Summarize(tt.statuses); got != tt.want {
+				t.Errorf("Summarize() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummaryPassRate(t *testing.T) {
+	if got := (Summary{Finished: 4, Passed: 3}).PassRate(); got != 0.75 {
+		t.Errorf("PassRate() = %v, want 0.75", got)
+	}
+	if got := (Summary{Builds: 2}).PassRate(); got != 0 {
+		t.Errorf("PassRate() with nothing finished = %v, want 0", got)
+	}
+}
+
+// gcsFiles serves a fixed set of GCS objects keyed by URL path.
+type gcsFiles map[string]string
+
+func (f gcsFiles) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, ok := f[req.URL.Path]
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusNotFound
+	}
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}, Request: req}, nil
+}
+
+func TestCollect(t *testing.T) {
+	orig := httpclient.Client.Transport
+	t.Cleanup(func() { httpclient.Client.Transport = orig })
+	httpclient.Client.Transport = gcsFiles{
+		"/bucket/logs/job/1/started.json":  `{"timestamp": 1000}`,
+		"/bucket/logs/job/1/finished.json": `{"timestamp": 4600, "passed": true}`,
+		"/bucket/logs/job/2/finished.json": `{"timestamp": 5000, "passed": false}`,
+		"/bucket/logs/job/3/started.json":  `{"timestamp": 6000}`,
+		"/bucket/logs/job/4/finished.json": ``,
+	}
+
+	got, err := Collect("bucket", "logs/job", []string{"1", "2", "3", "4"})
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if len(got) != 4 {
+		t.Fatalf("Collect() returned %d statuses, want 4", len(got))
+	}
+	if got[0] == nil || !got[0].Passed || got[0].Duration() != time.Hour {
+		t.Errorf("build 1 = %+v, want passed after 1h", got[0])
+	}
+	if got[1] == nil || got[1].Passed || got[1].Duration() != 0 {
+		t.Errorf("build 2 = %+v, want failed with unknown duration", got[1])
+	}
+	if got[2] != nil || got[3] != nil {
+		t.Errorf("builds 3 and 4 = %+v, %+v; want nil (still running)", got[2], got[3])
+	}
+}
+
+func TestForEachBuild(t *testing.T) {
+	orig := Workers
+	t.Cleanup(func() { Workers = orig })
+	Workers = 3
+
+	var mu sync.Mutex
+	var running, peak int
+	seen := make([]bool, 20)
+	forEachBuild(len(seen), func(i int) {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		running--
+		seen[i] = true
+		mu.Unlock()
+	})
+
+	if peak > Workers {
+		t.Errorf("forEachBuild ran %d calls at once, want at most %d", peak, Workers)
+	}
+	for i, ok := range seen {
+		if !ok {
+			t.Errorf("forEachBuild skipped index %d", i)
+		}
+	}
+}
+
+// gcsBuckets serves GCS listings, keyed by prefix, and objects, keyed by URL
+// path.
+type gcsBuckets struct {
+	listings map[string]string
+	objects  gcsFiles
+}
+
+func (g gcsBuckets) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Path == "/storage/v1/b/bucket/o" {
+		body := `{"items":[` + g.listings[req.URL.Query().Get("prefix")] + `]}`
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}, Request: req}, nil
+	}
+	return g.objects.RoundTrip(req)
+}
+
+func TestCollectJUnit(t *testing.T) {
+	orig := httpclient.Client.Transport
+	t.Cleanup(func() { httpclient.Client.Transport = orig })
+	httpclient.Client.Transport = gcsBuckets{
+		listings: map[string]string{
+			"logs/job/1/": `{"name":"logs/job/1/build-log.txt"},{"name":"logs/job/1/artifacts/e2e/junit_e2e.xml"}`,
+			"logs/job/2/": `{"name":"logs/job/2/artifacts/e2e/junit_e2e.xml"}`,
+			"logs/job/3/": `{"name":"logs/job/3/started.json"}`,
+		},
+		objects: gcsFiles{
+			"/bucket/logs/job/1/artifacts/e2e/junit_e2e.xml": `<testsuite name="e2e"><testcase name="fencing"/></testsuite>`,
+			"/bucket/logs/job/2/artifacts/e2e/junit_e2e.xml": `<testsuite name="e2e"><testcase name="fencing"><failure message="timeout"/></testcase></testsuite>`,
+		},
+	}
+
+	got, err := CollectJUnit("bucket", "logs/job", []string{"1", "2", "3"})
+	if err != nil {
+		t.Fatalf("CollectJUnit() error = %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("CollectJUnit() returned %d results, want 3", len(got))
+	}
+	if s := got[0]["e2e: fencing"].Status; s != junit.StatusPassed {
+		t.Errorf("build 1 fencing = %q, want passed", s)
+	}
+	if c := got[1]["e2e: fencing"]; c.Status != junit.StatusFailed || c.Message != "timeout" {
+		t.Errorf("build 2 fencing = %+v, want failed with its message", c)
+	}
+	if len(got[2]) != 0 {
+		t.Errorf("build 3 = %v, want no results (no reports)", got[2])
+	}
+}
@@ -0,0 +1,126 @@
+package watcher
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/clobrano/prow-helper/internal/httpclient"
+	"github.com/clobrano/prow-helper/internal/parser"
+)
+
+// BuildLogURL converts a Prow URL to the GCS URL of the job's build-log.txt.
+// GCS URL: https://storage.googleapis.com/<bucket>/<path>/build-log.txt
+func BuildLogURL(metadata *parser.ProwMetadata) string {
+	return parser.GCSObjectURL(metadata.Bucket, metadata.Path+"/build-log.txt")
+}
+
+// ErrLogNotFound is returned by FetchLog when build-log.txt does not exist,
+// typically because the job has not finished uploading it yet.
+var ErrLogNotFound = errors.New("build log not found (the job may still be running)")
+
+// FetchLog copies the whole object at logURL to w and returns the number of
+// bytes written. A missing object yields an error wrapping ErrLogNotFound.
+func FetchLog(logURL string, w io.Writer) (int64, error) {
+	resp, err := httpclient.Get(logURL)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch build log: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return 0, fmt.Errorf("%w: %s", ErrLogNotFound, logURL)
+	default:
+		return 0, fmt.Errorf("unexpected status code fetching build log: %d", resp.StatusCode)
+	}
+
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return n, fmt.Errorf("failed to read build log: %w", err)
+	}
+	return n, nil
+}
+
+// FetchLogChunk returns the bytes of the object at logURL from offset onward,
+// using an HTTP range request, together with the offset to use next time.
+// A missing object (404) or no new bytes (416) yield no data and no error.
+// If the server ignores the range and returns the whole object, the bytes
+// before offset are dropped.
+func FetchLogChunk(logURL string, offset int64) ([]byte, int64, error) {
+	req, err := http.NewRequest(http.MethodGet, logURL, nil)
+	if err != nil {
+		return nil, offset, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+
+	resp, err := httpclient.Do(req)
+	if err != nil {
+		return nil, offset, fmt.Errorf("failed to fetch build log: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotFound, http.StatusRequestedRangeNotSatisfiable:
+		return nil, offset, nil
+	case http.StatusPartialContent, http.StatusOK:
+	default:
+		return nil, offset, fmt.Errorf("unexpected status code fetching build log: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, offset, fmt.Errorf("failed to read build log: %w", err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		// Range ignored: the body is the whole object.
+		if int64(len(body)) <= offset {
+			return nil, offset, nil
+		}
+		body = body[offset:]
+	}
+	return body, offset + int64(len(body)), nil
+}
+
+// FollowLog prints the object at logURL to w as it grows, like tail -f,
+// checking for new bytes every interval. It returns once finishedURL shows
+// the job finished (after printing the remaining bytes) or when stop is
+// closed. Fetch errors are reported on w and retried at the next interval.
+func FollowLog(logURL, finishedURL string, interval time.Duration, w io.Writer, stop <-chan struct{}) (*JobStatus, error) {
+	var offset int64
+	flush := func() {
+		data, next, err := FetchLogChunk(logURL, offset)
+		if err != nil {
+			fmt.Fprintf(w, "Warning: %v\n", err)
+			return
+		}
+		w.Write(data)
+		offset = next
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		flush()
+
+		status, err := CheckJobStatus(finishedURL)
+		if err != nil && !errors.Is(err, ErrIncompleteStatus) {
+			return nil, err
+		}
+		if status != nil {
+			// The log may have grown between the last fetch and finished.json.
+			flush()
+			return status, nil
+		}
+
+		select {
+		case <-stop:
+			return nil, nil
+		case <-ticker.C:
+		}
+	}
+}
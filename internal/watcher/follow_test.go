@@ -0,0 +1,161 @@
+package watcher
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// growingLog serves a build log that gains one chunk per request, honouring
+// "Range: bytes=N-" like GCS does, and finished.json once every chunk is out.
+type growingLog struct {
+	mu          sync.Mutex
+	chunks      []string
+	served      int
+	ignoreRange bool
+	ranges      []string
+}
+
+func (g *growingLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if strings.HasSuffix(r.URL.Path, "/finished.json") {
+		if g.served < len(g.chunks) {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"timestamp": 1700000000, "passed": true}`)
+		return
+	}
+
+	if g.served < len(g.chunks) {
+		g.served++
+	}
+	body := strings.Join(g.chunks[:g.served], "")
+	rng := r.Header.Get("Range")
+	g.ranges = append(g.ranges, rng)
+	if g.ignoreRange || rng == "" {
+		fmt.Fprint(w, body)
+		return
+	}
+	offset, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+	if err != nil {
+		http.Error(w, "bad range", http.StatusBadRequest)
+		return
+	}
+	if offset >= len(body) {
+		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, len(body)-1, len(body)))
+	w.WriteHeader(http.StatusPartialContent)
+	fmt.Fprint(w, body[offset:])
+}
+
+func TestFetchLogChunk_Incremental(t *testing.T) {
+	log := &growingLog{chunks: []string{"line 1\n", "line 2\nline 3\n", "", "line 4\n"}}
+	server := httptest.NewServer(log)
+	defer server.Close()
+
+	var offset int64
+	var got []string
+	for i := 0; i < 5; i++ {
+		data, next, err := FetchLogChunk(server.URL+"/build-log.txt", offset)
+		if err != nil {
+			t.Fatalf("FetchLogChunk() error = %v", err)
+		}
+		got = append(got, string(data))
+		offset = next
+	}
+
+	want := []string{"line 1\n", "line 2\nline 3\n", "", "line 4\n", ""}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("chunks = %q, want %q", got, want)
+	}
+	wantRanges := []string{"bytes=0-", "bytes=7-", "bytes=21-", "bytes=21-", "bytes=28-"}
+	if fmt.Sprint(log.ranges) != fmt.Sprint(wantRanges) {
+		t.Errorf("Range headers = %q, want %q", log.ranges, wantRanges)
+	}
+}
+
+func TestFetchLogChunk_RangeIgnored(t *testing.T) {
+	log := &growingLog{chunks: []string{"abc", "def"}, ignoreRange: true}
+	server := httptest.NewServer(log)
+	defer server.Close()
+
+	data, offset, err := FetchLogChunk(server.URL+"/build-log.txt", 0)
+	if err != nil || string(data) != "abc" || offset != 3 {
+		t.Fatalf("first fetch = %q, %d, %v; want \"abc\", 3, nil", data, offset, err)
+	}
+	data, offset, err = FetchLogChunk(server.URL+"/build-log.txt", offset)
+	if err != nil || string(data) != "def" || offset != 6 {
+		t.Errorf("second fetch = %q, %d, %v; want \"def\", 6, nil", data, offset, err)
+	}
+}
+
+func TestFetchLogChunk_NotFound(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	data, offset, err := FetchLogChunk(server.URL+"/build-log.txt", 5)
+	if err != nil || len(data) != 0 || offset != 5 {
+		t.Errorf("FetchLogChunk() = %q, %d, %v; want no data, 5, nil", data, offset, err)
+	}
+}
+
+func TestFetchLog(t *testing.T) {
+	const body = "line 1\nline 2\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bucket/logs/job/1/build-log.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	n, err := FetchLog(server.URL+"/bucket/logs/job/1/build-log.txt", &buf)
+	if err != nil {
+		t.Fatalf("FetchLog() error = %v", err)
+	}
+	if buf.String() != body || n != int64(len(body)) {
+		t.Errorf("FetchLog() wrote %q (%d bytes), want %q", buf.String(), n, body)
+	}
+
+	buf.Reset()
+	_, err = FetchLog(server.URL+"/bucket/logs/job/2/build-log.txt", &buf)
+	if !errors.Is(err, ErrLogNotFound) {
+		t.Errorf("FetchLog() error = %v, want ErrLogNotFound", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("FetchLog() wrote %q for a missing log", buf.String())
+	}
+}
+
+func TestFollowLog_StopsWhenFinished(t *testing.T) {
+	log := &growingLog{chunks: []string{"starting\n", "running tests\n", "done\n"}}
+	server := httptest.NewServer(log)
+	defer server.Close()
+
+	var out bytes.Buffer
+	status, err := FollowLog(server.URL+"/build-log.txt", server.URL+"/finished.json",
+		time.Millisecond, &out, make(chan struct{}))
+	if err != nil {
+		t.Fatalf("FollowLog() error = %v", err)
+	}
+	if status == nil || !status.Passed {
+		t.Errorf("FollowLog() status = %+v, want passed", status)
+	}
+	if want := "starting\nrunning tests\ndone\n"; out.String() != want {
+		t.Errorf("FollowLog() output = %q, want %q", out.String(), want)
+	}
+}
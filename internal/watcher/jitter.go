@@ -0,0 +1,35 @@
+package watcher
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// IntervalJitter randomizes every wait between two polls of Watch and of the
+// monitor by up to ±IntervalJitter percent of the interval, so that users
+// polling the same jobs do not hit GCS at the same instants. It is set from
+// --interval-jitter; zero keeps the fixed interval.
+var IntervalJitter float64
+
+// randFloat returns a value in [0, 1). It is a variable so tests can make
+// jitter deterministic.
+var randFloat = rand.Float64
+
+// ValidateJitter checks that a jitter percentage is in [0, 100).
+func ValidateJitter(percent float64) error {
+	if percent < 0 || percent >= 100 {
+		return fmt.Errorf("interval jitter must be at least 0 and below 100 (percent), got %g", percent)
+	}
+	return nil
+}
+
+// JitteredInterval returns interval moved by a random amount of up to
+// ±percent percent of it. A non-positive percent returns interval unchanged.
+func JitteredInterval(interval time.Duration, percent float64) time.Duration {
+	if percent <= 0 || interval <= 0 {
+		return interval
+	}
+	delta := (randFloat()*2 - 1) * percent / 100 * float64(interval)
+	return interval + time.Duration(delta)
+}
@@ -0,0 +1,51 @@
+package watcher
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJitteredInterval_Bounds(t *testing.T) {
+	interval := 10 * time.Minute
+	tests := []struct {
+		name    string
+		percent float64
+		rand    float64
+		want    time.Duration
+	}{
+		{"no jitter", 0, 0.9, interval},
+		{"lowest", 20, 0, 8 * time.Minute},
+		{"middle", 20, 0.5, interval},
+		{"highest", 20, 1, 12 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := randFloat
+			randFloat = func() float64 { return tt.rand }
+			t.Cleanup(func() { randFloat = orig })
+
+			got := JitteredInterval(interval, tt.percent)
+			if diff := got - tt.want; diff < -time.Millisecond || diff > time.Millisecond {
+				t.Errorf("JitteredInterval(%v, %g) = %v, want %v", interval, tt.percent, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJitteredInterval_Random(t *testing.T) {
+	interval := time.Minute
+	lo, hi := interval-interval/4, interval+interval/4
+	for i := 0; i < 1000; i++ {
+		if got := JitteredInterval(interval, 25); got < lo || got > hi {
+			t.Fatalf("JitteredInterval(%v, 25) = %v, want within [%v, %v]", interval, got, lo, hi)
+		}
+	}
+}
+
+func TestValidateJitter(t *testing.T) {
+	for percent, wantErr := range map[float64]bool{0: false, 10: false, 99.5: false, -1: true, 100: true, 150: true} {
+		if err := ValidateJitter(percent); (err != nil) != wantErr {
+			t.Errorf("ValidateJitter(%g) error = %v, want error = %v", percent, err, wantErr)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package watcher
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/clobrano/prow-helper/internal/prowapi"
+)
+
+// fetchProwJob looks a job up in Prow's /prowjobs.js. It is a variable so
+// tests can fake the Prow API.
+var fetchProwJob = prowapi.FetchJob
+
+// PhaseTracker follows the Prow state of a job (triggered, pending, success,
+// …) as listed by the Prow /prowjobs.js API, which finished.json cannot tell
+// while the job runs. It warns early about a job stuck waiting to be
+// scheduled.
+type PhaseTracker struct {
+	JobURL   string                // Prow URL of the job (.../view/gs/<bucket>/<path>)
+	OnChange func(from, to string) // optional, called when the state changes after the first check
+
+	state string
+}
+
+// Update fetches the job's current state and returns it with the previously
+// known one ("" on the first call). On error the known state is kept.
+func (p *PhaseTracker) Update() (from, to string, err error) {
+	job, err := fetchProwJob(p.JobURL)
+	if err != nil {
+		return p.state, p.state, err
+	}
+	from, p.state = p.state, job.State
+	if from != "" && from != p.state && p.OnChange != nil {
+		p.OnChange(from, p.state)
+	}
+	return from, p.state, nil
+}
+
+// updatePhase updates phases, if set, and prints the job's state to w the
+// first time it is known and whenever it changes. A failed lookup only
+// produces a warning.
+func updatePhase(w io.Writer, phases *PhaseTracker) {
+	if phases == nil {
+		return
+	}
+	from, to, err := phases.Update()
+	switch {
+	case err != nil:
+		fmt.Fprintf(w, "\r%-100s\n", fmt.Sprintf("Warning: could not read job state: %v", err))
+	case from == "":
+		fmt.Fprintf(w, "\r%-100s\n", "Job state: "+to)
+	case from != to:
+		fmt.Fprintf(w, "\r%-100s\n", fmt.Sprintf("Job state: %s -> %s", from, to))
+	}
+}
@@ -0,0 +1,64 @@
+package watcher
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/clobrano/prow-helper/internal/prowapi"
+)
+
+// fakeProwStates makes fetchProwJob return the given states one call at a
+// time; an empty state stands for a lookup error.
+func fakeProwStates(t *testing.T, states ...string) {
+	t.Helper()
+	orig := fetchProwJob
+	t.Cleanup(func() { fetchProwJob = orig })
+	fetchProwJob = func(string) (*prowapi.Job, error) {
+		if len(states) == 0 {
+			t.Fatal("unexpected Prow lookup")
+		}
+		state := states[0]
+		states = states[1:]
+		if state == "" {
+			return nil, errors.New("prowjobs.js unreachable")
+		}
+		return &prowapi.Job{Name: "job", State: state}, nil
+	}
+}
+
+func TestPhaseTracker_Transitions(t *testing.T) {
+	fakeProwStates(t, "triggered", "triggered", "", "pending", "success")
+
+	var changes []string
+	p := &PhaseTracker{JobURL: "https://prow.example.com/view/gs/b/logs/job/1", OnChange: func(from, to string) {
+		changes = append(changes, from+"->"+to)
+	}}
+
+	var buf bytes.Buffer
+	for i := 0; i < 5; i++ {
+		updatePhase(&buf, p)
+	}
+
+	if got := strings.Join(changes, ","); got != "triggered->pending,pending->success" {
+		t.Errorf("OnChange calls = %q, want triggered->pending,pending->success", got)
+	}
+	out := buf.String()
+	for _, want := range []string{"Job state: triggered", "could not read job state", "Job state: triggered -> pending", "Job state: pending -> success"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output %q should contain %q", out, want)
+		}
+	}
+	if n := strings.Count(out, "Job state:"); n != 3 {
+		t.Errorf("printed %d state lines, want 3 (an unchanged state is not repeated):\n%s", n, out)
+	}
+}
+
+func TestUpdatePhase_Nil(t *testing.T) {
+	var buf bytes.Buffer
+	updatePhase(&buf, nil)
+	if buf.Len() != 0 {
+		t.Errorf("updatePhase(nil) wrote %q", buf.String())
+	}
+}
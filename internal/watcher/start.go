@@ -0,0 +1,54 @@
+package watcher
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/clobrano/prow-helper/internal/parser"
+)
+
+// DefaultStartPollInterval is the default time between started.json checks
+// while waiting for a job to start. Scheduling takes minutes rather than
+// hours, so it is shorter than DefaultPollInterval.
+const DefaultStartPollInterval = 30 * time.Second
+
+// ErrStartTimeout is returned by WaitForStart when started.json did not
+// appear within the timeout.
+var ErrStartTimeout = errors.New("job did not start in time")
+
+// WaitForStart polls started.json (or StartedFile) every interval until it
+// exists and returns the start time read from it, which is zero when the file
+// has no usable StartedField. Errors fetching the file are retried until
+// timeout; a timeout of zero waits indefinitely.
+func WaitForStart(metadata *parser.ProwMetadata, interval, timeout time.Duration) (time.Time, error) {
+	startedURL := BuildStartedJSONURL(metadata)
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	var lastErr error
+	for {
+		startTime, found, err := fetchStarted(startedURL)
+		if found {
+			return startTime, nil
+		}
+		if err != nil {
+			lastErr = err
+		}
+
+		wait := JitteredInterval(interval, IntervalJitter)
+		if !deadline.IsZero() {
+			left := time.Until(deadline)
+			if left <= 0 {
+				if lastErr != nil {
+					return time.Time{}, fmt.Errorf("%w after %s (last error: %v)", ErrStartTimeout, timeout, lastErr)
+				}
+				return time.Time{}, fmt.Errorf("%w after %s", ErrStartTimeout, timeout)
+			}
+			wait = min(wait, left)
+		}
+		time.Sleep(wait)
+	}
+}
@@ -0,0 +1,77 @@
+package watcher
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clobrano/prow-helper/internal/httpclient"
+	"github.com/clobrano/prow-helper/internal/parser"
+)
+
+// startedServer serves started.json with body from the request numbered
+// startAfter onward (counting from 1), and 404 before. It returns a pointer
+// to the number of started.json requests.
+func startedServer(t *testing.T, startAfter int, body string) *int {
+	t.Helper()
+	requests := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/started.json") {
+			http.NotFound(w, r)
+			return
+		}
+		requests++
+		if startAfter == 0 || requests < startAfter {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	origHost, origTransport := parser.GCSHost, httpclient.Client.Transport
+	t.Cleanup(func() { parser.GCSHost, httpclient.Client.Transport = origHost, origTransport })
+	parser.GCSHost = strings.TrimPrefix(server.URL, "https://")
+	httpclient.Client.Transport = server.Client().Transport
+	return &requests
+}
+
+func TestWaitForStart(t *testing.T) {
+	metadata := &parser.ProwMetadata{JobName: "job", BuildID: "1", Bucket: "bucket", Path: "logs/job/1"}
+
+	t.Run("404 then started", func(t *testing.T) {
+		requests := startedServer(t, 3, `{"timestamp": 1708770600}`)
+		got, err := WaitForStart(metadata, time.Millisecond, time.Minute)
+		if err != nil {
+			t.Fatalf("WaitForStart() error = %v", err)
+		}
+		if want := time.Unix(1708770600, 0); !got.Equal(want) {
+			t.Errorf("WaitForStart() = %v, want %v", got, want)
+		}
+		if *requests != 3 {
+			t.Errorf("started.json requested %d times, want 3", *requests)
+		}
+	})
+
+	t.Run("started without a timestamp", func(t *testing.T) {
+		startedServer(t, 1, `{"node": "worker-1"}`)
+		got, err := WaitForStart(metadata, time.Millisecond, time.Minute)
+		if err != nil {
+			t.Fatalf("WaitForStart() error = %v", err)
+		}
+		if !got.IsZero() {
+			t.Errorf("WaitForStart() = %v, want the zero time", got)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		startedServer(t, 0, "")
+		_, err := WaitForStart(metadata, time.Millisecond, 20*time.Millisecond)
+		if !errors.Is(err, ErrStartTimeout) {
+			t.Errorf("WaitForStart() error = %v, want ErrStartTimeout", err)
+		}
+	})
+}
@@ -1,63 +1,144 @@
 package watcher
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/clobrano/prow-helper/internal/httpclient"
+	"github.com/clobrano/prow-helper/internal/jsonpath"
 	"github.com/clobrano/prow-helper/internal/output"
 	"github.com/clobrano/prow-helper/internal/parser"
+	"github.com/clobrano/prow-helper/internal/retry"
 )
 
 const (
 	// DefaultPollInterval is the default time between status checks
 	DefaultPollInterval = 15 * time.Minute
 
-	// GCSBaseURL is the base URL for Google Cloud Storage
-	GCSBaseURL = "https://storage.googleapis.com"
+	// MaxIncompleteChecks is how many consecutive ErrIncompleteStatus results
+	// a caller should tolerate before treating the job as errored.
+	MaxIncompleteChecks = 3
 )
 
+// StartedFile and StartedField locate the job's start time: the artifact file
+// holding it and the dot-delimited JSON path of the value within it (Unix
+// seconds or an RFC 3339 string). They are set from the started_file and
+// started_field settings for job layouts that differ from the default.
+var (
+	StartedFile  = "started.json"
+	StartedField = "timestamp"
+)
+
+// MaxRetries is how many more times CheckJobStatus fetches finished.json
+// after a network error or a 5xx response. It waits InitialBackoff before the
+// first retry and twice as long before each following one.
+var (
+	MaxRetries     = 3
+	InitialBackoff = 2 * time.Second
+)
+
+// transientError marks a failed finished.json fetch worth retrying: a network
+// error or a 5xx response.
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+
+func (e *transientError) Unwrap() error { return e.err }
+
+// ErrIncompleteStatus is returned by CheckJobStatus when finished.json is
+// served but its body is not valid JSON. GCS may briefly serve an empty or
+// error body while the object is being written, so this is usually transient
+// and the check should be retried.
+var ErrIncompleteStatus = errors.New("finished.json is not readable yet")
+
+// ErrWatchTimeout is returned by Watch when the job has not finished within
+// the timeout.
+var ErrWatchTimeout = errors.New("job did not finish in time")
+
 // JobStatus represents the current status of a Prow job
 type JobStatus struct {
 	Finished  bool
 	Passed    bool
+	Result    string // result of finished.json, e.g. "SUCCESS", "FAILURE" or "ABORTED"
 	Timestamp time.Time
+	StartTime time.Time // from started.json when known, zero otherwise
+}
+
+// Duration returns how long the job ran, or zero if either end is unknown.
+func (s *JobStatus) Duration() time.Duration {
+	if s == nil || s.StartTime.IsZero() || s.Timestamp.IsZero() {
+		return 0
+	}
+	return s.Timestamp.Sub(s.StartTime)
 }
 
-// finishedJSON represents the structure of finished.json from Prow
+// finishedJSON represents the structure of finished.json from Prow. Older
+// artifacts only have result; passed is nil when absent.
 type finishedJSON struct {
 	Timestamp int64  `json:"timestamp"`
-	Passed    bool   `json:"passed"`
+	Passed    *bool  `json:"passed,omitempty"`
 	Result    string `json:"result"`
 }
 
-// startedJSON represents the structure of started.json from Prow
-type startedJSON struct {
-	Timestamp int64 `json:"timestamp"`
+// passed reports whether the job passed: from passed when present, and else
+// from a SUCCESS result.
+func (f finishedJSON) passed() bool {
+	if f.Passed != nil {
+		return *f.Passed
+	}
+	return f.Result == "SUCCESS"
 }
 
 // BuildFinishedJSONURL converts a Prow URL to the GCS finished.json URL.
 // Prow URL: https://prow.ci.openshift.org/view/gs/<bucket>/<path>
 // GCS URL:  https://storage.googleapis.com/<bucket>/<path>/finished.json
 func BuildFinishedJSONURL(metadata *parser.ProwMetadata) string {
-	return fmt.Sprintf("%s/%s/%s/finished.json", GCSBaseURL, metadata.Bucket, metadata.Path)
+	return parser.GCSObjectURL(metadata.Bucket, metadata.Path+"/finished.json")
 }
 
-// BuildStartedJSONURL converts a Prow URL to the GCS started.json URL.
+// BuildStartedJSONURL converts a Prow URL to the GCS URL of StartedFile.
 // GCS URL: https://storage.googleapis.com/<bucket>/<path>/started.json
 func BuildStartedJSONURL(metadata *parser.ProwMetadata) string {
-	return fmt.Sprintf("%s/%s/%s/started.json", GCSBaseURL, metadata.Bucket, metadata.Path)
+	return parser.GCSObjectURL(metadata.Bucket, metadata.Path+"/"+StartedFile)
 }
 
 // CheckJobStatus fetches finished.json and returns the job status.
 // Returns nil status if the job is still running (404 response).
+// A body that cannot be parsed yields an error wrapping ErrIncompleteStatus.
+// Network errors and 5xx responses are retried up to MaxRetries times, with
+// exponential backoff from InitialBackoff.
 func CheckJobStatus(finishedURL string) (*JobStatus, error) {
-	resp, err := http.Get(finishedURL)
+	policy := retry.Policy{
+		MaxAttempts: MaxRetries + 1,
+		BaseDelay:   InitialBackoff,
+		Retryable: func(err error) bool {
+			var transient *transientError
+			return errors.As(err, &transient)
+		},
+	}
+	var status *JobStatus
+	err := retry.Do(context.Background(), policy, func() error {
+		var err error
+		status, err = checkJobStatusOnce(finishedURL)
+		return err
+	})
+	return status, err
+}
+
+// checkJobStatusOnce is a single CheckJobStatus request. Its failures worth
+// retrying are transientErrors.
+func checkJobStatusOnce(finishedURL string) (*JobStatus, error) {
+	resp, err := httpclient.Get(finishedURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch job status: %w", err)
+		return nil, &transientError{fmt.Errorf("failed to fetch job status: %w", err)}
 	}
 	defer resp.Body.Close()
 
@@ -66,6 +147,9 @@ func CheckJobStatus(finishedURL string) (*JobStatus, error) {
 		return nil, nil
 	}
 
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, &transientError{fmt.Errorf("unexpected status code: %d", resp.StatusCode)}
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
@@ -77,55 +161,79 @@ func CheckJobStatus(finishedURL string) (*JobStatus, error) {
 
 	var finished finishedJSON
 	if err := json.Unmarshal(body, &finished); err != nil {
-		return nil, fmt.Errorf("failed to parse finished.json: %w", err)
+		return nil, fmt.Errorf("%w: %v", ErrIncompleteStatus, err)
 	}
 
 	return &JobStatus{
 		Finished:  true,
-		Passed:    finished.Passed,
+		Passed:    finished.passed(),
+		Result:    finished.Result,
 		Timestamp: time.Unix(finished.Timestamp, 0),
 	}, nil
 }
 
-// FetchJobStartTime fetches started.json and returns the job start time.
-// Returns a zero time.Time if the file is not yet available (404).
+// FetchJobStartTime fetches started.json (or StartedFile) and returns the job
+// start time read from its StartedField.
+// Returns a zero time.Time if the file is not yet available (404) or the
+// field is missing or zero.
 func FetchJobStartTime(startedURL string) (time.Time, error) {
-	resp, err := http.Get(startedURL)
+	startTime, _, err := fetchStarted(startedURL)
+	return startTime, err
+}
+
+// fetchStarted fetches started.json and returns the start time it holds.
+// found reports whether the file exists; the time is zero when it does not
+// or when it has no StartedField.
+func fetchStarted(startedURL string) (startTime time.Time, found bool, err error) {
+	resp, err := httpclient.Get(startedURL)
 	if err != nil {
-		return time.Time{}, fmt.Errorf("failed to fetch started.json: %w", err)
+		return time.Time{}, false, fmt.Errorf("failed to fetch %s: %w", StartedFile, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return time.Time{}, nil
+		return time.Time{}, false, nil
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return time.Time{}, fmt.Errorf("unexpected status code fetching started.json: %d", resp.StatusCode)
+		return time.Time{}, false, fmt.Errorf("unexpected status code fetching %s: %d", StartedFile, resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return time.Time{}, fmt.Errorf("failed to read started.json body: %w", err)
+		return time.Time{}, false, fmt.Errorf("failed to read %s body: %w", StartedFile, err)
 	}
 
-	var started startedJSON
-	if err := json.Unmarshal(body, &started); err != nil {
-		return time.Time{}, fmt.Errorf("failed to parse started.json: %w", err)
+	startTime, err = jsonpath.LookupTime(body, StartedField)
+	if errors.Is(err, jsonpath.ErrNotFound) {
+		return time.Time{}, true, nil
 	}
-
-	if started.Timestamp == 0 {
-		return time.Time{}, nil
+	if err != nil {
+		return time.Time{}, true, fmt.Errorf("failed to parse %s: %w", StartedFile, err)
 	}
 
-	return time.Unix(started.Timestamp, 0), nil
+	return startTime, true, nil
 }
 
 // Watch polls the job status until the job completes.
 // It checks finished.json at the specified interval until the job finishes.
 // Returns the final job status when complete.
-func Watch(metadata *parser.ProwMetadata, interval time.Duration, w io.Writer) (*JobStatus, error) {
+// When fetchStartTime is false, started.json is not fetched: the start time
+// and elapsed time are left out of the output and of the returned status.
+// When the job is still running after timeout, Watch gives up with an error
+// wrapping ErrWatchTimeout; a timeout of zero waits indefinitely.
+func Watch(metadata *parser.ProwMetadata, interval, timeout time.Duration, w io.Writer, fetchStartTime bool) (*JobStatus, error) {
+	return WatchWithPhases(metadata, interval, timeout, w, nil, fetchStartTime)
+}
+
+// WatchWithPhases is Watch that, when phases is non-nil, also updates it at
+// each check while the job runs and prints its state transitions to w.
+func WatchWithPhases(metadata *parser.ProwMetadata, interval, timeout time.Duration, w io.Writer, phases *PhaseTracker, fetchStartTime bool) (*JobStatus, error) {
 	finishedURL := BuildFinishedJSONURL(metadata)
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
 
 	output.PrintField(w, "Watching job", metadata.JobName)
 	output.PrintField(w, "Build ID", metadata.BuildID)
@@ -133,63 +241,89 @@ func Watch(metadata *parser.ProwMetadata, interval time.Duration, w io.Writer) (
 		output.PrintField(w, "Job page", metadata.RawURL)
 	}
 	output.PrintField(w, "Polling interval", interval.String())
+	if timeout > 0 {
+		output.PrintField(w, "Timeout", timeout.String())
+	}
 	output.PrintField(w, "Checking", finishedURL)
 
 	// Fetch job start time from started.json (best-effort)
-	startedURL := BuildStartedJSONURL(metadata)
-	startTime, err := FetchJobStartTime(startedURL)
-	if err != nil {
-		fmt.Fprintf(w, "Note: could not fetch job start time: %v\n", err)
+	var startTime time.Time
+	if fetchStartTime {
+		var err error
+		startTime, err = FetchJobStartTime(BuildStartedJSONURL(metadata))
+		if err != nil {
+			fmt.Fprintf(w, "Note: could not fetch job start time: %v\n", err)
+		}
 	}
 	if !startTime.IsZero() {
 		output.PrintField(w, "Started at", startTime.Format("2006-01-02 15:04:05"))
 	}
 
-	// Check immediately first
+	// Check immediately first. An unreadable finished.json is treated as
+	// still running: the polling loop below retries it.
 	status, err := CheckJobStatus(finishedURL)
-	if err != nil {
+	if err != nil && !errors.Is(err, ErrIncompleteStatus) {
 		return nil, err
 	}
 	if status != nil {
 		fmt.Fprintf(w, "Job already finished\n")
+		status.StartTime = startTime
 		return status, nil
 	}
 
 	fmt.Fprintf(w, "Job is running, waiting for completion...\n")
 	output.PrintStatus(w, output.StatusRunning)
+	updatePhase(w, phases)
 
-	checkTicker := time.NewTicker(interval)
-	defer checkTicker.Stop()
+	// A timer rather than a ticker, so that each wait gets its own jitter.
+	wait := JitteredInterval(interval, IntervalJitter)
+	checkTimer := time.NewTimer(wait)
+	defer checkTimer.Stop()
 	countdownTicker := time.NewTicker(time.Second)
 	defer countdownTicker.Stop()
+	var timeoutC <-chan time.Time
+	if !deadline.IsZero() {
+		timeoutTimer := time.NewTimer(time.Until(deadline))
+		defer timeoutTimer.Stop()
+		timeoutC = timeoutTimer.C
+	}
 
 	lastCheckTime := time.Now()
-	nextCheckTime := lastCheckTime.Add(interval)
-	printCountdown(w, startTime, lastCheckTime, nextCheckTime)
+	nextCheckTime := lastCheckTime.Add(wait)
+	printCountdown(w, startTime, lastCheckTime, nextCheckTime, deadline)
 
 	for {
 		select {
-		case t := <-checkTicker.C:
+		case <-timeoutC:
+			fmt.Fprintln(w)
+			return nil, fmt.Errorf("%w after %s", ErrWatchTimeout, timeout)
+
+		case t := <-checkTimer.C:
+			updatePhase(w, phases)
 			status, err := CheckJobStatus(finishedURL)
 			if err != nil {
 				fmt.Fprintf(w, "\r%-100s\n", fmt.Sprintf("Warning: %v", err))
 			} else if status != nil {
 				fmt.Fprintf(w, "\r%-100s\n", "Job completed!")
+				status.StartTime = startTime
 				return status, nil
 			}
+			wait = JitteredInterval(interval, IntervalJitter)
+			checkTimer.Reset(wait)
 			lastCheckTime = t
-			nextCheckTime = t.Add(interval)
-			printCountdown(w, startTime, lastCheckTime, nextCheckTime)
+			nextCheckTime = time.Now().Add(wait)
+			printCountdown(w, startTime, lastCheckTime, nextCheckTime, deadline)
 
 		case <-countdownTicker.C:
-			printCountdown(w, startTime, lastCheckTime, nextCheckTime)
+			printCountdown(w, startTime, lastCheckTime, nextCheckTime, deadline)
 		}
 	}
 }
 
 // printCountdown overwrites the current terminal line with elapsed time since
-// the job started, the last check time, and a live countdown to the next check.
-func printCountdown(w io.Writer, startTime, lastCheck, nextCheck time.Time) {
+// the job started, the last check time, and a live countdown to the next check
+// and, when deadline is set, to the watch timeout.
+func printCountdown(w io.Writer, startTime, lastCheck, nextCheck, deadline time.Time) {
 	timeLeft := time.Until(nextCheck).Truncate(time.Second)
 	if timeLeft < 0 {
 		timeLeft = 0
@@ -203,6 +337,9 @@ func printCountdown(w io.Writer, startTime, lastCheck, nextCheck time.Time) {
 	}
 	parts = append(parts, fmt.Sprintf("[last check: %s]", lastCheck.Format("15:04:05")))
 	parts = append(parts, fmt.Sprintf("[next check in: %s]", timeLeft))
+	if !deadline.IsZero() {
+		parts = append(parts, fmt.Sprintf("[timeout in: %s]", max(time.Until(deadline).Truncate(time.Second), 0)))
+	}
 
 	fmt.Fprintf(w, "\r%-100s", strings.Join(parts, " "))
 }
@@ -2,11 +2,16 @@ package watcher
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/clobrano/prow-helper/internal/httpclient"
 	"github.com/clobrano/prow-helper/internal/parser"
 )
 
@@ -44,10 +49,12 @@ func TestBuildFinishedJSONURL(t *testing.T) {
 	}
 }
 
+func boolPtr(b bool) *bool { return &b }
+
 func TestCheckJobStatus_JobFinished(t *testing.T) {
 	finished := finishedJSON{
 		Timestamp: time.Now().Unix(),
-		Passed:    true,
+		Passed:    boolPtr(true),
 		Result:    "SUCCESS",
 	}
 	body, _ := json.Marshal(finished)
@@ -79,7 +86,7 @@ func TestCheckJobStatus_JobFinished(t *testing.T) {
 func TestCheckJobStatus_JobFailed(t *testing.T) {
 	finished := finishedJSON{
 		Timestamp: time.Now().Unix(),
-		Passed:    false,
+		Passed:    boolPtr(false),
 		Result:    "FAILURE",
 	}
 	body, _ := json.Marshal(finished)
@@ -106,6 +113,48 @@ func TestCheckJobStatus_JobFailed(t *testing.T) {
 	if status.Passed {
 		t.Error("CheckJobStatus().Passed = true, want false")
 	}
+
+	if status.Result != "FAILURE" {
+		t.Errorf("CheckJobStatus().Result = %q, want FAILURE", status.Result)
+	}
+}
+
+func TestCheckJobStatus_Schemas(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantPassed bool
+	}{
+		{name: "old schema, success", body: `{"timestamp": 1708774200, "result": "SUCCESS"}`, wantPassed: true},
+		{name: "old schema, failure", body: `{"timestamp": 1708774200, "result": "FAILURE"}`, wantPassed: false},
+		{name: "old schema, aborted", body: `{"timestamp": 1708774200, "result": "ABORTED"}`, wantPassed: false},
+		{name: "new schema, passed", body: `{"timestamp": 1708774200, "passed": true, "result": "SUCCESS", "revision": "abc123"}`, wantPassed: true},
+		{name: "new schema, failed", body: `{"timestamp": 1708774200, "passed": false, "result": "FAILURE", "revision": "abc123"}`, wantPassed: false},
+		// passed is authoritative when present.
+		{name: "passed false despite SUCCESS", body: `{"timestamp": 1708774200, "passed": false, "result": "SUCCESS"}`, wantPassed: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			status, err := CheckJobStatus(server.URL)
+			if err != nil {
+				t.Fatalf("CheckJobStatus() error = %v", err)
+			}
+			if status == nil || !status.Finished {
+				t.Fatalf("CheckJobStatus() = %+v, want a finished status", status)
+			}
+			if status.Passed != tt.wantPassed {
+				t.Errorf("CheckJobStatus().Passed = %v, want %v", status.Passed, tt.wantPassed)
+			}
+			if want := time.Unix(1708774200, 0); !status.Timestamp.Equal(want) {
+				t.Errorf("CheckJobStatus().Timestamp = %v, want %v", status.Timestamp, want)
+			}
+		})
+	}
 }
 
 func TestCheckJobStatus_JobRunning(t *testing.T) {
@@ -137,7 +186,46 @@ func TestCheckJobStatus_InvalidJSON(t *testing.T) {
 	}
 }
 
+func TestCheckJobStatus_TransientBadBody(t *testing.T) {
+	finished, _ := json.Marshal(finishedJSON{Timestamp: time.Now().Unix(), Passed: boolPtr(true), Result: "SUCCESS"})
+	bodies := [][]byte{[]byte(""), []byte("<html>Service Unavailable</html>"), finished}
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write(bodies[calls])
+		calls++
+	}))
+	defer server.Close()
+
+	for i := 0; i < 2; i++ {
+		status, err := CheckJobStatus(server.URL)
+		if !errors.Is(err, ErrIncompleteStatus) {
+			t.Fatalf("check %d: CheckJobStatus() error = %v, want ErrIncompleteStatus", i, err)
+		}
+		if status != nil {
+			t.Errorf("check %d: CheckJobStatus() status = %+v, want nil", i, status)
+		}
+	}
+
+	status, err := CheckJobStatus(server.URL)
+	if err != nil {
+		t.Fatalf("CheckJobStatus() error = %v", err)
+	}
+	if status == nil || !status.Finished || !status.Passed {
+		t.Errorf("CheckJobStatus() = %+v, want a finished, passed job", status)
+	}
+}
+
+// fastRetries shrinks the CheckJobStatus backoff for the duration of the test.
+func fastRetries(t *testing.T) {
+	t.Helper()
+	origRetries, origBackoff := MaxRetries, InitialBackoff
+	MaxRetries, InitialBackoff = 3, time.Millisecond
+	t.Cleanup(func() { MaxRetries, InitialBackoff = origRetries, origBackoff })
+}
+
 func TestCheckJobStatus_ServerError(t *testing.T) {
+	fastRetries(t)
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 	}))
@@ -149,6 +237,85 @@ func TestCheckJobStatus_ServerError(t *testing.T) {
 	}
 }
 
+func TestCheckJobStatus_Retries(t *testing.T) {
+	fastRetries(t)
+	body, _ := json.Marshal(finishedJSON{Timestamp: time.Now().Unix(), Passed: boolPtr(true), Result: "SUCCESS"})
+
+	tests := []struct {
+		name         string
+		statuses     []int // status of each request; the last one repeats
+		wantRequests int
+		wantStatus   bool
+		wantErr      bool
+	}{
+		{name: "passes on the first try", statuses: []int{http.StatusOK}, wantRequests: 1, wantStatus: true},
+		{name: "still running is not retried", statuses: []int{http.StatusNotFound}, wantRequests: 1},
+		{name: "recovers from 5xx", statuses: []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK}, wantRequests: 3, wantStatus: true},
+		{name: "gives up after MaxRetries", statuses: []int{http.StatusServiceUnavailable}, wantRequests: 4, wantErr: true},
+		{name: "4xx is not retried", statuses: []int{http.StatusForbidden}, wantRequests: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				code := tt.statuses[min(requests, len(tt.statuses)-1)]
+				requests++
+				w.WriteHeader(code)
+				if code == http.StatusOK {
+					w.Write(body)
+				}
+			}))
+			defer server.Close()
+
+			status, err := CheckJobStatus(server.URL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckJobStatus() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (status != nil) != tt.wantStatus {
+				t.Errorf("CheckJobStatus() = %+v, want a status = %v", status, tt.wantStatus)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("CheckJobStatus() made %d requests, want %d", requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestCheckJobStatus_RetriesNetworkErrors(t *testing.T) {
+	fastRetries(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	start := time.Now()
+	InitialBackoff = 10 * time.Millisecond
+	if _, err := CheckJobStatus(url); err == nil {
+		t.Fatal("CheckJobStatus() error = nil, want the connection error")
+	}
+	// 10ms + 20ms + 40ms of backoff between the 4 attempts.
+	if elapsed := time.Since(start); elapsed < 70*time.Millisecond {
+		t.Errorf("CheckJobStatus() returned after %v, want it to back off between retries", elapsed)
+	}
+}
+
+func TestGCSHostMirrorURLs(t *testing.T) {
+	orig := parser.GCSHost
+	defer func() { parser.GCSHost = orig }()
+	parser.GCSHost = "gcs-mirror.example.com"
+
+	metadata := &parser.ProwMetadata{Bucket: "test-platform-results", Path: "logs/periodic-ci-test/12345"}
+	base := "https://gcs-mirror.example.com/test-platform-results/logs/periodic-ci-test/12345/"
+	for name, got := range map[string]string{
+		"finished.json": BuildFinishedJSONURL(metadata),
+		"started.json":  BuildStartedJSONURL(metadata),
+		"build-log.txt": BuildLogURL(metadata),
+	} {
+		if got != base+name {
+			t.Errorf("%s URL = %s, want %s", name, got, base+name)
+		}
+	}
+}
+
 func TestBuildStartedJSONURL(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -185,21 +352,35 @@ func TestBuildStartedJSONURL(t *testing.T) {
 
 func TestFetchJobStartTime_Success(t *testing.T) {
 	expectedTime := time.Unix(1700000000, 0)
-	started := startedJSON{Timestamp: expectedTime.Unix()}
-	body, _ := json.Marshal(started)
-
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write(body)
-	}))
-	defer server.Close()
-
-	got, err := FetchJobStartTime(server.URL)
-	if err != nil {
-		t.Fatalf("FetchJobStartTime() error = %v", err)
+	tests := []struct {
+		name  string
+		field string
+		body  string
+	}{
+		{name: "default field", field: StartedField, body: `{"timestamp": 1700000000, "node": "x"}`},
+		{name: "nested field", field: "metadata.started", body: `{"timestamp": 1, "metadata": {"started": 1700000000}}`},
+		{name: "RFC 3339 field", field: "status.startTime", body: `{"status": {"startTime": "2023-11-14T22:13:20Z"}}`},
 	}
-	if !got.Equal(expectedTime) {
-		t.Errorf("FetchJobStartTime() = %v, want %v", got, expectedTime)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			origField := StartedField
+			StartedField = tt.field
+			defer func() { StartedField = origField }()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			got, err := FetchJobStartTime(server.URL)
+			if err != nil {
+				t.Fatalf("FetchJobStartTime() error = %v", err)
+			}
+			if !got.Equal(expectedTime) {
+				t.Errorf("FetchJobStartTime() = %v, want %v", got, expectedTime)
+			}
+		})
 	}
 }
 
@@ -246,7 +427,7 @@ func TestFetchJobStartTime_ServerError(t *testing.T) {
 func TestWatch_AlreadyFinished(t *testing.T) {
 	finished := finishedJSON{
 		Timestamp: time.Now().Unix(),
-		Passed:    true,
+		Passed:    boolPtr(true),
 		Result:    "SUCCESS",
 	}
 	body, _ := json.Marshal(finished)
@@ -272,3 +453,148 @@ func TestWatch_AlreadyFinished(t *testing.T) {
 		t.Error("Expected status.Passed to be true")
 	}
 }
+
+func TestWatch_StartTime(t *testing.T) {
+	for _, fetch := range []bool{true, false} {
+		t.Run(fmt.Sprintf("fetchStartTime=%v", fetch), func(t *testing.T) {
+			startedRequests := 0
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case strings.HasSuffix(r.URL.Path, "/started.json"):
+					startedRequests++
+					w.Write([]byte(`{"timestamp": 1708770600}`))
+				case strings.HasSuffix(r.URL.Path, "/finished.json"):
+					w.Write([]byte(`{"timestamp": 1708774200, "passed": true, "result": "SUCCESS"}`))
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			origHost, origTransport := parser.GCSHost, httpclient.Client.Transport
+			defer func() { parser.GCSHost, httpclient.Client.Transport = origHost, origTransport }()
+			parser.GCSHost = strings.TrimPrefix(server.URL, "https://")
+			httpclient.Client.Transport = server.Client().Transport
+
+			metadata := &parser.ProwMetadata{JobName: "job", BuildID: "1", Bucket: "bucket", Path: "logs/job/1"}
+			status, err := Watch(metadata, time.Minute, 0, io.Discard, fetch)
+			if err != nil {
+				t.Fatalf("Watch() error = %v", err)
+			}
+			if !status.Passed {
+				t.Error("Watch() status.Passed = false, want true")
+			}
+
+			wantRequests := 0
+			if fetch {
+				wantRequests = 1
+			}
+			if startedRequests != wantRequests {
+				t.Errorf("started.json requested %d times, want %d", startedRequests, wantRequests)
+			}
+			if got := !status.StartTime.IsZero(); got != fetch {
+				t.Errorf("status.StartTime = %v, want it set: %v", status.StartTime, fetch)
+			}
+		})
+	}
+}
+
+func TestWatch_Timeout(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	origHost, origTransport := parser.GCSHost, httpclient.Client.Transport
+	defer func() { parser.GCSHost, httpclient.Client.Transport = origHost, origTransport }()
+	parser.GCSHost = strings.TrimPrefix(server.URL, "https://")
+	httpclient.Client.Transport = server.Client().Transport
+
+	metadata := &parser.ProwMetadata{JobName: "job", BuildID: "1", Bucket: "bucket", Path: "logs/job/1"}
+	var out strings.Builder
+	status, err := Watch(metadata, 20*time.Millisecond, 100*time.Millisecond, &out, false)
+	if !errors.Is(err, ErrWatchTimeout) {
+		t.Fatalf("Watch() error = %v, want ErrWatchTimeout", err)
+	}
+	if status != nil {
+		t.Errorf("Watch() status = %+v, want nil", status)
+	}
+	if !strings.Contains(out.String(), "100ms") {
+		t.Errorf("Watch() output = %q, want the timeout", out.String())
+	}
+}
+
+func TestPrintCountdown_Timeout(t *testing.T) {
+	now := time.Now()
+	var buf strings.Builder
+	printCountdown(&buf, time.Time{}, now, now.Add(time.Minute), time.Time{})
+	if strings.Contains(buf.String(), "timeout in") {
+		t.Errorf("printCountdown() without deadline = %q, want no timeout", buf.String())
+	}
+	buf.Reset()
+	printCountdown(&buf, time.Time{}, now, now.Add(time.Minute), now.Add(time.Hour+30*time.Second))
+	if !strings.Contains(buf.String(), "[timeout in: 1h0m") {
+		t.Errorf("printCountdown() with deadline = %q, want the time left", buf.String())
+	}
+}
+
+func TestJobStatusDuration(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	status := &JobStatus{Finished: true, StartTime: start, Timestamp: start.Add(72 * time.Minute)}
+	if got := status.Duration(); got != 72*time.Minute {
+		t.Errorf("Duration() = %v, want 1h12m0s", got)
+	}
+
+	if got := (&JobStatus{Finished: true, Timestamp: start}).Duration(); got != 0 {
+		t.Errorf("Duration() without start time = %v, want 0", got)
+	}
+	var nilStatus *JobStatus
+	if got := nilStatus.Duration(); got != 0 {
+		t.Errorf("Duration() on nil status = %v, want 0", got)
+	}
+}
+
+func TestFetchJobStartTime_AlternativeFileAndField(t *testing.T) {
+	origFile, origField := StartedFile, StartedField
+	StartedFile, StartedField = "prowjob.json", "status.startTime"
+	defer func() { StartedFile, StartedField = origFile, origField }()
+
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.Write([]byte(`{"kind":"ProwJob","status":{"startTime":"2024-02-24T10:30:00Z"}}`))
+	}))
+	defer server.Close()
+
+	metadata := &parser.ProwMetadata{Bucket: "bucket", Path: "logs/job/1"}
+	if url := BuildStartedJSONURL(metadata); url != parser.GCSBaseURL()+"/bucket/logs/job/1/prowjob.json" {
+		t.Errorf("BuildStartedJSONURL() = %s, want it to point at prowjob.json", url)
+	}
+
+	got, err := FetchJobStartTime(server.URL + "/bucket/logs/job/1/prowjob.json")
+	if err != nil {
+		t.Fatalf("FetchJobStartTime() error = %v", err)
+	}
+	want, _ := time.Parse(time.RFC3339, "2024-02-24T10:30:00Z")
+	if !got.Equal(want) {
+		t.Errorf("FetchJobStartTime() = %v, want %v", got, want)
+	}
+	if gotPath != "/bucket/logs/job/1/prowjob.json" {
+		t.Errorf("requested %s", gotPath)
+	}
+}
+
+func TestFetchJobStartTime_MissingField(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"node":"x"}`))
+	}))
+	defer server.Close()
+
+	got, err := FetchJobStartTime(server.URL)
+	if err != nil {
+		t.Fatalf("FetchJobStartTime() error = %v", err)
+	}
+	if !got.IsZero() {
+		t.Errorf("FetchJobStartTime() = %v, want zero time when the field is missing", got)
+	}
+}
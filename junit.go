@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/clobrano/prow-helper/internal/junit"
+)
+
+var flagJUnitOnly string
+
+var junitCmd = &cobra.Command{
+	Use:   "junit <artifacts-dir | prow-url>",
+	Short: "Summarize the JUnit results of a build",
+	Long: `junit prints a summary of a build's JUnit reports (junit*.xml): the test
+counts by status and the failed tests with their messages.
+
+With --only, just the tests whose ID ("<suite>: <name>") matches a regular
+expression are reported, each with its status and full failure message, which
+answers "did this specific test pass". The exit code is 6 when any reported
+test failed.
+
+Like analyze, junit works offline on a local directory, and downloads the
+build (or reuses an earlier download) when given a Prow URL.
+
+Example:
+  prow-helper junit ./artifacts/20240224-1030-job-name-12345
+  prow-helper junit --only 'e2e.*fencing' <prow-url>`,
+	Args: cobra.ExactArgs(1),
+	RunE: runJUnit,
+}
+
+func init() {
+	junitCmd.Flags().StringVar(&flagJUnitOnly, "only", "", "Report only the tests whose \"<suite>: <name>\" matches this regular expression")
+	junitCmd.Flags().StringVar(&flagDest, "dest", "", "Download destination directory, when given a Prow URL")
+	rootCmd.AddCommand(junitCmd)
+}
+
+func runJUnit(cmd *cobra.Command, args []string) error {
+	var only *regexp.Regexp
+	if flagJUnitOnly != "" {
+		var err error
+		if only, err = regexp.Compile(flagJUnitOnly); err != nil {
+			return fmt.Errorf("invalid --only: %w", err)
+		}
+	}
+
+	dir, err := resolveBuildDir(args[0])
+	if err != nil {
+		return err
+	}
+	results, err := junit.ParseDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read JUnit reports: %w", err)
+	}
+
+	if only == nil {
+		printJUnitSummary(os.Stdout, results)
+	} else {
+		selected := results.Filter(only)
+		if len(selected) == 0 {
+			return fmt.Errorf("no tests match --only %q (%d test(s) found)", flagJUnitOnly, len(results))
+		}
+		results = selected
+		printJUnitTests(os.Stdout, results)
+	}
+
+	for _, c := range results {
+		if c.Status == junit.StatusFailed {
+			os.Exit(ExitJobFailed)
+		}
+	}
+	return nil
+}
+
+// printJUnitTests writes the test counts by status and every test, sorted by
+// ID, with its status and, for failed tests, their whole message.
+func printJUnitTests(w io.Writer, results junit.Results) {
+	counts := make(map[junit.Status]int)
+	for _, c := range results {
+		counts[c.Status]++
+	}
+	fmt.Fprintf(w, "Tests: %d passed, %d failed, %d skipped\n",
+		counts[junit.StatusPassed], counts[junit.StatusFailed], counts[junit.StatusSkipped])
+
+	for _, c := range results.Sorted() {
+		fmt.Fprintf(w, "  %-7s %s\n", strings.ToUpper(string(c.Status)), c.ID())
+		if c.Status != junit.StatusFailed {
+			continue
+		}
+		for _, line := range strings.Split(strings.TrimSpace(c.Message), "\n") {
+			if line != "" {
+				fmt.Fprintf(w, "          %s\n", line)
+			}
+		}
+	}
+}
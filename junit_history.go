@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/clobrano/prow-helper/internal/config"
+	"github.com/clobrano/prow-helper/internal/downloader"
+	"github.com/clobrano/prow-helper/internal/junit"
+	"github.com/clobrano/prow-helper/internal/parser"
+	"github.com/clobrano/prow-helper/internal/stats"
+)
+
+// DefaultJUnitHistoryBuilds is how many recent builds junit-history looks at
+// by default.
+const DefaultJUnitHistoryBuilds = 10
+
+var flagJUnitHistoryBuilds int
+var flagJUnitHistoryTest string
+
+var junitHistoryCmd = &cobra.Command{
+	Use:   "junit-history <prow-job-url>",
+	Short: "Show the pass/fail history of tests across a job's recent builds",
+	Long: `junit-history lists the most recent builds of a job (the Prow URL of the job,
+without a build ID), reads their JUnit reports (junit*.xml) and prints, for
+every test whose "<suite>: <name>" matches --test, a matrix of its status in
+each build and its flake rate: the share of the builds that ran it in which it
+failed. Without --test, the tests that failed in at least one build are shown.
+
+Example:
+  prow-helper junit-history --builds 10 --test 'fencing' https://prow.ci.openshift.org/view/gs/test-platform-results/logs/job-name`,
+	Args: cobra.ExactArgs(1),
+	RunE: runJUnitHistory,
+}
+
+func init() {
+	junitHistoryCmd.Flags().IntVar(&flagJUnitHistoryBuilds, "builds", DefaultJUnitHistoryBuilds, "Number of most recent builds to consider")
+	junitHistoryCmd.Flags().StringVar(&flagJUnitHistoryTest, "test", "", "Regular expression selecting the tests by \"<suite>: <name>\" (default: the tests that failed at least once)")
+	rootCmd.AddCommand(junitHistoryCmd)
+}
+
+func runJUnitHistory(cmd *cobra.Command, args []string) error {
+	if flagJUnitHistoryBuilds < 1 {
+		return fmt.Errorf("--builds must be at least 1")
+	}
+	var re *regexp.Regexp
+	if flagJUnitHistoryTest != "" {
+		var err error
+		if re, err = regexp.Compile(flagJUnitHistoryTest); err != nil {
+			return fmt.Errorf("invalid --test: %w", err)
+		}
+	}
+	cfg, err := config.Load(&config.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if reportConfigIssues(config.Validate(cfg)) {
+		return fmt.Errorf("invalid configuration")
+	}
+	applyConfig(cfg)
+
+	bucket, jobPath, err := parser.SplitViewPath(args[0])
+	if err != nil {
+		return err
+	}
+	buildIDs, err := downloader.ListBuildIDs(bucket, jobPath)
+	if err != nil {
+		return err
+	}
+	if len(buildIDs) == 0 {
+		return fmt.Errorf("%w: gs://%s/%s/", downloader.ErrNoBuilds, bucket, jobPath)
+	}
+	if len(buildIDs) > flagJUnitHistoryBuilds {
+		buildIDs = buildIDs[len(buildIDs)-flagJUnitHistoryBuilds:]
+	}
+
+	fmt.Printf("Fetching JUnit reports of %d builds...\n", len(buildIDs))
+	results, err := stats.CollectJUnit(bucket, jobPath, buildIDs)
+	if err != nil {
+		return err
+	}
+	printJUnitHistory(os.Stdout, junit.NewHistory(buildIDs, results, re))
+	return nil
+}
+
+// historyCells maps a test status to its cell in the history matrix; a test
+// that did not run in a build is shown as "-".
+var historyCells = map[junit.Status]string{
+	junit.StatusPassed:  "P",
+	junit.StatusFailed:  "F",
+	junit.StatusSkipped: "S",
+	"":                  "-",
+}
+
+// printJUnitHistory writes the numbered builds, oldest first, then one row per
+// test with its status in each build and its flake rate.
+func printJUnitHistory(w io.Writer, h junit.History) {
+	fmt.Fprintln(w, "Builds:")
+	for i, id := range h.Builds {
+		fmt.Fprintf(w, "  %2d  %s\n", i+1, id)
+	}
+	fmt.Fprintln(w)
+	if len(h.Tests) == 0 {
+		fmt.Fprintln(w, "No matching tests")
+		return
+	}
+
+	width := len("Test")
+	for _, test := range h.Tests {
+		width = max(width, len(test))
+	}
+	header := make([]string, len(h.Builds))
+	for i := range h.Builds {
+		header[i] = fmt.Sprintf("%2d", i+1)
+	}
+	fmt.Fprintf(w, "%-*s %s  Flake rate\n", width, "Test", strings.Join(header, " "))
+	for _, test := range h.Tests {
+		cells := make([]string, len(h.Builds))
+		for i, s := range h.Status[test] {
+			cells[i] = fmt.Sprintf("%2s", historyCells[s])
+		}
+		passed, failed := h.Counts(test)
+		rate := fmt.Sprintf("%.0f%% (%d/%d)", h.FlakeRate(test)*100, failed, passed+failed)
+		if h.Flaky(test) {
+			rate += " flaky"
+		}
+		fmt.Fprintf(w, "%-*s %s  %s\n", width, test, strings.Join(cells, " "), rate)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/clobrano/prow-helper/internal/junit"
+)
+
+func TestPrintJUnitHistory(t *testing.T) {
+	h := junit.History{
+		Builds: []string{"1790000000000000001", "1790000000000000002", "1790000000000000003"},
+		Tests:  []string{"e2e: fencing reboots the node", "e2e: upgrade"},
+		Status: map[string][]junit.Status{
+			"e2e: fencing reboots the node": {junit.StatusPassed, junit.StatusFailed, junit.StatusPassed},
+			"e2e: upgrade":                  {junit.StatusSkipped, junit.StatusPassed, ""},
+		},
+	}
+
+	var buf bytes.Buffer
+	printJUnitHistory(&buf, h)
+
+	want := `Builds:
+   1  1790000000000000001
+   2  1790000000000000002
+   3  1790000000000000003
+
+Test                           1  2  3  Flake rate
+e2e: fencing reboots the node  P  F  P  33% (1/3) flaky
+e2e: upgrade                   S  P  -  0% (0/1)
+`
+	if buf.String() != want {
+		t.Errorf("printJUnitHistory() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestPrintJUnitHistory_NoTests(t *testing.T) {
+	var buf bytes.Buffer
+	printJUnitHistory(&buf, junit.History{Builds: []string{"1"}})
+
+	want := "Builds:\n   1  1\n\nNo matching tests\n"
+	if buf.String() != want {
+		t.Errorf("printJUnitHistory() = %q, want %q", buf.String(), want)
+	}
+}
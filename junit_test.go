@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/clobrano/prow-helper/internal/junit"
+)
+
+func TestPrintJUnitTests_Only(t *testing.T) {
+	results, err := junit.ParseDir("testdata/analyze")
+	if err != nil {
+		t.Fatalf("ParseDir() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	printJUnitTests(&buf, results.Filter(regexp.MustCompile(`node|upgrade`)))
+
+	want := `Tests: 0 passed, 1 failed, 1 skipped
+  FAILED  e2e: reboots a node
+          timed out waiting for node to be Ready
+  SKIPPED e2e: upgrades the cluster
+`
+	if buf.String() != want {
+		t.Errorf("printJUnitTests() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
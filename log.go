@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/clobrano/prow-helper/internal/config"
+	"github.com/clobrano/prow-helper/internal/parser"
+	"github.com/clobrano/prow-helper/internal/watcher"
+)
+
+var flagLogOutput string
+
+var logCmd = &cobra.Command{
+	Use:   "log <prow-url>",
+	Short: "Print a job's build log",
+	Long: `log fetches only the job's build-log.txt, with a single HTTP request, and
+prints it to stdout, or writes it to a file with -o. It is much faster than
+downloading the whole artifact tree when the build log is all you need.
+
+The build log is uploaded when the job finishes: use tail to follow a job that
+is still running.
+
+Example:
+  prow-helper log https://prow.ci.openshift.org/view/gs/test-platform-results/logs/job-name/12345
+  prow-helper log -o build-log.txt https://prow.ci.openshift.org/view/gs/test-platform-results/logs/job-name/12345`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLog,
+}
+
+func init() {
+	logCmd.Flags().StringVarP(&flagLogOutput, "output", "o", "", "Write the build log to this file instead of stdout")
+	rootCmd.AddCommand(logCmd)
+}
+
+func runLog(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(&config.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if reportConfigIssues(config.Validate(cfg)) {
+		return fmt.Errorf("invalid configuration")
+	}
+	applyConfig(cfg)
+
+	metadata, err := parser.ParseURL(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse URL: %v\n", err)
+		os.Exit(ExitInvalidURL)
+		return nil
+	}
+
+	if err := writeBuildLog(watcher.BuildLogURL(metadata), flagLogOutput, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to fetch build log: %v\n", err)
+		os.Exit(ExitDownloadFailed)
+		return nil
+	}
+	return nil
+}
+
+// writeBuildLog copies the build log at logURL to the file outPath, or to
+// stdout when outPath is empty. The file is not left behind if the fetch
+// fails.
+func writeBuildLog(logURL, outPath string, stdout io.Writer) error {
+	if outPath == "" {
+		_, err := watcher.FetchLog(logURL, stdout)
+		return err
+	}
+
+	f, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	_, err = watcher.FetchLog(logURL, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(outPath)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/clobrano/prow-helper/internal/watcher"
+)
+
+const testBuildLog = "INFO starting\nINFO done\n"
+
+func newBuildLogServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bucket/logs/job/1/build-log.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, testBuildLog)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestWriteBuildLog_Stdout(t *testing.T) {
+	server := newBuildLogServer(t)
+
+	var buf bytes.Buffer
+	if err := writeBuildLog(server.URL+"/bucket/logs/job/1/build-log.txt", "", &buf); err != nil {
+		t.Fatalf("writeBuildLog() error = %v", err)
+	}
+	if buf.String() != testBuildLog {
+		t.Errorf("stdout = %q, want %q", buf.String(), testBuildLog)
+	}
+}
+
+func TestWriteBuildLog_File(t *testing.T) {
+	server := newBuildLogServer(t)
+	out := filepath.Join(t.TempDir(), "build-log.txt")
+
+	var stdout bytes.Buffer
+	if err := writeBuildLog(server.URL+"/bucket/logs/job/1/build-log.txt", out, &stdout); err != nil {
+		t.Fatalf("writeBuildLog() error = %v", err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != testBuildLog {
+		t.Errorf("file = %q, want %q", got, testBuildLog)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("stdout = %q, want nothing when writing to a file", stdout.String())
+	}
+}
+
+func TestWriteBuildLog_NotFound(t *testing.T) {
+	server := newBuildLogServer(t)
+	out := filepath.Join(t.TempDir(), "build-log.txt")
+
+	err := writeBuildLog(server.URL+"/bucket/logs/job/2/build-log.txt", out, &bytes.Buffer{})
+	if !errors.Is(err, watcher.ErrLogNotFound) {
+		t.Errorf("writeBuildLog() error = %v, want ErrLogNotFound", err)
+	}
+	if _, statErr := os.Stat(out); !os.IsNotExist(statErr) {
+		t.Errorf("output file should not be left behind after a failed fetch (stat: %v)", statErr)
+	}
+}
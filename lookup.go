@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/clobrano/prow-helper/internal/config"
+	"github.com/clobrano/prow-helper/internal/parser"
+	"github.com/clobrano/prow-helper/internal/prompt"
+	"github.com/clobrano/prow-helper/internal/prowapi"
+	"github.com/clobrano/prow-helper/internal/selector"
+)
+
+// fetchProwJobs lists the jobs of a Prow status page; tests replace it.
+var fetchProwJobs = prowapi.FetchJobs
+
+// jobQuery identifies the job to look up when no Prow URL is given: a job
+// name (substring), and optionally the number of its pull request and the
+// pull request's author.
+type jobQuery struct {
+	Job    string
+	PR     string
+	Author string
+}
+
+// newJobQuery builds the jobQuery of the --job, --pr and --author flags.
+// With --job, --pr is a pull request number rather than a GitHub URL.
+func newJobQuery(job, pr, author string) (jobQuery, error) {
+	if job == "" {
+		return jobQuery{}, fmt.Errorf("--author requires --job")
+	}
+	if pr != "" {
+		if n, err := strconv.Atoi(pr); err != nil || n < 1 {
+			return jobQuery{}, fmt.Errorf("with --job, --pr must be a pull request number, got %q", pr)
+		}
+	}
+	return jobQuery{Job: job, PR: pr, Author: author}, nil
+}
+
+// statusPageURL returns the URL of the status page of the first allowed Prow
+// host, filtered by q.
+func (q jobQuery) statusPageURL() string {
+	v := url.Values{}
+	v.Set("job", q.Job)
+	if q.PR != "" {
+		v.Set("pull", q.PR)
+	}
+	if q.Author != "" {
+		v.Set("author", q.Author)
+	}
+	u := url.URL{Scheme: "https", Host: parser.PrimaryHost(), Path: "/", RawQuery: v.Encode()}
+	return u.String()
+}
+
+// runJobLookupWorkflow finds the Prow jobs matching q, lets the user pick
+// some of them when there are several, and runs the download workflow on
+// each choice.
+func runJobLookupWorkflow(cmd *cobra.Command, q jobQuery) error {
+	cfg, err := config.Load(cliConfig())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(ExitConfigError)
+		return nil
+	}
+	applyConfig(cfg)
+
+	urls, err := lookupJobURLs(q)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not find prow jobs matching --job %s: %v\n", q.Job, err)
+		os.Exit(ExitInvalidURL)
+		return nil
+	}
+	if len(urls) == 0 {
+		fmt.Println("No jobs selected. Exiting.")
+		return nil
+	}
+	if len(urls) == 1 {
+		fmt.Fprintf(progressOut(), "Resolved job: %s\n", urls[0])
+		return executeWorkflow(urls[0], flagNotifyComplete)
+	}
+	return runWorkflowsSequentially(cmd, urls, nil, "job", "pr", "author")
+}
+
+// lookupJobURLs returns the view URLs of the jobs matching q: the only
+// match, or those the user selected among several.
+func lookupJobURLs(q jobQuery) ([]string, error) {
+	jobs, err := fetchProwJobs(q.statusPageURL())
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("no job found on %s", parser.PrimaryHost())
+	}
+	if len(jobs) == 1 {
+		return []string{jobs[0].URL}, nil
+	}
+
+	items := make([]selector.Item, len(jobs))
+	for i, j := range jobs {
+		label := fmt.Sprintf("%s  %s", j.Name, j.State)
+		if j.PRRef != "" {
+			label += "  " + j.PRRef
+		}
+		items[i] = selector.Item{Key: j.URL, Label: label}
+	}
+	if promptPolicy() != prompt.Ask {
+		return nil, prompt.Disabled(fmt.Sprintf("choosing among the %d matching jobs (narrow them down with --pr or --author)", len(jobs)))
+	}
+	indices, err := runSelector(items, nil, selector.Options{})
+	if err != nil {
+		return nil, err
+	}
+	sort.Ints(indices)
+
+	selected := make([]string, len(indices))
+	for i, idx := range indices {
+		selected[i] = jobs[idx].URL
+	}
+	return selected, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/clobrano/prow-helper/internal/prompt"
+	"github.com/clobrano/prow-helper/internal/prowapi"
+	"github.com/clobrano/prow-helper/internal/selector"
+)
+
+// sampleLookupJobs is what the fake status page lists for every query.
+var sampleLookupJobs = []prowapi.Job{
+	{Name: "pull-ci-openshift-api-master-e2e-aws", State: "failure", URL: jobURL("pull-ci-openshift-api-master-e2e-aws", "333"), PRRef: "[openshift/api PR1234]", PR: 1234},
+	{Name: "pull-ci-openshift-api-master-e2e-aws", State: "success", URL: jobURL("pull-ci-openshift-api-master-e2e-aws", "222"), PRRef: "[openshift/api PR1234]", PR: 1234},
+	{Name: "pull-ci-openshift-api-master-e2e-aws-upgrade", State: "pending", URL: jobURL("pull-ci-openshift-api-master-e2e-aws-upgrade", "111"), PRRef: "[openshift/api PR1234]", PR: 1234},
+}
+
+// fakeStatusPage replaces fetchProwJobs with one that returns jobs and
+// records the status page URL it was given.
+func fakeStatusPage(t *testing.T, jobs []prowapi.Job) *string {
+	t.Helper()
+	var pageURL string
+	orig := fetchProwJobs
+	fetchProwJobs = func(u string) ([]prowapi.Job, error) {
+		pageURL = u
+		return jobs, nil
+	}
+	t.Cleanup(func() { fetchProwJobs = orig })
+	return &pageURL
+}
+
+// scriptSelector replaces runSelector with one that picks choice and
+// returns a pointer to the items it was offered (nil if it never ran).
+func scriptSelector(t *testing.T, choice []int) *[]selector.Item {
+	t.Helper()
+	var offered []selector.Item
+	orig := runSelector
+	runSelector = func(items []selector.Item, refreshFn func() ([]selector.Item, error), opts selector.Options) ([]int, error) {
+		offered = items
+		return choice, nil
+	}
+	t.Cleanup(func() { runSelector = orig })
+	return &offered
+}
+
+func TestNewJobQuery(t *testing.T) {
+	tests := []struct {
+		job, pr, author string
+		wantErr         bool
+	}{
+		{job: "e2e-aws"},
+		{job: "e2e-aws", pr: "1234", author: "clobrano"},
+		{job: "e2e-aws", pr: "https://github.com/openshift/api/pull/1234", wantErr: true},
+		{job: "e2e-aws", pr: "0", wantErr: true},
+		{author: "clobrano", wantErr: true},
+	}
+	for _, tt := range tests {
+		_, err := newJobQuery(tt.job, tt.pr, tt.author)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("newJobQuery(%q, %q, %q) error = %v, wantErr %v", tt.job, tt.pr, tt.author, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLookupJobURLs_SingleMatch(t *testing.T) {
+	pageURL := fakeStatusPage(t, sampleLookupJobs[2:])
+	offered := scriptSelector(t, []int{0})
+
+	urls, err := lookupJobURLs(jobQuery{Job: "e2e-aws-upgrade", PR: "1234", Author: "clobrano"})
+	if err != nil {
+		t.Fatalf("lookupJobURLs() error = %v", err)
+	}
+	if want := []string{sampleLookupJobs[2].URL}; fmt.Sprint(urls) != fmt.Sprint(want) {
+		t.Errorf("lookupJobURLs() = %v, want %v", urls, want)
+	}
+	if *offered != nil {
+		t.Errorf("selector was offered %d items, want a single match to be used directly", len(*offered))
+	}
+
+	u, err := url.Parse(*pageURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if u.Host != "prow.ci.openshift.org" || q.Get("job") != "e2e-aws-upgrade" || q.Get("pull") != "1234" || q.Get("author") != "clobrano" {
+		t.Errorf("status page URL = %s, want the job, pull and author filters on the Prow host", *pageURL)
+	}
+}
+
+func TestLookupJobURLs_MultipleMatches(t *testing.T) {
+	fakeStatusPage(t, sampleLookupJobs)
+	offered := scriptSelector(t, []int{2, 0})
+
+	urls, err := lookupJobURLs(jobQuery{Job: "e2e-aws"})
+	if err != nil {
+		t.Fatalf("lookupJobURLs() error = %v", err)
+	}
+	if len(*offered) != 3 {
+		t.Fatalf("selector was offered %d items, want 3", len(*offered))
+	}
+	if want := "pull-ci-openshift-api-master-e2e-aws  success  [openshift/api PR1234]"; (*offered)[1].Label != want {
+		t.Errorf("selector item label = %q, want %q", (*offered)[1].Label, want)
+	}
+	want := []string{sampleLookupJobs[0].URL, sampleLookupJobs[2].URL}
+	if fmt.Sprint(urls) != fmt.Sprint(want) {
+		t.Errorf("lookupJobURLs() = %v, want %v", urls, want)
+	}
+}
+
+func TestLookupJobURLs_NoMatch(t *testing.T) {
+	fakeStatusPage(t, nil)
+	if _, err := lookupJobURLs(jobQuery{Job: "missing"}); err == nil {
+		t.Error("lookupJobURLs() error = nil, want an error when no job matches")
+	}
+}
+
+func TestLookupJobURLs_NoPrompt(t *testing.T) {
+	fakeStatusPage(t, sampleLookupJobs)
+	offered := scriptSelector(t, []int{0})
+	flagNoPrompt = true
+	t.Cleanup(func() { flagNoPrompt = false })
+
+	_, err := lookupJobURLs(jobQuery{Job: "e2e-aws"})
+	if !errors.Is(err, prompt.ErrDisabled) {
+		t.Errorf("lookupJobURLs() error = %v, want ErrDisabled", err)
+	}
+	if *offered != nil {
+		t.Error("selector ran with --no-prompt")
+	}
+}
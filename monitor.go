@@ -1,10 +1,18 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -12,9 +20,11 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/clobrano/prow-helper/internal/config"
+	"github.com/clobrano/prow-helper/internal/httpclient"
 	"github.com/clobrano/prow-helper/internal/notifier"
 	"github.com/clobrano/prow-helper/internal/output"
 	"github.com/clobrano/prow-helper/internal/parser"
+	"github.com/clobrano/prow-helper/internal/prompt"
 	"github.com/clobrano/prow-helper/internal/prowapi"
 	"github.com/clobrano/prow-helper/internal/selector"
 	"github.com/clobrano/prow-helper/internal/watcher"
@@ -22,16 +32,48 @@ import (
 
 var flagMonitorInterval time.Duration
 var flagMonitorNtfyChannel string
+var flagMonitorAutoSelectSingle bool
+var flagMonitorRepeat bool
+var flagMonitorSelect string
+var flagMonitorPick string
+var flagMonitorRecord string
+var flagMonitorReplay string
+var flagMonitorMaxSelect int
+var flagMonitorFollowNewer bool
+var flagMonitorSummaryFormat string
+var flagMonitorProgress bool
+var flagMonitorCompact bool
+var flagMonitorExcludeFinished bool
+var flagMonitorFilterState string
+var flagMonitorFilterAuthor string
+var flagMonitorFilterJob string
+var flagMonitorCheckConcurrency int
+var flagMonitorStatusFile string
+var flagMonitorColumns string
+var flagMonitorList string
+
+// progressBarWidth is the number of cells of the --progress bar.
+const progressBarWidth = 20
+
+// DefaultCheckConcurrency is how many finished.json checks monitor runs at
+// the same time by default.
+const DefaultCheckConcurrency = 10
+
+// checkJobStatus fetches the status of a job from its finished.json URL;
+// tests replace it.
+var checkJobStatus = watcher.CheckJobStatus
 
 var monitorCmd = &cobra.Command{
-	Use:   "monitor <prow-status-url>",
+	Use:   "monitor <prow-status-url>... | --list <watchlist>",
 	Short: "Fetch and monitor prow jobs from a status page",
 	Long: `monitor fetches all prow job links from a Prow status page (e.g. filtered by
 author) and lets you choose which jobs to watch.
 
 The Prow status page is a React SPA — job data is loaded at runtime from the
 /prowjobs.js API. The monitor command calls that API directly and filters by
-any query parameters present in the URL (author, job, state).
+any query parameters present in the URL (author, job, state). The
+--filter-author, --filter-job and --filter-state flags set these parameters
+on every URL, replacing the values already there.
 
 An interactive list lets you select which jobs to monitor:
   Type       – filter the list (substring match against job name / state)
@@ -41,29 +83,195 @@ An interactive list lets you select which jobs to monitor:
   ENTER      – confirm the selection and start monitoring
   ESC        – clear the search (first press) or cancel (second press)
 
+Use --select to skip the list and monitor every job whose name matches a
+regular expression (or plain substring).
+
+Several status pages, e.g. of different Prow instances, can be given: their
+jobs are merged into one list, in which each job is shown with its host when
+they come from more than one.
+
+Use --list to monitor a watchlist of the configuration instead: a named set
+of job URLs and status pages, whose jobs are filtered by the watchlist's
+select pattern. Its jobs are monitored without asking.
+
 Example:
-  prow-helper monitor https://prow.ci.openshift.org/?author=clobrano`,
-	Args: cobra.ExactArgs(1),
+  prow-helper monitor https://prow.ci.openshift.org/?author=clobrano
+  prow-helper monitor --select e2e-metal https://prow.ci.openshift.org/?author=clobrano
+  prow-helper monitor --filter-state pending --filter-author clobrano https://prow.ci.openshift.org/
+  prow-helper monitor https://prow.ci.openshift.org/?author=clobrano https://prow.example.com/?author=clobrano
+  prow-helper monitor --list nightly-metal`,
+	Args: cobra.ArbitraryArgs,
 	RunE: runMonitor,
 }
 
 func init() {
 	monitorCmd.Flags().DurationVar(&flagMonitorInterval, "interval", watcher.DefaultPollInterval,
 		"Polling interval for job status checks")
-	monitorCmd.Flags().StringVar(&flagMonitorNtfyChannel, "ntfy-channel", "", "ntfy.sh channel for push notifications")
+	monitorCmd.Flags().Float64Var(&flagIntervalJitter, "interval-jitter", 0,
+		"Vary each polling interval randomly by up to this percentage, to spread the load of many monitors")
+	monitorCmd.Flags().StringVar(&flagMonitorNtfyChannel, "ntfy-channel", "", "ntfy.sh channel for push notifications (comma-separated for several)")
+	monitorCmd.Flags().StringVar(&flagWebhookURL, "webhook-url", "", "URL to POST a JSON object to when a job finishes")
+	monitorCmd.Flags().StringVar(&flagWebhookOn, "webhook-on", "", "Comma-separated events posted to --webhook-url (default: completion and failure events)")
+	monitorCmd.Flags().BoolVar(&flagNotifyFallback, "notify-fallback", false,
+		"Fall back to the other notification channel (ntfy.sh or desktop) when one fails")
+	monitorCmd.Flags().BoolVar(&flagNotifyOnlyFail, "notify-only-on-failure", false,
+		"Send only failure notifications, suppressing success ones")
+	monitorCmd.Flags().IntVar(&flagMonitorCheckConcurrency, "check-concurrency", DefaultCheckConcurrency,
+		"Maximum number of job status checks run at the same time")
+	monitorCmd.Flags().BoolVar(&flagMonitorAutoSelectSingle, "auto-select-single", false,
+		"Skip the interactive selector when exactly one job is found")
+	monitorCmd.Flags().StringVar(&flagMonitorSelect, "select", "",
+		"Monitor every job whose name matches this regex or substring, without the interactive selector")
+	monitorCmd.Flags().StringVar(&flagMonitorPick, "pick", "",
+		"Monitor the jobs at these 1-based positions of the list (e.g. \"1-3,5,8\"), without the interactive selector")
+	monitorCmd.Flags().IntVar(&flagMonitorMaxSelect, "max-select", 0,
+		"Refuse to confirm a selection of more than this many jobs (or of none) in the interactive selector")
+	monitorCmd.Flags().BoolVar(&flagMonitorExcludeFinished, "exclude-finished", false,
+		"Leave out the jobs that have already finished (success, failure, aborted, error), to choose among running ones")
+	monitorCmd.Flags().StringVar(&flagMonitorFilterState, "filter-state", "",
+		"List only the jobs in this state (e.g. pending, success, failure), overriding the state parameter of the URL")
+	monitorCmd.Flags().StringVar(&flagMonitorFilterAuthor, "filter-author", "",
+		"List only the jobs of this PR author, overriding the author parameter of the URL")
+	monitorCmd.Flags().StringVar(&flagMonitorFilterJob, "filter-job", "",
+		"List only the jobs whose name contains this string, overriding the job parameter of the URL")
+	monitorCmd.Flags().BoolVar(&flagMonitorRepeat, "repeat", false,
+		"When all selected jobs finish, keep re-fetching the page and monitor newly appeared jobs")
+	monitorCmd.Flags().BoolVar(&flagMonitorFollowNewer, "follow-newer", false,
+		"At each check, re-fetch the page and switch to a newer build of the same job and PR (e.g. after a retest)")
+	monitorCmd.Flags().StringVar(&flagMonitorSummaryFormat, "summary-format", summaryText,
+		"Format of the summary printed when all jobs are done: text, markdown or json")
+	monitorCmd.Flags().BoolVar(&flagMonitorProgress, "progress", false,
+		"Show a progress bar of the finished jobs under the status table")
+	monitorCmd.Flags().BoolVar(&flagMonitorCompact, "compact", false,
+		"Show only the progress bar of the finished jobs instead of the status table")
+	monitorCmd.Flags().StringVar(&flagMonitorList, "list", "",
+		"Monitor the jobs of this watchlist of the configuration instead of status page URLs")
+	monitorCmd.Flags().StringVar(&flagMonitorColumns, "columns", "",
+		"Comma-separated columns of the status table, in order: "+strings.Join(statusColumnNames, ", "))
+	monitorCmd.Flags().StringVar(&flagMonitorStatusFile, "status-file", "",
+		"Also write the status table to this file, replaced atomically every round")
+	monitorCmd.Flags().StringVar(&flagMonitorRecord, "record", "",
+		"Save every prowjobs.js and finished.json response to this directory")
+	monitorCmd.Flags().StringVar(&flagMonitorReplay, "replay", "",
+		"Serve responses from a --record directory instead of the network")
+	monitorCmd.MarkFlagsMutuallyExclusive("record", "replay")
+	monitorCmd.MarkFlagsMutuallyExclusive("select", "pick")
+	monitorCmd.MarkFlagsMutuallyExclusive("columns", "compact")
+	for _, flag := range []string{"select", "pick", "repeat", "follow-newer"} {
+		monitorCmd.MarkFlagsMutuallyExclusive("list", flag)
+	}
+	monitorCmd.RegisterFlagCompletionFunc("select", completeMonitorSelect)
 	rootCmd.AddCommand(monitorCmd)
 }
 
 // monitorEntry holds the parsed metadata for a prow job and its latest known status.
 type monitorEntry struct {
 	metadata       *parser.ProwMetadata
+	host           string             // host of the status page that listed the job
 	prRef          string             // "[org/repo PR<num>]" or "" for non-PR jobs
+	author         string             // PR author, "" when unknown
+	url            string             // job URL as listed by the status page
 	state          string             // original state from the API (triggered, pending, success, …)
 	startTime      time.Time          // zero if the API did not provide one
 	completionTime time.Time          // zero while still running
 	status         *watcher.JobStatus // nil while still running
 	err            error
 	notified       bool // true once a completion notification has been sent
+	incomplete     int  // consecutive checks that found an unreadable finished.json
+}
+
+// key identifies the job build behind an entry, independently of the URL
+// form it was listed with.
+func (e *monitorEntry) key() string {
+	return e.metadata.Bucket + "/" + e.metadata.Path
+}
+
+// lineage identifies the job an entry's build belongs to: builds with the
+// same job name for the same pull request (or no pull request) on the same
+// Prow host are runs of the same thing, and a newer one supersedes older ones.
+func (e *monitorEntry) lineage() string {
+	return e.host + "|" + e.metadata.JobName + "|" + e.prRef
+}
+
+// buildIDLess reports whether build ID a is older than b. Prow build IDs are
+// increasing decimal numbers, possibly too large for an int64.
+func buildIDLess(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+// supersedeEntries replaces, in place, each entry for which fresh lists a
+// newer build of the same job and PR (e.g. after a /retest) with the newest
+// such build, unless that build is already monitored. It returns a
+// description of each switch.
+func supersedeEntries(entries, fresh []*monitorEntry) []string {
+	monitored := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		monitored[e.key()] = true
+	}
+	newest := make(map[string]*monitorEntry)
+	for _, f := range fresh {
+		if n, ok := newest[f.lineage()]; !ok || buildIDLess(n.metadata.BuildID, f.metadata.BuildID) {
+			newest[f.lineage()] = f
+		}
+	}
+
+	var switched []string
+	for i, e := range entries {
+		n, ok := newest[e.lineage()]
+		if !ok || !buildIDLess(e.metadata.BuildID, n.metadata.BuildID) || monitored[n.key()] {
+			continue
+		}
+		switched = append(switched, fmt.Sprintf("%s: build %s superseded by %s",
+			displayName(e), e.metadata.BuildID, n.metadata.BuildID))
+		monitored[n.key()] = true
+		entries[i] = n
+	}
+	return switched
+}
+
+// displayName returns the job name prefixed with its PR reference, if any.
+func displayName(e *monitorEntry) string {
+	if e.prRef != "" {
+		return e.prRef + " " + e.metadata.JobName
+	}
+	return e.metadata.JobName
+}
+
+// multipleHosts reports whether entries were listed by more than one Prow
+// host, in which case each job is shown with its host.
+func multipleHosts(entries []*monitorEntry) bool {
+	for _, e := range entries {
+		if e.host != entries[0].host {
+			return true
+		}
+	}
+	return false
+}
+
+// hostDisplayName is displayName prefixed with the entry's host when
+// withHost is set.
+func hostDisplayName(e *monitorEntry, withHost bool) string {
+	if withHost && e.host != "" {
+		return e.host + " " + displayName(e)
+	}
+	return displayName(e)
+}
+
+// newEntries returns the entries of fresh that are not in known and records
+// them in known, so each job is reported as new only once across fetches.
+func newEntries(known map[string]bool, fresh []*monitorEntry) []*monitorEntry {
+	var added []*monitorEntry
+	for _, e := range fresh {
+		if known[e.key()] {
+			continue
+		}
+		known[e.key()] = true
+		added = append(added, e)
+	}
+	return added
 }
 
 // formatTimeSuffix returns " (sch: HH:MM, dur: Xm Xs)" when startTime is known.
@@ -100,7 +308,10 @@ func buildEntriesAndItems(jobs []prowapi.Job) ([]*monitorEntry, []selector.Item,
 		}
 		entries = append(entries, &monitorEntry{
 			metadata:       meta,
+			host:           j.Host,
 			prRef:          j.PRRef,
+			author:         j.Author,
+			url:            j.URL,
 			state:          j.State,
 			startTime:      j.StartTime,
 			completionTime: j.CompletionTime,
@@ -111,12 +322,10 @@ func buildEntriesAndItems(jobs []prowapi.Job) ([]*monitorEntry, []selector.Item,
 		return nil, nil, fmt.Errorf("no valid prow job URLs found")
 	}
 	idxWidth := len(fmt.Sprintf("%d", len(entries)))
+	withHost := multipleHosts(entries)
 	items := make([]selector.Item, len(entries))
 	for i, e := range entries {
-		jobDisplay := e.metadata.JobName
-		if e.prRef != "" {
-			jobDisplay = e.prRef + " " + e.metadata.JobName
-		}
+		jobDisplay := hostDisplayName(e, withHost)
 		items[i] = selector.Item{
 			Key: keys[i],
 			Label: fmt.Sprintf("[%*d] %-*s  %s%s",
@@ -129,102 +338,430 @@ func buildEntriesAndItems(jobs []prowapi.Job) ([]*monitorEntry, []selector.Item,
 	return entries, items, nil
 }
 
+// matchingEntries returns the entries whose job name matches pattern. The
+// pattern is a regular expression; if it does not compile it is matched as a
+// plain substring instead.
+func matchingEntries(entries []*monitorEntry, pattern string) []*monitorEntry {
+	match := func(name string) bool { return strings.Contains(name, pattern) }
+	if re, err := regexp.Compile(pattern); err == nil {
+		match = re.MatchString
+	}
+
+	var selected []*monitorEntry
+	for _, e := range entries {
+		if match(e.metadata.JobName) {
+			selected = append(selected, e)
+		}
+	}
+	return selected
+}
+
+// selectByPattern is matchingEntries for --select: an empty result is an error.
+func selectByPattern(entries []*monitorEntry, pattern string) ([]*monitorEntry, error) {
+	selected := matchingEntries(entries, pattern)
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no jobs match --select %q (%d job(s) fetched)", pattern, len(entries))
+	}
+	return selected, nil
+}
+
+// selectByPick returns the entries at the 1-based positions listed in spec
+// (see parsePickSpec), numbered as in the interactive selector.
+func selectByPick(entries []*monitorEntry, spec string) ([]*monitorEntry, error) {
+	indices, err := parsePickSpec(spec, len(entries))
+	if err != nil {
+		return nil, fmt.Errorf("invalid --pick: %w", err)
+	}
+	selected := make([]*monitorEntry, len(indices))
+	for i, idx := range indices {
+		selected[i] = entries[idx]
+	}
+	return selected, nil
+}
+
+// parsePickSpec parses a comma-separated list of 1-based positions and
+// inclusive ranges ("1-3,5,8") among n items, and returns the matching
+// 0-based indices in ascending order without duplicates.
+func parsePickSpec(spec string, n int) ([]int, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, fmt.Errorf("empty selection")
+	}
+	picked := make(map[int]bool)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		first, last, isRange := strings.Cut(part, "-")
+		from, err := strconv.Atoi(strings.TrimSpace(first))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a position or range", part)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(strings.TrimSpace(last)); err != nil {
+				return nil, fmt.Errorf("%q is not a position or range", part)
+			}
+			if to < from {
+				return nil, fmt.Errorf("range %q is reversed", part)
+			}
+		}
+		if from < 1 || to > n {
+			return nil, fmt.Errorf("%q is out of range (1-%d)", part, n)
+		}
+		for i := from; i <= to; i++ {
+			picked[i-1] = true
+		}
+	}
+
+	indices := make([]int, 0, len(picked))
+	for i := range picked {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	return indices, nil
+}
+
+// selectInteractively lets the user pick among entries with the interactive
+// selector. It returns the chosen entries (nil if none) and the full entry
+// list, which changes when the user refreshes the list from the selector.
+func selectInteractively(pageURLs []string, entries []*monitorEntry, items []selector.Item) ([]*monitorEntry, []*monitorEntry, error) {
+	if promptPolicy() != prompt.Ask {
+		if flagMonitorAutoSelectSingle && len(entries) == 1 {
+			return entries, entries, nil
+		}
+		return nil, entries, prompt.Disabled("choosing the jobs to monitor (use --select or --pick)")
+	}
+
+	refreshFn := func() ([]selector.Item, error) {
+		refreshed, fetchErr := fetchJobs(pageURLs)
+		if fetchErr != nil {
+			return nil, fetchErr
+		}
+		if flagMonitorExcludeFinished {
+			refreshed = unfinishedJobs(refreshed)
+		}
+		if len(refreshed) == 0 {
+			return nil, fmt.Errorf("no prow jobs found")
+		}
+		freshEntries, freshItems, buildErr := buildEntriesAndItems(refreshed)
+		if buildErr != nil {
+			return nil, buildErr
+		}
+		entries = freshEntries
+		return freshItems, nil
+	}
+
+	opts := selector.Options{AutoSelectSingle: flagMonitorAutoSelectSingle}
+	if flagMonitorMaxSelect > 0 {
+		opts.MinSelect = 1
+		opts.MaxSelect = flagMonitorMaxSelect
+	}
+	selectedIndices, err := selector.Run(items, refreshFn, opts)
+	if err != nil {
+		return nil, entries, err
+	}
+
+	// Restore original order (selector returns indices in map-iteration order).
+	sort.Ints(selectedIndices)
+
+	var selected []*monitorEntry
+	for _, idx := range selectedIndices {
+		selected = append(selected, entries[idx])
+	}
+	return selected, entries, nil
+}
+
 func runMonitor(cmd *cobra.Command, args []string) error {
-	pageURL := args[0]
+	switch {
+	case flagMonitorList != "" && len(args) > 0:
+		return fmt.Errorf("--list cannot be combined with status page URLs")
+	case flagMonitorList == "" && len(args) == 0:
+		return fmt.Errorf("requires at least one status page URL, or --list")
+	}
+	filters := map[string]string{
+		"state":  flagMonitorFilterState,
+		"author": flagMonitorFilterAuthor,
+		"job":    flagMonitorFilterJob,
+	}
+	pageURLs, err := withFilterParams(args, filters)
+	if err != nil {
+		return err
+	}
+	if err := validateSummaryFormat(flagMonitorSummaryFormat); err != nil {
+		return err
+	}
+	if err := watcher.ValidateJitter(flagIntervalJitter); err != nil {
+		return err
+	}
+	watcher.IntervalJitter = flagIntervalJitter
+	if flagMonitorCheckConcurrency < 1 {
+		return fmt.Errorf("--check-concurrency must be at least 1")
+	}
+	if statusColumns, err = parseColumns(flagMonitorColumns); err != nil {
+		return err
+	}
 
 	// Load configuration so ntfy channel can come from env var / config file
 	// when not explicitly set via the --ntfy-channel flag.
-	cfg, err := config.Load(&config.Config{NtfyChannel: flagMonitorNtfyChannel})
+	cfg, err := config.Load(&config.Config{NtfyChannel: flagMonitorNtfyChannel, WebhookURL: flagWebhookURL, WebhookOn: flagWebhookOn})
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
+	if reportConfigIssues(config.Validate(cfg)) {
+		return fmt.Errorf("invalid configuration")
+	}
+	applyConfig(cfg)
 	ntfyChannel := cfg.NtfyChannel
 
-	fmt.Fprintf(os.Stdout, "Fetching prow jobs from %s...\n", pageURL)
+	if err := setupRecordReplay(flagMonitorRecord, flagMonitorReplay); err != nil {
+		return err
+	}
+	if flagMonitorList != "" {
+		return monitorWatchlist(cfg, flagMonitorList, filters)
+	}
+
+	fmt.Fprintf(os.Stdout, "Fetching prow jobs from %s...\n", strings.Join(pageURLs, ", "))
 	if ntfyChannel != "" {
 		fmt.Fprintf(os.Stdout, "Ntfy channel: %s\n", ntfyChannel)
 	}
 
-	jobs, err := prowapi.FetchJobs(pageURL)
+	jobs, err := fetchJobs(pageURLs)
 	if err != nil {
-		return fmt.Errorf("failed to fetch prow jobs: %w", err)
+		return err
 	}
 	if len(jobs) == 0 {
 		return fmt.Errorf("no prow jobs found (try adjusting the filter parameters in the URL)")
 	}
+	if flagMonitorExcludeFinished {
+		running := unfinishedJobs(jobs)
+		if len(running) == 0 {
+			return fmt.Errorf("all %d prow job(s) found have finished (drop --exclude-finished to list them)", len(jobs))
+		}
+		jobs = running
+	}
 
 	entries, items, err := buildEntriesAndItems(jobs)
 	if err != nil {
 		return err
 	}
 
-	refreshFn := func() ([]selector.Item, error) {
-		refreshed, fetchErr := prowapi.FetchJobs(pageURL)
-		if fetchErr != nil {
-			return nil, fmt.Errorf("failed to fetch prow jobs: %w", fetchErr)
+	var selected []*monitorEntry
+	if flagMonitorSelect != "" {
+		selected, err = selectByPattern(entries, flagMonitorSelect)
+		if err != nil {
+			return err
 		}
-		if len(refreshed) == 0 {
-			return nil, fmt.Errorf("no prow jobs found")
+	} else if flagMonitorPick != "" {
+		selected, err = selectByPick(entries, flagMonitorPick)
+		if err != nil {
+			return err
 		}
-		newEntries, newItems, buildErr := buildEntriesAndItems(refreshed)
-		if buildErr != nil {
-			return nil, buildErr
+	} else {
+		selected, entries, err = selectInteractively(pageURLs, entries, items)
+		if err != nil {
+			return err
+		}
+		if len(selected) == 0 {
+			fmt.Println("No jobs selected. Exiting.")
+			return nil
 		}
-		entries = newEntries
-		return newItems, nil
 	}
 
-	selectedIndices, err := selector.Run(items, refreshFn)
-	if err != nil {
-		return err
+	var refetch func() ([]*monitorEntry, error)
+	if flagMonitorRepeat {
+		// Every job listed so far is known, selected or not: only jobs that
+		// appear later (and match --select, if given) are added automatically.
+		known := make(map[string]bool)
+		newEntries(known, entries)
+		refetch = func() ([]*monitorEntry, error) {
+			fresh, fetchErr := fetchEntries(pageURLs)
+			if fetchErr != nil {
+				return nil, fetchErr
+			}
+			added := newEntries(known, fresh)
+			if flagMonitorSelect != "" {
+				added = matchingEntries(added, flagMonitorSelect)
+			}
+			if flagMonitorExcludeFinished {
+				added = unfinishedEntries(added)
+			}
+			return added, nil
+		}
 	}
-	if len(selectedIndices) == 0 {
-		fmt.Println("No jobs selected. Exiting.")
-		return nil
+
+	var latest func() ([]*monitorEntry, error)
+	if flagMonitorFollowNewer {
+		latest = func() ([]*monitorEntry, error) { return fetchEntries(pageURLs) }
 	}
 
-	// Restore original order (selector returns indices in map-iteration order).
-	sort.Ints(selectedIndices)
+	fmt.Fprintf(os.Stdout, "\nMonitoring %d job(s) (interval: %s)...\n\n", len(selected), flagMonitorInterval)
+	return monitorJobs(selected, flagMonitorInterval, ntfyChannel, refetch, latest)
+}
 
-	selected := make([]*monitorEntry, len(selectedIndices))
-	for i, idx := range selectedIndices {
-		selected[i] = entries[idx]
+// withFilterParams returns pageURLs with the non-empty values of params set
+// as their query parameters, replacing the values the URLs already have.
+func withFilterParams(pageURLs []string, params map[string]string) ([]string, error) {
+	result := make([]string, len(pageURLs))
+	for i, pageURL := range pageURLs {
+		u, err := url.Parse(pageURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status page URL %q: %w", pageURL, err)
+		}
+		q := u.Query()
+		result[i] = pageURL
+		for key, value := range params {
+			if value != "" {
+				q.Set(key, value)
+				u.RawQuery = q.Encode()
+				result[i] = u.String()
+			}
+		}
 	}
+	return result, nil
+}
 
-	fmt.Fprintf(os.Stdout, "\nMonitoring %d job(s) (interval: %s)...\n\n", len(selected), flagMonitorInterval)
-	return monitorJobs(selected, flagMonitorInterval, ntfyChannel)
+// fetchJobs fetches the jobs listed on each status page and merges them, in
+// page order. A job listed by several pages (e.g. with overlapping filters)
+// is kept once.
+func fetchJobs(pageURLs []string) ([]prowapi.Job, error) {
+	var merged []prowapi.Job
+	seen := make(map[string]bool)
+	for _, pageURL := range pageURLs {
+		jobs, err := prowapi.FetchJobs(pageURL)
+		if err != nil {
+			if len(pageURLs) > 1 {
+				return nil, fmt.Errorf("failed to fetch prow jobs from %s: %w", pageURL, err)
+			}
+			return nil, fmt.Errorf("failed to fetch prow jobs: %w", err)
+		}
+		for _, j := range jobs {
+			if seen[j.URL] {
+				continue
+			}
+			seen[j.URL] = true
+			merged = append(merged, j)
+		}
+	}
+	return merged, nil
+}
+
+// unfinishedJobs returns the jobs that are not in a terminal state.
+func unfinishedJobs(jobs []prowapi.Job) []prowapi.Job {
+	var running []prowapi.Job
+	for _, j := range jobs {
+		if !j.Finished() {
+			running = append(running, j)
+		}
+	}
+	return running
+}
+
+// unfinishedEntries returns the entries whose job was not in a terminal
+// state when listed.
+func unfinishedEntries(entries []*monitorEntry) []*monitorEntry {
+	var running []*monitorEntry
+	for _, e := range entries {
+		if !prowapi.IsTerminalState(e.state) {
+			running = append(running, e)
+		}
+	}
+	return running
+}
+
+// fetchEntries fetches the jobs currently listed on the status pages. Empty
+// pages yield no entries and no error.
+func fetchEntries(pageURLs []string) ([]*monitorEntry, error) {
+	jobs, err := fetchJobs(pageURLs)
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+	entries, _, err := buildEntriesAndItems(jobs)
+	return entries, err
+}
+
+// setupRecordReplay routes HTTP traffic through a Recorder writing to
+// recordDir or a Replayer reading from replayDir. Both empty is a no-op.
+func setupRecordReplay(recordDir, replayDir string) error {
+	switch {
+	case recordDir != "":
+		recorder, err := httpclient.NewRecorder(recordDir, nil)
+		if err != nil {
+			return err
+		}
+		httpclient.Client.Transport = recorder
+		fmt.Fprintf(os.Stdout, "Recording responses to %s\n", recordDir)
+	case replayDir != "":
+		replayer, err := httpclient.NewReplayer(replayDir)
+		if err != nil {
+			return err
+		}
+		httpclient.Client.Transport = replayer
+		fmt.Fprintf(os.Stdout, "Replaying responses from %s\n", replayDir)
+	}
+	return nil
 }
 
 // monitorJobs polls all selected jobs until they all complete, printing a
-// status table after each check round.
-func monitorJobs(entries []*monitorEntry, interval time.Duration, ntfyChannel string) error {
+// status table after each check round. When refetch is non-nil, monitoring
+// does not stop once every job is done: refetch is called at each interval
+// and the jobs it returns are added to the monitored set. When latest is
+// non-nil, it is called before each check round and monitored builds are
+// switched to newer builds of the same job it lists (see supersedeEntries);
+// monitoring does not stop either, so that a finished job is followed to its
+// retest.
+func monitorJobs(entries []*monitorEntry, interval time.Duration, ntfyChannel string, refetch, latest func() ([]*monitorEntry, error)) error {
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	defer signal.Stop(sigCh)
 
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+	// A timer rather than a ticker, so that each wait gets its own jitter
+	// (see watcher.IntervalJitter).
+	timer := time.NewTimer(watcher.JitteredInterval(interval, watcher.IntervalJitter))
+	defer timer.Stop()
 
 	// Initial check immediately so we don't wait a full interval before first output.
 	checkAllStatuses(entries)
 	// Silence notifications for jobs that were already finished before we started.
 	markAlreadyFinished(entries)
 	printStatusTable(entries)
+	outcomes := newOutcomeHistory()
 
+	waiting := false
 	for {
-		if allEntriesDone(entries) {
+		if !waiting && allEntriesDone(entries) {
 			fmt.Println("\nAll monitored jobs have completed.")
-			printFinalSummary(entries)
-			return nil
+			if err := printFinalSummary(os.Stdout, entries, flagMonitorSummaryFormat); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to print summary: %v\n", err)
+			}
+			if refetch == nil && latest == nil {
+				return nil
+			}
+			if refetch != nil {
+				fmt.Println("\nWaiting for new jobs (Ctrl+C to stop)...")
+			} else {
+				fmt.Println("\nWaiting for newer builds (Ctrl+C to stop)...")
+			}
+			waiting = true
 		}
 
 		select {
 		case <-sigCh:
 			fmt.Println("\nInterrupted.")
 			return nil
-		case <-ticker.C:
+		case <-timer.C:
+			timer.Reset(watcher.JitteredInterval(interval, watcher.IntervalJitter))
+			if waiting {
+				var found bool
+				if entries, found = followUp(entries, refetch, latest); !found {
+					continue
+				}
+				waiting = false
+			} else if latest != nil {
+				switchToNewerBuilds(entries, latest)
+			}
 			checkAllStatuses(entries)
-			notifyCompletions(entries, ntfyChannel)
+			notifyCompletions(entries, ntfyChannel, outcomes)
 			printStatusTable(entries)
 		}
 	}
@@ -241,31 +778,107 @@ func markAlreadyFinished(entries []*monitorEntry) {
 	}
 }
 
+// outcomeHistory follows the outcomes of the successive builds of each job
+// lineage (see monitorEntry.lineage), e.g. the retests of a flaky presubmit
+// added by --repeat, to tell when a failing job starts passing.
+type outcomeHistory struct {
+	failures map[string]int  // builds failed in a row, per lineage
+	counted  map[string]bool // builds already counted, by key
+}
+
+func newOutcomeHistory() *outcomeHistory {
+	return &outcomeHistory{failures: make(map[string]int), counted: make(map[string]bool)}
+}
+
+// record counts the outcome of e's finished build, once. When the build
+// passed after failed ones, it returns how many builds failed in a row before
+// it; otherwise it returns 0.
+func (h *outcomeHistory) record(e *monitorEntry) int {
+	if h.counted[e.key()] {
+		return 0
+	}
+	h.counted[e.key()] = true
+	if !e.status.Passed {
+		h.failures[e.lineage()]++
+		return 0
+	}
+	failures := h.failures[e.lineage()]
+	delete(h.failures, e.lineage())
+	return failures
+}
+
 // notifyCompletions sends a desktop and/or ntfy notification for each entry
 // that just transitioned to a finished state and has not yet been notified.
-func notifyCompletions(entries []*monitorEntry, ntfyChannel string) {
+// Every finished build is recorded in outcomes, and a build that passed after
+// failed builds of the same job is notified as a recovery.
+func notifyCompletions(entries []*monitorEntry, ntfyChannel string, outcomes *outcomeHistory) {
 	for _, e := range entries {
-		if e.notified {
+		if e.status == nil || !e.status.Finished {
 			continue
 		}
-		if e.status == nil || !e.status.Finished {
+		recoveredAfter := outcomes.record(e)
+		if e.notified {
 			continue
 		}
 		e.notified = true
-		jobDisplay := e.metadata.JobName
-		if e.prRef != "" {
-			jobDisplay = e.prRef + " " + jobDisplay
+		jobDisplay := displayName(e)
+		msg := notifier.FormatJobCompletionMessage(jobDisplay, e.status.Passed, entryDuration(e))
+		if recoveredAfter > 0 {
+			msg = notifier.FormatJobRecoveredMessage(jobDisplay, recoveredAfter)
+		}
+		event := notifier.EventJobFailed
+		if e.status.Passed {
+			event = notifier.EventJobPassed
 		}
-		msg := notifier.FormatJobStatusMessage(jobDisplay, e.status.Passed)
-		sendNotificationWithConfig(jobDisplay, msg, e.status.Passed, ntfyChannel, true)
+		sendNotificationWithConfig(event, jobDisplay, msg, e.status.Passed, ntfyChannel, true)
+	}
+}
+
+// followUp looks for more to monitor once every entry is done: the jobs
+// returned by refetch, appended to entries, and the newer builds listed by
+// latest, which replace the finished ones. Either function may be nil. It
+// returns the entries and whether any was added or switched.
+func followUp(entries []*monitorEntry, refetch, latest func() ([]*monitorEntry, error)) ([]*monitorEntry, bool) {
+	found := false
+	if refetch != nil {
+		added, err := refetch()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		} else if len(added) > 0 {
+			fmt.Printf("\nFound %d new job(s).\n\n", len(added))
+			entries = append(entries, added...)
+			found = true
+		}
+	}
+	if latest != nil && switchToNewerBuilds(entries, latest) {
+		found = true
 	}
+	return entries, found
+}
+
+// switchToNewerBuilds applies supersedeEntries with the jobs returned by
+// latest, reporting each switch, and returns whether any entry switched. A
+// failed fetch only produces a warning.
+func switchToNewerBuilds(entries []*monitorEntry, latest func() ([]*monitorEntry, error)) bool {
+	fresh, err := latest()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return false
+	}
+	switched := supersedeEntries(entries, fresh)
+	for _, msg := range switched {
+		fmt.Printf("%s\n", msg)
+	}
+	return len(switched) > 0
 }
 
 // checkAllStatuses fetches the current finished.json status for every entry
-// that has not yet completed. Checks are performed concurrently.
+// that has not yet completed. Checks are performed concurrently, at most
+// --check-concurrency at a time.
 func checkAllStatuses(entries []*monitorEntry) {
 	var wg sync.WaitGroup
 	var mu sync.Mutex
+	sem := make(chan struct{}, max(flagMonitorCheckConcurrency, 1))
 
 	for _, e := range entries {
 		if e.status != nil && e.status.Finished {
@@ -273,16 +886,25 @@ func checkAllStatuses(entries []*monitorEntry) {
 		}
 		wg.Add(1)
 		e := e
+		sem <- struct{}{}
 		go func() {
 			defer wg.Done()
 			finishedURL := watcher.BuildFinishedJSONURL(e.metadata)
-			status, err := watcher.CheckJobStatus(finishedURL)
+			status, err := checkJobStatus(finishedURL)
+			<-sem
 			mu.Lock()
 			defer mu.Unlock()
-			if err != nil {
+			switch {
+			case errors.Is(err, watcher.ErrIncompleteStatus) && e.incomplete < watcher.MaxIncompleteChecks:
+				// finished.json is probably still being written: retry next round.
+				e.incomplete++
+			case err != nil:
 				e.err = err
-			} else if status != nil {
-				e.status = status
+			default:
+				e.incomplete = 0
+				if status != nil {
+					e.status = status
+				}
 			}
 		}()
 	}
@@ -299,57 +921,125 @@ func allEntriesDone(entries []*monitorEntry) bool {
 	return true
 }
 
-// printStatusTable prints the current status of all monitored jobs.
+// countDone returns how many entries have a finished status or an error.
+func countDone(entries []*monitorEntry) int {
+	done := 0
+	for _, e := range entries {
+		if e.err != nil || (e.status != nil && e.status.Finished) {
+			done++
+		}
+	}
+	return done
+}
+
+// renderBar renders a progress bar of width cells for done out of total
+// items, e.g. "[####----] 12/20 done". A cell is filled only once its whole
+// share is done, so the bar is full only when done == total.
+func renderBar(done, total, width int) string {
+	width = max(width, 0)
+	done = min(max(done, 0), max(total, 0))
+	filled := 0
+	if total > 0 {
+		filled = done * width / total
+	}
+	return fmt.Sprintf("[%s%s] %d/%d done",
+		strings.Repeat("#", filled), strings.Repeat("-", width-filled), done, max(total, 0))
+}
+
+// printStatusTable prints the current status of all monitored jobs, and
+// writes it to the --status-file if any.
 func printStatusTable(entries []*monitorEntry) {
-	fmt.Printf("[%s]\n", time.Now().Format("15:04:05"))
+	var buf bytes.Buffer
+	renderStatusTable(&buf, entries)
+	os.Stdout.Write(buf.Bytes())
+	if flagMonitorStatusFile != "" {
+		if err := writeStatusFile(flagMonitorStatusFile, buf.Bytes()); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+}
+
+// renderStatusTable writes the current status of all monitored jobs to w,
+// followed by a progress bar with --progress. With --compact only the bar is
+// written.
+func renderStatusTable(w io.Writer, entries []*monitorEntry) {
+	bar := renderBar(countDone(entries), len(entries), progressBarWidth)
+	if flagMonitorCompact {
+		fmt.Fprintf(w, "[%s] %s\n", time.Now().Format("15:04:05"), bar)
+		return
+	}
+	fmt.Fprintf(w, "[%s]\n", time.Now().Format("15:04:05"))
+	if len(statusColumns) > 0 {
+		renderColumns(w, entries, statusColumns)
+	} else {
+		renderDefaultRows(w, entries)
+	}
+	if flagMonitorProgress {
+		fmt.Fprintf(w, "  %s\n", bar)
+	}
+	fmt.Fprintln(w)
+}
+
+// entryStatus returns the formatted current status of an entry: running,
+// passed, or failed with the error that prevented checking it, if any.
+func entryStatus(e *monitorEntry) string {
+	switch {
+	case e.err != nil:
+		return output.FormatStatus(output.StatusFailed) + fmt.Sprintf(" (error: %v)", e.err)
+	case e.status == nil || !e.status.Finished:
+		return output.FormatStatus(output.StatusRunning)
+	case e.status.Passed:
+		return output.FormatStatus(output.StatusSucceeded)
+	default:
+		return output.FormatStatus(output.StatusFailed)
+	}
+}
+
+// renderDefaultRows writes one row per entry: its index, status, name and
+// times.
+func renderDefaultRows(w io.Writer, entries []*monitorEntry) {
 	idxWidth := len(fmt.Sprintf("%d", len(entries)))
+	withHost := multipleHosts(entries)
 	for i, e := range entries {
-		var statusStr string
-		switch {
-		case e.err != nil:
-			statusStr = output.FormatStatus(output.StatusFailed) + fmt.Sprintf(" (error: %v)", e.err)
-		case e.status == nil || !e.status.Finished:
-			statusStr = output.FormatStatus(output.StatusRunning)
-		case e.status.Passed:
-			statusStr = output.FormatStatus(output.StatusSucceeded)
-		default:
-			statusStr = output.FormatStatus(output.StatusFailed)
-		}
+		statusStr := entryStatus(e)
 		// For running jobs use live elapsed time; for finished use the watcher timestamp.
 		var endTime time.Time
 		if e.status != nil && e.status.Finished {
 			endTime = e.status.Timestamp
 		}
-		jobDisplay := e.metadata.JobName
-		if e.prRef != "" {
-			jobDisplay = e.prRef + " " + e.metadata.JobName
-		}
-		fmt.Printf("  [%*d] %-*s  %s%s\n",
+		jobDisplay := hostDisplayName(e, withHost)
+		fmt.Fprintf(w, "  [%*d] %-*s  %s%s\n",
 			idxWidth, i+1,
 			stateWidth, statusStr,
 			jobDisplay,
 			formatTimeSuffix(e.startTime, endTime))
 	}
-	fmt.Println()
 }
 
-// printFinalSummary prints a summary of pass/fail counts once all jobs are done.
-func printFinalSummary(entries []*monitorEntry) {
-	fmt.Println("Summary:")
-	passed, failed, errored := 0, 0, 0
-	for _, e := range entries {
-		switch {
-		case e.err != nil:
-			errored++
-		case e.status != nil && e.status.Passed:
-			passed++
-		default:
-			failed++
-		}
+// writeStatusFile replaces the file at path with table. The table is written
+// to a temporary file in the same directory, then renamed over path, so that
+// readers always see a complete table.
+func writeStatusFile(path string, table []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write status file: %w", err)
+	}
+	tmp := f.Name()
+	_, err = f.Write(table)
+	if cerr := f.Close(); err == nil {
+		err = cerr
 	}
-	fmt.Printf("  Passed:  %d\n", passed)
-	fmt.Printf("  Failed:  %d\n", failed)
-	if errored > 0 {
-		fmt.Printf("  Errored: %d\n", errored)
+	if err == nil {
+		// CreateTemp makes the file private; the status is meant to be read
+		// like any other file.
+		err = os.Chmod(tmp, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write status file: %w", err)
 	}
+	return nil
 }
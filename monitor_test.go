@@ -0,0 +1,850 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+
+	"github.com/clobrano/prow-helper/internal/httpclient"
+	"github.com/clobrano/prow-helper/internal/notifier"
+	"github.com/clobrano/prow-helper/internal/parser"
+	"github.com/clobrano/prow-helper/internal/prowapi"
+	"github.com/clobrano/prow-helper/internal/watcher"
+)
+
+func jobURL(name, build string) string {
+	return "https://prow.ci.openshift.org/view/gs/test-platform-results/logs/" + name + "/" + build
+}
+
+func TestNewEntries_AcrossFetchRounds(t *testing.T) {
+	first, _, err := buildEntriesAndItems([]prowapi.Job{
+		{URL: jobURL("job-a", "100"), State: "pending"},
+		{URL: jobURL("job-b", "200"), State: "success"},
+	})
+	if err != nil {
+		t.Fatalf("buildEntriesAndItems() error = %v", err)
+	}
+
+	known := make(map[string]bool)
+	if got := newEntries(known, first); len(got) != 2 {
+		t.Fatalf("first round: got %d new entries, want 2", len(got))
+	}
+
+	// Second round: job-a is unchanged, job-b has a new build, job-c appeared.
+	second, _, err := buildEntriesAndItems([]prowapi.Job{
+		{URL: jobURL("job-a", "100"), State: "success"},
+		{URL: jobURL("job-b", "200"), State: "success"},
+		{URL: jobURL("job-b", "201"), State: "pending"},
+		{URL: jobURL("job-c", "300"), State: "triggered"},
+	})
+	if err != nil {
+		t.Fatalf("buildEntriesAndItems() error = %v", err)
+	}
+
+	added := newEntries(known, second)
+	if len(added) != 2 {
+		t.Fatalf("second round: got %d new entries, want 2", len(added))
+	}
+	if added[0].metadata.JobName != "job-b" || added[0].metadata.BuildID != "201" {
+		t.Errorf("added[0] = %s/%s, want job-b/201", added[0].metadata.JobName, added[0].metadata.BuildID)
+	}
+	if added[1].metadata.JobName != "job-c" || added[1].metadata.BuildID != "300" {
+		t.Errorf("added[1] = %s/%s, want job-c/300", added[1].metadata.JobName, added[1].metadata.BuildID)
+	}
+
+	// A third identical round reports nothing new.
+	if got := newEntries(known, second); len(got) != 0 {
+		t.Errorf("third round: got %d new entries, want 0", len(got))
+	}
+}
+
+func TestSelectByPattern(t *testing.T) {
+	entries, _, err := buildEntriesAndItems([]prowapi.Job{
+		{URL: jobURL("periodic-ci-e2e-metal-ipi", "1")},
+		{URL: jobURL("periodic-ci-e2e-aws", "2")},
+		{URL: jobURL("pull-ci-e2e-metal-ovn", "3")},
+		{URL: jobURL("pull-ci-unit", "4")},
+	})
+	if err != nil {
+		t.Fatalf("buildEntriesAndItems() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		pattern string
+		want    []string
+	}{
+		{"substring", "e2e-metal", []string{"1", "3"}},
+		{"regex", "^pull-ci-", []string{"3", "4"}},
+		{"regex alternation", "aws|unit", []string{"2", "4"}},
+		{"invalid regex falls back to substring", "e2e-metal-ipi(", nil},
+		{"invalid regex matching literally", "ci-e2e-aws", []string{"2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectByPattern(entries, tt.pattern)
+			if len(tt.want) == 0 {
+				if err == nil {
+					t.Fatalf("selectByPattern(%q) = %d entries, want error", tt.pattern, len(got))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("selectByPattern(%q) error = %v", tt.pattern, err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("selectByPattern(%q) = %d entries, want %d", tt.pattern, len(got), len(tt.want))
+			}
+			for i, e := range got {
+				if e.metadata.BuildID != tt.want[i] {
+					t.Errorf("selectByPattern(%q)[%d] build = %s, want %s", tt.pattern, i, e.metadata.BuildID, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSelectByPattern_NoMatchErrors(t *testing.T) {
+	entries, _, _ := buildEntriesAndItems([]prowapi.Job{{URL: jobURL("job-a", "1")}})
+
+	_, err := selectByPattern(entries, "e2e-metal")
+	if err == nil {
+		t.Fatal("selectByPattern() should fail when nothing matches")
+	}
+	if !strings.Contains(err.Error(), `"e2e-metal"`) {
+		t.Errorf("error %q should mention the pattern", err)
+	}
+}
+
+// fakeProw serves a prowjobs.js listing two jobs and their finished.json
+// files. job-a finishes (passing) on its second status check; job-b is
+// finished and failed from the start.
+type fakeProw struct {
+	checksA int
+}
+
+func (f *fakeProw) RoundTrip(req *http.Request) (*http.Response, error) {
+	respond := func(status int, body string) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: status,
+			Header:     make(http.Header),
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	}
+	switch {
+	case req.URL.Path == "/prowjobs.js":
+		return respond(http.StatusOK, `var allBuilds = {"items":[
+			{"spec":{"job":"job-a"},"status":{"state":"pending","url":"`+jobURL("job-a", "1")+`"}},
+			{"spec":{"job":"job-b"},"status":{"state":"failure","url":"`+jobURL("job-b", "2")+`"}}]}`)
+	case strings.HasSuffix(req.URL.Path, "/job-a/1/finished.json"):
+		f.checksA++
+		if f.checksA < 2 {
+			return respond(http.StatusNotFound, "")
+		}
+		return respond(http.StatusOK, `{"timestamp":1700000000,"passed":true}`)
+	case strings.HasSuffix(req.URL.Path, "/job-b/2/finished.json"):
+		return respond(http.StatusOK, `{"timestamp":1700000000,"passed":false}`)
+	}
+	return respond(http.StatusNotFound, "")
+}
+
+// monitorRounds fetches the job list and runs the given number of status
+// check rounds, returning a summary of each entry's status after each round.
+func monitorRounds(t *testing.T, rounds int) []string {
+	t.Helper()
+	jobs, err := prowapi.FetchJobs("https://prow.ci.openshift.org/")
+	if err != nil {
+		t.Fatalf("FetchJobs() error = %v", err)
+	}
+	entries, _, err := buildEntriesAndItems(jobs)
+	if err != nil {
+		t.Fatalf("buildEntriesAndItems() error = %v", err)
+	}
+
+	var got []string
+	for i := 0; i < rounds; i++ {
+		checkAllStatuses(entries)
+		for _, e := range entries {
+			state := "running"
+			switch {
+			case e.err != nil:
+				state = "error: " + e.err.Error()
+			case e.status != nil && e.status.Passed:
+				state = "passed"
+			case e.status != nil:
+				state = "failed"
+			}
+			got = append(got, fmt.Sprintf("round %d %s %s", i, e.metadata.JobName, state))
+		}
+	}
+	return got
+}
+
+func TestMonitorRecordThenReplay(t *testing.T) {
+	origTransport := httpclient.Client.Transport
+	defer func() { httpclient.Client.Transport = origTransport }()
+	dir := t.TempDir()
+
+	recorder, err := httpclient.NewRecorder(dir, &fakeProw{})
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+	httpclient.Client.Transport = recorder
+	recorded := monitorRounds(t, 3)
+
+	if err := setupRecordReplay("", dir); err != nil {
+		t.Fatalf("setupRecordReplay() error = %v", err)
+	}
+	replayed := monitorRounds(t, 3)
+
+	if strings.Join(replayed, "\n") != strings.Join(recorded, "\n") {
+		t.Errorf("replayed monitor run differs from the recorded one:\nrecorded:\n%s\nreplayed:\n%s",
+			strings.Join(recorded, "\n"), strings.Join(replayed, "\n"))
+	}
+	// Sanity check: the recording captured job-a's transition to passed.
+	if recorded[0] != "round 0 job-a running" || recorded[4] != "round 2 job-a passed" {
+		t.Errorf("unexpected recorded run:\n%s", strings.Join(recorded, "\n"))
+	}
+}
+
+// sequenceTransport answers every request with the next body in bodies,
+// repeating the last one once they are exhausted.
+type sequenceTransport struct {
+	bodies []string
+	calls  int
+}
+
+func (s *sequenceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := s.bodies[len(s.bodies)-1]
+	if s.calls < len(s.bodies) {
+		body = s.bodies[s.calls]
+	}
+	s.calls++
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestCheckAllStatuses_TransientBadFinishedJSON(t *testing.T) {
+	origTransport := httpclient.Client.Transport
+	defer func() { httpclient.Client.Transport = origTransport }()
+	httpclient.Client.Transport = &sequenceTransport{bodies: []string{"", `{"timestamp":1700000000,"passed":true}`}}
+
+	entries, _, _ := buildEntriesAndItems([]prowapi.Job{{URL: jobURL("job-a", "1")}})
+	e := entries[0]
+
+	checkAllStatuses(entries)
+	if e.err != nil {
+		t.Fatalf("after a bad body: err = %v, want the job to stay running", e.err)
+	}
+	if e.status != nil {
+		t.Fatalf("after a bad body: status = %+v, want nil", e.status)
+	}
+
+	checkAllStatuses(entries)
+	if e.err != nil || e.status == nil || !e.status.Passed {
+		t.Errorf("after a good body: err = %v, status = %+v, want a passed job", e.err, e.status)
+	}
+}
+
+func TestCheckAllStatuses_PersistentBadFinishedJSON(t *testing.T) {
+	origTransport := httpclient.Client.Transport
+	defer func() { httpclient.Client.Transport = origTransport }()
+	httpclient.Client.Transport = &sequenceTransport{bodies: []string{"<html>error</html>"}}
+
+	entries, _, _ := buildEntriesAndItems([]prowapi.Job{{URL: jobURL("job-a", "1")}})
+	e := entries[0]
+
+	for i := 0; i < watcher.MaxIncompleteChecks; i++ {
+		checkAllStatuses(entries)
+		if e.err != nil {
+			t.Fatalf("check %d: err = %v, want the bad body tolerated", i, e.err)
+		}
+	}
+	checkAllStatuses(entries)
+	if !errors.Is(e.err, watcher.ErrIncompleteStatus) {
+		t.Errorf("err = %v, want ErrIncompleteStatus once the tolerance is exhausted", e.err)
+	}
+}
+
+func TestNotifyCompletions_OnlyOnFailure(t *testing.T) {
+	sent := captureNotifications(t)
+	flagNotifyOnlyFail = true
+	t.Cleanup(func() { flagNotifyOnlyFail = false })
+
+	entries := []*monitorEntry{
+		{metadata: &parser.ProwMetadata{JobName: "passed-job"}, status: &watcher.JobStatus{Finished: true, Passed: true}},
+		{metadata: &parser.ProwMetadata{JobName: "failed-job"}, status: &watcher.JobStatus{Finished: true, Passed: false}},
+		{metadata: &parser.ProwMetadata{JobName: "running-job"}},
+	}
+	notifyCompletions(entries, "channel", newOutcomeHistory())
+
+	if len(*sent) != 1 || (*sent)[0] {
+		t.Errorf("notifications sent = %v, want only the failure", *sent)
+	}
+}
+
+func TestSupersedeEntries_AcrossFetchRounds(t *testing.T) {
+	first, _, err := buildEntriesAndItems([]prowapi.Job{
+		{URL: jobURL("pull-ci-e2e", "100"), PRRef: "[org/repo PR1]", State: "pending"},
+		{URL: jobURL("pull-ci-e2e", "110"), PRRef: "[org/repo PR2]", State: "pending"},
+		{URL: jobURL("periodic-ci-e2e", "120"), State: "pending"},
+	})
+	if err != nil {
+		t.Fatalf("buildEntriesAndItems() error = %v", err)
+	}
+	entries := append([]*monitorEntry(nil), first...)
+
+	// Second round: PR1's job was retested twice, PR2 is unchanged, and a
+	// build with a lower ID (listed late) must not replace the periodic one.
+	second, _, err := buildEntriesAndItems([]prowapi.Job{
+		{URL: jobURL("pull-ci-e2e", "100"), PRRef: "[org/repo PR1]", State: "aborted"},
+		{URL: jobURL("pull-ci-e2e", "130"), PRRef: "[org/repo PR1]", State: "pending"},
+		{URL: jobURL("pull-ci-e2e", "99"), PRRef: "[org/repo PR1]", State: "failure"},
+		{URL: jobURL("pull-ci-e2e", "1000"), PRRef: "[org/repo PR1]", State: "triggered"},
+		{URL: jobURL("pull-ci-e2e", "110"), PRRef: "[org/repo PR2]", State: "pending"},
+		{URL: jobURL("periodic-ci-e2e", "119"), State: "success"},
+	})
+	if err != nil {
+		t.Fatalf("buildEntriesAndItems() error = %v", err)
+	}
+
+	switched := supersedeEntries(entries, second)
+	if len(switched) != 1 {
+		t.Fatalf("supersedeEntries() switched %d entries (%v), want 1", len(switched), switched)
+	}
+	want := []string{"1000", "110", "120"}
+	for i, e := range entries {
+		if e.metadata.BuildID != want[i] {
+			t.Errorf("entries[%d] build = %s, want %s", i, e.metadata.BuildID, want[i])
+		}
+	}
+	if entries[0].status != nil || entries[0].notified {
+		t.Error("the newer build should be monitored from scratch")
+	}
+
+	// Repeating the same round changes nothing.
+	if again := supersedeEntries(entries, second); len(again) != 0 {
+		t.Errorf("second supersedeEntries() switched %v, want nothing", again)
+	}
+}
+
+func TestSupersedeEntries_SkipsAlreadyMonitoredBuild(t *testing.T) {
+	entries, _, _ := buildEntriesAndItems([]prowapi.Job{
+		{URL: jobURL("job", "1"), State: "pending"},
+		{URL: jobURL("job", "2"), State: "pending"},
+	})
+	fresh, _, _ := buildEntriesAndItems([]prowapi.Job{{URL: jobURL("job", "2"), State: "pending"}})
+
+	if switched := supersedeEntries(entries, fresh); len(switched) != 0 {
+		t.Errorf("supersedeEntries() switched %v, want nothing (build 2 is already monitored)", switched)
+	}
+}
+
+func TestFollowUp_NewerBuildOfFinishedJob(t *testing.T) {
+	entries, _, err := buildEntriesAndItems([]prowapi.Job{
+		{URL: jobURL("pull-ci-e2e", "100"), PRRef: "[org/repo PR1]"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries[0].status = &watcher.JobStatus{Finished: true}
+	entries[0].notified = true
+	retested, _, err := buildEntriesAndItems([]prowapi.Job{
+		{URL: jobURL("pull-ci-e2e", "100"), PRRef: "[org/repo PR1]"},
+		{URL: jobURL("pull-ci-e2e", "101"), PRRef: "[org/repo PR1]"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fresh := retested[:1]
+	latest := func() ([]*monitorEntry, error) { return fresh, nil }
+
+	// Nothing newer yet: the finished job keeps being followed.
+	if got, found := followUp(entries, nil, latest); found || len(got) != 1 || got[0].metadata.BuildID != "100" {
+		t.Fatalf("followUp() = %v, %v, want no change", got, found)
+	}
+	// The retest shows up: it replaces the finished build.
+	fresh = retested
+	got, found := followUp(entries, nil, latest)
+	if !found || len(got) != 1 || got[0].metadata.BuildID != "101" {
+		t.Fatalf("followUp() = %v, %v, want build 101", got, found)
+	}
+	if allEntriesDone(got) {
+		t.Error("allEntriesDone() = true after switching to the retest, want it monitored")
+	}
+}
+
+func TestParsePickSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		n       int
+		want    []int
+		wantErr bool
+	}{
+		{name: "singleton", spec: "2", n: 5, want: []int{1}},
+		{name: "range", spec: "1-3", n: 5, want: []int{0, 1, 2}},
+		{name: "ranges and singletons", spec: "1-3,5,8", n: 8, want: []int{0, 1, 2, 4, 7}},
+		{name: "spaces", spec: " 4 , 1 - 2 ", n: 5, want: []int{0, 1, 3}},
+		{name: "overlaps and duplicates", spec: "3,2-4,3", n: 5, want: []int{1, 2, 3}},
+		{name: "single-item range", spec: "5-5", n: 5, want: []int{4}},
+		{name: "past the end", spec: "1,6", n: 5, wantErr: true},
+		{name: "range past the end", spec: "4-6", n: 5, wantErr: true},
+		{name: "zero", spec: "0", n: 5, wantErr: true},
+		{name: "reversed range", spec: "3-1", n: 5, wantErr: true},
+		{name: "empty", spec: "", n: 5, wantErr: true},
+		{name: "empty item", spec: "1,,2", n: 5, wantErr: true},
+		{name: "not a number", spec: "one", n: 5, wantErr: true},
+		{name: "open range", spec: "2-", n: 5, wantErr: true},
+		{name: "negative", spec: "-1", n: 5, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePickSpec(tt.spec, tt.n)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePickSpec(%q, %d) error = %v, wantErr %v", tt.spec, tt.n, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("parsePickSpec(%q, %d) = %v, want %v", tt.spec, tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectByPick(t *testing.T) {
+	entries := []*monitorEntry{
+		{metadata: &parser.ProwMetadata{JobName: "a"}},
+		{metadata: &parser.ProwMetadata{JobName: "b"}},
+		{metadata: &parser.ProwMetadata{JobName: "c"}},
+	}
+	got, err := selectByPick(entries, "3,1")
+	if err != nil {
+		t.Fatalf("selectByPick() error = %v", err)
+	}
+	if len(got) != 2 || got[0] != entries[0] || got[1] != entries[2] {
+		t.Errorf("selectByPick(\"3,1\") picked %d entries, want a and c in list order", len(got))
+	}
+	if _, err := selectByPick(entries, "4"); err == nil || !strings.Contains(err.Error(), "--pick") {
+		t.Errorf("selectByPick(\"4\") error = %v, want an out-of-range --pick error", err)
+	}
+}
+
+func TestRenderBar(t *testing.T) {
+	tests := []struct {
+		name               string
+		done, total, width int
+		want               string
+	}{
+		{name: "nothing done", done: 0, total: 20, width: 8, want: "[--------] 0/20 done"},
+		{name: "partial", done: 12, total: 20, width: 8, want: "[####----] 12/20 done"},
+		{name: "partial rounds down", done: 19, total: 20, width: 8, want: "[#######-] 19/20 done"},
+		{name: "all done", done: 20, total: 20, width: 8, want: "[########] 20/20 done"},
+		{name: "wider than total", done: 1, total: 2, width: 10, want: "[#####-----] 1/2 done"},
+		{name: "zero width", done: 1, total: 2, width: 0, want: "[] 1/2 done"},
+		{name: "negative width", done: 1, total: 2, width: -3, want: "[] 1/2 done"},
+		{name: "no jobs", done: 0, total: 0, width: 4, want: "[----] 0/0 done"},
+		{name: "done past total", done: 5, total: 3, width: 3, want: "[###] 3/3 done"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderBar(tt.done, tt.total, tt.width); got != tt.want {
+				t.Errorf("renderBar(%d, %d, %d) = %q, want %q", tt.done, tt.total, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountDone(t *testing.T) {
+	entries := []*monitorEntry{
+		{metadata: &parser.ProwMetadata{JobName: "running"}},
+		{metadata: &parser.ProwMetadata{JobName: "pending"}, status: &watcher.JobStatus{}},
+		{metadata: &parser.ProwMetadata{JobName: "passed"}, status: &watcher.JobStatus{Finished: true, Passed: true}},
+		{metadata: &parser.ProwMetadata{JobName: "failed"}, status: &watcher.JobStatus{Finished: true}},
+		{metadata: &parser.ProwMetadata{JobName: "error"}, err: errors.New("boom")},
+	}
+	if got := countDone(entries); got != 3 {
+		t.Errorf("countDone() = %d, want 3", got)
+	}
+}
+
+// twoHostProw serves a different prowjobs.js on each of two Prow hosts. Both
+// list the build shared/3 and a build of job "e2e".
+type twoHostProw struct{}
+
+func (twoHostProw) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := ""
+	switch req.URL.Host {
+	case "prow.ci.openshift.org":
+		body = `var allBuilds = {"items":[
+			{"spec":{"job":"e2e"},"status":{"state":"pending","url":"` + jobURL("e2e", "1") + `"}},
+			{"spec":{"job":"shared"},"status":{"state":"pending","url":"` + jobURL("shared", "3") + `"}}]}`
+	case "prow.example.com":
+		body = `var allBuilds = {"items":[
+			{"spec":{"job":"e2e"},"status":{"state":"success","url":"https://prow.example.com/view/gs/internal-results/logs/e2e/2"}},
+			{"spec":{"job":"shared"},"status":{"state":"pending","url":"` + jobURL("shared", "3") + `"}}]}`
+	}
+	status := http.StatusOK
+	if body == "" {
+		status = http.StatusNotFound
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestFetchEntries_MultipleHosts(t *testing.T) {
+	origTransport := httpclient.Client.Transport
+	defer func() { httpclient.Client.Transport = origTransport }()
+	httpclient.Client.Transport = twoHostProw{}
+	origHosts := parser.AllowedHosts
+	defer func() { parser.AllowedHosts = origHosts }()
+	parser.AllowedHosts = []string{"prow.ci.openshift.org", "prow.example.com"}
+
+	entries, err := fetchEntries([]string{"https://prow.ci.openshift.org/", "https://prow.example.com/?state=success"})
+	if err != nil {
+		t.Fatalf("fetchEntries() error = %v", err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.host+" "+e.metadata.Bucket+"/"+e.metadata.JobName+"/"+e.metadata.BuildID)
+	}
+	want := []string{
+		"prow.ci.openshift.org test-platform-results/e2e/1",
+		"prow.ci.openshift.org test-platform-results/shared/3",
+		"prow.example.com internal-results/e2e/2",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("fetchEntries() = %q, want %q", got, want)
+	}
+
+	if !multipleHosts(entries) {
+		t.Error("multipleHosts() = false, want true")
+	}
+	if multipleHosts(entries[:2]) {
+		t.Error("multipleHosts() of a single host = true, want false")
+	}
+	if got := hostDisplayName(entries[2], true); got != "prow.example.com e2e" {
+		t.Errorf("hostDisplayName() = %q, want the job prefixed with its host", got)
+	}
+
+	// The same job name on two hosts is two different jobs.
+	if entries[0].lineage() == entries[2].lineage() {
+		t.Errorf("lineage() of e2e on both hosts = %q, want them distinct", entries[0].lineage())
+	}
+}
+
+func TestFetchEntries_FailingHost(t *testing.T) {
+	origTransport := httpclient.Client.Transport
+	defer func() { httpclient.Client.Transport = origTransport }()
+	httpclient.Client.Transport = twoHostProw{}
+
+	_, err := fetchEntries([]string{"https://prow.ci.openshift.org/", "https://prow.unknown.example/"})
+	if err == nil || !strings.Contains(err.Error(), "prow.unknown.example") {
+		t.Errorf("fetchEntries() error = %v, want it to name the failing page", err)
+	}
+}
+
+func TestUnfinishedJobs(t *testing.T) {
+	jobs := []prowapi.Job{
+		{Name: "triggered", State: "triggered", URL: jobURL("triggered", "1")},
+		{Name: "passed", State: "success", URL: jobURL("passed", "2")},
+		{Name: "pending", State: "pending", URL: jobURL("pending", "3")},
+		{Name: "failed", State: "failure", URL: jobURL("failed", "4")},
+		{Name: "aborted", State: "aborted", URL: jobURL("aborted", "5")},
+		{Name: "errored", State: "error", URL: jobURL("errored", "6")},
+	}
+	var got []string
+	for _, j := range unfinishedJobs(jobs) {
+		got = append(got, j.Name)
+	}
+	if want := []string{"triggered", "pending"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("unfinishedJobs() = %v, want %v", got, want)
+	}
+
+	entries, _, err := buildEntriesAndItems(jobs)
+	if err != nil {
+		t.Fatalf("buildEntriesAndItems() error = %v", err)
+	}
+	got = nil
+	for _, e := range unfinishedEntries(entries) {
+		got = append(got, e.metadata.JobName)
+	}
+	if want := []string{"triggered", "pending"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("unfinishedEntries() = %v, want %v", got, want)
+	}
+
+	if got := unfinishedJobs(jobs[1:2]); len(got) != 0 {
+		t.Errorf("unfinishedJobs() of finished jobs = %v, want none", got)
+	}
+}
+
+func TestWithFilterParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		url    string
+		params map[string]string
+		want   string
+	}{
+		{
+			name:   "no flags keeps the URL",
+			url:    "https://prow.ci.openshift.org/?job=e2e&author=clobrano",
+			params: map[string]string{"state": "", "author": "", "job": ""},
+			want:   "https://prow.ci.openshift.org/?job=e2e&author=clobrano",
+		},
+		{
+			name:   "adds a parameter",
+			url:    "https://prow.ci.openshift.org/",
+			params: map[string]string{"state": "pending"},
+			want:   "https://prow.ci.openshift.org/?state=pending",
+		},
+		{
+			name:   "flag overrides the URL",
+			url:    "https://prow.ci.openshift.org/?author=someone&state=success",
+			params: map[string]string{"author": "clobrano"},
+			want:   "https://prow.ci.openshift.org/?author=clobrano&state=success",
+		},
+		{
+			name:   "flags combine with the URL",
+			url:    "https://prow.ci.openshift.org/?author=clobrano&type=presubmit",
+			params: map[string]string{"state": "failure", "job": "e2e-metal", "author": ""},
+			want:   "https://prow.ci.openshift.org/?author=clobrano&job=e2e-metal&state=failure&type=presubmit",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := withFilterParams([]string{tt.url}, tt.params)
+			if err != nil {
+				t.Fatalf("withFilterParams() error = %v", err)
+			}
+			if got[0] != tt.want {
+				t.Errorf("withFilterParams() = %q, want %q", got[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestWithFilterParams_FiltersFetchedJobs(t *testing.T) {
+	origTransport := httpclient.Client.Transport
+	defer func() { httpclient.Client.Transport = origTransport }()
+	httpclient.Client.Transport = twoHostProw{}
+	origHosts := parser.AllowedHosts
+	defer func() { parser.AllowedHosts = origHosts }()
+	parser.AllowedHosts = []string{"prow.ci.openshift.org", "prow.example.com"}
+
+	// --filter-state pending replaces state=success on the second page and
+	// applies to the first one too.
+	pageURLs, err := withFilterParams(
+		[]string{"https://prow.ci.openshift.org/?job=shared", "https://prow.example.com/?state=success"},
+		map[string]string{"state": "pending"})
+	if err != nil {
+		t.Fatalf("withFilterParams() error = %v", err)
+	}
+	jobs, err := fetchJobs(pageURLs)
+	if err != nil {
+		t.Fatalf("fetchJobs() error = %v", err)
+	}
+	var got []string
+	for _, j := range jobs {
+		got = append(got, j.Host+" "+j.Name+" "+j.State)
+	}
+	want := []string{"prow.ci.openshift.org shared pending"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("fetchJobs() = %q, want %q", got, want)
+	}
+}
+
+func TestWithFilterParams_InvalidURL(t *testing.T) {
+	if _, err := withFilterParams([]string{"://bad"}, map[string]string{"state": "pending"}); err == nil {
+		t.Error("withFilterParams() error = nil, want an error for an invalid URL")
+	}
+}
+
+func TestCheckAllStatuses_Concurrency(t *testing.T) {
+	const limit = 3
+	orig, origLimit := checkJobStatus, flagMonitorCheckConcurrency
+	t.Cleanup(func() { checkJobStatus, flagMonitorCheckConcurrency = orig, origLimit })
+	flagMonitorCheckConcurrency = limit
+
+	var mu sync.Mutex
+	running, peak, calls := 0, 0, 0
+	checkJobStatus = func(finishedURL string) (*watcher.JobStatus, error) {
+		mu.Lock()
+		running++
+		calls++
+		peak = max(peak, running)
+		mu.Unlock()
+		// Give the other checks time to start if the limit lets them.
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return &watcher.JobStatus{Finished: true, Passed: true}, nil
+	}
+
+	var jobs []prowapi.Job
+	for i := 0; i < 20; i++ {
+		jobs = append(jobs, prowapi.Job{URL: jobURL("job", strconv.Itoa(i))})
+	}
+	entries, _, err := buildEntriesAndItems(jobs)
+	if err != nil {
+		t.Fatalf("buildEntriesAndItems() error = %v", err)
+	}
+	checkAllStatuses(entries)
+
+	if calls != len(entries) {
+		t.Errorf("checked %d jobs, want %d", calls, len(entries))
+	}
+	if peak > limit {
+		t.Errorf("peak concurrent checks = %d, want at most %d", peak, limit)
+	}
+	if peak < 2 {
+		t.Errorf("peak concurrent checks = %d, want the checks to run concurrently", peak)
+	}
+	if !allEntriesDone(entries) {
+		t.Error("allEntriesDone() = false, want every status aggregated")
+	}
+}
+
+func TestPrintStatusTable_StatusFile(t *testing.T) {
+	origColor := color.NoColor
+	color.NoColor = true
+	t.Cleanup(func() { color.NoColor = origColor })
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "status.txt")
+	origFile, origStdout := flagMonitorStatusFile, os.Stdout
+	flagMonitorStatusFile = path
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = devNull
+	defer func() {
+		flagMonitorStatusFile, os.Stdout = origFile, origStdout
+		devNull.Close()
+	}()
+
+	entries := []*monitorEntry{
+		{metadata: &parser.ProwMetadata{JobName: "job-a", BuildID: "100"}},
+		{metadata: &parser.ProwMetadata{JobName: "job-b", BuildID: "200"}},
+	}
+	rounds := []struct {
+		update func()
+		want   []string
+	}{
+		{func() {}, []string{"[1] 🔄 RUNNING", "[2] 🔄 RUNNING"}},
+		{func() { entries[0].status = &watcher.JobStatus{Finished: true, Passed: true} }, []string{"[1] ✅ PASSED", "[2] 🔄 RUNNING"}},
+		{func() { entries[1].status = &watcher.JobStatus{Finished: true} }, []string{"[1] ✅ PASSED", "[2] ❌ FAILED"}},
+	}
+	for i, r := range rounds {
+		r.update()
+		printStatusTable(entries)
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("round %d: %v", i+1, err)
+		}
+		var want bytes.Buffer
+		renderStatusTable(&want, entries)
+		// The tables can only differ by the clock at the top.
+		_, gotRows, _ := strings.Cut(string(data), "\n")
+		_, wantRows, _ := strings.Cut(want.String(), "\n")
+		if gotRows != wantRows {
+			t.Errorf("round %d: status file =\n%s\nwant\n%s", i+1, data, want.String())
+		}
+		for _, s := range r.want {
+			if !strings.Contains(string(data), s) {
+				t.Errorf("round %d: status file missing %q:\n%s", i+1, s, data)
+			}
+		}
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("status file directory holds %d files, want only the status file", len(files))
+	}
+}
+
+func TestWriteStatusFile_Error(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "status.txt")
+	if err := writeStatusFile(path, []byte("table\n")); err == nil {
+		t.Error("writeStatusFile() error = nil, want an error for a missing directory")
+	}
+}
+
+func TestNotifyCompletions_FlakeRecovery(t *testing.T) {
+	var messages []string
+	orig := sendMulti
+	sendMulti = func(m notifier.Multi, title, message string, success bool) error {
+		messages = append(messages, message)
+		return nil
+	}
+	t.Cleanup(func() { sendMulti = orig })
+
+	build := func(id string, passed bool) *monitorEntry {
+		entries, _, err := buildEntriesAndItems([]prowapi.Job{{URL: jobURL("pull-ci-e2e", id), PRRef: "[org/repo PR1]"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[0].status = &watcher.JobStatus{Finished: true, Passed: passed}
+		return entries[0]
+	}
+
+	// Each round of --repeat adds the next retest of the same job and PR; a
+	// periodic job passing in between is unrelated.
+	outcomes := newOutcomeHistory()
+	var entries []*monitorEntry
+	rounds := []*monitorEntry{build("100", false), build("101", false), build("102", true)}
+	periodic, _, _ := buildEntriesAndItems([]prowapi.Job{{URL: jobURL("periodic-ci-e2e", "200")}})
+	periodic[0].status = &watcher.JobStatus{Finished: true, Passed: true}
+	for i, e := range rounds {
+		entries = append(entries, e)
+		if i == 1 {
+			entries = append(entries, periodic[0])
+		}
+		notifyCompletions(entries, "", outcomes)
+	}
+	// A later round with nothing new finished sends nothing.
+	notifyCompletions(entries, "", outcomes)
+
+	var recoveries []string
+	for _, msg := range messages {
+		if strings.Contains(msg, "recovered") {
+			recoveries = append(recoveries, msg)
+		}
+	}
+	want := "Job [org/repo PR1] pull-ci-e2e recovered: now PASSING after 2 failures"
+	if len(recoveries) != 1 || recoveries[0] != want {
+		t.Errorf("recovery notifications = %q, want exactly [%q]", recoveries, want)
+	}
+	if len(messages) != 4 {
+		t.Errorf("sent %d notifications, want 4 (two failures, the periodic job, the recovery): %q", len(messages), messages)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/clobrano/prow-helper/internal/analyzer"
+)
+
+// workflowOutcome records how each step of a workflow went, so a failed
+// analysis can be reported without losing the fact that the download worked.
+type workflowOutcome struct {
+	JobFailed   bool  // --watch saw the job finish with a failure
+	Downloaded  bool  // artifacts were downloaded (false when existing ones were reused)
+	DownloadErr error // nil when the download succeeded, was skipped or was not run
+	AnalysisErr error // nil when analysis succeeded or was not run
+}
+
+// exitCodeFor returns the process exit code for a workflow outcome.
+//
+// A job that --watch saw fail always exits with ExitJobFailed, whether or not
+// its artifacts were then downloaded and analyzed, so scripts can rely on the
+// exit code for the job's result. Otherwise a failed download exits with
+// ExitDownloadFailed, a failed analysis with ExitAnalysisFailed (or, with
+// --propagate-exit, with the analysis command's own non-zero exit code), and
+// anything else with ExitSuccess.
+func exitCodeFor(o workflowOutcome) int {
+	switch {
+	case o.JobFailed:
+		return ExitJobFailed
+	case o.DownloadErr != nil:
+		return ExitDownloadFailed
+	case o.AnalysisErr != nil:
+		var exitErr *analyzer.ExitError
+		if flagPropagateExit && errors.As(o.AnalysisErr, &exitErr) && exitErr.ExitCode > 0 {
+			return exitErr.ExitCode
+		}
+		return ExitAnalysisFailed
+	default:
+		return ExitSuccess
+	}
+}
+
+// analysisFailureMessage is the notification body for a workflow whose
+// analysis failed, stating what happened to the artifacts first.
+func (o workflowOutcome) analysisFailureMessage(jobName, destPath string) string {
+	fetched := "downloaded OK"
+	if !o.Downloaded {
+		fetched = "existing artifacts used"
+	}
+
+	var exitErr *analyzer.ExitError
+	failure := fmt.Sprintf("analysis failed (%v)", o.AnalysisErr)
+	if errors.As(o.AnalysisErr, &exitErr) {
+		failure = fmt.Sprintf("analysis failed (exit %d)", exitErr.ExitCode)
+	}
+
+	return fmt.Sprintf("Job: %s\n\n%s, %s\n\nArtifacts: %s", jobName, fetched, failure, destPath)
+}
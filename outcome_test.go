@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/clobrano/prow-helper/internal/analyzer"
+)
+
+func TestWorkflowOutcome_AnalysisFailureMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		outcome workflowOutcome
+		want    string
+	}{
+		{
+			name:    "downloaded, analysis exited non-zero",
+			outcome: workflowOutcome{Downloaded: true, AnalysisErr: &analyzer.ExitError{ExitCode: 2, Message: "exit status 2"}},
+			want:    "downloaded OK, analysis failed (exit 2)",
+		},
+		{
+			name:    "existing artifacts, analysis exited non-zero",
+			outcome: workflowOutcome{AnalysisErr: &analyzer.ExitError{ExitCode: 1, Message: "exit status 1"}},
+			want:    "existing artifacts used, analysis failed (exit 1)",
+		},
+		{
+			name:    "downloaded, analysis could not start",
+			outcome: workflowOutcome{Downloaded: true, AnalysisErr: errors.New("command not found")},
+			want:    "downloaded OK, analysis failed (command not found)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := tt.outcome.analysisFailureMessage("my-job", "/tmp/artifacts/my-job/1")
+			if !strings.Contains(msg, tt.want) {
+				t.Errorf("analysisFailureMessage() = %q, want it to contain %q", msg, tt.want)
+			}
+			if !strings.Contains(msg, "my-job") || !strings.Contains(msg, "/tmp/artifacts/my-job/1") {
+				t.Errorf("analysisFailureMessage() = %q, want job name and artifacts path", msg)
+			}
+		})
+	}
+}
+
+func TestExitCodeFor(t *testing.T) {
+	dlErr := errors.New("download failed")
+	anErr := &analyzer.ExitError{ExitCode: 1, Message: "exit status 1"}
+
+	tests := []struct {
+		name    string
+		outcome workflowOutcome
+		want    int
+	}{
+		{"downloaded", workflowOutcome{Downloaded: true}, ExitSuccess},
+		{"existing artifacts reused", workflowOutcome{}, ExitSuccess},
+		{"analysis failed", workflowOutcome{Downloaded: true, AnalysisErr: anErr}, ExitAnalysisFailed},
+		{"download failed", workflowOutcome{DownloadErr: dlErr}, ExitDownloadFailed},
+		{"download and analysis failed", workflowOutcome{DownloadErr: dlErr, AnalysisErr: anErr}, ExitDownloadFailed},
+		{"job failed, nothing else run", workflowOutcome{JobFailed: true}, ExitJobFailed},
+		{"job failed, analyzed", workflowOutcome{JobFailed: true, Downloaded: true}, ExitJobFailed},
+		{"job failed, analysis failed", workflowOutcome{JobFailed: true, Downloaded: true, AnalysisErr: anErr}, ExitJobFailed},
+		{"job failed, download failed", workflowOutcome{JobFailed: true, DownloadErr: dlErr}, ExitJobFailed},
+		{"job failed, download and analysis failed", workflowOutcome{JobFailed: true, DownloadErr: dlErr, AnalysisErr: anErr}, ExitJobFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCodeFor(tt.outcome); got != tt.want {
+				t.Errorf("exitCodeFor() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExitCodeFor_PropagateExit(t *testing.T) {
+	orig := flagPropagateExit
+	t.Cleanup(func() { flagPropagateExit = orig })
+
+	tests := []struct {
+		name      string
+		propagate bool
+		outcome   workflowOutcome
+		want      int
+	}{
+		{"default mapping", false, workflowOutcome{AnalysisErr: &analyzer.ExitError{ExitCode: 42}}, ExitAnalysisFailed},
+		{"analyzer code propagated", true, workflowOutcome{AnalysisErr: &analyzer.ExitError{ExitCode: 42}}, 42},
+		{"wrapped analyzer code propagated", true, workflowOutcome{AnalysisErr: fmt.Errorf("analysis: %w", &analyzer.ExitError{ExitCode: 9})}, 9},
+		{"killed by a signal", true, workflowOutcome{AnalysisErr: &analyzer.ExitError{ExitCode: -1}}, ExitAnalysisFailed},
+		{"analysis could not start", true, workflowOutcome{AnalysisErr: errors.New("command not found")}, ExitAnalysisFailed},
+		{"success unchanged", true, workflowOutcome{Downloaded: true}, ExitSuccess},
+		{"job failure still wins", true, workflowOutcome{JobFailed: true, AnalysisErr: &analyzer.ExitError{ExitCode: 42}}, ExitJobFailed},
+		{"download failure still wins", true, workflowOutcome{DownloadErr: errors.New("x"), AnalysisErr: &analyzer.ExitError{ExitCode: 42}}, ExitDownloadFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flagPropagateExit = tt.propagate
+			if got := exitCodeFor(tt.outcome); got != tt.want {
+				t.Errorf("exitCodeFor() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
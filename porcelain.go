@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/clobrano/prow-helper/internal/analyzer"
+	"github.com/clobrano/prow-helper/internal/output"
+)
+
+// porcelainReport is the --porcelain result of a workflow. Every field is
+// always printed, empty when it does not apply (e.g. status without --watch,
+// analysis_exit without an analysis command).
+type porcelainReport struct {
+	Job          string
+	Build        string
+	Dest         string
+	Status       string // "passed" or "failed", from --watch
+	AnalysisExit string // exit code of the analysis command, -1 if it could not start
+}
+
+// fields returns the report as key/value pairs in their documented order.
+// The keys are part of the stable --porcelain interface: only add new ones.
+func (r porcelainReport) fields() [][2]string {
+	return [][2]string{
+		{"job", r.Job},
+		{"build", r.Build},
+		{"dest", r.Dest},
+		{"status", r.Status},
+		{"analysis_exit", r.AnalysisExit},
+	}
+}
+
+// outputMode returns how the workflow result is reported, from the flags.
+func outputMode() output.Mode {
+	switch {
+	case flagPorcelain:
+		return output.ModePorcelain
+	case flagJSON || flagJQ != "":
+		return output.ModeJSON
+	}
+	return output.ModeHuman
+}
+
+// emitPorcelain prints r on stdout in --porcelain mode.
+func emitPorcelain(r porcelainReport) {
+	if outputMode() != output.ModePorcelain {
+		return
+	}
+	if err := output.WritePorcelain(os.Stdout, r.fields()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to print result: %v\n", err)
+	}
+}
+
+// analysisExitCode returns the exit code of a failed analysis, or "-1" when
+// the command could not be run at all.
+func analysisExitCode(err error) string {
+	var exitErr *analyzer.ExitError
+	if errors.As(err, &exitErr) {
+		return strconv.Itoa(exitErr.ExitCode)
+	}
+	return "-1"
+}
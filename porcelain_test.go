@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/clobrano/prow-helper/internal/analyzer"
+	"github.com/clobrano/prow-helper/internal/output"
+)
+
+func TestPorcelainReport_Fields(t *testing.T) {
+	r := porcelainReport{
+		Job:          "pull-ci-openshift-api-master-unit",
+		Build:        "5678",
+		Dest:         "/tmp/prow artifacts/20260101-1200-5678",
+		Status:       "passed",
+		AnalysisExit: "0",
+	}
+
+	var buf bytes.Buffer
+	if err := output.WritePorcelain(&buf, r.fields()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "job=pull-ci-openshift-api-master-unit\n" +
+		"build=5678\n" +
+		"dest='/tmp/prow artifacts/20260101-1200-5678'\n" +
+		"status=passed\n" +
+		"analysis_exit=0\n"
+	if buf.String() != want {
+		t.Errorf("porcelain output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestPorcelainReport_AlwaysAllKeys(t *testing.T) {
+	var keys []string
+	for _, f := range (porcelainReport{}).fields() {
+		keys = append(keys, f[0])
+	}
+	want := []string{"job", "build", "dest", "status", "analysis_exit"}
+	if len(keys) != len(want) {
+		t.Fatalf("keys = %v, want %v", keys, want)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Errorf("keys = %v, want %v", keys, want)
+			break
+		}
+	}
+}
+
+func TestAnalysisExitCode(t *testing.T) {
+	if got := analysisExitCode(&analyzer.ExitError{ExitCode: 3}); got != "3" {
+		t.Errorf("analysisExitCode(exit 3) = %q, want \"3\"", got)
+	}
+	if got := analysisExitCode(errors.New("command not found")); got != "-1" {
+		t.Errorf("analysisExitCode(not started) = %q, want \"-1\"", got)
+	}
+}
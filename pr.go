@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/clobrano/prow-helper/internal/config"
+	"github.com/clobrano/prow-helper/internal/parser"
+	"github.com/clobrano/prow-helper/internal/prompt"
+	"github.com/clobrano/prow-helper/internal/resolver"
+	"github.com/clobrano/prow-helper/internal/selector"
+)
+
+// runSelector is the interactive job selector.
+// It is a variable so tests can replace the TUI with a scripted choice.
+var runSelector = selector.Run
+
+// runPRWorkflow finds the Prow jobs of a GitHub pull request, lets the user
+// pick some of them and runs the download workflow on each choice.
+func runPRWorkflow(cmd *cobra.Command, prURL string) error {
+	cfg, err := config.Load(cliConfig())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(ExitConfigError)
+		return nil
+	}
+	applyConfig(cfg)
+
+	urls, err := resolvePRJobURLs(prURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not find prow jobs for %s: %v\n", prURL, err)
+		os.Exit(ExitInvalidURL)
+		return nil
+	}
+	if len(urls) == 0 {
+		fmt.Println("No jobs selected. Exiting.")
+		return nil
+	}
+	if len(urls) == 1 {
+		return executeWorkflow(urls[0], flagNotifyComplete)
+	}
+	return runWorkflowsSequentially(cmd, urls, nil, "pr")
+}
+
+// resolvePRJobURLs returns the Prow job URLs, among those posted on the pull
+// request, that the user selected. A single job is selected automatically.
+func resolvePRJobURLs(prURL string) ([]string, error) {
+	links, err := resolver.FindPRJobLinks(prURL)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make([]string, 0, len(links))
+	items := make([]selector.Item, 0, len(links))
+	for _, link := range links {
+		meta, parseErr := parser.ParseURL(link)
+		if parseErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not parse job URL %s: %v\n", link, parseErr)
+			continue
+		}
+		urls = append(urls, link)
+		items = append(items, selector.Item{
+			Key:   link,
+			Label: fmt.Sprintf("%s  %s", meta.JobName, meta.BuildID),
+		})
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no valid prow job URLs found")
+	}
+
+	if len(items) > 1 && promptPolicy() != prompt.Ask {
+		return nil, prompt.Disabled(fmt.Sprintf("choosing among the %d jobs of the pull request (pass a job's Prow URL instead)", len(items)))
+	}
+	indices, err := runSelector(items, nil, selector.Options{AutoSelectSingle: true})
+	if err != nil {
+		return nil, err
+	}
+	sort.Ints(indices)
+
+	selected := make([]string, len(indices))
+	for i, idx := range indices {
+		selected[i] = urls[idx]
+	}
+	return selected, nil
+}
+
+// runWorkflowsSequentially runs the workflow for each URL, one after the
+// other, in a child process of this executable. Each job gets its own
+// process because the workflow exits (or execs the analysis command) when it
+// is done. Flags given on the command line are passed on, except those named
+// in exclude, which selected the jobs. analyzeCmds, when not nil, holds the
+// analysis command of each URL, passed instead of the --analyze-cmd flags.
+func runWorkflowsSequentially(cmd *cobra.Command, urls, analyzeCmds []string, exclude ...string) error {
+	execPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+	if analyzeCmds != nil {
+		exclude = append(exclude, "analyze-cmd")
+	}
+	baseArgs := childArgs(cmd, exclude...)
+
+	failed := 0
+	for i, u := range urls {
+		fmt.Printf("\n[%d/%d] %s\n", i+1, len(urls), u)
+		args := slices.Clone(baseArgs)
+		if analyzeCmds != nil {
+			args = append(args, "--analyze-cmd="+analyzeCmds[i])
+		}
+		child := execCommand(execPath, append(args, u)...)
+		child.Stdin = os.Stdin
+		child.Stdout = os.Stdout
+		child.Stderr = os.Stderr
+		if err := child.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: workflow for %s failed: %v\n", u, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d jobs failed", failed, len(urls))
+	}
+	return nil
+}
+
+// childArgs rebuilds the command-line flags that were explicitly set on cmd
+// from the values cobra parsed, skipping the flags named in exclude.
+func childArgs(cmd *cobra.Command, exclude ...string) []string {
+	skip := make(map[string]bool, len(exclude))
+	for _, name := range exclude {
+		skip[name] = true
+	}
+
+	var args []string
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if skip[f.Name] {
+			return
+		}
+		// A list flag prints as "[a,b]": pass its items one by one.
+		if list, ok := f.Value.(pflag.SliceValue); ok {
+			for _, item := range list.GetSlice() {
+				args = append(args, "--"+f.Name+"="+item)
+			}
+			return
+		}
+		args = append(args, "--"+f.Name+"="+f.Value.String())
+	})
+	return args
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/clobrano/prow-helper/internal/prompt"
+	"github.com/clobrano/prow-helper/internal/resolver"
+	"github.com/clobrano/prow-helper/internal/selector"
+)
+
+func TestResolvePRJobURLs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/openshift/api/issues/1234/comments" {
+			t.Errorf("unexpected GitHub API path %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `[
+			{"body":"https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_api/1234/pull-ci-unit/111"},
+			{"body":"https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_api/1234/pull-ci-e2e/222"},
+			{"body":"https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_api/1234/pull-ci-lint/333"}
+		]`)
+	}))
+	defer server.Close()
+
+	origBase := resolver.GitHubAPIBaseURL
+	resolver.GitHubAPIBaseURL = server.URL
+	defer func() { resolver.GitHubAPIBaseURL = origBase }()
+
+	origSelector := runSelector
+	defer func() { runSelector = origSelector }()
+	var offered []selector.Item
+	runSelector = func(items []selector.Item, refreshFn func() ([]selector.Item, error), opts selector.Options) ([]int, error) {
+		offered = items
+		return []int{2, 0}, nil // pick lint and unit, out of order
+	}
+
+	urls, err := resolvePRJobURLs("https://github.com/openshift/api/pull/1234")
+	if err != nil {
+		t.Fatalf("resolvePRJobURLs() error = %v", err)
+	}
+
+	if len(offered) != 3 {
+		t.Fatalf("selector was offered %d items, want 3", len(offered))
+	}
+	if offered[1].Label != "pull-ci-e2e  222" {
+		t.Errorf("selector item label = %q, want %q", offered[1].Label, "pull-ci-e2e  222")
+	}
+
+	want := []string{
+		"https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_api/1234/pull-ci-unit/111",
+		"https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_api/1234/pull-ci-lint/333",
+	}
+	if len(urls) != len(want) {
+		t.Fatalf("resolvePRJobURLs() = %v, want %v", urls, want)
+	}
+	for i := range want {
+		if urls[i] != want[i] {
+			t.Errorf("resolvePRJobURLs()[%d] = %q, want %q", i, urls[i], want[i])
+		}
+	}
+}
+
+func TestResolvePRJobURLs_NoPrompt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[
+			{"body":"https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_api/1234/pull-ci-unit/111"},
+			{"body":"https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_api/1234/pull-ci-e2e/222"}
+		]`)
+	}))
+	defer server.Close()
+
+	origBase := resolver.GitHubAPIBaseURL
+	resolver.GitHubAPIBaseURL = server.URL
+	defer func() { resolver.GitHubAPIBaseURL = origBase }()
+
+	origSelector := runSelector
+	defer func() { runSelector = origSelector }()
+	runSelector = func(items []selector.Item, refreshFn func() ([]selector.Item, error), opts selector.Options) ([]int, error) {
+		t.Error("selector shown despite --no-prompt")
+		return nil, nil
+	}
+	flagNoPrompt = true
+	defer func() { flagNoPrompt = false }()
+
+	_, err := resolvePRJobURLs("https://github.com/openshift/api/pull/1234")
+	if !errors.Is(err, prompt.ErrDisabled) {
+		t.Errorf("resolvePRJobURLs() error = %v, want prompt.ErrDisabled", err)
+	}
+}
+
+func TestChildArgs(t *testing.T) {
+	var dest, pr string
+	var watch bool
+	cmd := &cobra.Command{Use: "test", Run: func(*cobra.Command, []string) {}}
+	cmd.Flags().StringVar(&dest, "dest", "", "")
+	cmd.Flags().StringVar(&pr, "pr", "", "")
+	cmd.Flags().BoolVar(&watch, "watch", false, "")
+	cmd.Flags().StringVar(new(string), "analyze-cmd", "", "")
+	cmd.Flags().StringSliceVar(new([]string), "include", nil, "")
+
+	if err := cmd.ParseFlags([]string{"--dest", "/tmp/a b", "--pr=https://github.com/o/r/pull/1", "--watch", "--include=*.log,junit*.xml"}); err != nil {
+		t.Fatalf("ParseFlags() error = %v", err)
+	}
+
+	got := childArgs(cmd, "pr")
+	want := []string{"--dest=/tmp/a b", "--include=*.log", "--include=junit*.xml", "--watch=true"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("childArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestRunWorkflowsSequentially_PerURLAnalyzeCmd(t *testing.T) {
+	var calls [][]string
+	orig := execCommand
+	execCommand = func(name string, arg ...string) *exec.Cmd {
+		calls = append(calls, arg)
+		return exec.Command("true")
+	}
+	t.Cleanup(func() { execCommand = orig })
+
+	newCmd := func(args ...string) *cobra.Command {
+		cmd := &cobra.Command{Use: "test", Run: func(*cobra.Command, []string) {}}
+		cmd.Flags().String("dest", "", "")
+		cmd.Flags().StringArray("analyze-cmd", nil, "")
+		if err := cmd.ParseFlags(args); err != nil {
+			t.Fatalf("ParseFlags() error = %v", err)
+		}
+		return cmd
+	}
+	urls := []string{jobURL("e2e-aws", "1"), jobURL("e2e-metal", "2")}
+
+	cmd := newCmd("--dest=/tmp/a", "--analyze-cmd", "junit-summary", "--analyze-cmd", "my-analyzer --deep, slow")
+	if err := runWorkflowsSequentially(cmd, urls, []string{"junit-summary", "my-analyzer --deep, slow"}); err != nil {
+		t.Fatalf("runWorkflowsSequentially() error = %v", err)
+	}
+	want := [][]string{
+		{"--dest=/tmp/a", "--analyze-cmd=junit-summary", urls[0]},
+		{"--dest=/tmp/a", "--analyze-cmd=my-analyzer --deep, slow", urls[1]},
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("child args = %q, want %q", calls, want)
+	}
+
+	// A single --analyze-cmd is passed on to every URL as is.
+	calls = nil
+	if err := runWorkflowsSequentially(newCmd("--analyze-cmd", "junit-summary"), urls, nil); err != nil {
+		t.Fatalf("runWorkflowsSequentially() error = %v", err)
+	}
+	want = [][]string{{"--analyze-cmd=junit-summary", urls[0]}, {"--analyze-cmd=junit-summary", urls[1]}}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("child args = %q, want %q", calls, want)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/clobrano/prow-helper/internal/config"
+	"github.com/clobrano/prow-helper/internal/downloader"
+	"github.com/clobrano/prow-helper/internal/prompt"
+)
+
+var flagPruneOlderThan string
+var flagPruneDryRun bool
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune --older-than <age>",
+	Short: "Remove downloaded builds older than an age",
+	Long: `prune removes the build folders under the destination directory
+(<dest>/<job-name>/<build>) that were last modified more than --older-than
+ago. The age is a number of days ("30d") or a duration ("12h"). Only folders
+named like a download (the build ID, optionally with the date prefix) are
+considered, and a protected destination (home, /, XDG config/state/cache) is
+refused unless --force is set.
+
+The folders are listed with their size, followed by the total space
+reclaimed, and removed once confirmed (--yes skips the question). With
+--dry-run nothing is removed.
+
+Example:
+  prow-helper prune --older-than 30d --dry-run
+  prow-helper prune --older-than 30d`,
+	Args: cobra.NoArgs,
+	RunE: runPrune,
+}
+
+func init() {
+	pruneCmd.Flags().StringVar(&flagDest, "dest", "", "Download destination directory")
+	pruneCmd.Flags().StringVar(&flagPruneOlderThan, "older-than", "", "Remove the builds last modified longer ago than this, e.g. 30d or 12h")
+	pruneCmd.Flags().BoolVar(&flagPruneDryRun, "dry-run", false, "List the builds that would be removed and the space reclaimed, without removing them")
+	pruneCmd.Flags().BoolVar(&flagForce, "force", false, "Prune even when the destination is a protected directory (home, /, XDG config/state/cache)")
+	pruneCmd.MarkFlagRequired("older-than")
+	rootCmd.AddCommand(pruneCmd)
+}
+
+func runPrune(cmd *cobra.Command, args []string) error {
+	age, err := parseAge(flagPruneOlderThan)
+	if err != nil {
+		return fmt.Errorf("invalid --older-than: %w", err)
+	}
+	cfg, err := config.Load(&config.Config{Dest: flagDest})
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if reportConfigIssues(config.Validate(cfg)) {
+		return fmt.Errorf("invalid configuration")
+	}
+	if err := config.CheckDestination(cfg.Dest); err != nil && !flagForce {
+		return fmt.Errorf("refusing to prune: %w (use --force to override)", err)
+	}
+
+	plan, err := downloader.PlanPrune(cfg.Dest, age)
+	if err != nil {
+		return err
+	}
+	// Until confirmed, the removal is worded as for a dry run.
+	printPrunePlan(os.Stdout, plan, flagPruneDryRun || promptPolicy() != prompt.Yes)
+	if flagPruneDryRun || len(plan.Dirs) == 0 {
+		return nil
+	}
+	ok, err := confirmPrune(promptPolicy(), plan, os.Stdin, os.Stdout)
+	if err != nil || !ok {
+		return err
+	}
+	return plan.Execute()
+}
+
+// confirmPrune asks whether to remove the folders of plan, which have been
+// listed already. prompt.Yes removes them without asking; anything but a
+// yes answer keeps them.
+func confirmPrune(policy prompt.Policy, plan downloader.PrunePlan, stdin io.Reader, stdout io.Writer) (bool, error) {
+	switch policy {
+	case prompt.Never:
+		return false, prompt.Disabled(fmt.Sprintf("removing %d build(s) (use --yes)", len(plan.Dirs)))
+	case prompt.Yes:
+		return true, nil
+	}
+
+	fmt.Fprintf(stdout, "Remove these %d build(s)? [y/N] ", len(plan.Dirs))
+	input, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && input == "" {
+		return false, fmt.Errorf("failed to read the answer: %w", err)
+	}
+	switch strings.TrimSpace(strings.ToLower(input)) {
+	case "y", "yes":
+		return true, nil
+	}
+	fmt.Fprintln(stdout, "Nothing removed")
+	return false, nil
+}
+
+// printPrunePlan lists the folders of plan with their sizes and the total
+// reclaimed, worded for a dry run or an actual removal.
+func printPrunePlan(w io.Writer, plan downloader.PrunePlan, dryRun bool) {
+	if len(plan.Dirs) == 0 {
+		fmt.Fprintln(w, "Nothing to prune")
+		return
+	}
+	verb, reclaimed := "Removing", "Reclaimed"
+	if dryRun {
+		verb, reclaimed = "Would remove", "Would reclaim"
+	}
+	fmt.Fprintf(w, "%s %d build(s):\n", verb, len(plan.Dirs))
+	for _, c := range plan.Dirs {
+		fmt.Fprintf(w, "  %s\n", c)
+	}
+	fmt.Fprintf(w, "%s %s\n", reclaimed, plan.TotalString())
+}
+
+// parseAge parses a number of days ("30d") or a Go duration ("12h").
+func parseAge(s string) (time.Duration, error) {
+	var d time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number of days", s)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, err
+		}
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%q must be positive", s)
+	}
+	return d, nil
+}
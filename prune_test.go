@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clobrano/prow-helper/internal/config"
+	"github.com/clobrano/prow-helper/internal/downloader"
+	"github.com/clobrano/prow-helper/internal/prompt"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "30d", want: 30 * 24 * time.Hour},
+		{in: "12h", want: 12 * time.Hour},
+		{in: "1h30m", want: 90 * time.Minute},
+		{in: "0d", wantErr: true},
+		{in: "-1h", wantErr: true},
+		{in: "xd", wantErr: true},
+		{in: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseAge(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseAge(%q) = %v, %v, want %v (error: %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPrintPrunePlan_DryRun(t *testing.T) {
+	dest := t.TempDir()
+	old := time.Now().Add(-72 * time.Hour)
+	for build, size := range map[string]int{"100": 1536, "200": 512} {
+		dir := filepath.Join(dest, "job-a", build)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "build-log.txt"), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(dir, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plan, err := downloader.PlanPrune(dest, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("PlanPrune() error = %v", err)
+	}
+	var buf bytes.Buffer
+	printPrunePlan(&buf, plan, true)
+	out := buf.String()
+	for _, want := range []string{"Would remove 2 build(s):", "1.5 KiB", "512 B", "Would reclaim 2.0 KiB"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("printPrunePlan() output %q does not contain %q", out, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dest, "job-a", "100")); err != nil {
+		t.Errorf("dry run removed a build: %v", err)
+	}
+
+	buf.Reset()
+	printPrunePlan(&buf, downloader.PrunePlan{}, true)
+	if buf.String() != "Nothing to prune\n" {
+		t.Errorf("printPrunePlan() of an empty plan = %q", buf.String())
+	}
+}
+
+func TestConfirmPrune(t *testing.T) {
+	plan := downloader.PrunePlan{Dirs: []downloader.PruneCandidate{{Path: "/dest/job-a/100"}}}
+	tests := []struct {
+		name    string
+		policy  prompt.Policy
+		input   string
+		want    bool
+		wantErr error
+	}{
+		{name: "yes", policy: prompt.Ask, input: "y\n", want: true},
+		{name: "yes in full", policy: prompt.Ask, input: "Yes\n", want: true},
+		{name: "no", policy: prompt.Ask, input: "n\n"},
+		{name: "empty answer", policy: prompt.Ask, input: "\n"},
+		{name: "--yes", policy: prompt.Yes, want: true},
+		{name: "--no-prompt", policy: prompt.Never, wantErr: prompt.ErrDisabled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			got, err := confirmPrune(tt.policy, plan, strings.NewReader(tt.input), &out)
+			if got != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("confirmPrune() = %v, %v, want %v, %v", got, err, tt.want, tt.wantErr)
+			}
+			if tt.policy == prompt.Ask && !strings.Contains(out.String(), "Remove these 1 build(s)? [y/N]") {
+				t.Errorf("confirmPrune() output = %q, want the question", out.String())
+			}
+		})
+	}
+}
+
+func TestRunPrune_ProtectedDestination(t *testing.T) {
+	origDest, origAge, origForce := flagDest, flagPruneOlderThan, flagForce
+	t.Cleanup(func() { flagDest, flagPruneOlderThan, flagForce = origDest, origAge, origForce })
+	flagDest, flagPruneOlderThan, flagForce = string(filepath.Separator), "30d", false
+
+	err := runPrune(pruneCmd, nil)
+	if !errors.Is(err, config.ErrUnsafeDestination) || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("runPrune() error = %v, want the protected destination refused", err)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/clobrano/prow-helper/internal/analyzer"
+	"github.com/clobrano/prow-helper/internal/config"
+	"github.com/clobrano/prow-helper/internal/history"
+)
+
+// errNoDownloadInHistory is returned by reanalyze when no history entry
+// records a download folder.
+var errNoDownloadInHistory = errors.New("no download recorded in the history")
+
+// runReanalysis runs the analysis command on a download folder; tests replace
+// it to observe the invocation instead of replacing the test process.
+var runReanalysis = analyzer.RunAnalysis
+
+var reanalyzeCmd = &cobra.Command{
+	Use:   "reanalyze",
+	Short: "Run the last analysis command again on the last download",
+	Long: `reanalyze looks up the most recent download in the history and runs the
+analysis command again on its folder, without downloading anything. The
+command is the one recorded with the download, or analyze_cmd from the
+configuration when the download was not analyzed.
+
+This is the loop for tweaking an analyzer: change it, then run
+  prow-helper reanalyze`,
+	Args: cobra.NoArgs,
+	RunE: runReanalyze,
+}
+
+func init() {
+	rootCmd.AddCommand(reanalyzeCmd)
+}
+
+func runReanalyze(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(&config.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if reportConfigIssues(config.Validate(cfg)) {
+		return fmt.Errorf("invalid configuration")
+	}
+	applyConfig(cfg)
+
+	path, err := history.DefaultPath()
+	if err != nil {
+		return err
+	}
+	entries, err := history.List(path)
+	if err != nil {
+		return err
+	}
+	entry, analyzeCmd, err := reanalysisFor(entries, cfg.AnalyzeCmd)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(entry.Dest); err != nil || !info.IsDir() {
+		return fmt.Errorf("download folder %s of %s is gone", entry.Dest, entry.URL)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Re-analyzing %s\n", entry.URL)
+	fmt.Fprintf(cmd.OutOrStdout(), "Running analysis: %s %s\n", analyzeCmd, entry.Dest)
+	return runReanalysis(analyzeCmd, entry.Dest)
+}
+
+// reanalysisFor picks the most recent of entries, newest first as returned by
+// history.List, that records a download folder, and the analysis command to
+// run on it: the recorded one, or defaultCmd when the download was not
+// analyzed.
+func reanalysisFor(entries []history.Entry, defaultCmd string) (history.Entry, string, error) {
+	for _, e := range entries {
+		if e.Dest == "" {
+			continue
+		}
+		analyzeCmd := e.AnalyzeCmd
+		if analyzeCmd == "" {
+			analyzeCmd = defaultCmd
+		}
+		if analyzeCmd == "" {
+			return history.Entry{}, "", fmt.Errorf("no analysis command recorded for %s and analyze_cmd is not set", e.URL)
+		}
+		return e, analyzeCmd, nil
+	}
+	return history.Entry{}, "", errNoDownloadInHistory
+}
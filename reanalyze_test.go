@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adrg/xdg"
+
+	"github.com/clobrano/prow-helper/internal/history"
+)
+
+func TestReanalysisFor(t *testing.T) {
+	entries := []history.Entry{
+		{URL: "https://prow.example.com/view/gs/b/logs/job/4", Outcome: "download failed"},
+		{URL: "https://prow.example.com/view/gs/b/logs/job/3", Outcome: "analyzed", Dest: "/dest/job/3", AnalyzeCmd: "my-analyzer --verbose"},
+		{URL: "https://prow.example.com/view/gs/b/logs/job/2", Outcome: "downloaded", Dest: "/dest/job/2"},
+	}
+
+	tests := []struct {
+		name       string
+		entries    []history.Entry
+		defaultCmd string
+		wantDest   string
+		wantCmd    string
+		wantErr    bool
+	}{
+		{name: "skips entries without a download", entries: entries, defaultCmd: "other", wantDest: "/dest/job/3", wantCmd: "my-analyzer --verbose"},
+		{name: "falls back to analyze_cmd", entries: entries[2:], defaultCmd: "other", wantDest: "/dest/job/2", wantCmd: "other"},
+		{name: "no command at all", entries: entries[2:], wantErr: true},
+		{name: "no download", entries: entries[:1], wantErr: true},
+		{name: "empty history", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, cmd, err := reanalysisFor(tt.entries, tt.defaultCmd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("reanalysisFor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if entry.Dest != tt.wantDest || cmd != tt.wantCmd {
+				t.Errorf("reanalysisFor() = (%q, %q), want (%q, %q)", entry.Dest, cmd, tt.wantDest, tt.wantCmd)
+			}
+		})
+	}
+	if _, _, err := reanalysisFor(nil, "x"); !errors.Is(err, errNoDownloadInHistory) {
+		t.Errorf("reanalysisFor(nil) error = %v, want errNoDownloadInHistory", err)
+	}
+}
+
+func TestRunReanalyze_FromHistory(t *testing.T) {
+	t.Cleanup(xdg.Reload)
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	xdg.Reload()
+
+	dest := t.TempDir()
+	path, err := history.DefaultPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := []history.Entry{
+		{URL: "https://prow.example.com/view/gs/b/logs/job/1", Outcome: "analyzed", Dest: filepath.Join(dest, "gone"), AnalyzeCmd: "old-analyzer"},
+		{URL: "https://prow.example.com/view/gs/b/logs/job/2", Outcome: "analysis failed", Dest: dest, AnalyzeCmd: "my-analyzer --mode 'deep dive'"},
+		{URL: "https://prow.example.com/view/gs/b/logs/job/3", Outcome: "watch failed"},
+	}
+	for _, e := range recorded {
+		e.Time = time.Now()
+		if err := history.Append(path, e, history.MaxEntries); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var gotCmd, gotPath string
+	orig := runReanalysis
+	runReanalysis = func(cmdStr, artifactsPath string) error {
+		gotCmd, gotPath = cmdStr, artifactsPath
+		return nil
+	}
+	defer func() { runReanalysis = orig }()
+
+	var out bytes.Buffer
+	reanalyzeCmd.SetOut(&out)
+	defer reanalyzeCmd.SetOut(nil)
+	if err := runReanalyze(reanalyzeCmd, nil); err != nil {
+		t.Fatalf("runReanalyze() error = %v", err)
+	}
+	if gotCmd != "my-analyzer --mode 'deep dive'" || gotPath != dest {
+		t.Errorf("runReanalyze() ran (%q, %q), want (%q, %q)", gotCmd, gotPath, "my-analyzer --mode 'deep dive'", dest)
+	}
+}
+
+func TestRecordDownloadHistory(t *testing.T) {
+	t.Cleanup(xdg.Reload)
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	xdg.Reload()
+
+	recordDownloadHistory("https://prow.example.com/view/gs/b/logs/job/1", "analyzed", "/dest/job/1", "my-analyzer")
+
+	path, err := history.DefaultPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := history.Get(path, 1)
+	if err != nil {
+		t.Fatalf("history.Get() error = %v", err)
+	}
+	if e.Dest != "/dest/job/1" || e.AnalyzeCmd != "my-analyzer" || e.Outcome != "analyzed" || e.Time.IsZero() {
+		t.Errorf("recorded %+v, want dest, analysis command, outcome and time", e)
+	}
+}
+
+func TestRunReanalyze_FromAnotherDirectory(t *testing.T) {
+	t.Cleanup(xdg.Reload)
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	xdg.Reload()
+
+	// The download is recorded with the relative path of a relative --dest.
+	workdir := t.TempDir()
+	dest := filepath.Join(workdir, "artifacts", "job", "1")
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(workdir)
+	recordDownloadHistory("https://prow.example.com/view/gs/b/logs/job/1", "analyzed", filepath.Join("artifacts", "job", "1"), "my-analyzer")
+
+	t.Chdir(t.TempDir())
+	var gotPath string
+	orig := runReanalysis
+	runReanalysis = func(cmdStr, artifactsPath string) error {
+		gotPath = artifactsPath
+		return nil
+	}
+	defer func() { runReanalysis = orig }()
+
+	reanalyzeCmd.SetOut(&bytes.Buffer{})
+	defer reanalyzeCmd.SetOut(nil)
+	if err := runReanalyze(reanalyzeCmd, nil); err != nil {
+		t.Fatalf("runReanalyze() error = %v", err)
+	}
+	if gotPath != dest {
+		t.Errorf("runReanalyze() ran on %q, want the recorded download %q", gotPath, dest)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/itchyny/gojq"
+
+	"github.com/clobrano/prow-helper/internal/parser"
+	"github.com/clobrano/prow-helper/internal/watcher"
+)
+
+// watchResult is the machine-readable outcome of a --watch run.
+//
+// Its text form is a single line that scripts can rely on:
+//
+//	RESULT: <PASSED|FAILED> job=<job-name> build=<build-id> duration=<duration|unknown>
+//
+// where duration uses Go's duration notation (e.g. 1h12m3s).
+type watchResult struct {
+	Result   string `json:"result"`
+	Job      string `json:"job"`
+	Build    string `json:"build"`
+	Duration string `json:"duration"`
+	URL      string `json:"url,omitempty"`
+}
+
+// newWatchResult builds the watch result for a finished job.
+func newWatchResult(metadata *parser.ProwMetadata, status *watcher.JobStatus) watchResult {
+	result := "FAILED"
+	if status.Passed {
+		result = "PASSED"
+	}
+	duration := "unknown"
+	if d := status.Duration(); d > 0 {
+		duration = d.Truncate(time.Second).String()
+	}
+	return watchResult{
+		Result:   result,
+		Job:      metadata.JobName,
+		Build:    metadata.BuildID,
+		Duration: duration,
+		URL:      metadata.RawURL,
+	}
+}
+
+// Line returns the stable single-line representation of r.
+func (r watchResult) Line() string {
+	return fmt.Sprintf("RESULT: %s job=%s build=%s duration=%s", r.Result, r.Job, r.Build, r.Duration)
+}
+
+// printWatchResult writes r to w either as the RESULT line or, when asJSON is
+// set, as a single-line JSON object. A non-nil query is applied to the JSON
+// object instead (see writeJQ), and implies asJSON.
+func printWatchResult(w io.Writer, r watchResult, asJSON bool, query *gojq.Code) error {
+	if query != nil {
+		return writeJQ(w, query, r)
+	}
+	if !asJSON {
+		_, err := fmt.Fprintln(w, r.Line())
+		return err
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// compileJQ parses and compiles a jq expression such as ".result".
+func compileJQ(expr string) (*gojq.Code, error) {
+	query, err := gojq.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --jq expression: %w", err)
+	}
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --jq expression: %w", err)
+	}
+	return code, nil
+}
+
+// writeJQ runs query over the JSON form of v and writes each value it
+// produces on its own line. Strings are written raw (like jq -r) so a single
+// field can be used directly in a shell; other values are written as JSON.
+func writeJQ(w io.Writer, query *gojq.Code, v any) error {
+	// Round-trip through JSON so the query sees the same object --json prints.
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var input any
+	if err := json.Unmarshal(data, &input); err != nil {
+		return err
+	}
+
+	iter := query.Run(input)
+	for {
+		value, ok := iter.Next()
+		if !ok {
+			return nil
+		}
+		if err, isErr := value.(error); isErr {
+			return fmt.Errorf("--jq: %w", err)
+		}
+		if str, isStr := value.(string); isStr {
+			if _, err := fmt.Fprintln(w, str); err != nil {
+				return err
+			}
+			continue
+		}
+		out, err := gojq.Marshal(value)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w, string(out)); err != nil {
+			return err
+		}
+	}
+}
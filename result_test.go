@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/clobrano/prow-helper/internal/parser"
+	"github.com/clobrano/prow-helper/internal/watcher"
+)
+
+func TestPrintWatchResult(t *testing.T) {
+	metadata := &parser.ProwMetadata{
+		JobName: "periodic-ci-test",
+		BuildID: "12345",
+		RawURL:  "https://prow.ci.openshift.org/view/gs/bucket/logs/periodic-ci-test/12345",
+	}
+	start := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name   string
+		status *watcher.JobStatus
+		want   string
+	}{
+		{
+			name:   "passed with duration",
+			status: &watcher.JobStatus{Finished: true, Passed: true, StartTime: start, Timestamp: start.Add(72*time.Minute + 3*time.Second)},
+			want:   "RESULT: PASSED job=periodic-ci-test build=12345 duration=1h12m3s\n",
+		},
+		{
+			name:   "failed without start time",
+			status: &watcher.JobStatus{Finished: true, Passed: false, Timestamp: start},
+			want:   "RESULT: FAILED job=periodic-ci-test build=12345 duration=unknown\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := printWatchResult(&buf, newWatchResult(metadata, tt.status), false, nil); err != nil {
+				t.Fatalf("printWatchResult() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("printWatchResult() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintWatchResult_JSON(t *testing.T) {
+	metadata := &parser.ProwMetadata{JobName: "job", BuildID: "1", RawURL: "https://prow.ci.openshift.org/view/gs/b/logs/job/1"}
+	status := &watcher.JobStatus{Finished: true, Passed: false}
+
+	var buf bytes.Buffer
+	if err := printWatchResult(&buf, newWatchResult(metadata, status), true, nil); err != nil {
+		t.Fatalf("printWatchResult() error = %v", err)
+	}
+
+	var got map[string]string
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not valid JSON: %v (%q)", err, buf.String())
+	}
+	want := map[string]string{
+		"result":   "FAILED",
+		"job":      "job",
+		"build":    "1",
+		"duration": "unknown",
+		"url":      metadata.RawURL,
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("JSON %q = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestPrintWatchResult_JQ(t *testing.T) {
+	r := watchResult{
+		Result:   "FAILED",
+		Job:      "periodic-ci-e2e",
+		Build:    "1234",
+		Duration: "1h12m3s",
+		URL:      "https://prow.ci.openshift.org/view/gs/test-platform-results/logs/periodic-ci-e2e/1234",
+	}
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{".result", "FAILED\n"},
+		{".job + \"/\" + .build", "periodic-ci-e2e/1234\n"},
+		{".result == \"PASSED\"", "false\n"},
+		{"{result, build}", `{"build":"1234","result":"FAILED"}` + "\n"},
+		{".job, .duration", "periodic-ci-e2e\n1h12m3s\n"},
+		{".missing", "null\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			query, err := compileJQ(tt.expr)
+			if err != nil {
+				t.Fatalf("compileJQ(%q) error = %v", tt.expr, err)
+			}
+			var buf bytes.Buffer
+			if err := printWatchResult(&buf, r, false, query); err != nil {
+				t.Fatalf("printWatchResult() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("printWatchResult() with --jq %q = %q, want %q", tt.expr, buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileJQ_Invalid(t *testing.T) {
+	if _, err := compileJQ(".result |"); err == nil {
+		t.Error("compileJQ() should reject a malformed expression")
+	}
+}
+
+func TestPrintWatchResult_JQRuntimeError(t *testing.T) {
+	query, err := compileJQ(".result + 1")
+	if err != nil {
+		t.Fatalf("compileJQ() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := printWatchResult(&buf, watchResult{Result: "PASSED"}, false, query); err == nil {
+		t.Error("printWatchResult() should report an expression that fails at run time")
+	}
+}
@@ -2,21 +2,27 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/itchyny/gojq"
 	"github.com/spf13/cobra"
 
 	"github.com/clobrano/prow-helper/internal/analyzer"
 	"github.com/clobrano/prow-helper/internal/config"
 	"github.com/clobrano/prow-helper/internal/downloader"
+	"github.com/clobrano/prow-helper/internal/httpclient"
 	"github.com/clobrano/prow-helper/internal/notifier"
 	"github.com/clobrano/prow-helper/internal/output"
 	"github.com/clobrano/prow-helper/internal/parser"
+	"github.com/clobrano/prow-helper/internal/prompt"
 	"github.com/clobrano/prow-helper/internal/resolver"
 	"github.com/clobrano/prow-helper/internal/watcher"
 )
@@ -30,21 +36,69 @@ const (
 	ExitConfigError    = 4
 	ExitWatchFailed    = 5
 	ExitJobFailed      = 6
+	ExitWatchTimeout   = 7
 )
 
 var (
 	// CLI flags
 	flagDest           string
-	flagAnalyzeCmd     string
+	flagAnalyzeCmds    []string
+	flagAnalyzeShell   bool
 	flagBackground     bool
+	flagNoFork         bool
 	flagNotifyComplete bool // Internal flag set by background mode
 	flagWatch          bool
 	flagNtfyChannel    string
+	flagBuild          string
+	flagJSON           bool
+	flagBuildID        string
+	flagPR             string
+	flagJob            string
+	flagAuthor         string
+	flagNotifyFallback bool
+	flagDiffAgainst    string
+	flagFollowSymlinks bool
+	flagVerify         bool
+	flagPrintCmd       bool
+	flagJQ             string
+	flagForce          bool
+	flagKeepGoing      bool
+	flagQuietDownload  bool
+	flagQuietRename    bool
+	flagPropagateExit  bool
+	flagWatchPhases    bool
+	flagNoStartTime    bool
+	flagWaitForStart   bool
+	flagStartTimeout   time.Duration
+	flagWatchTimeout   time.Duration
+	flagNotifyOnlyFail bool
+	flagPorcelain      bool
+	flagNoDatePrefix   bool
+	flagDestCollision  string
+	flagPRLatest       bool
+	flagTar            string
+	flagTarOnly        bool
+	flagIntervalJitter float64
+	flagYes            bool
+	flagNoPrompt       bool
+	flagEnvPrefix      string
+	flagConfigFile     string
+	flagUserAgent      string
+	flagPrintConfig    bool
+	flagWebhookURL     string
+	flagWebhookOn      string
+	flagPreset         string
+	flagInclude        []string
+	flagExclude        []string
 )
 
+// downloadFilter selects the artifacts to download; it is resolved from
+// --preset, --include and --exclude when the command starts.
+var downloadFilter downloader.Filter
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
-	Use:   "prow-helper <prow-url>",
+	Use:   "prow-helper <prow-url> | --pr <github-pr-url>",
 	Short: "Download and analyze PROW CI test artifacts",
 	Long: `prow-helper automates the workflow of downloading PROW CI test artifacts
 and running analysis on them.
@@ -58,23 +112,85 @@ Example:
 
   prow-helper --dest ~/artifacts --analyze-cmd "my-analyzer" <url>
 
+  prow-helper --analyze-cmd "junit-summary" --analyze-cmd "my-analyzer" <url1> <url2>
+
   prow-helper --background <url>
 
   prow-helper --watch <url>
 
-  prow-helper --watch --ntfy-channel my-channel <url>`,
-	Args: cobra.ExactArgs(1),
-	RunE: runMain,
+  prow-helper --watch --ntfy-channel my-channel <url>
+
+  prow-helper --build latest https://prow.ci.openshift.org/view/gs/test-platform-results/logs/job-name
+
+  prow-helper --pr https://github.com/openshift/api/pull/1234
+
+  prow-helper --job e2e-aws --pr 1234`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if flagPR != "" || flagJob != "" || flagAuthor != "" || flagPrintConfig {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
+	PersistentPreRunE: applyConfigFlags,
+	RunE:              runMain,
 }
 
 func init() {
 	rootCmd.Flags().StringVar(&flagDest, "dest", "", "Download destination directory")
-	rootCmd.Flags().StringVar(&flagAnalyzeCmd, "analyze-cmd", "", "Command to run after download")
+	rootCmd.Flags().StringArrayVar(&flagAnalyzeCmds, "analyze-cmd", nil, "Command to run after download; with several URLs, repeat it to give each URL its own, in order")
+	rootCmd.Flags().BoolVar(&flagAnalyzeShell, "analyze-shell", false, "Run the analysis command through sh -c, allowing pipes, redirections and &&")
 	rootCmd.Flags().BoolVar(&flagBackground, "background", false, "Run in background and notify when done")
+	rootCmd.Flags().BoolVar(&flagNoFork, "no-fork", false, "With --background, stay in this process (e.g. under nohup or a supervisor) but still notify when done")
 	rootCmd.Flags().BoolVar(&flagNotifyComplete, "notify-on-complete", false, "Internal flag for background mode notifications")
 	rootCmd.Flags().MarkHidden("notify-on-complete") // Hide from help output
 	rootCmd.Flags().BoolVar(&flagWatch, "watch", false, "Poll job status until completion before downloading")
-	rootCmd.Flags().StringVar(&flagNtfyChannel, "ntfy-channel", "", "ntfy.sh channel for notifications")
+	rootCmd.Flags().Float64Var(&flagIntervalJitter, "interval-jitter", 0, "With --watch, vary each polling interval randomly by up to this percentage")
+	rootCmd.Flags().DurationVar(&flagWatchTimeout, "timeout", 0, "With --watch, give up if the job has not finished after this long (default: no limit)")
+	rootCmd.Flags().BoolVar(&flagWatchPhases, "watch-phases", false, "With --watch, also follow the job's Prow state (triggered, pending, ...) and notify its transitions")
+	rootCmd.Flags().BoolVar(&flagNoStartTime, "no-start-time", false, "With --watch, skip fetching started.json: the start and elapsed times are not shown")
+	rootCmd.Flags().BoolVar(&flagWaitForStart, "wait-for-start", false, "Wait until the job starts (started.json appears) and print its start time; with --watch, then wait for completion")
+	rootCmd.Flags().DurationVar(&flagStartTimeout, "start-timeout", 0, "With --wait-for-start, give up if the job has not started after this long (default: no limit)")
+	rootCmd.Flags().StringVar(&flagNtfyChannel, "ntfy-channel", "", "ntfy.sh channel for notifications (comma-separated for several)")
+	rootCmd.Flags().BoolVar(&flagJSON, "json", false, "Print the --watch result as a JSON object instead of the RESULT line")
+	rootCmd.Flags().BoolVar(&flagPorcelain, "porcelain", false, "Print the result as stable key=value lines on stdout (progress goes to stderr)")
+	rootCmd.Flags().StringVar(&flagJQ, "jq", "", "Apply a jq expression to the --watch JSON result (e.g. '.result'); implies --json")
+	rootCmd.Flags().StringVar(&flagBuild, "build", "", "Build to fetch when the URL points at a job (only \"latest\" is supported)")
+	rootCmd.Flags().StringVar(&flagBuildID, "build-id", "", "Build ID to use, replacing or filling in the one from the URL")
+	rootCmd.MarkFlagsMutuallyExclusive("build", "build-id")
+	rootCmd.Flags().StringVar(&flagWebhookURL, "webhook-url", "", "URL to POST a JSON object to for each notification event selected by --webhook-on")
+	rootCmd.Flags().StringVar(&flagWebhookOn, "webhook-on", "", "Comma-separated events posted to --webhook-url (default: completion and failure events)")
+	rootCmd.Flags().BoolVar(&flagNotifyFallback, "notify-fallback", false, "Fall back to the other notification channel (ntfy.sh or desktop) when one fails")
+	rootCmd.Flags().BoolVar(&flagNotifyOnlyFail, "notify-only-on-failure", false, "Send only failure notifications, suppressing success ones")
+	rootCmd.Flags().BoolVar(&flagPrintCmd, "print-cmd", false, "Print the gsutil command that would download the artifacts and exit")
+	rootCmd.Flags().BoolVar(&flagNoDatePrefix, "no-date-prefix", false, "Keep the <job>/<build> folder name instead of prefixing it with the job's start date")
+	rootCmd.Flags().BoolVar(&flagQuietRename, "quiet-rename", false, "Do not warn when the folder cannot be renamed with the job's start date (e.g. no started.json); the reason is still logged with "+debugEnv+" set")
+	rootCmd.Flags().StringVar(&flagDestCollision, "dest-collision-suffix", "", "Suffix of the new folder created next to an existing destination: timestamp (-YYYYMMDD-HHMMSS) or counter (-1, -2, ...)")
+	rootCmd.Flags().StringVar(&flagPreset, "preset", downloader.DefaultPreset, "Named set of artifacts to download: "+strings.Join(downloader.PresetNames(), ", "))
+	rootCmd.Flags().StringSliceVar(&flagInclude, "include", nil, "Also download the files matching these globs (with --preset all, only them)")
+	rootCmd.Flags().StringSliceVar(&flagExclude, "exclude", nil, "Skip the files matching these globs")
+	rootCmd.Flags().BoolVar(&flagQuietDownload, "quiet-download", false, "Hide gsutil's per-file output; the download start, completion and summary are still printed")
+	rootCmd.Flags().BoolVar(&flagPRLatest, "dest-per-pr-latest", false, "For PR jobs, point <dest>/<org>_<repo>/PR<num>/<job-name>/latest at the downloaded build")
+	rootCmd.Flags().StringVar(&flagTar, "tar", "", "After downloading, also package the artifacts into this .tar.gz file")
+	rootCmd.Flags().BoolVar(&flagTarOnly, "tar-only", false, "With --tar, remove the downloaded directory once packaged (kept when an analysis command needs it)")
+	rootCmd.Flags().BoolVar(&flagForce, "force", false, "Download even when the destination is a protected directory (home, /, XDG config/state/cache)")
+	rootCmd.Flags().BoolVar(&flagKeepGoing, "keep-going", false, "Run analysis as a child process and report its failure instead of aborting")
+	rootCmd.Flags().BoolVar(&flagPropagateExit, "propagate-exit", false, "Exit with the analysis command's own exit code when it fails, instead of 3")
+	rootCmd.Flags().StringVar(&flagDiffAgainst, "diff-against", "", "Skip the artifacts found with the same path and size in this earlier download, fetching only new and changed ones over HTTPS (public buckets only)")
+	rootCmd.Flags().BoolVar(&flagFollowSymlinks, "follow-symlinks", false, "Fetch the targets of Prow symlink markers (.txt files holding a gs:// URL) in their place, over HTTPS (public buckets only)")
+	rootCmd.Flags().BoolVar(&flagVerify, "verify", false, "Keep the files already in the destination only when their CRC32C checksum matches too, not just their size; downloads over HTTPS (public buckets only)")
+	rootCmd.Flags().StringVar(&flagPR, "pr", "", "GitHub pull request whose Prow jobs to choose from (instead of a Prow URL); with --job, the pull request number")
+	rootCmd.Flags().StringVar(&flagJob, "job", "", "Find the job by name (substring) on the Prow status page instead of giving its URL; narrow it down with --pr and --author")
+	rootCmd.Flags().StringVar(&flagAuthor, "author", "", "With --job, only consider jobs of pull requests by this author")
+	for _, other := range []string{"json", "jq", "print-cmd"} {
+		rootCmd.MarkFlagsMutuallyExclusive("porcelain", other)
+	}
+	rootCmd.PersistentFlags().BoolVarP(&flagYes, "yes", "y", false, "Answer every prompt with its safe default (e.g. a new timestamped folder when the destination exists)")
+	rootCmd.PersistentFlags().BoolVar(&flagNoPrompt, "no-prompt", false, "Fail instead of prompting, to catch unexpected interactive points in automation")
+	rootCmd.MarkFlagsMutuallyExclusive("yes", "no-prompt")
+	rootCmd.Flags().BoolVar(&flagPrintConfig, "print-config-path", false, "Print the config files read, highest precedence first, and exit")
+	rootCmd.PersistentFlags().StringVar(&flagConfigFile, "config", "", "Config file read over the project and XDG ones (default: $PROW_HELPER_CONFIG)")
+	rootCmd.PersistentFlags().StringVar(&flagUserAgent, "user-agent", "", "User-Agent header of every HTTP request (default: prow-helper/<version>)")
+	rootCmd.PersistentFlags().StringVar(&flagEnvPrefix, "config-env-prefix", config.DefaultEnvPrefix, "Prefix of the environment variables settings are read from (e.g. TRIAGE_ reads TRIAGE_DEST)")
 	rootCmd.Version = Version
 }
 
@@ -86,29 +202,124 @@ func Execute() {
 }
 
 func runMain(cmd *cobra.Command, args []string) error {
-	prowURL := args[0]
+	if flagPrintConfig {
+		printConfigPaths(os.Stdout, config.ResolvePaths())
+		return nil
+	}
+	if err := watcher.ValidateJitter(flagIntervalJitter); err != nil {
+		return err
+	}
+	watcher.IntervalJitter = flagIntervalJitter
+	filter, err := downloader.ResolveFilter(flagPreset, flagInclude, flagExclude)
+	if err != nil {
+		return err
+	}
+	downloadFilter = filter
+	downloader.Quiet = flagQuietDownload
+	if flagDiffAgainst != "" {
+		if info, err := os.Stat(flagDiffAgainst); err != nil || !info.IsDir() {
+			return fmt.Errorf("--diff-against %s: not a directory", flagDiffAgainst)
+		}
+	}
+	downloader.DiffAgainst = flagDiffAgainst
+	downloader.FollowLinks = flagFollowSymlinks
+	downloader.Verify = flagVerify
+	if os.Getenv(debugEnv) != "" {
+		debugOut = os.Stderr
+	}
+	if flagTarOnly && flagTar == "" {
+		return fmt.Errorf("--tar-only requires --tar")
+	}
+	if flagStartTimeout != 0 && !flagWaitForStart {
+		return fmt.Errorf("--start-timeout requires --wait-for-start")
+	}
+	if flagWatchTimeout != 0 && !flagWatch {
+		return fmt.Errorf("--timeout requires --watch")
+	}
+	if flagNoFork && !flagBackground {
+		return fmt.Errorf("--no-fork requires --background")
+	}
 
 	// If background mode, fork and exit parent
 	if flagBackground {
-		return runInBackground(os.Args)
+		if !flagNoFork {
+			return runInBackground(cmd, args)
+		}
+		// Run here as the background process would, so that the jobs of
+		// --pr and --job inherit the notifications too.
+		cmd.Flags().Set("notify-on-complete", "true")
 	}
 
-	return executeWorkflow(prowURL, flagNotifyComplete)
-}
+	analyzeCmds, err := perURLAnalyzeCmds(args, flagAnalyzeCmds)
+	if err != nil {
+		return err
+	}
 
-// runInBackground forks the current process to run in background
-func runInBackground(args []string) error {
-	// Remove --background flag from args
-	newArgs := make([]string, 0, len(args))
-	for i := 0; i < len(args); i++ {
-		if args[i] == "--background" || args[i] == "-background" {
-			continue
+	if flagJob != "" || flagAuthor != "" {
+		q, err := newJobQuery(flagJob, flagPR, flagAuthor)
+		if err != nil {
+			return err
 		}
-		newArgs = append(newArgs, args[i])
+		return runJobLookupWorkflow(cmd, q)
+	}
+	if flagPR != "" {
+		return runPRWorkflow(cmd, flagPR)
+	}
+	if len(args) > 1 {
+		return runWorkflowsSequentially(cmd, args, analyzeCmds)
 	}
 
-	// Add internal flag to indicate we're in background mode (for notifications)
-	newArgs = append(newArgs, "--notify-on-complete")
+	return executeWorkflow(args[0], flagNotifyComplete)
+}
+
+// perURLAnalyzeCmds returns the analysis command of each of urls when
+// --analyze-cmd was given once per URL, in the same order, and nil when it
+// was given at most once, for every URL. Any other count is an error.
+func perURLAnalyzeCmds(urls, cmds []string) ([]string, error) {
+	switch {
+	case len(cmds) <= 1:
+		return nil, nil
+	case len(urls) < 2:
+		return nil, fmt.Errorf("--analyze-cmd can only be repeated with several URLs, one per URL")
+	case len(cmds) != len(urls):
+		return nil, fmt.Errorf("--analyze-cmd given %d times for %d URLs: give it once for all of them, or once per URL in the same order", len(cmds), len(urls))
+	}
+	return cmds, nil
+}
+
+// singleAnalyzeCmd returns the --analyze-cmd that applies to every URL, or ""
+// when it was not given or given once per URL.
+func singleAnalyzeCmd() string {
+	if len(flagAnalyzeCmds) == 1 {
+		return flagAnalyzeCmds[0]
+	}
+	return ""
+}
+
+// cliConfig returns the configuration values given as command-line flags.
+func cliConfig() *config.Config {
+	cfg := &config.Config{
+		Dest:        flagDest,
+		AnalyzeCmd:  singleAnalyzeCmd(),
+		NtfyChannel: flagNtfyChannel,
+		WebhookURL:  flagWebhookURL,
+		WebhookOn:   flagWebhookOn,
+	}
+	if flagAnalyzeShell {
+		cfg.AnalyzeShell = "true"
+	}
+	if flagNoDatePrefix {
+		cfg.DatePrefix = "false"
+	}
+	cfg.CollisionSuffix = flagDestCollision
+	return cfg
+}
+
+// runInBackground starts prow-helper again in a new session, detached from
+// the terminal's job control, to run the workflow with the flags and args
+// cobra parsed; the parent only prints the child's PID.
+func runInBackground(cmd *cobra.Command, args []string) error {
+	newArgs := append([]string{os.Args[0]}, backgroundArgs(cmd, args)...)
 
 	execPath, err := os.Executable()
 	if err != nil {
@@ -120,6 +331,9 @@ func runInBackground(args []string) error {
 		Dir:   ".",
 		Env:   os.Environ(),
 		Files: []uintptr{0, 1, 2}, // stdin, stdout, stderr
+		// A new session keeps the child alive when the terminal's process
+		// group is signalled (Ctrl+C) or the shell exits.
+		Sys: &syscall.SysProcAttr{Setsid: true},
 	}
 
 	pid, err := syscall.ForkExec(execPath, newArgs, procAttr)
@@ -131,16 +345,63 @@ func runInBackground(args []string) error {
 	return nil
 }
 
+// backgroundArgs returns the arguments of the background process: the flags
+// set on cmd except --background and --no-fork, whatever form they were given
+// in, then --notify-on-complete and args.
+func backgroundArgs(cmd *cobra.Command, args []string) []string {
+	newArgs := childArgs(cmd, "background", "no-fork", "notify-on-complete")
+	newArgs = append(newArgs, "--notify-on-complete")
+	return append(newArgs, args...)
+}
+
 // executeWorkflow runs the main download and analysis workflow
 func executeWorkflow(prowURL string, sendNotification bool) error {
 
-	// Step 1: Validate URL; if not a direct prow URL, try to resolve it from the page
+	// Step 1: Load configuration (it decides which Prow hosts are accepted)
+	cfg, err := config.Load(cliConfig())
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to load configuration: %v", err)
+		fmt.Fprintln(os.Stderr, errMsg)
+		if sendNotification {
+			notifier.Notify("Configuration", errMsg, false)
+		}
+		os.Exit(ExitConfigError)
+		return nil
+	}
+
+	if issues := config.Validate(cfg); reportConfigIssues(issues) {
+		errMsg := "Invalid configuration"
+		if sendNotification {
+			notifier.Notify("Configuration", errMsg, false)
+		}
+		os.Exit(ExitConfigError)
+		return nil
+	}
+	applyConfig(cfg)
+
+	// Compile --jq up front so a typo does not surface only after a long watch.
+	var jqQuery *gojq.Code
+	if flagJQ != "" {
+		if jqQuery, err = compileJQ(flagJQ); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			if sendNotification {
+				notifier.Notify("Configuration", err.Error(), false)
+			}
+			os.Exit(ExitConfigError)
+			return nil
+		}
+	}
+
+	// Step 2: Validate URL; if not a direct prow URL, try to resolve it from the page
 	if err := parser.ValidateURL(prowURL); err != nil {
-		fmt.Fprintf(os.Stdout, "Not a direct prow URL (%v), attempting to find prow job link on page...\n", err)
+		fmt.Fprintf(progressOut(), "Not a direct prow URL (%v), attempting to find prow job link on page...\n", err)
 		resolved, resolveErr := resolveProwURL(prowURL)
 		if resolveErr != nil {
 			errMsg := fmt.Sprintf("Invalid PROW URL and could not resolve prow job link: %v", resolveErr)
 			fmt.Fprintln(os.Stderr, errMsg)
+			if hint := urlErrorHint(err); hint != "" {
+				fmt.Fprintln(os.Stderr, "Hint: "+hint)
+			}
 			if sendNotification {
 				notifier.Notify("URL Validation", errMsg, false)
 			}
@@ -150,45 +411,76 @@ func executeWorkflow(prowURL string, sendNotification bool) error {
 		prowURL = resolved
 	}
 
-	// Step 2: Parse URL to get metadata
+	// Step 2.5: If requested, complete a job URL with its newest build ID
+	if flagBuild != "" {
+		resolved, err := resolveBuildURL(prowURL, flagBuild)
+		if err != nil {
+			errMsg := fmt.Sprintf("Failed to resolve build: %v", err)
+			fmt.Fprintln(os.Stderr, errMsg)
+			if sendNotification {
+				notifier.Notify("URL Validation", errMsg, false)
+			}
+			os.Exit(ExitInvalidURL)
+			return nil
+		}
+		fmt.Fprintf(progressOut(), "Resolved %s build: %s\n", flagBuild, resolved)
+		prowURL = resolved
+	}
+
+	// Step 3: Parse URL to get metadata
 	metadata, err := parser.ParseURL(prowURL)
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to parse URL: %v", err)
 		fmt.Fprintln(os.Stderr, errMsg)
+		if hint := urlErrorHint(err); hint != "" {
+			fmt.Fprintln(os.Stderr, "Hint: "+hint)
+		}
 		if sendNotification {
 			notifier.Notify("URL Parsing", errMsg, false)
 		}
 		os.Exit(ExitInvalidURL)
 		return nil
 	}
+	// A gcsweb or GCS link is carried on as the job URL it maps to.
+	prowURL = metadata.RawURL
 
-	output.PrintField(os.Stdout, "Job", metadata.JobName)
-	if metadata.PRRef != "" {
-		output.PrintField(os.Stdout, "PR", metadata.PRRef)
-	}
-	output.PrintField(os.Stdout, "Build ID", metadata.BuildID)
-
-	// Step 3: Load configuration
-	cliConfig := &config.Config{
-		Dest:        flagDest,
-		AnalyzeCmd:  flagAnalyzeCmd,
-		NtfyChannel: flagNtfyChannel,
+	if flagBuildID != "" {
+		metadata, err = parser.WithBuildID(metadata, flagBuildID)
+		if err != nil {
+			errMsg := fmt.Sprintf("Failed to apply --build-id: %v", err)
+			fmt.Fprintln(os.Stderr, errMsg)
+			if hint := urlErrorHint(err); hint != "" {
+				fmt.Fprintln(os.Stderr, "Hint: "+hint)
+			}
+			if sendNotification {
+				notifier.Notify("URL Parsing", errMsg, false)
+			}
+			os.Exit(ExitInvalidURL)
+			return nil
+		}
 	}
 
-	cfg, err := config.Load(cliConfig)
-	if err != nil {
-		errMsg := fmt.Sprintf("Failed to load configuration: %v", err)
-		fmt.Fprintln(os.Stderr, errMsg)
-		if sendNotification {
-			notifier.Notify("Configuration", errMsg, false)
-		}
-		os.Exit(ExitConfigError)
+	if flagPrintCmd {
+		printDownloadCommand(os.Stdout, metadata, cfg.Dest)
 		return nil
 	}
 
+	out := progressOut()
+	report := porcelainReport{Job: metadata.JobName, Build: metadata.BuildID}
+
+	fields := output.NewFieldTable()
+	fields.Add("Job", metadata.JobName)
+	if metadata.PRRef != "" {
+		fields.Add("PR", metadata.PRRef)
+	}
+	fields.Add("Build ID", metadata.BuildID)
+	if metadata.Kind != "" {
+		fields.Add("Kind", string(metadata.Kind))
+	}
 	if cfg.NtfyChannel != "" {
-		output.PrintField(os.Stdout, "Ntfy channel", cfg.NtfyChannel)
+		fields.Add("Ntfy channel", cfg.NtfyChannel)
 	}
+	fields.Flush(out)
 
 	// jobDisplay combines the PR reference (when available) with the job name for
 	// use in console output and notification titles/messages.
@@ -197,121 +489,308 @@ func executeWorkflow(prowURL string, sendNotification bool) error {
 		jobDisplay = metadata.PRRef + " " + metadata.JobName
 	}
 
+	var outcome workflowOutcome
+
+	// Step 3.5: Wait for a just-triggered job to be scheduled
+	if flagWaitForStart {
+		fmt.Fprintln(out, "Waiting for the job to start...")
+		startTime, err := watcher.WaitForStart(metadata, watcher.DefaultStartPollInterval, flagStartTimeout)
+		if err != nil {
+			errMsg := fmt.Sprintf("Wait for start failed: %v", err)
+			fmt.Fprintln(os.Stderr, errMsg)
+			sendNotificationWithConfig(notifier.EventWatchFailed, jobDisplay, errMsg, false, cfg.NtfyChannel, true)
+			os.Exit(ExitWatchFailed)
+			return nil
+		}
+		if startTime.IsZero() {
+			fmt.Fprintln(out, "Job started")
+		} else {
+			output.PrintField(out, "Started at", startTime.Format("2006-01-02 15:04:05"))
+		}
+		if !flagWatch {
+			return nil
+		}
+	}
+
 	// Step 4: If watch mode, poll until job completes
 	if flagWatch {
-		status, err := watcher.Watch(metadata, watcher.DefaultPollInterval, os.Stdout)
+		var phases *watcher.PhaseTracker
+		if flagWatchPhases {
+			phases = &watcher.PhaseTracker{JobURL: prowURL, OnChange: func(from, to string) {
+				msg := fmt.Sprintf("Job: %s\n\nState: %s -> %s", jobDisplay, from, to)
+				sendNotificationWithConfig(notifier.EventJobStateChanged, jobDisplay, msg, true, cfg.NtfyChannel, true)
+			}}
+		}
+		status, err := watcher.WatchWithPhases(metadata, watcher.DefaultPollInterval, flagWatchTimeout, out, phases, !flagNoStartTime)
 		if err != nil {
 			errMsg := fmt.Sprintf("Watch failed: %v", err)
 			fmt.Fprintln(os.Stderr, errMsg)
-			sendNotificationWithConfig(jobDisplay, errMsg, false, cfg.NtfyChannel, true)
+			sendNotificationWithConfig(notifier.EventWatchFailed, jobDisplay, errMsg, false, cfg.NtfyChannel, true)
+			if errors.Is(err, watcher.ErrWatchTimeout) {
+				recordHistory(prowURL, "watch timed out")
+				os.Exit(ExitWatchTimeout)
+				return nil
+			}
+			recordHistory(prowURL, "watch failed")
 			os.Exit(ExitWatchFailed)
 			return nil
 		}
 
+		result := newWatchResult(metadata, status)
+		if err := printWatchResult(out, result, outputMode() == output.ModeJSON, jqQuery); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to print watch result: %v\n", err)
+		}
+		report.Status = "failed"
+		if status.Passed {
+			report.Status = "passed"
+		}
+		outcome.JobFailed = !status.Passed
+
+		// Step 4.5: Let the classify command decide whether the result is
+		// worth a notification and a download
+		decision := classification{Notify: true, Download: cfg.AnalyzeCmd != ""}
+		if cfg.ClassifyCmd != "" {
+			decision, err = classifyJob(cfg.ClassifyCmd, result, status, decision)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v, keeping the default actions\n", err)
+			}
+			output.PrintField(out, "Classified", decision.String())
+			muteNotifications = !decision.Notify
+		}
+
 		if !status.Passed {
 			// Job failed
 			msg := output.FormatJobStatusMessage(jobDisplay, false)
-			fmt.Println(msg)
-
-			// If no analyze command, just notify and exit
-			if cfg.AnalyzeCmd == "" {
-				sendNotificationWithConfig(jobDisplay, notifier.FormatJobStatusMessage(jobDisplay, false), false, cfg.NtfyChannel, true)
-				os.Exit(ExitJobFailed)
+			fmt.Fprintln(out, msg)
+
+			// If there is nothing to download, just notify and exit
+			if !decision.Download {
+				sendNotificationWithConfig(notifier.EventJobFailed, jobDisplay, notifier.FormatJobCompletionMessage(jobDisplay, false, status.Duration()), false, cfg.NtfyChannel, true)
+				recordHistory(prowURL, "job failed")
+				emitPorcelain(report)
+				os.Exit(exitCodeFor(outcome))
 				return nil
 			}
-			// If analyze command is set, continue to download artifacts for analysis
+			// Otherwise continue to download artifacts for analysis
 		} else {
 			// Job passed
 			msg := output.FormatJobStatusMessage(jobDisplay, true)
-			fmt.Println(msg)
+			fmt.Fprintln(out, msg)
 
-			// If no analyze command, just notify and exit
-			if cfg.AnalyzeCmd == "" {
-				sendNotificationWithConfig(jobDisplay, notifier.FormatJobStatusMessage(jobDisplay, true), true, cfg.NtfyChannel, true)
+			// If there is nothing to download, just notify and exit
+			if !decision.Download {
+				sendNotificationWithConfig(notifier.EventJobPassed, jobDisplay, notifier.FormatJobCompletionMessage(jobDisplay, true, status.Duration()), true, cfg.NtfyChannel, true)
+				recordHistory(prowURL, "job passed")
+				emitPorcelain(report)
 				return nil
 			}
-			// If analyze command is set, continue to download artifacts for analysis
+			// Otherwise continue to download artifacts for analysis
 		}
 	}
 
-	// Step 5: Resolve destination with conflict handling
-	destPath, skip, err := downloader.ResolveDestination(cfg.Dest, metadata, os.Stdin, os.Stdout)
+	// Step 5: Resolve destination with conflict handling, refusing
+	// destinations where artifacts would cause damage unless --force is set
+	if err := config.CheckDestination(cfg.Dest); err != nil && !flagForce {
+		errMsg := fmt.Sprintf("Refusing to download: %v (use --force to override)", err)
+		fmt.Fprintln(os.Stderr, errMsg)
+		sendNotificationWithConfig(notifier.EventDownloadFailed, jobDisplay, errMsg, false, cfg.NtfyChannel, sendNotification)
+		os.Exit(ExitConfigError)
+		return nil
+	}
+	destPath, skip, err := downloader.ResolveDestinationWithPolicy(cfg.Dest, metadata, promptPolicy(), os.Stdin, out)
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to resolve destination: %v", err)
 		fmt.Fprintln(os.Stderr, errMsg)
-		sendNotificationWithConfig(jobDisplay, errMsg, false, cfg.NtfyChannel, sendNotification)
-		os.Exit(ExitDownloadFailed)
+		sendNotificationWithConfig(notifier.EventDownloadFailed, jobDisplay, errMsg, false, cfg.NtfyChannel, sendNotification)
+		recordHistory(prowURL, "download failed")
+		emitPorcelain(report)
+		outcome.DownloadErr = err
+		os.Exit(exitCodeFor(outcome))
 		return nil
 	}
 
+	report.Dest = destPath
 	if skip {
-		fmt.Println("Skipping download, using existing artifacts")
+		fmt.Fprintln(out, "Skipping download, using existing artifacts")
 	} else {
 		// Step 6: Download artifacts
-		output.PrintField(os.Stdout, "Downloading to", destPath)
+		output.PrintField(out, "Downloading to", destPath)
 
 		// Notify download start
-		if sendNotification || cfg.NtfyChannel != "" {
-			sendNotificationWithConfig(jobDisplay, notifier.FormatDownloadStartMessage(jobDisplay), true, cfg.NtfyChannel, sendNotification)
+		if progressNotified(cfg, sendNotification) {
+			sendNotificationWithConfig(notifier.EventDownloadStart, jobDisplay, notifier.FormatDownloadStartMessage(jobDisplay), true, cfg.NtfyChannel, sendNotification)
 		}
 
-		gcsPath := "gs://" + metadata.Bucket + "/" + metadata.Path
-		if err := downloader.Download(gcsPath, destPath, os.Stdout, os.Stderr); err != nil {
+		downloadStart := time.Now()
+		if err := downloader.DownloadWithFilter(parser.GCSPath(metadata), destPath, downloadFilter, out, os.Stderr); err != nil {
 			errMsg := fmt.Sprintf("Download failed: %v", err)
 			fmt.Fprintln(os.Stderr, errMsg)
-			sendNotificationWithConfig(jobDisplay, notifier.FormatFailureMessage(jobDisplay, err), false, cfg.NtfyChannel, sendNotification)
-			os.Exit(ExitDownloadFailed)
+			sendNotificationWithConfig(notifier.EventDownloadFailed, jobDisplay, notifier.FormatFailureMessage(jobDisplay, err), false, cfg.NtfyChannel, sendNotification)
+			recordHistory(prowURL, "download failed")
+			emitPorcelain(report)
+			outcome.DownloadErr = err
+			os.Exit(exitCodeFor(outcome))
 			return nil
 		}
 
-		fmt.Println("Download complete!")
+		fmt.Fprintln(out, "Download complete!")
+		outcome.Downloaded = true
+		if summary, err := downloader.SummarizeDownload(destPath, time.Since(downloadStart)); err == nil {
+			output.PrintField(out, "Downloaded", summary.String())
+		}
 
 		// Step 5.5: Rename folder with date prefix from started.json
-		newDestPath, err := downloader.RenameWithDatePrefix(destPath)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: Failed to rename folder with date prefix: %v\n", err)
-			fmt.Fprintln(os.Stderr, "Continuing with original folder name...")
-		} else {
-			fmt.Printf("Renamed folder to: %s\n", newDestPath)
-			destPath = newDestPath // Update destPath for analysis
+		destPath = applyDatePrefix(out, destPath, cfg.DatePrefixEnabled(), flagQuietRename)
+		report.Dest = destPath
+		if flagPRLatest {
+			updatePRLatestLink(out, cfg.Dest, metadata, destPath)
 		}
 
 		// Notify download complete (only if we will run analysis)
-		if (sendNotification || cfg.NtfyChannel != "") && cfg.AnalyzeCmd != "" {
-			sendNotificationWithConfig(jobDisplay, notifier.FormatDownloadCompleteMessage(jobDisplay, destPath), true, cfg.NtfyChannel, sendNotification)
+		if progressNotified(cfg, sendNotification) && cfg.AnalyzeCmd != "" {
+			sendNotificationWithConfig(notifier.EventDownloadComplete, jobDisplay, notifier.FormatDownloadCompleteMessage(jobDisplay, destPath), true, cfg.NtfyChannel, sendNotification)
+		}
+	}
+
+	// Step 6.5: Package the artifacts into a tarball if requested
+	if flagTar != "" {
+		if err := downloader.TarDir(destPath, flagTar); err != nil {
+			errMsg := fmt.Sprintf("Failed to write tarball: %v", err)
+			fmt.Fprintln(os.Stderr, errMsg)
+			sendNotificationWithConfig(notifier.EventDownloadFailed, jobDisplay, errMsg, false, cfg.NtfyChannel, sendNotification)
+			recordDownloadHistory(prowURL, "tar failed", destPath, "")
+			emitPorcelain(report)
+			outcome.DownloadErr = err
+			os.Exit(exitCodeFor(outcome))
+			return nil
+		}
+		output.PrintField(out, "Tarball", flagTar)
+		if flagTarOnly {
+			if cfg.AnalyzeCmd != "" {
+				fmt.Fprintf(out, "Keeping %s for the analysis command\n", destPath)
+			} else if err := os.RemoveAll(destPath); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to remove %s: %v\n", destPath, err)
+			} else {
+				report.Dest = flagTar
+			}
 		}
 	}
 
 	// Step 7: Run analysis command if configured
 	if cfg.AnalyzeCmd != "" {
-		output.PrintField(os.Stdout, "Running analysis", cfg.AnalyzeCmd+" "+destPath)
+		output.PrintField(out, "Running analysis", cfg.AnalyzeCmd+" "+destPath)
 
 		// Notify analysis start
-		if sendNotification || cfg.NtfyChannel != "" {
-			sendNotificationWithConfig(jobDisplay, notifier.FormatAnalysisStartMessage(jobDisplay, cfg.AnalyzeCmd), true, cfg.NtfyChannel, sendNotification)
+		if progressNotified(cfg, sendNotification) {
+			sendNotificationWithConfig(notifier.EventAnalysisStart, jobDisplay, notifier.FormatAnalysisStartMessage(jobDisplay, cfg.AnalyzeCmd), true, cfg.NtfyChannel, sendNotification)
 		}
 
-		if err := analyzer.RunAnalysis(cfg.AnalyzeCmd, destPath); err != nil {
+		runAnalysis := func(cmdStr, path string) error {
+			// The analysis replaces this process, so its outcome is unknown.
+			recordDownloadHistory(prowURL, "analysis started", destPath, cfg.AnalyzeCmd)
+			return analyzer.RunAnalysis(cmdStr, path)
+		}
+		if !analysisReplacesProcess(outcome) {
+			runAnalysis = func(cmdStr, path string) error {
+				return analyzer.RunAnalysisWithIO(cmdStr, path, out, os.Stderr)
+			}
+		}
+		if err := runAnalysis(cfg.AnalyzeCmd, destPath); err != nil {
 			errMsg := fmt.Sprintf("Analysis failed: %v", err)
 			fmt.Fprintln(os.Stderr, errMsg)
-			sendNotificationWithConfig(jobDisplay, notifier.FormatFailureMessage(jobDisplay, err), false, cfg.NtfyChannel, sendNotification)
-			os.Exit(ExitAnalysisFailed)
+			outcome.AnalysisErr = err
+			msg := notifier.FormatFailureMessage(jobDisplay, err)
+			if flagKeepGoing {
+				msg = outcome.analysisFailureMessage(jobDisplay, destPath)
+			}
+			sendNotificationWithConfig(notifier.EventAnalysisFailed, jobDisplay, msg, false, cfg.NtfyChannel, sendNotification)
+			recordDownloadHistory(prowURL, "analysis failed", destPath, cfg.AnalyzeCmd)
+			report.AnalysisExit = analysisExitCode(err)
+			emitPorcelain(report)
+			os.Exit(exitCodeFor(outcome))
 			return nil
 		}
 
-		fmt.Println("Analysis complete!")
+		fmt.Fprintln(out, "Analysis complete!")
 
-		sendNotificationWithConfig(jobDisplay, notifier.FormatAnalysisSuccessMessage(jobDisplay, destPath), true, cfg.NtfyChannel, sendNotification)
+		sendNotificationWithConfig(notifier.EventAnalysisComplete, jobDisplay, notifier.FormatAnalysisSuccessMessage(jobDisplay, destPath), true, cfg.NtfyChannel, sendNotification)
+		recordDownloadHistory(prowURL, "analyzed", destPath, cfg.AnalyzeCmd)
+		report.AnalysisExit = "0"
 	} else {
-		sendNotificationWithConfig(jobDisplay, notifier.FormatDownloadOnlyMessage(jobDisplay, destPath), true, cfg.NtfyChannel, sendNotification)
+		sendNotificationWithConfig(notifier.EventDownloadComplete, jobDisplay, notifier.FormatDownloadOnlyMessage(jobDisplay, destPath), true, cfg.NtfyChannel, sendNotification)
+		recordDownloadHistory(prowURL, "downloaded", destPath, "")
 	}
 
+	emitPorcelain(report)
+	if code := exitCodeFor(outcome); code != ExitSuccess {
+		os.Exit(code)
+	}
 	return nil
 }
 
+// analysisReplacesProcess reports whether the analysis command is exec'd in
+// place of prow-helper. It runs as a child process instead when something is
+// left to report afterwards: its failure alongside the download result
+// (--keep-going, --porcelain), or the failure of the watched job, whose exit
+// code must not be replaced by the analysis command's.
+func analysisReplacesProcess(outcome workflowOutcome) bool {
+	return !flagKeepGoing && !flagPorcelain && !outcome.JobFailed
+}
+
+// renameWithDatePrefix renames a download folder after the job's start date.
+// It is a variable so tests can observe whether the rename happens.
+var renameWithDatePrefix = downloader.RenameWithDatePrefix
+
+// debugEnv is the environment variable that, when set, sends debug messages
+// to stderr.
+const debugEnv = "PROW_HELPER_DEBUG"
+
+// debugOut receives the messages about conditions the user chose to ignore,
+// which are not worth a warning: stderr when debugEnv is set, and nowhere
+// otherwise.
+var debugOut io.Writer = io.Discard
+
+// applyDatePrefix renames the download folder destPath with the job's start
+// date when enabled, and returns the folder to use from then on. A failed
+// rename keeps destPath and produces a warning, or with quiet a debug message
+// only.
+func applyDatePrefix(out io.Writer, destPath string, enabled, quiet bool) string {
+	if !enabled {
+		return destPath
+	}
+	newDestPath, err := renameWithDatePrefix(destPath)
+	if err != nil {
+		if quiet {
+			fmt.Fprintf(debugOut, "Debug: not renaming folder with date prefix: %v\n", err)
+			return destPath
+		}
+		fmt.Fprintf(os.Stderr, "Warning: Failed to rename folder with date prefix: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Continuing with original folder name...")
+		return destPath
+	}
+	fmt.Fprintf(out, "Renamed folder to: %s\n", newDestPath)
+	return newDestPath
+}
+
+// updatePRLatestLink points the PR's latest link for the job (see
+// downloader.PRLatestLinkPath) at destPath. Jobs that do not test a pull
+// request are left alone and a failed update only produces a warning.
+func updatePRLatestLink(out io.Writer, baseDest string, metadata *parser.ProwMetadata, destPath string) {
+	linkPath, ok := downloader.PRLatestLinkPath(baseDest, metadata)
+	if !ok {
+		return
+	}
+	if err := downloader.UpdateLatestLink(linkPath, destPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to update latest link: %v\n", err)
+		return
+	}
+	output.PrintField(out, "Latest link", linkPath)
+}
+
 // resolveProwURL fetches the given URL and extracts a prow job link from the page.
 // If exactly one prow job link is found it is returned automatically.
-// If multiple are found the user is prompted to select one.
+// If multiple are found the user is prompted to select one, or, with --yes,
+// the first one is used.
 func resolveProwURL(pageURL string) (string, error) {
 	links, err := resolver.FindProwJobLinks(pageURL)
 	if err != nil {
@@ -319,53 +798,206 @@ func resolveProwURL(pageURL string) (string, error) {
 	}
 
 	if len(links) == 1 {
-		fmt.Printf("Found prow job link: %s\n", links[0])
+		fmt.Fprintf(progressOut(), "Found prow job link: %s\n", links[0])
 		return links[0], nil
 	}
 
 	// Multiple links found: let the user choose
-	fmt.Printf("Found %d prow job links on page:\n", len(links))
+	fmt.Fprintf(progressOut(), "Found %d prow job links on page:\n", len(links))
 	for i, link := range links {
-		fmt.Printf("  [%d] %s\n", i+1, link)
+		fmt.Fprintf(progressOut(), "  [%d] %s\n", i+1, link)
+	}
+	switch promptPolicy() {
+	case prompt.Never:
+		return "", prompt.Disabled(fmt.Sprintf("choosing among %d prow job links", len(links)))
+	case prompt.Yes:
+		fmt.Fprintf(progressOut(), "Using the first link: %s\n", links[0])
+		return links[0], nil
 	}
 
 	reader := bufio.NewReader(os.Stdin)
 	for {
-		fmt.Printf("Select a link [1-%d]: ", len(links))
+		fmt.Fprintf(progressOut(), "Select a link [1-%d]: ", len(links))
 		input, err := reader.ReadString('\n')
 		if err != nil {
 			return "", fmt.Errorf("failed to read selection: %w", err)
 		}
 		n, err := strconv.Atoi(strings.TrimSpace(input))
 		if err != nil || n < 1 || n > len(links) {
-			fmt.Printf("Invalid selection, please enter a number between 1 and %d\n", len(links))
+			fmt.Fprintf(progressOut(), "Invalid selection, please enter a number between 1 and %d\n", len(links))
 			continue
 		}
 		return links[n-1], nil
 	}
 }
 
-// sendNotificationWithConfig sends notifications using configured methods.
-// ntfy.sh is used whenever ntfyChannel is non-empty, regardless of background mode.
-// Desktop notification is sent only when sendDesktop is true (background mode).
-func sendNotificationWithConfig(title, message string, success bool, ntfyChannel string, sendDesktop bool) {
-	statusIcon := "Success"
-	if !success {
-		statusIcon = "Failed"
+// applyConfigFlags points configuration loading at the environment variables
+// named after --config-env-prefix and at the --config file, and sets the
+// User-Agent of HTTP requests. It runs before every command.
+func applyConfigFlags(cmd *cobra.Command, args []string) error {
+	prefix, err := config.NormalizeEnvPrefix(flagEnvPrefix)
+	if err != nil {
+		return err
 	}
-	fullTitle := fmt.Sprintf("prow-helper: %s - %s", title, statusIcon)
+	config.EnvPrefix = prefix
+	config.ConfigFile = flagConfigFile
+	httpclient.UserAgent = userAgent(flagUserAgent, Version)
+	return nil
+}
 
-	if ntfyChannel != "" {
-		if err := notifier.NotifyNtfy(ntfyChannel, fullTitle, message); err != nil {
-			fmt.Printf("Warning: ntfy notification failed: %v\n", err)
-		}
+// userAgent returns the User-Agent to send: custom when set, and else
+// prow-helper/<version>.
+func userAgent(custom, version string) string {
+	if custom = strings.TrimSpace(custom); custom != "" {
+		return custom
+	}
+	return "prow-helper/" + version
+}
+
+// urlErrorHint returns advice on fixing the Prow URL rejected with err by the
+// parser, or "" when there is none.
+func urlErrorHint(err error) string {
+	switch {
+	case errors.Is(err, parser.ErrInvalidHost):
+		return fmt.Sprintf("accepted Prow hosts are %s; add others to prow_hosts in the config file", strings.Join(parser.AllowedHosts, ", "))
+	case errors.Is(err, parser.ErrInvalidScheme):
+		return "use the https:// URL of the job page"
+	case errors.Is(err, parser.ErrInvalidPath):
+		return "copy the URL of the job page, which contains /view/gs/"
+	case errors.Is(err, parser.ErrMissingPath):
+		return "did you forget the build ID? The URL must end with <job-name>/<build-id>; use --build latest for the newest build"
+	case errors.Is(err, parser.ErrInvalidBuildID):
+		return "the build ID is the number at the end of the job URL"
+	}
+	return ""
+}
+
+// promptPolicy returns how interactive prompts behave, as selected by --yes
+// and --no-prompt.
+func promptPolicy() prompt.Policy {
+	return prompt.FromFlags(flagYes, flagNoPrompt)
+}
+
+// progressOut is where informational messages are written: stderr when
+// stdout is reserved for the command printed by --print-cmd or the
+// --porcelain result.
+func progressOut() *os.File {
+	if flagPrintCmd || flagPorcelain {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// printDownloadCommand writes the gsutil command that downloads the build's
+// artifacts selected by downloadFilter into its destination under baseDest,
+// without resolving conflicts.
+func printDownloadCommand(w io.Writer, metadata *parser.ProwMetadata, baseDest string) {
+	args := downloader.GsutilArgs(parser.GCSPath(metadata), downloader.BuildDestinationPath(baseDest, metadata), downloadFilter)
+	fmt.Fprintln(w, parser.QuoteCommand(args))
+}
+
+// resolveBuildURL completes jobURL, a Prow URL pointing at a job rather than
+// a build, with the ID of the requested build. Only "latest" is supported.
+func resolveBuildURL(jobURL, build string) (string, error) {
+	if build != "latest" {
+		return "", fmt.Errorf("unsupported --build value %q (only \"latest\" is supported)", build)
+	}
+	bucket, jobPath, err := parser.SplitViewPath(jobURL)
+	if err != nil {
+		return "", err
+	}
+	buildID, err := downloader.ResolveLatestBuild(bucket, jobPath)
+	if err != nil {
+		return "", err
 	}
+	return strings.TrimSuffix(jobURL, "/") + "/" + buildID, nil
+}
 
-	if sendDesktop {
-		if err := notifier.Notify(title, message, success); err != nil {
-			fmt.Printf("Warning: desktop notification failed: %v\n", err)
+// applyConfig propagates the settings that tune package-level behaviour from
+// the resolved configuration.
+func applyConfig(cfg *config.Config) {
+	if hosts := cfg.AllowedProwHosts(); len(hosts) > 0 {
+		parser.AllowedHosts = hosts
+	}
+	parser.ProwHost = cfg.ProwHost
+	if d, err := time.ParseDuration(cfg.NtfyTimeout); err == nil && d > 0 {
+		notifier.NtfyTimeout = d
+	}
+	if cfg.StartedFile != "" {
+		watcher.StartedFile = cfg.StartedFile
+	}
+	if cfg.StartedField != "" {
+		watcher.StartedField = cfg.StartedField
+	}
+	if cfg.GCSHost != "" {
+		parser.GCSHost = cfg.GCSHost
+	}
+	parser.GCSBase = cfg.GCSBase
+	if cfg.CollisionSuffix != "" {
+		downloader.CollisionSuffix = cfg.CollisionSuffix
+	}
+	if cfg.Downloader != "" {
+		downloader.Backend = cfg.Downloader
+	}
+	analyzer.Shell = cfg.AnalyzeShellEnabled()
+	notifier.NtfyEmail = cfg.NtfyEmail
+	notifier.NtfyExtraHeaders = cfg.NtfyExtraHeaders
+	notifier.WebhookURL = cfg.WebhookURL
+	notifier.WebhookEvents = notifier.DefaultWebhookEvents
+	if events, err := notifier.ParseEvents(cfg.WebhookOn); err == nil && len(events) > 0 {
+		notifier.WebhookEvents = events
+	}
+}
+
+// reportConfigIssues prints configuration issues to stderr and returns true
+// when at least one of them is an error rather than a warning.
+func reportConfigIssues(issues []config.Issue) bool {
+	for _, issue := range issues {
+		if issue.Warning {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", issue)
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", issue)
 		}
 	}
+	return config.HasErrors(issues)
+}
+
+// muteNotifications is set when the classify command decides a job is not
+// worth notifying: sendNotificationWithConfig then sends nothing.
+var muteNotifications bool
+
+// sendNotificationWithConfig sends notifications using configured methods.
+// ntfy.sh is used whenever ntfyChannel is non-empty, regardless of background mode.
+// Desktop notification is sent only when sendDesktop is true (background mode).
+// With --notify-fallback, a failed channel falls back to the other one.
+// event tells the configured webhook what happened (see notifier.Multi).
+func sendNotificationWithConfig(event notifier.Event, title, message string, success bool, ntfyChannel string, sendDesktop bool) {
+	if muteNotifications || (success && flagNotifyOnlyFail) {
+		return
+	}
+	m := notifier.Multi{
+		NtfyChannel: ntfyChannel,
+		Ntfy:        ntfyChannel != "",
+		Desktop:     sendDesktop,
+		Fallback:    flagNotifyFallback,
+		Event:       event,
+	}
+	if err := sendMulti(m, title, message, success); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+}
+
+// progressNotified reports whether the progress events of a download
+// (download_start, download_complete before an analysis, analysis_start) are
+// sent: when desktop notifications are on, or an ntfy.sh channel or a
+// webhook is configured.
+func progressNotified(cfg *config.Config, sendDesktop bool) bool {
+	return sendDesktop || cfg.NtfyChannel != "" || cfg.WebhookURL != ""
+}
+
+// sendMulti delivers a notification; tests replace it to observe deliveries.
+var sendMulti = func(m notifier.Multi, title, message string, success bool) error {
+	return m.Send(title, message, success)
 }
 
 // For testing: allow overriding exec.Command
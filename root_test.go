@@ -1,12 +1,22 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/mattn/go-shellwords"
+	"github.com/spf13/cobra"
+
 	"github.com/clobrano/prow-helper/internal/config"
+	"github.com/clobrano/prow-helper/internal/downloader"
+	"github.com/clobrano/prow-helper/internal/notifier"
 	"github.com/clobrano/prow-helper/internal/parser"
+	"github.com/clobrano/prow-helper/internal/watcher"
 )
 
 func TestURLValidationIntegration(t *testing.T) {
@@ -73,6 +83,99 @@ analyze_cmd: "echo test"
 	}
 }
 
+func TestSelfHostedProwIntegration(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     string
+		jobURL     string // a build on the self-hosted Prow
+		mirrorURL  string // a direct link to one of its artifacts
+		wantHost   string
+		wantObject string // URL finished.json is read from
+	}{
+		{
+			name: "prow_hosts and gcs_host",
+			config: `prow_hosts:
+  - prow.internal.example.com
+gcs_host: gcs.internal.example.com
+`,
+			jobURL:     "https://prow.internal.example.com/view/gs/internal-results/logs/my-job/456",
+			mirrorURL:  "https://gcs.internal.example.com/internal-results/logs/my-job/456/build-log.txt",
+			wantHost:   "prow.internal.example.com",
+			wantObject: "https://gcs.internal.example.com/internal-results/logs/my-job/456/finished.json",
+		},
+		{
+			name: "prow_host and gcs_base",
+			config: `prow_host: deck.ci.example.com
+gcs_base: https://mirror.ci.example.com/storage
+`,
+			jobURL:     "https://deck.ci.example.com/view/gs/ci-results/logs/my-job/456",
+			mirrorURL:  "https://mirror.ci.example.com/storage/ci-results/logs/my-job/456/build-log.txt",
+			wantHost:   "deck.ci.example.com",
+			wantObject: "https://mirror.ci.example.com/storage/ci-results/logs/my-job/456/finished.json",
+		},
+		{
+			name: "prow_host set to the default host wins over prow_hosts",
+			config: `prow_host: prow.ci.openshift.org
+prow_hosts:
+  - prow.internal.example.com
+gcs_host: gcs.internal.example.com
+`,
+			jobURL:     "https://prow.ci.openshift.org/view/gs/test-platform-results/logs/my-job/456",
+			mirrorURL:  "https://gcs.internal.example.com/test-platform-results/logs/my-job/456/build-log.txt",
+			wantHost:   "prow.ci.openshift.org",
+			wantObject: "https://gcs.internal.example.com/test-platform-results/logs/my-job/456/finished.json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			origHosts, origProwHost, origGCS, origBase := parser.AllowedHosts, parser.ProwHost, parser.GCSHost, parser.GCSBase
+			t.Cleanup(func() {
+				parser.AllowedHosts, parser.ProwHost, parser.GCSHost, parser.GCSBase = origHosts, origProwHost, origGCS, origBase
+			})
+
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.config), 0644); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+			fileConfig, err := config.LoadConfigFile(configPath)
+			if err != nil {
+				t.Fatalf("LoadConfigFile() error = %v", err)
+			}
+			applyConfig(config.MergeConfig(&config.Config{}, &config.Config{}, fileConfig, config.DefaultConfig()))
+
+			metadata, err := parser.ParseURL(tt.jobURL)
+			if err != nil {
+				t.Fatalf("ParseURL() error = %v", err)
+			}
+			if metadata.Host != tt.wantHost || metadata.BuildID != "456" {
+				t.Errorf("ParseURL() = %+v, want build 456 on %s", metadata, tt.wantHost)
+			}
+			if got := watcher.BuildFinishedJSONURL(metadata); got != tt.wantObject {
+				t.Errorf("BuildFinishedJSONURL() = %q, want %q", got, tt.wantObject)
+			}
+			// The configured hosts add to the default host rather than replacing it.
+			if _, err := parser.ParseURL("https://prow.ci.openshift.org/view/gs/test-platform-results/logs/my-job/456"); err != nil {
+				t.Errorf("ParseURL() of the default host error = %v, want it still accepted", err)
+			}
+			if _, err := parser.ParseURL("https://prow.unknown.example.com/view/gs/bucket/logs/my-job/456"); !errors.Is(err, parser.ErrInvalidHost) {
+				t.Errorf("ParseURL() of an unlisted host error = %v, want ErrInvalidHost", err)
+			}
+
+			// Direct links to the mirror map back to the self-hosted Prow.
+			metadata, err = parser.ParseURL(tt.mirrorURL)
+			if err != nil {
+				t.Fatalf("ParseURL() of a mirror URL error = %v", err)
+			}
+			if metadata.Host != tt.wantHost {
+				t.Errorf("ParseURL(%q) Host = %q, want %q", tt.mirrorURL, metadata.Host, tt.wantHost)
+			}
+			if got := (jobQuery{Job: "my-job"}).statusPageURL(); !strings.HasPrefix(got, "https://"+tt.wantHost+"/") {
+				t.Errorf("statusPageURL() = %q, want the self-hosted Prow", got)
+			}
+		})
+	}
+}
+
 func TestConfigMergingIntegration(t *testing.T) {
 	// Test that CLI overrides everything
 	cliConfig := &config.Config{Dest: "/cli/path", AnalyzeCmd: "cli-cmd"}
@@ -118,7 +221,7 @@ func TestGsutilCommandConstruction(t *testing.T) {
 	}
 
 	cmd := parser.BuildGsutilCommand(metadata, "/tmp/dest")
-	expected := "gsutil -m cp -r gs://test-platform-results/logs/test-job/12345/ /tmp/dest"
+	expected := "gsutil -m cp -r 'gs://test-platform-results/logs/test-job/12345/*' /tmp/dest"
 
 	if cmd != expected {
 		t.Errorf("BuildGsutilCommand() = %v, want %v", cmd, expected)
@@ -141,3 +244,350 @@ func TestExitCodeConstants(t *testing.T) {
 		t.Errorf("ExitAnalysisFailed = %d, want 3", ExitAnalysisFailed)
 	}
 }
+
+func TestPrintDownloadCommand_MatchesExecutedArgv(t *testing.T) {
+	metadata, err := parser.ParseURL("https://prow.ci.openshift.org/view/gs/test-platform-results/pr-logs/pull/openshift_api/1234/pull-ci-openshift-api-master-unit/5678")
+	if err != nil {
+		t.Fatalf("ParseURL() error = %v", err)
+	}
+	baseDest := filepath.Join(t.TempDir(), "my artifacts")
+
+	var buf bytes.Buffer
+	printDownloadCommand(&buf, metadata, baseDest)
+
+	printed := strings.TrimSuffix(buf.String(), "\n")
+	if strings.Contains(printed, "\n") {
+		t.Fatalf("printed output %q should be a single line", buf.String())
+	}
+	got, err := shellwords.Parse(printed)
+	if err != nil {
+		t.Fatalf("printed command %q is not valid shell: %v", printed, err)
+	}
+
+	// The argv downloader.Download executes for the same build and destination.
+	want := parser.GsutilCopyArgs(parser.GCSPath(metadata), downloader.BuildDestinationPath(baseDest, metadata))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("printed command parses to %q, want the executed argv %q", got, want)
+	}
+}
+
+// captureNotifications replaces sendMulti for the test and returns a pointer
+// to the success flag of every notification sent.
+func captureNotifications(t *testing.T) *[]bool {
+	t.Helper()
+	var sent []bool
+	orig := sendMulti
+	sendMulti = func(m notifier.Multi, title, message string, success bool) error {
+		sent = append(sent, success)
+		return nil
+	}
+	t.Cleanup(func() { sendMulti = orig })
+	return &sent
+}
+
+func TestSendNotificationWithConfig_OnlyOnFailure(t *testing.T) {
+	tests := []struct {
+		name        string
+		onlyFailure bool
+		success     bool
+		wantSent    bool
+	}{
+		{name: "success sent by default", onlyFailure: false, success: true, wantSent: true},
+		{name: "failure sent by default", onlyFailure: false, success: false, wantSent: true},
+		{name: "success suppressed", onlyFailure: true, success: true, wantSent: false},
+		{name: "failure still sent", onlyFailure: true, success: false, wantSent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := captureNotifications(t)
+			flagNotifyOnlyFail = tt.onlyFailure
+			t.Cleanup(func() { flagNotifyOnlyFail = false })
+
+			sendNotificationWithConfig(notifier.EventJobPassed, "job", "message", tt.success, "channel", true)
+
+			if got := len(*sent) == 1; got != tt.wantSent {
+				t.Errorf("notifications sent = %v, want sent = %v", *sent, tt.wantSent)
+			}
+		})
+	}
+}
+
+func TestApplyDatePrefix(t *testing.T) {
+	tests := []struct {
+		name       string
+		enabled    bool
+		renameErr  error
+		want       string
+		wantRename bool
+	}{
+		{name: "disabled keeps the path", enabled: false, want: "/dest/job/123", wantRename: false},
+		{name: "enabled renames", enabled: true, want: "/dest/job/20260101-1200-job-123", wantRename: true},
+		{name: "failed rename keeps the path", enabled: true, renameErr: errors.New("no started.json"), want: "/dest/job/123", wantRename: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			renamed := false
+			orig := renameWithDatePrefix
+			renameWithDatePrefix = func(path string) (string, error) {
+				renamed = true
+				if tt.renameErr != nil {
+					return "", tt.renameErr
+				}
+				return "/dest/job/20260101-1200-job-123", nil
+			}
+			t.Cleanup(func() { renameWithDatePrefix = orig })
+
+			got := applyDatePrefix(&bytes.Buffer{}, "/dest/job/123", tt.enabled, false)
+			if got != tt.want {
+				t.Errorf("applyDatePrefix() = %q, want %q", got, tt.want)
+			}
+			if renamed != tt.wantRename {
+				t.Errorf("rename called = %v, want %v", renamed, tt.wantRename)
+			}
+		})
+	}
+}
+
+func TestApplyDatePrefix_Quiet(t *testing.T) {
+	orig := renameWithDatePrefix
+	renameWithDatePrefix = func(path string) (string, error) {
+		return "", errors.New("started.json not found")
+	}
+	t.Cleanup(func() { renameWithDatePrefix = orig })
+
+	var debug bytes.Buffer
+	origDebug := debugOut
+	debugOut = &debug
+	t.Cleanup(func() { debugOut = origDebug })
+
+	stderr, err := os.CreateTemp(t.TempDir(), "stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	origStderr := os.Stderr
+	os.Stderr = stderr
+	t.Cleanup(func() { os.Stderr = origStderr })
+
+	for _, quiet := range []bool{true, false} {
+		debug.Reset()
+		stderr.Truncate(0)
+		stderr.Seek(0, 0)
+
+		if got := applyDatePrefix(&bytes.Buffer{}, "/dest/job/123", true, quiet); got != "/dest/job/123" {
+			t.Errorf("applyDatePrefix(quiet=%v) = %q, want the original path", quiet, got)
+		}
+		warned, _ := os.ReadFile(stderr.Name())
+		if gotWarning := strings.Contains(string(warned), "Warning:"); gotWarning == quiet {
+			t.Errorf("applyDatePrefix(quiet=%v) wrote %q to stderr, want a warning = %v", quiet, warned, !quiet)
+		}
+		if gotDebug := strings.Contains(debug.String(), "started.json not found"); gotDebug != quiet {
+			t.Errorf("applyDatePrefix(quiet=%v) debug output = %q, want the error = %v", quiet, debug.String(), quiet)
+		}
+	}
+}
+
+func TestUpdatePRLatestLink(t *testing.T) {
+	base := t.TempDir()
+	dest := filepath.Join(base, "pull-ci-openshift-api-master-unit", "1001")
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	periodic := &parser.ProwMetadata{JobName: "periodic-ci-job", BuildID: "1001"}
+	updatePRLatestLink(&bytes.Buffer{}, base, periodic, dest)
+	entries, _ := os.ReadDir(base)
+	if len(entries) != 1 {
+		t.Errorf("periodic job created %d entries in dest, want only the build", len(entries))
+	}
+
+	pr := &parser.ProwMetadata{JobName: "pull-ci-openshift-api-master-unit", BuildID: "1001", Org: "openshift", Repo: "api", PRNumber: "42"}
+	var out bytes.Buffer
+	updatePRLatestLink(&out, base, pr, dest)
+	link := filepath.Join(base, "openshift_api", "PR42", "pull-ci-openshift-api-master-unit", "latest")
+	if info, err := os.Stat(link); err != nil || !info.IsDir() {
+		t.Errorf("latest link %s not pointing at the build: %v", link, err)
+	}
+	if !strings.Contains(out.String(), link) {
+		t.Errorf("output %q does not mention the link", out.String())
+	}
+}
+
+func TestCliConfig_NoDatePrefix(t *testing.T) {
+	flagNoDatePrefix = true
+	t.Cleanup(func() { flagNoDatePrefix = false })
+
+	merged := config.MergeConfig(cliConfig(), &config.Config{}, &config.Config{DatePrefix: "true"}, config.DefaultConfig())
+	if merged.DatePrefixEnabled() {
+		t.Error("--no-date-prefix should override date_prefix: true from the config file")
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	tests := []struct {
+		custom, version, want string
+	}{
+		{"", "1.2.3", "prow-helper/1.2.3"},
+		{"", "dev", "prow-helper/dev"},
+		{"my-bot/2.0", "1.2.3", "my-bot/2.0"},
+		{"  my-bot/2.0  ", "1.2.3", "my-bot/2.0"},
+		{"   ", "1.2.3", "prow-helper/1.2.3"},
+	}
+	for _, tt := range tests {
+		if got := userAgent(tt.custom, tt.version); got != tt.want {
+			t.Errorf("userAgent(%q, %q) = %q, want %q", tt.custom, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestURLErrorHint(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://example.com/view/gs/bucket/logs/job/123", "prow_hosts"},
+		{"https://prow.ci.openshift.org/view/gs/bucket/logs", "did you forget the build ID?"},
+		{"http://prow.ci.openshift.org/view/gs/bucket/logs/job/123", "https://"},
+		{"https://prow.ci.openshift.org/?author=clobrano", "/view/gs/"},
+	}
+	for _, tt := range tests {
+		_, err := parser.ParseURL(tt.url)
+		if got := urlErrorHint(err); !strings.Contains(got, tt.want) {
+			t.Errorf("urlErrorHint(ParseURL(%q)) = %q, want it to mention %q", tt.url, got, tt.want)
+		}
+	}
+	if got := urlErrorHint(errors.New("network down")); got != "" {
+		t.Errorf("urlErrorHint() of an unrelated error = %q, want none", got)
+	}
+}
+
+func TestPerURLAnalyzeCmds(t *testing.T) {
+	urls := []string{"https://prow.example.com/view/gs/b/logs/a/1", "https://prow.example.com/view/gs/b/logs/b/2"}
+	tests := []struct {
+		name    string
+		urls    []string
+		cmds    []string
+		want    []string
+		wantErr string
+	}{
+		{name: "none", urls: urls},
+		{name: "one for all", urls: urls, cmds: []string{"junit-summary"}},
+		{name: "one per URL", urls: urls, cmds: []string{"junit-summary", "my-analyzer"}, want: []string{"junit-summary", "my-analyzer"}},
+		{name: "too few", urls: append(urls, urls[0]), cmds: []string{"junit-summary", "my-analyzer"}, wantErr: "given 2 times for 3 URLs"},
+		{name: "too many", urls: urls[:1], cmds: []string{"junit-summary", "my-analyzer"}, wantErr: "only be repeated with several URLs"},
+		{name: "no URLs", cmds: []string{"junit-summary", "my-analyzer"}, wantErr: "only be repeated with several URLs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := perURLAnalyzeCmds(tt.urls, tt.cmds)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("perURLAnalyzeCmds() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("perURLAnalyzeCmds() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackgroundArgs(t *testing.T) {
+	url := "https://prow.ci.openshift.org/view/gs/test-platform-results/logs/job/123"
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "bare flag",
+			args: []string{"--background", "--dest", "/tmp/a b", url},
+			want: []string{"--dest=/tmp/a b", "--notify-on-complete", url},
+		},
+		{
+			name: "explicit value",
+			args: []string{"--dest=/tmp/x", "--background=true", url},
+			want: []string{"--dest=/tmp/x", "--notify-on-complete", url},
+		},
+		{
+			name: "numeric value",
+			args: []string{url, "--background=1", "--include=*.log,junit*.xml"},
+			want: []string{"--include=*.log", "--include=junit*.xml", "--notify-on-complete", url},
+		},
+		{
+			name: "with no-fork and an earlier notify-on-complete",
+			args: []string{"--background", "--no-fork", "--notify-on-complete", "--analyze-cmd", "my-analyzer --background", url},
+			want: []string{"--analyze-cmd=my-analyzer --background", "--notify-on-complete", url},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "test", Run: func(*cobra.Command, []string) {}}
+			cmd.Flags().Bool("background", false, "")
+			cmd.Flags().Bool("no-fork", false, "")
+			cmd.Flags().Bool("notify-on-complete", false, "")
+			cmd.Flags().String("dest", "", "")
+			cmd.Flags().String("analyze-cmd", "", "")
+			cmd.Flags().StringSlice("include", nil, "")
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatalf("ParseFlags() error = %v", err)
+			}
+
+			if got := backgroundArgs(cmd, cmd.Flags().Args()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("backgroundArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalysisReplacesProcess(t *testing.T) {
+	origKeepGoing, origPorcelain := flagKeepGoing, flagPorcelain
+	t.Cleanup(func() { flagKeepGoing, flagPorcelain = origKeepGoing, origPorcelain })
+
+	tests := []struct {
+		name                 string
+		keepGoing, porcelain bool
+		outcome              workflowOutcome
+		want                 bool
+	}{
+		{name: "default", outcome: workflowOutcome{Downloaded: true}, want: true},
+		{name: "job failed", outcome: workflowOutcome{JobFailed: true, Downloaded: true}},
+		{name: "--keep-going", keepGoing: true, outcome: workflowOutcome{Downloaded: true}},
+		{name: "--porcelain", porcelain: true, outcome: workflowOutcome{Downloaded: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flagKeepGoing, flagPorcelain = tt.keepGoing, tt.porcelain
+			if got := analysisReplacesProcess(tt.outcome); got != tt.want {
+				t.Errorf("analysisReplacesProcess() = %v, want %v", got, tt.want)
+			}
+			// Whatever the analysis does, a failed job keeps its exit code.
+			if tt.outcome.JobFailed && exitCodeFor(tt.outcome) != ExitJobFailed {
+				t.Errorf("exitCodeFor() = %d, want %d", exitCodeFor(tt.outcome), ExitJobFailed)
+			}
+		})
+	}
+}
+
+func TestProgressNotified(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		desktop bool
+		want    bool
+	}{
+		{name: "nothing configured", cfg: config.Config{}},
+		{name: "desktop", cfg: config.Config{}, desktop: true, want: true},
+		{name: "ntfy", cfg: config.Config{NtfyChannel: "chan"}, want: true},
+		{name: "webhook only", cfg: config.Config{WebhookURL: "https://hooks.example.com/prow"}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := progressNotified(&tt.cfg, tt.desktop); got != tt.want {
+				t.Errorf("progressNotified() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/clobrano/prow-helper/internal/config"
+	"github.com/clobrano/prow-helper/internal/downloader"
+	"github.com/clobrano/prow-helper/internal/output"
+	"github.com/clobrano/prow-helper/internal/parser"
+	"github.com/clobrano/prow-helper/internal/stats"
+)
+
+// DefaultStatsBuilds is how many recent builds stats looks at by default.
+const DefaultStatsBuilds = 20
+
+var flagStatsBuilds int
+
+var statsCmd = &cobra.Command{
+	Use:   "stats <prow-job-url>",
+	Short: "Show pass rate and durations of a job's recent builds",
+	Long: `stats lists the most recent builds of a job (the Prow URL of the job, without a
+build ID), reads their started.json and finished.json, and prints the pass
+rate and the min/median/max duration of the finished ones. Use it to pick a
+sensible watch timeout or to judge how flaky a job is.
+
+Example:
+  prow-helper stats https://prow.ci.openshift.org/view/gs/test-platform-results/logs/job-name`,
+	Args: cobra.ExactArgs(1),
+	RunE: runStats,
+}
+
+func init() {
+	statsCmd.Flags().IntVar(&flagStatsBuilds, "builds", DefaultStatsBuilds, "Number of most recent builds to consider")
+	rootCmd.AddCommand(statsCmd)
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	if flagStatsBuilds < 1 {
+		return fmt.Errorf("--builds must be at least 1")
+	}
+	cfg, err := config.Load(&config.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if reportConfigIssues(config.Validate(cfg)) {
+		return fmt.Errorf("invalid configuration")
+	}
+	applyConfig(cfg)
+
+	bucket, jobPath, err := parser.SplitViewPath(args[0])
+	if err != nil {
+		return err
+	}
+	buildIDs, err := downloader.ListBuildIDs(bucket, jobPath)
+	if err != nil {
+		return err
+	}
+	if len(buildIDs) == 0 {
+		return fmt.Errorf("%w: gs://%s/%s/", downloader.ErrNoBuilds, bucket, jobPath)
+	}
+	if len(buildIDs) > flagStatsBuilds {
+		buildIDs = buildIDs[len(buildIDs)-flagStatsBuilds:]
+	}
+
+	fmt.Printf("Fetching status of %d builds...\n", len(buildIDs))
+	statuses, err := stats.Collect(bucket, jobPath, buildIDs)
+	if err != nil {
+		return err
+	}
+	printStats(os.Stdout, stats.Summarize(statuses))
+	return nil
+}
+
+// printStats writes the summary of a job's builds.
+func printStats(w io.Writer, s stats.Summary) {
+	fields := output.NewFieldTable()
+	defer fields.Flush(w)
+
+	fields.Add("Builds", fmt.Sprintf("%d (%d finished, %d running)", s.Builds, s.Finished, s.Builds-s.Finished))
+	if s.Finished == 0 {
+		return
+	}
+	fields.Add("Pass rate", fmt.Sprintf("%.0f%% (%d/%d)", s.PassRate()*100, s.Passed, s.Finished))
+	if s.Timed == 0 {
+		fields.Add("Duration", "unknown (no start times)")
+		return
+	}
+	fields.Add("Duration", fmt.Sprintf("min %s  median %s  max %s",
+		s.Min.Round(time.Second), s.Median.Round(time.Second), s.Max.Round(time.Second)))
+}
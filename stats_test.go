@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+
+	"github.com/clobrano/prow-helper/internal/stats"
+)
+
+func TestPrintStats(t *testing.T) {
+	orig := color.NoColor
+	color.NoColor = true
+	t.Cleanup(func() { color.NoColor = orig })
+
+	tests := []struct {
+		name    string
+		summary stats.Summary
+		want    string
+	}{
+		{
+			name: "timed builds",
+			summary: stats.Summary{Builds: 5, Finished: 4, Passed: 3, Timed: 4,
+				Min: 50 * time.Minute, Median: 62*time.Minute + 30*time.Second, Max: 2 * time.Hour},
+			want: "   Builds: 5 (4 finished, 1 running)\nPass rate: 75% (3/4)\n Duration: min 50m0s  median 1h2m30s  max 2h0m0s\n",
+		},
+		{
+			name:    "no start times",
+			summary: stats.Summary{Builds: 1, Finished: 1},
+			want:    "   Builds: 1 (1 finished, 0 running)\nPass rate: 0% (0/1)\n Duration: unknown (no start times)\n",
+		},
+		{
+			name:    "nothing finished",
+			summary: stats.Summary{Builds: 2},
+			want:    "Builds: 2 (0 finished, 2 running)\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			printStats(&buf, tt.summary)
+			if buf.String() != tt.want {
+				t.Errorf("printStats() =\n%q\nwant\n%q", buf.String(), tt.want)
+			}
+		})
+	}
+}
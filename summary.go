@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Formats accepted by monitor --summary-format.
+const (
+	summaryText     = "text"
+	summaryMarkdown = "markdown"
+	summaryJSON     = "json"
+)
+
+// summaryFormats lists the accepted --summary-format values.
+var summaryFormats = []string{summaryText, summaryMarkdown, summaryJSON}
+
+// validateSummaryFormat returns an error if format is not one of summaryFormats.
+func validateSummaryFormat(format string) error {
+	for _, f := range summaryFormats {
+		if format == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid --summary-format %q (want one of: %s)", format, strings.Join(summaryFormats, ", "))
+}
+
+// summaryJob is the outcome of one monitored job, shared by every summary
+// format.
+type summaryJob struct {
+	Job      string `json:"job"`
+	PR       string `json:"pr,omitempty"`
+	Build    string `json:"build"`
+	Status   string `json:"status"` // passed, failed or error
+	Duration string `json:"duration,omitempty"`
+	URL      string `json:"url"`
+	Error    string `json:"error,omitempty"`
+}
+
+// monitorSummary is the final summary of a monitor run.
+type monitorSummary struct {
+	Passed  int          `json:"passed"`
+	Failed  int          `json:"failed"`
+	Errored int          `json:"errored"`
+	Jobs    []summaryJob `json:"jobs"`
+}
+
+// newMonitorSummary collects the outcome of every entry.
+func newMonitorSummary(entries []*monitorEntry) monitorSummary {
+	s := monitorSummary{Jobs: []summaryJob{}}
+	for _, e := range entries {
+		job := summaryJob{
+			Job:   e.metadata.JobName,
+			PR:    e.prRef,
+			Build: e.metadata.BuildID,
+			URL:   e.metadata.RawURL,
+		}
+		switch {
+		case e.err != nil:
+			s.Errored++
+			job.Status = "error"
+			job.Error = e.err.Error()
+		case e.status != nil && e.status.Passed:
+			s.Passed++
+			job.Status = "passed"
+		default:
+			s.Failed++
+			job.Status = "failed"
+		}
+		if d := entryDuration(e); d > 0 {
+			job.Duration = d.Truncate(time.Second).String()
+		}
+		s.Jobs = append(s.Jobs, job)
+	}
+	return s
+}
+
+// entryDuration returns how long a finished entry's job ran, or 0 if unknown.
+func entryDuration(e *monitorEntry) time.Duration {
+	if e.status == nil || !e.status.Finished {
+		return 0
+	}
+	if !e.startTime.IsZero() && !e.status.Timestamp.IsZero() {
+		return e.status.Timestamp.Sub(e.startTime)
+	}
+	return e.status.Duration()
+}
+
+// printFinalSummary writes the summary of a finished monitor run to w in the
+// given format (one of summaryFormats).
+func printFinalSummary(w io.Writer, entries []*monitorEntry, format string) error {
+	s := newMonitorSummary(entries)
+	switch format {
+	case summaryMarkdown:
+		return writeMarkdownSummary(w, s)
+	case summaryJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	default:
+		return writeTextSummary(w, s)
+	}
+}
+
+// writeTextSummary writes the pass/fail counts as plain text.
+func writeTextSummary(w io.Writer, s monitorSummary) error {
+	fmt.Fprintln(w, "Summary:")
+	fmt.Fprintf(w, "  Passed:  %d\n", s.Passed)
+	fmt.Fprintf(w, "  Failed:  %d\n", s.Failed)
+	if s.Errored > 0 {
+		fmt.Fprintf(w, "  Errored: %d\n", s.Errored)
+	}
+	return nil
+}
+
+// summaryEmoji maps a summaryJob status to the emoji shown in markdown.
+var summaryEmoji = map[string]string{
+	"passed": "✅",
+	"failed": "❌",
+	"error":  "⚠️",
+}
+
+// writeMarkdownSummary writes s as a GitHub-flavoured markdown table, one
+// row per job, followed by the pass/fail counts.
+func writeMarkdownSummary(w io.Writer, s monitorSummary) error {
+	fmt.Fprintln(w, "| Job | Status | Duration | Link |")
+	fmt.Fprintln(w, "|-----|--------|----------|------|")
+	for _, j := range s.Jobs {
+		name := j.Job
+		if j.PR != "" {
+			name = j.PR + " " + name
+		}
+		duration := j.Duration
+		if duration == "" {
+			duration = "-"
+		}
+		fmt.Fprintf(w, "| %s | %s %s | %s | [%s](%s) |\n",
+			markdownCell(name), summaryEmoji[j.Status], j.Status, duration, j.Build, j.URL)
+	}
+	fmt.Fprintf(w, "\n**Passed:** %d · **Failed:** %d", s.Passed, s.Failed)
+	if s.Errored > 0 {
+		fmt.Fprintf(w, " · **Errored:** %d", s.Errored)
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
+// markdownCell escapes the characters that would break a markdown table cell.
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clobrano/prow-helper/internal/parser"
+	"github.com/clobrano/prow-helper/internal/watcher"
+)
+
+// summaryEntries returns one passed, one failed and one errored entry.
+func summaryEntries() []*monitorEntry {
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	return []*monitorEntry{
+		{
+			metadata:  &parser.ProwMetadata{JobName: "e2e-aws", BuildID: "100", RawURL: jobURL("e2e-aws", "100")},
+			startTime: start,
+			status:    &watcher.JobStatus{Finished: true, Passed: true, Timestamp: start.Add(90 * time.Minute)},
+		},
+		{
+			metadata: &parser.ProwMetadata{JobName: "e2e-gcp", BuildID: "200", RawURL: jobURL("e2e-gcp", "200")},
+			prRef:    "[org/repo PR7]",
+			status:   &watcher.JobStatus{Finished: true, Passed: false},
+		},
+		{
+			metadata: &parser.ProwMetadata{JobName: "unit", BuildID: "300", RawURL: jobURL("unit", "300")},
+			err:      errors.New("boom"),
+		},
+	}
+}
+
+func TestValidateSummaryFormat(t *testing.T) {
+	for _, f := range []string{"text", "markdown", "json"} {
+		if err := validateSummaryFormat(f); err != nil {
+			t.Errorf("validateSummaryFormat(%q) = %v, want nil", f, err)
+		}
+	}
+	if err := validateSummaryFormat("yaml"); err == nil {
+		t.Error("validateSummaryFormat(\"yaml\") = nil, want error")
+	}
+}
+
+func TestPrintFinalSummaryText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printFinalSummary(&buf, summaryEntries(), summaryText); err != nil {
+		t.Fatal(err)
+	}
+	want := "Summary:\n  Passed:  1\n  Failed:  1\n  Errored: 1\n"
+	if buf.String() != want {
+		t.Errorf("text summary = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestPrintFinalSummaryMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printFinalSummary(&buf, summaryEntries(), summaryMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	wantRows := []string{
+		"| Job | Status | Duration | Link |",
+		"|-----|--------|----------|------|",
+		"| e2e-aws | ✅ passed | 1h30m0s | [100](" + jobURL("e2e-aws", "100") + ") |",
+		"| [org/repo PR7] e2e-gcp | ❌ failed | - | [200](" + jobURL("e2e-gcp", "200") + ") |",
+		"| unit | ⚠️ error | - | [300](" + jobURL("unit", "300") + ") |",
+	}
+	if len(lines) < len(wantRows) {
+		t.Fatalf("markdown summary has %d lines, want at least %d:\n%s", len(lines), len(wantRows), buf.String())
+	}
+	for i, want := range wantRows {
+		if lines[i] != want {
+			t.Errorf("line %d = %q, want %q", i, lines[i], want)
+		}
+	}
+	if got := lines[len(lines)-1]; got != "**Passed:** 1 · **Failed:** 1 · **Errored:** 1" {
+		t.Errorf("totals line = %q", got)
+	}
+}
+
+func TestMarkdownCellEscapesPipes(t *testing.T) {
+	if got := markdownCell("a|b"); got != `a\|b` {
+		t.Errorf("markdownCell(\"a|b\") = %q", got)
+	}
+}
+
+func TestPrintFinalSummaryJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printFinalSummary(&buf, summaryEntries(), summaryJSON); err != nil {
+		t.Fatal(err)
+	}
+	var got monitorSummary
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("summary is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if got.Passed != 1 || got.Failed != 1 || got.Errored != 1 {
+		t.Errorf("counts = %d/%d/%d, want 1/1/1", got.Passed, got.Failed, got.Errored)
+	}
+	want := []summaryJob{
+		{Job: "e2e-aws", Build: "100", Status: "passed", Duration: "1h30m0s", URL: jobURL("e2e-aws", "100")},
+		{Job: "e2e-gcp", PR: "[org/repo PR7]", Build: "200", Status: "failed", URL: jobURL("e2e-gcp", "200")},
+		{Job: "unit", Build: "300", Status: "error", URL: jobURL("unit", "300"), Error: "boom"},
+	}
+	if len(got.Jobs) != len(want) {
+		t.Fatalf("got %d jobs, want %d", len(got.Jobs), len(want))
+	}
+	for i := range want {
+		if got.Jobs[i] != want[i] {
+			t.Errorf("jobs[%d] = %+v, want %+v", i, got.Jobs[i], want[i])
+		}
+	}
+
+	// The keys are part of the output contract.
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"passed", "failed", "errored", "jobs"} {
+		if _, ok := raw[key]; !ok {
+			t.Errorf("JSON summary lacks %q key", key)
+		}
+	}
+}
+
+func TestPrintFinalSummaryJSONNoJobs(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printFinalSummary(&buf, nil, summaryJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"jobs": []`) {
+		t.Errorf("empty summary should have an empty jobs array, got %s", buf.String())
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/clobrano/prow-helper/internal/config"
+	"github.com/clobrano/prow-helper/internal/parser"
+	"github.com/clobrano/prow-helper/internal/watcher"
+)
+
+// DefaultTailInterval is how often tail checks build-log.txt for new output.
+const DefaultTailInterval = 10 * time.Second
+
+var flagTailInterval time.Duration
+
+var tailCmd = &cobra.Command{
+	Use:   "tail <prow-url>",
+	Short: "Follow a running job's build log",
+	Long: `tail prints a job's build-log.txt as it grows, like tail -f, and stops once
+the job's finished.json appears. Only the new bytes are fetched each time
+(HTTP range requests), so following a long log stays cheap.
+
+Exits with 6 if the job failed.
+
+Example:
+  prow-helper tail https://prow.ci.openshift.org/view/gs/test-platform-results/logs/job-name/12345`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTail,
+}
+
+func init() {
+	tailCmd.Flags().DurationVar(&flagTailInterval, "interval", DefaultTailInterval,
+		"How often to check the build log for new output")
+	rootCmd.AddCommand(tailCmd)
+}
+
+func runTail(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(&config.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if reportConfigIssues(config.Validate(cfg)) {
+		return fmt.Errorf("invalid configuration")
+	}
+	applyConfig(cfg)
+
+	metadata, err := parser.ParseURL(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse URL: %v\n", err)
+		os.Exit(ExitInvalidURL)
+		return nil
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	stop := make(chan struct{})
+	go func() {
+		<-sigCh
+		close(stop)
+	}()
+
+	status, err := watcher.FollowLog(watcher.BuildLogURL(metadata), watcher.BuildFinishedJSONURL(metadata),
+		flagTailInterval, os.Stdout, stop)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Tail failed: %v\n", err)
+		os.Exit(ExitWatchFailed)
+		return nil
+	}
+	if status == nil {
+		return nil
+	}
+	if !status.Passed {
+		fmt.Fprintln(os.Stderr, "Job finished: FAILED")
+		os.Exit(ExitJobFailed)
+		return nil
+	}
+	fmt.Fprintln(os.Stderr, "Job finished: PASSED")
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/clobrano/prow-helper/internal/config"
+	"github.com/clobrano/prow-helper/internal/parser"
+)
+
+// monitorWatchlist monitors the jobs of the watchlist called name in cfg.
+// filters are the --filter-* query parameters, applied to its status pages.
+func monitorWatchlist(cfg *config.Config, name string, filters map[string]string) error {
+	w, ok := cfg.Watchlists[name]
+	if !ok {
+		if len(cfg.Watchlists) == 0 {
+			return fmt.Errorf("no watchlist %q: the configuration defines no watchlists", name)
+		}
+		return fmt.Errorf("no watchlist %q in the configuration (known: %s)", name,
+			strings.Join(slices.Sorted(maps.Keys(cfg.Watchlists)), ", "))
+	}
+
+	fmt.Fprintf(os.Stdout, "Fetching watchlist %s...\n", name)
+	if cfg.NtfyChannel != "" {
+		fmt.Fprintf(os.Stdout, "Ntfy channel: %s\n", cfg.NtfyChannel)
+	}
+	entries, err := resolveWatchlist(w, filters)
+	if err != nil {
+		return fmt.Errorf("watchlist %s: %w", name, err)
+	}
+	if flagMonitorExcludeFinished {
+		if entries = unfinishedEntries(entries); len(entries) == 0 {
+			return fmt.Errorf("all the jobs of watchlist %s have finished (drop --exclude-finished to monitor them)", name)
+		}
+	}
+
+	fmt.Fprintf(os.Stdout, "\nMonitoring %d job(s) (interval: %s)...\n\n", len(entries), flagMonitorInterval)
+	return monitorJobs(entries, flagMonitorInterval, cfg.NtfyChannel, nil, nil)
+}
+
+// resolveWatchlist returns the entries to monitor for w: one per job URL
+// (a /view/ URL), followed by the jobs listed by its status page URLs whose
+// name matches w.Select, or all of them when it is empty. filters are set as
+// query parameters of the status pages. A job listed twice is kept once.
+func resolveWatchlist(w config.Watchlist, filters map[string]string) ([]*monitorEntry, error) {
+	var entries, fresh []*monitorEntry
+	var pages []string
+	for _, raw := range w.URLs {
+		u, err := url.Parse(raw)
+		if err != nil || !strings.HasPrefix(u.Path, "/view/") {
+			pages = append(pages, raw)
+			continue
+		}
+		meta, err := parser.ParseURL(raw)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, &monitorEntry{metadata: meta, host: u.Host, url: raw})
+	}
+
+	if len(pages) > 0 {
+		pages, err := withFilterParams(pages, filters)
+		if err != nil {
+			return nil, err
+		}
+		if fresh, err = fetchEntries(pages); err != nil {
+			return nil, err
+		}
+		if w.Select != "" {
+			fresh = matchingEntries(fresh, w.Select)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var resolved []*monitorEntry
+	for _, e := range append(entries, fresh...) {
+		if !seen[e.key()] {
+			seen[e.key()] = true
+			resolved = append(resolved, e)
+		}
+	}
+	if len(resolved) == 0 {
+		if w.Select != "" {
+			return nil, fmt.Errorf("no job matches %q", w.Select)
+		}
+		return nil, fmt.Errorf("no jobs found")
+	}
+	return resolved, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/clobrano/prow-helper/internal/config"
+	"github.com/clobrano/prow-helper/internal/httpclient"
+	"github.com/clobrano/prow-helper/internal/parser"
+)
+
+func TestResolveWatchlist(t *testing.T) {
+	origTransport := httpclient.Client.Transport
+	defer func() { httpclient.Client.Transport = origTransport }()
+	httpclient.Client.Transport = twoHostProw{}
+	origHosts := parser.AllowedHosts
+	defer func() { parser.AllowedHosts = origHosts }()
+	parser.AllowedHosts = []string{"prow.ci.openshift.org", "prow.example.com"}
+
+	tests := []struct {
+		name      string
+		watchlist config.Watchlist
+		filters   map[string]string
+		want      []string
+		wantErr   string
+	}{
+		{
+			name:      "job URLs",
+			watchlist: config.Watchlist{URLs: []string{jobURL("metal", "7"), jobURL("metal", "8")}},
+			want:      []string{"prow.ci.openshift.org metal/7", "prow.ci.openshift.org metal/8"},
+		},
+		{
+			name:      "status page with a select pattern",
+			watchlist: config.Watchlist{URLs: []string{"https://prow.ci.openshift.org/", "https://prow.example.com/"}, Select: "^e2e$"},
+			want:      []string{"prow.ci.openshift.org e2e/1", "prow.example.com e2e/2"},
+		},
+		{
+			name:      "status page without a select pattern",
+			watchlist: config.Watchlist{URLs: []string{"https://prow.ci.openshift.org/"}},
+			want:      []string{"prow.ci.openshift.org e2e/1", "prow.ci.openshift.org shared/3"},
+		},
+		{
+			name: "job URLs first, listed jobs once",
+			watchlist: config.Watchlist{
+				URLs:   []string{"https://prow.ci.openshift.org/", jobURL("shared", "3"), "https://prow.example.com/"},
+				Select: "shared",
+			},
+			want: []string{"prow.ci.openshift.org shared/3"},
+		},
+		{
+			name:      "filters apply to the status pages",
+			watchlist: config.Watchlist{URLs: []string{"https://prow.ci.openshift.org/", "https://prow.example.com/"}, Select: "e2e"},
+			filters:   map[string]string{"state": "success"},
+			want:      []string{"prow.example.com e2e/2"},
+		},
+		{
+			name:      "nothing matches",
+			watchlist: config.Watchlist{URLs: []string{"https://prow.ci.openshift.org/"}, Select: "upgrade"},
+			wantErr:   `no job matches "upgrade"`,
+		},
+		{
+			name:      "invalid job URL",
+			watchlist: config.Watchlist{URLs: []string{"https://prow.ci.openshift.org/view/gs/bucket"}},
+			wantErr:   "missing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := resolveWatchlist(tt.watchlist, tt.filters)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolveWatchlist() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveWatchlist() error = %v", err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.host+" "+e.metadata.JobName+"/"+e.metadata.BuildID)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("resolveWatchlist() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMonitorWatchlist_Unknown(t *testing.T) {
+	cfg := &config.Config{Watchlists: map[string]config.Watchlist{
+		"nightly-metal": {URLs: []string{jobURL("metal", "7")}},
+		"upgrades":      {URLs: []string{jobURL("upgrade", "8")}},
+	}}
+	err := monitorWatchlist(cfg, "nightly", nil)
+	if err == nil || !strings.Contains(err.Error(), "known: nightly-metal, upgrades") {
+		t.Errorf("monitorWatchlist() error = %v, want the known watchlists listed", err)
+	}
+}